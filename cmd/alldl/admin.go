@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/your-username/alldownloads/internal/auth"
+	"github.com/your-username/alldownloads/internal/jobs"
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+// backend carries out the admin commands, either directly against the
+// database and Redis or through a running API's admin endpoints.
+type backend interface {
+	Products(ctx context.Context) ([]store.Product, error)
+	// Versions includes hidden versions, as the admin API does.
+	Versions(ctx context.Context, productID string) ([]store.ProductVersion, error)
+	// Refresh queues a fetch of productIDs, or of every product when empty.
+	Refresh(ctx context.Context, productIDs []string) ([]store.FetchJob, error)
+	QueueStatus(ctx context.Context) (*queueStatus, error)
+	RequeueDead(ctx context.Context, stuckAfter time.Duration, dryRun bool) ([]store.FetchJob, error)
+	// CreateAPIKey returns the stored key and its plaintext, which is only
+	// ever available here.
+	CreateAPIKey(ctx context.Context, req keyRequest) (*store.APIKey, string, error)
+	Close()
+}
+
+// queueStatus has the shape of the API's GET /admin/queue response.
+type queueStatus struct {
+	Pending    int64             `json:"pending"`
+	Processing int64             `json:"processing"`
+	Workers    []workerHeartbeat `json:"workers"`
+}
+
+type workerHeartbeat struct {
+	ID            string    `json:"id"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+}
+
+type keyRequest struct {
+	Name               string     `json:"name"`
+	Scopes             []string   `json:"scopes"`
+	RateLimitPerMinute int        `json:"rate_limit_per_minute"`
+	ExpiresAt          *time.Time `json:"expires_at"`
+}
+
+// backendFlags choose the backend. Without an API URL the commands connect
+// to the database and Redis named by DB_URL and REDIS_URL.
+type backendFlags struct {
+	apiURL string
+	token  string
+	json   bool
+}
+
+func (f *backendFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.apiURL, "api", os.Getenv("ALLDL_API_URL"), "go through the API at this URL, e.g. https://downloads.example.com, instead of the database (default $ALLDL_API_URL)")
+	token := os.Getenv("ALLDL_TOKEN")
+	if token == "" {
+		token = os.Getenv("AUTH_TOKEN")
+	}
+	fs.StringVar(&f.token, "token", token, "admin API key or bootstrap token for --api (default $ALLDL_TOKEN, then $AUTH_TOKEN)")
+	fs.BoolVar(&f.json, "json", false, "print JSON instead of a table")
+}
+
+func (f *backendFlags) open(ctx context.Context) (backend, error) {
+	if f.apiURL != "" {
+		if f.token == "" {
+			return nil, fmt.Errorf("--api needs an admin token; set --token or ALLDL_TOKEN")
+		}
+		return newAPIBackend(f.apiURL, f.token), nil
+	}
+	return openDirectBackend(ctx)
+}
+
+// parseBackendArgs registers the backend flags on fs, parses args and
+// checks that there are between min and max positional arguments, max < 0
+// meaning no limit.
+func parseBackendArgs(fs *flag.FlagSet, flags *backendFlags, args []string, min, max int) ([]string, error) {
+	flags.register(fs)
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) < min || (max >= 0 && len(args) > max) {
+		fs.Usage()
+		return nil, exitCode(2)
+	}
+	return args, nil
+}
+
+func runProducts(ctx context.Context, args []string) error {
+	var flags backendFlags
+	if _, err := parseBackendArgs(newFlagSet("products"), &flags, args, 0, 0); err != nil {
+		return err
+	}
+
+	b, err := flags.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	products, err := b.Products(ctx)
+	if err != nil {
+		return err
+	}
+	if flags.json {
+		return printJSON(products)
+	}
+	printProducts(products)
+	return nil
+}
+
+func runVersions(ctx context.Context, args []string) error {
+	var flags backendFlags
+	args, err := parseBackendArgs(newFlagSet("versions"), &flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	b, err := flags.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	versions, err := b.Versions(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if flags.json {
+		return printJSON(versions)
+	}
+	printStoredVersions(versions)
+	return nil
+}
+
+func runRefresh(ctx context.Context, args []string) error {
+	var flags backendFlags
+	fs := newFlagSet("refresh")
+	all := fs.Bool("all", false, "refresh every product")
+	args, err := parseBackendArgs(fs, &flags, args, 0, -1)
+	if err != nil {
+		return err
+	}
+	// Refreshing everything by accident is expensive, so it has to be asked
+	// for explicitly.
+	if *all == (len(args) > 0) {
+		fs.Usage()
+		return exitCode(2)
+	}
+
+	b, err := flags.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	queued, err := b.Refresh(ctx, args)
+	if err != nil {
+		return err
+	}
+	if flags.json {
+		return printJSON(queued)
+	}
+	printJobs(queued)
+	fmt.Fprintf(os.Stderr, "%d jobs queued\n", len(queued))
+	return nil
+}
+
+func runQueue(ctx context.Context, args []string) error {
+	var flags backendFlags
+	if _, err := parseBackendArgs(newFlagSet("queue"), &flags, args, 0, 0); err != nil {
+		return err
+	}
+
+	b, err := flags.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	status, err := b.QueueStatus(ctx)
+	if err != nil {
+		return err
+	}
+	if flags.json {
+		return printJSON(status)
+	}
+	printQueueStatus(status)
+	return nil
+}
+
+func runRequeueDead(ctx context.Context, args []string) error {
+	var flags backendFlags
+	fs := newFlagSet("requeue-dead")
+	stuckAfter := fs.Duration("stuck-after", jobs.DefaultStuckAfter, "treat running jobs started longer ago than this as abandoned")
+	dryRun := fs.Bool("dry-run", false, "list the dead jobs without requeueing them")
+	if _, err := parseBackendArgs(fs, &flags, args, 0, 0); err != nil {
+		return err
+	}
+	if *stuckAfter <= 0 {
+		return fmt.Errorf("--stuck-after must be positive")
+	}
+
+	b, err := flags.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	dead, err := b.RequeueDead(ctx, *stuckAfter, *dryRun)
+	if err != nil {
+		return err
+	}
+	if flags.json {
+		return printJSON(dead)
+	}
+	printJobs(dead)
+	if *dryRun {
+		fmt.Fprintf(os.Stderr, "%d dead jobs\n", len(dead))
+	} else {
+		fmt.Fprintf(os.Stderr, "%d jobs requeued\n", len(dead))
+	}
+	return nil
+}
+
+func runCreateKey(ctx context.Context, args []string) error {
+	var flags backendFlags
+	fs := newFlagSet("create-key")
+	scopes := fs.String("scopes", auth.ScopeRead, "comma-separated scopes: "+strings.Join(auth.Scopes, ", "))
+	rateLimit := fs.Int("rate-limit", 0, "requests per minute, 0 for the default")
+	expiresIn := fs.Duration("expires-in", 0, "expire the key after this long, 0 for never")
+	args, err := parseBackendArgs(fs, &flags, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	req := keyRequest{Name: args[0], RateLimitPerMinute: *rateLimit}
+	for _, scope := range strings.Split(*scopes, ",") {
+		scope = strings.TrimSpace(scope)
+		if !auth.ValidScope(scope) {
+			return fmt.Errorf("unknown scope %q; scopes are %s", scope, strings.Join(auth.Scopes, ", "))
+		}
+		req.Scopes = append(req.Scopes, scope)
+	}
+	if *rateLimit < 0 {
+		return fmt.Errorf("--rate-limit must not be negative")
+	}
+	if *expiresIn < 0 {
+		return fmt.Errorf("--expires-in must not be negative")
+	}
+	if *expiresIn > 0 {
+		expiresAt := time.Now().Add(*expiresIn).UTC()
+		req.ExpiresAt = &expiresAt
+	}
+
+	b, err := flags.open(ctx)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+
+	key, plaintext, err := b.CreateAPIKey(ctx, req)
+	if err != nil {
+		return err
+	}
+	if flags.json {
+		return printJSON(struct {
+			*store.APIKey
+			Key string `json:"key"`
+		}{key, plaintext})
+	}
+
+	fmt.Fprintf(os.Stderr, "created key %s (%s) with scopes %s\n", key.ID, key.Name, strings.Join(key.Scopes, ", "))
+	fmt.Fprintln(os.Stderr, "store it now; it cannot be shown again:")
+	fmt.Println(plaintext)
+	return nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/your-username/alldownloads/internal/problem"
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+// apiBackend goes through a running API's admin endpoints, so that it needs
+// nothing but an admin token and leaves the usual trail in the audit log.
+type apiBackend struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// apiPath is where the API mounts the current version; see cmd/api.
+const apiPath = "/api/v1"
+
+func newAPIBackend(baseURL, token string) *apiBackend {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	if !strings.HasSuffix(baseURL, apiPath) {
+		baseURL += apiPath
+	}
+	return &apiBackend{baseURL: baseURL, token: token, client: &http.Client{Timeout: time.Minute}}
+}
+
+func (b *apiBackend) Close() {}
+
+// do sends a request and decodes the JSON response into out. Error
+// responses are problem documents, which are turned into errors.
+func (b *apiBackend) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	u := b.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return responseError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response from %s %s: %w", method, path, err)
+	}
+	return nil
+}
+
+func responseError(resp *http.Response) error {
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	var p problem.Problem
+	if json.Unmarshal(data, &p) != nil || p.Title == "" {
+		return fmt.Errorf("%s %s: %s", resp.Request.Method, resp.Request.URL.Path, resp.Status)
+	}
+
+	msg := p.Title
+	if p.Detail != "" {
+		msg += ": " + p.Detail
+	}
+	for _, fe := range p.Errors {
+		msg += fmt.Sprintf("; %s %s", fe.Field, fe.Message)
+	}
+	return fmt.Errorf("%s (HTTP %d)", msg, p.Status)
+}
+
+func (b *apiBackend) Products(ctx context.Context) ([]store.Product, error) {
+	query := url.Values{"limit": {strconv.Itoa(store.MaxPageSize)}}
+
+	var products []store.Product
+	for {
+		var page store.ProductPage
+		if err := b.do(ctx, http.MethodGet, "/products", query, nil, &page); err != nil {
+			return nil, err
+		}
+		products = append(products, page.Products...)
+		if page.NextCursor == "" {
+			return products, nil
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+}
+
+func (b *apiBackend) Versions(ctx context.Context, productID string) ([]store.ProductVersion, error) {
+	path := "/admin/products/" + url.PathEscape(productID) + "/versions"
+	query := url.Values{"limit": {strconv.Itoa(store.MaxPageSize)}}
+
+	var versions []store.ProductVersion
+	for {
+		var page store.VersionPage
+		if err := b.do(ctx, http.MethodGet, path, query, nil, &page); err != nil {
+			return nil, err
+		}
+		versions = append(versions, page.Versions...)
+		if page.NextCursor == "" {
+			return versions, nil
+		}
+		query.Set("cursor", page.NextCursor)
+	}
+}
+
+// refreshMutation is used rather than POST /refresh because it takes a list
+// of products and returns the queued jobs.
+const refreshMutation = `mutation Refresh($productIds: [ID!]) {
+  refresh(productIds: $productIds) { id status error createdAt updatedAt product { id } }
+}`
+
+func (b *apiBackend) Refresh(ctx context.Context, productIDs []string) ([]store.FetchJob, error) {
+	variables := map[string]interface{}{}
+	if len(productIDs) > 0 {
+		variables["productIds"] = productIDs
+	}
+
+	var resp struct {
+		Data struct {
+			Refresh []struct {
+				ID        string    `json:"id"`
+				Status    string    `json:"status"`
+				Error     string    `json:"error"`
+				CreatedAt time.Time `json:"createdAt"`
+				UpdatedAt time.Time `json:"updatedAt"`
+				Product   *struct {
+					ID string `json:"id"`
+				} `json:"product"`
+			} `json:"refresh"`
+		} `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	body := map[string]interface{}{"query": refreshMutation, "variables": variables}
+	if err := b.do(ctx, http.MethodPost, "/graphql", nil, body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Errors) > 0 {
+		return nil, fmt.Errorf("refresh failed: %s", resp.Errors[0].Message)
+	}
+
+	queued := []store.FetchJob{}
+	for _, j := range resp.Data.Refresh {
+		job := store.FetchJob{ID: j.ID, Status: j.Status, Error: j.Error, CreatedAt: j.CreatedAt, UpdatedAt: j.UpdatedAt}
+		if j.Product != nil {
+			job.ProductID = j.Product.ID
+		}
+		queued = append(queued, job)
+	}
+	return queued, nil
+}
+
+func (b *apiBackend) QueueStatus(ctx context.Context) (*queueStatus, error) {
+	var status queueStatus
+	if err := b.do(ctx, http.MethodGet, "/admin/queue", nil, nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+func (b *apiBackend) RequeueDead(ctx context.Context, stuckAfter time.Duration, dryRun bool) ([]store.FetchJob, error) {
+	query := url.Values{
+		"stuck_after": {stuckAfter.String()},
+		"dry_run":     {strconv.FormatBool(dryRun)},
+	}
+
+	var result struct {
+		Jobs []store.FetchJob `json:"jobs"`
+	}
+	if err := b.do(ctx, http.MethodPost, "/admin/queue/requeue-dead", query, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Jobs, nil
+}
+
+func (b *apiBackend) CreateAPIKey(ctx context.Context, req keyRequest) (*store.APIKey, string, error) {
+	var created struct {
+		store.APIKey
+		Key string `json:"key"`
+	}
+	if err := b.do(ctx, http.MethodPost, "/admin/keys", nil, req, &created); err != nil {
+		return nil, "", err
+	}
+	return &created.APIKey, created.Key, nil
+}
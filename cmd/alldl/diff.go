@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+// versionDiff is what storing a fetch would change for one product.
+type versionDiff struct {
+	ProductID string                  `json:"product_id"`
+	Fetcher   string                  `json:"fetcher"`
+	Added     []*store.ProductVersion `json:"added"`
+	Changed   []versionChange         `json:"changed"`
+	// Missing are fetched versions stored earlier that the fetcher no longer
+	// returns. The worker keeps them.
+	Missing []store.ProductVersion `json:"missing"`
+}
+
+type versionChange struct {
+	Version      string `json:"version"`
+	Platform     string `json:"platform"`
+	Architecture string `json:"architecture"`
+	// Manual changes are not applied: the worker leaves versions an admin
+	// created or edited alone.
+	Manual bool                   `json:"manual"`
+	Fields map[string]fieldChange `json:"fields"`
+}
+
+type fieldChange struct {
+	Stored  string `json:"stored"`
+	Fetched string `json:"fetched"`
+}
+
+func (d versionDiff) empty() bool {
+	return len(d.Added) == 0 && len(d.Changed) == 0 && len(d.Missing) == 0
+}
+
+func runDiff(ctx context.Context, args []string) error {
+	var flags fetchFlags
+	fs := newFlagSet("diff")
+	flags.register(fs)
+
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	st, err := openStore()
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	product, err := st.GetProduct(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	if product == nil {
+		return fmt.Errorf("no product %q", args[0])
+	}
+
+	stored, err := storedVersions(ctx, st, product.ID)
+	if err != nil {
+		return err
+	}
+
+	fetched, err := fetch(ctx, flags, product.ID, productFetcher(product))
+	if err != nil {
+		return err
+	}
+
+	diff := diffVersions(stored, fetched)
+	diff.ProductID = product.ID
+	diff.Fetcher = productFetcher(product)
+
+	if flags.json {
+		if err := printJSON(diff); err != nil {
+			return err
+		}
+	} else {
+		printDiff(diff)
+	}
+
+	// Like diff(1), report differences through the exit status.
+	if !diff.empty() {
+		return exitCode(1)
+	}
+	return nil
+}
+
+// storedVersions returns every version of productID, including hidden ones,
+// since the worker updates those too.
+func storedVersions(ctx context.Context, st store.Store, productID string) ([]store.ProductVersion, error) {
+	filter := store.VersionFilter{ProductID: productID, IncludeHidden: true, Limit: store.MaxPageSize}
+
+	var versions []store.ProductVersion
+	for {
+		page, err := st.ListVersions(ctx, filter)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, page.Versions...)
+		if page.NextCursor == "" {
+			return versions, nil
+		}
+		filter.Cursor = page.NextCursor
+	}
+}
+
+// diffVersions matches versions on the store's upsert key and compares the
+// fields a fetch overwrites.
+func diffVersions(stored []store.ProductVersion, fetched []*store.ProductVersion) versionDiff {
+	diff := versionDiff{
+		Added:   []*store.ProductVersion{},
+		Changed: []versionChange{},
+		Missing: []store.ProductVersion{},
+	}
+
+	byKey := make(map[string]store.ProductVersion, len(stored))
+	for _, v := range stored {
+		byKey[versionKey(v)] = v
+	}
+
+	seen := make(map[string]bool, len(fetched))
+	for _, f := range fetched {
+		key := versionKey(*f)
+		seen[key] = true
+
+		s, ok := byKey[key]
+		if !ok {
+			diff.Added = append(diff.Added, f)
+			continue
+		}
+
+		fields := map[string]fieldChange{}
+		compare := func(name, stored, fetched string) {
+			if stored != fetched {
+				fields[name] = fieldChange{Stored: stored, Fetched: fetched}
+			}
+		}
+		compare("channel", s.Channel, f.Channel)
+		compare("download_url", s.DownloadURL, f.DownloadURL)
+		compare("checksum", s.Checksum, f.Checksum)
+		compare("checksum_type", s.ChecksumType, f.ChecksumType)
+		compare("file_size", strconv.FormatInt(s.FileSize, 10), strconv.FormatInt(f.FileSize, 10))
+		compare("filename", s.Filename, f.Filename)
+
+		if len(fields) > 0 {
+			diff.Changed = append(diff.Changed, versionChange{
+				Version:      f.Version,
+				Platform:     f.Platform,
+				Architecture: f.Architecture,
+				Manual:       s.Source == store.VersionSourceManual,
+				Fields:       fields,
+			})
+		}
+	}
+
+	for _, v := range stored {
+		if !seen[versionKey(v)] && v.Source != store.VersionSourceManual {
+			diff.Missing = append(diff.Missing, v)
+		}
+	}
+
+	return diff
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/user"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/your-username/alldownloads/internal/api"
+	"github.com/your-username/alldownloads/internal/auth"
+	"github.com/your-username/alldownloads/internal/config"
+	"github.com/your-username/alldownloads/internal/events"
+	"github.com/your-username/alldownloads/internal/jobs"
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+// directBackend works on the database and Redis the way the API handlers
+// do, so that it can be used when the API is down.
+type directBackend struct {
+	st    store.Store
+	queue *jobs.Queue
+	bus   *events.Bus
+	// actor identifies the operator in the audit log, since there is no
+	// authenticated principal.
+	actor string
+}
+
+func openDirectBackend(ctx context.Context) (*directBackend, error) {
+	cfg := config.Load()
+
+	st, err := openStore()
+	if err != nil {
+		return nil, err
+	}
+
+	queue, err := jobs.NewQueue(cfg.RedisURL, zap.NewNop())
+	if err != nil {
+		st.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+	if err := queue.Ping(ctx); err != nil {
+		st.Close()
+		queue.Close()
+		return nil, fmt.Errorf("failed to connect to Redis: %w", err)
+	}
+
+	bus, err := events.NewBus(cfg.RedisURL, zap.NewNop())
+	if err != nil {
+		st.Close()
+		queue.Close()
+		return nil, err
+	}
+
+	actor := "unknown"
+	if u, err := user.Current(); err == nil {
+		actor = u.Username
+	}
+	if host, err := os.Hostname(); err == nil {
+		actor += "@" + host
+	}
+
+	return &directBackend{st: st, queue: queue, bus: bus, actor: actor}, nil
+}
+
+func (b *directBackend) Close() {
+	b.bus.Close()
+	b.queue.Close()
+	b.st.Close()
+}
+
+func (b *directBackend) Products(ctx context.Context) ([]store.Product, error) {
+	return b.st.GetProducts(ctx)
+}
+
+func (b *directBackend) Versions(ctx context.Context, productID string) ([]store.ProductVersion, error) {
+	product, err := b.st.GetProduct(ctx, productID)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, fmt.Errorf("no product %q", productID)
+	}
+	return storedVersions(ctx, b.st, productID)
+}
+
+func (b *directBackend) Refresh(ctx context.Context, productIDs []string) ([]store.FetchJob, error) {
+	target := "*"
+	if len(productIDs) == 0 {
+		products, err := b.st.GetProducts(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range products {
+			if p.Scheduled() {
+				productIDs = append(productIDs, p.ID)
+			}
+		}
+	} else {
+		products, err := b.st.GetProductsByIDs(ctx, productIDs)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range productIDs {
+			if products[id] == nil {
+				return nil, fmt.Errorf("no product %q", id)
+			}
+		}
+		if len(productIDs) == 1 {
+			target = productIDs[0]
+		}
+	}
+
+	queued := []store.FetchJob{}
+	var jobIDs []string
+	for _, productID := range productIDs {
+		job := &store.FetchJob{ProductID: productID, Status: store.JobStatusPending}
+		if err := b.st.CreateFetchJob(ctx, job); err != nil {
+			return queued, err
+		}
+		if err := b.queue.Enqueue(ctx, job.ID); err != nil {
+			return queued, fmt.Errorf("failed to enqueue job %s: %w", job.ID, err)
+		}
+		b.bus.Publish(ctx, events.Event{Type: events.JobQueued, JobID: job.ID, ProductID: productID})
+		queued = append(queued, *job)
+		jobIDs = append(jobIDs, job.ID)
+	}
+
+	b.audit(ctx, api.AuditActionRefresh, "product", target, map[string]interface{}{"jobs_queued": len(queued), "job_ids": jobIDs})
+	return queued, nil
+}
+
+func (b *directBackend) QueueStatus(ctx context.Context) (*queueStatus, error) {
+	var status queueStatus
+	var err error
+	if status.Pending, err = b.queue.GetQueueLength(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get queue length: %w", err)
+	}
+	if status.Processing, err = b.queue.GetProcessingCount(ctx); err != nil {
+		return nil, fmt.Errorf("failed to get processing count: %w", err)
+	}
+
+	heartbeats, err := b.queue.GetHeartbeats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get worker heartbeats: %w", err)
+	}
+	for id, at := range heartbeats {
+		status.Workers = append(status.Workers, workerHeartbeat{ID: id, LastHeartbeat: at.UTC()})
+	}
+	sort.Slice(status.Workers, func(i, j int) bool { return status.Workers[i].ID < status.Workers[j].ID })
+	return &status, nil
+}
+
+func (b *directBackend) RequeueDead(ctx context.Context, stuckAfter time.Duration, dryRun bool) ([]store.FetchJob, error) {
+	dead, err := jobs.FindDeadJobs(ctx, b.st, b.queue, stuckAfter)
+	if err != nil || dryRun {
+		return dead, err
+	}
+
+	requeued := []store.FetchJob{}
+	var jobIDs []string
+	for i := range dead {
+		job := &dead[i]
+		if err := jobs.RequeueJob(ctx, b.st, b.queue, job); err != nil {
+			return requeued, err
+		}
+		b.bus.Publish(ctx, events.Event{Type: events.JobQueued, JobID: job.ID, ProductID: job.ProductID})
+		requeued = append(requeued, *job)
+		jobIDs = append(jobIDs, job.ID)
+	}
+
+	if len(requeued) > 0 {
+		b.audit(ctx, api.AuditActionJobRequeue, "fetch_job", "*", map[string]interface{}{"jobs_requeued": len(requeued), "job_ids": jobIDs})
+	}
+	return requeued, nil
+}
+
+func (b *directBackend) CreateAPIKey(ctx context.Context, req keyRequest) (*store.APIKey, string, error) {
+	plaintext, prefix, hash, err := auth.GenerateKey()
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := &store.APIKey{
+		Name:               req.Name,
+		Prefix:             prefix,
+		KeyHash:            hash,
+		Scopes:             req.Scopes,
+		RateLimitPerMinute: req.RateLimitPerMinute,
+		ExpiresAt:          req.ExpiresAt,
+	}
+	if err := b.st.CreateAPIKey(ctx, key); err != nil {
+		return nil, "", err
+	}
+
+	b.audit(ctx, api.AuditActionAPIKeyCreate, "api_key", key.ID, map[string]interface{}{
+		"id":                    key.ID,
+		"name":                  key.Name,
+		"prefix":                key.Prefix,
+		"scopes":                key.Scopes,
+		"rate_limit_per_minute": key.RateLimitPerMinute,
+		"expires_at":            key.ExpiresAt,
+	})
+	return key, plaintext, nil
+}
+
+// audit records a change in the same log the API writes, attributed to the
+// operator running the command. Like the API, it warns rather than fails
+// since the change has already been made.
+func (b *directBackend) audit(ctx context.Context, action, targetType, targetID string, after map[string]interface{}) {
+	changes := make(map[string]store.AuditChange, len(after))
+	for field, value := range after {
+		changes[field] = store.AuditChange{After: value}
+	}
+
+	entry := &store.AuditEntry{
+		ActorType:  "cli",
+		ActorID:    b.actor,
+		ActorName:  "alldl",
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Changes:    changes,
+	}
+	if err := b.st.CreateAuditEntry(ctx, entry); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: failed to write audit entry: %v\n", err)
+	}
+}
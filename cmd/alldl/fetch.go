@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/your-username/alldownloads/internal/sources"
+	"github.com/your-username/alldownloads/internal/sources/sourcestest"
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+// fetchFlags are shared by fetch and diff.
+type fetchFlags struct {
+	json        bool
+	fixtures    bool
+	fixturesDir string
+	timeout     time.Duration
+}
+
+func (f *fetchFlags) register(fs *flag.FlagSet) {
+	fs.BoolVar(&f.json, "json", false, "print JSON instead of a table")
+	fs.BoolVar(&f.fixtures, "fixtures", false, "replay recorded vendor responses instead of fetching from the vendor sites")
+	fs.StringVar(&f.fixturesDir, "fixtures-dir", filepath.Join("internal", "sources", "testdata"), "directory holding a fixture directory per fetcher")
+	fs.DurationVar(&f.timeout, "timeout", 2*time.Minute, "give up on the fetch after this long")
+}
+
+func runFetch(ctx context.Context, args []string) error {
+	var flags fetchFlags
+	fs := newFlagSet("fetch")
+	flags.register(fs)
+
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	if err := exactArgs(fs, args, 1); err != nil {
+		return err
+	}
+
+	productID, fetcherName, err := resolveFetcher(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	versions, err := fetch(ctx, flags, productID, fetcherName)
+	if err != nil {
+		return err
+	}
+
+	if flags.json {
+		return printJSON(versions)
+	}
+	printVersions(versions)
+	return nil
+}
+
+// resolveFetcher maps a product to the fetcher it uses. Products named after
+// their fetcher resolve without a database, so that a fetcher can be tried
+// on a machine that only has the source tree.
+func resolveFetcher(ctx context.Context, product string) (productID, fetcherName string, err error) {
+	if sources.IsRegistered(product) {
+		return product, product, nil
+	}
+
+	st, err := openStore()
+	if err != nil {
+		return "", "", fmt.Errorf("%q is not a fetcher name, and looking it up as a product failed: %w", product, err)
+	}
+	defer st.Close()
+
+	p, err := st.GetProduct(ctx, product)
+	if err != nil {
+		return "", "", err
+	}
+	if p == nil {
+		return "", "", fmt.Errorf("no product or fetcher named %q", product)
+	}
+	return p.ID, productFetcher(p), nil
+}
+
+// productFetcher mirrors the worker: products without a fetcher use the one
+// named after them.
+func productFetcher(p *store.Product) string {
+	if p.Fetcher != "" {
+		return p.Fetcher
+	}
+	return p.ID
+}
+
+// fetch runs the named fetcher for productID, against the fixtures when
+// asked to, and returns its versions sorted for display.
+func fetch(ctx context.Context, flags fetchFlags, productID, fetcherName string) ([]*store.ProductVersion, error) {
+	ctx, cancel := context.WithTimeout(ctx, flags.timeout)
+	defer cancel()
+
+	var opts []sources.Option
+	var replayURL string
+	if flags.fixtures {
+		dir := filepath.Join(flags.fixturesDir, fetcherName)
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("no fixtures recorded for %s: %w", fetcherName, err)
+		}
+
+		srv := sourcestest.Replay(dir)
+		defer func() {
+			for _, request := range srv.Missing() {
+				fmt.Fprintf(os.Stderr, "warning: no fixture for %s\n", request)
+			}
+			srv.Close()
+		}()
+		opts = append(opts, sources.WithBaseURL(srv.URL), sources.WithTransport(srv.Transport()))
+		replayURL = srv.URL
+	}
+
+	fetcher, ok := sources.NewFetcher(fetcherName, opts...)
+	if !ok {
+		return nil, fmt.Errorf("no fetcher %q; available fetchers are %v", fetcherName, sources.Names())
+	}
+
+	versions, err := fetcher.Fetch(sources.WithProductID(ctx, productID))
+	if err != nil {
+		return nil, fmt.Errorf("fetcher %s failed: %w", fetcherName, err)
+	}
+
+	for _, v := range versions {
+		v.ProductID = productID
+		// Show the vendor's URLs rather than the replay server's, which
+		// also keeps diff --fixtures comparable with the stored versions.
+		if replayURL != "" && strings.HasPrefix(v.DownloadURL, replayURL) {
+			v.DownloadURL = sources.BaseURL(fetcherName) + strings.TrimPrefix(v.DownloadURL, replayURL)
+		}
+		if v.Channel == "" {
+			v.Channel = store.ChannelStable
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool {
+		return versionKey(*versions[i]) < versionKey(*versions[j])
+	})
+	return versions, nil
+}
+
+// versionKey identifies a version the way the store's upsert does.
+func versionKey(v store.ProductVersion) string {
+	return v.Platform + "/" + v.Architecture + " " + v.Version
+}
@@ -0,0 +1,163 @@
+// Command alldl runs AllDownloads tasks from a terminal. It reads the same
+// environment, and .env file, as the API and worker.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/joho/godotenv"
+
+	"github.com/your-username/alldownloads/internal/config"
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     func(ctx context.Context, args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"fetch", "[flags] <product>", "run a fetcher and print the versions it finds", runFetch},
+		{"diff", "[flags] <product>", "compare a fetcher's output with the stored versions", runDiff},
+		{"products", "[flags]", "list the products", runProducts},
+		{"versions", "[flags] <product>", "list a product's stored versions, hidden ones included", runVersions},
+		{"refresh", "[flags] --all | <product>...", "queue a fetch of some or all products", runRefresh},
+		{"queue", "[flags]", "show the queue length, jobs in progress and live workers", runQueue},
+		{"requeue-dead", "[flags]", "queue again the jobs that failed or whose worker died", runRequeueDead},
+		{"create-key", "[flags] <name>", "create an API key and print it", runCreateKey},
+		{"migrate", "[flags] [up | down [n] | status | force <version>]", "apply, revert or inspect database migrations", runMigrate},
+	}
+}
+
+// exitCode ends the program with a status but no message, for commands such
+// as diff whose output already explains the result.
+type exitCode int
+
+func (c exitCode) Error() string {
+	return fmt.Sprintf("exit status %d", int(c))
+}
+
+func main() {
+	// Like the API and worker, fall back to the environment without a .env.
+	_ = godotenv.Load(".env")
+
+	if len(os.Args) < 2 || os.Args[1] == "help" || os.Args[1] == "-h" || os.Args[1] == "--help" {
+		usage()
+		if len(os.Args) < 2 {
+			os.Exit(2)
+		}
+		return
+	}
+
+	cmd, ok := lookupCommand(os.Args[1])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "alldl: unknown command %q\n\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	err := cmd.run(ctx, os.Args[2:])
+	stop()
+
+	var code exitCode
+	switch {
+	case err == nil:
+	case errors.As(err, &code):
+		os.Exit(int(code))
+	case errors.Is(err, flag.ErrHelp):
+		os.Exit(2)
+	default:
+		fmt.Fprintf(os.Stderr, "alldl %s: %v\n", cmd.name, err)
+		os.Exit(1)
+	}
+}
+
+func lookupCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+	}
+	return command{}, false
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: alldl <command> [arguments]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-13s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, `Run "alldl <command> -h" for a command's flags. The admin commands`)
+	fmt.Fprintln(os.Stderr, `connect to the database and Redis, or with --api to a running API.`)
+}
+
+// newFlagSet returns a flag set for cmd whose usage lists its arguments.
+func newFlagSet(name string) *flag.FlagSet {
+	cmd, _ := lookupCommand(name)
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: alldl %s %s\n\n%s.\n\nFlags:\n", cmd.name, cmd.args, capitalize(cmd.summary))
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses flags wherever they appear among args, so that both
+// "alldl fetch --json ubuntu" and "alldl fetch ubuntu --json" work, and
+// returns the positional arguments.
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// exactArgs checks that exactly n positional arguments were given.
+func exactArgs(fs *flag.FlagSet, args []string, n int) error {
+	if len(args) != n {
+		fs.Usage()
+		return exitCode(2)
+	}
+	return nil
+}
+
+func openStore() (store.Store, error) {
+	cfg := config.Load()
+
+	st, err := store.Open(cfg.DatabaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return st, nil
+}
+
+func capitalize(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToUpper(s[:1]) + s[1:]
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/your-username/alldownloads/internal/config"
+	"github.com/your-username/alldownloads/internal/migrate"
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+func runMigrate(ctx context.Context, args []string) error {
+	fs := newFlagSet("migrate")
+	dir := fs.String("dir", "", "apply the migrations in this directory instead of those built into alldl")
+
+	args, err := parseArgs(fs, args)
+	if err != nil {
+		return err
+	}
+	action := "up"
+	if len(args) > 0 {
+		action, args = args[0], args[1:]
+	}
+
+	// The optional count for down and the version for force.
+	var n int64
+	switch {
+	case action == "down" && len(args) == 0:
+		n = 1
+	case (action == "down" || action == "force") && len(args) == 1:
+		if n, err = strconv.ParseInt(args[0], 10, 64); err != nil || n < 0 || (action == "down" && n == 0) {
+			return fmt.Errorf("invalid %s argument %q", action, args[0])
+		}
+	case (action == "up" || action == "status") && len(args) == 0:
+	default:
+		fs.Usage()
+		return exitCode(2)
+	}
+
+	databaseURL := config.Load().DatabaseURL
+	if store.IsSQLiteURL(databaseURL) {
+		return errors.New("migrations apply to Postgres; SQLite databases get their schema when the store opens them")
+	}
+
+	migrations, err := migrate.Embedded()
+	if *dir != "" {
+		migrations, err = migrate.Load(os.DirFS(*dir))
+	}
+	if err != nil {
+		return err
+	}
+
+	// Migrations change the schema under the API, so they always connect
+	// directly rather than through it.
+	m, err := migrate.Open(ctx, databaseURL, migrations, newCLILogger())
+	if err != nil {
+		return err
+	}
+	defer m.Close(context.Background())
+
+	switch action {
+	case "status":
+		return printMigrationStatus(ctx, m)
+	case "down":
+		_, err = m.Down(ctx, int(n))
+	case "force":
+		err = m.Force(ctx, n)
+	default:
+		_, err = m.Up(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	version, _, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("schema version %d\n", version)
+	return nil
+}
+
+func printMigrationStatus(ctx context.Context, m *migrate.Migrator) error {
+	version, dirty, err := m.Version(ctx)
+	if err != nil {
+		return err
+	}
+	state := ""
+	if dirty {
+		state = " (dirty)"
+	}
+	fmt.Printf("schema version %d%s, this binary knows up to %d\n", version, state, m.Latest())
+
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	for _, migration := range pending {
+		fmt.Printf("pending %s\n", migration)
+	}
+	return nil
+}
+
+// newCLILogger logs the migrator's progress as plain lines on stderr.
+func newCLILogger() *zap.Logger {
+	encoder := zapcore.NewConsoleEncoder(zapcore.EncoderConfig{
+		MessageKey:  "msg",
+		LevelKey:    "level",
+		EncodeLevel: zapcore.LowercaseLevelEncoder,
+	})
+	return zap.New(zapcore.NewCore(encoder, zapcore.Lock(os.Stderr), zap.InfoLevel))
+}
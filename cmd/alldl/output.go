@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func newTable() *tabwriter.Writer {
+	return tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+}
+
+func printVersions(versions []*store.ProductVersion) {
+	if len(versions) == 0 {
+		fmt.Fprintln(os.Stderr, "no versions found")
+		return
+	}
+
+	w := newTable()
+	fmt.Fprintln(w, "PLATFORM\tARCH\tVERSION\tCHANNEL\tLATEST\tSIZE\tCHECKSUM\tDOWNLOAD URL")
+	for _, v := range versions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\t%s\t%s\n",
+			v.Platform, v.Architecture, v.Version, v.Channel, v.IsLatest,
+			formatSize(v.FileSize), shortChecksum(v.Checksum), v.DownloadURL)
+	}
+	w.Flush()
+
+	fmt.Fprintf(os.Stderr, "%d versions\n", len(versions))
+}
+
+func printDiff(diff versionDiff) {
+	for _, v := range diff.Added {
+		fmt.Printf("+ %s  %s\n", versionKey(*v), v.DownloadURL)
+	}
+	for _, c := range diff.Changed {
+		note := ""
+		if c.Manual {
+			note = "  (manual, not overwritten)"
+		}
+		fmt.Printf("~ %s/%s %s%s\n", c.Platform, c.Architecture, c.Version, note)
+
+		names := make([]string, 0, len(c.Fields))
+		for name := range c.Fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Printf("    %s: %q -> %q\n", name, c.Fields[name].Stored, c.Fields[name].Fetched)
+		}
+	}
+	for _, v := range diff.Missing {
+		fmt.Printf("- %s  (no longer returned, kept by the worker)\n", versionKey(v))
+	}
+
+	fmt.Fprintf(os.Stderr, "%s via %s: %d added, %d changed, %d no longer returned\n",
+		diff.ProductID, diff.Fetcher, len(diff.Added), len(diff.Changed), len(diff.Missing))
+}
+
+func printProducts(products []store.Product) {
+	if len(products) == 0 {
+		fmt.Fprintln(os.Stderr, "no products")
+		return
+	}
+
+	w := newTable()
+	fmt.Fprintln(w, "ID\tNAME\tVENDOR\tCATEGORY\tFETCHER\tLAST SUCCESS\tSTALE")
+	for _, p := range products {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%t\n",
+			p.ID, p.Name, p.Vendor, p.Category, productFetcher(&p), formatTime(p.LastSuccessAt), p.Stale)
+	}
+	w.Flush()
+
+	fmt.Fprintf(os.Stderr, "%d products\n", len(products))
+}
+
+// printStoredVersions differs from printVersions in showing the flags only
+// stored versions have.
+func printStoredVersions(versions []store.ProductVersion) {
+	if len(versions) == 0 {
+		fmt.Fprintln(os.Stderr, "no versions")
+		return
+	}
+
+	w := newTable()
+	fmt.Fprintln(w, "PLATFORM\tARCH\tVERSION\tCHANNEL\tLATEST\tSOURCE\tFLAGS\tSIZE\tLAST FETCHED")
+	for _, v := range versions {
+		var flags []string
+		if v.Pinned {
+			flags = append(flags, "pinned")
+		}
+		if v.Hidden {
+			flags = append(flags, "hidden")
+		}
+		if len(flags) == 0 {
+			flags = append(flags, "-")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\t%s\t%s\t%s\n",
+			v.Platform, v.Architecture, v.Version, v.Channel, v.IsLatest, v.Source,
+			strings.Join(flags, ","), formatSize(v.FileSize), formatTime(&v.LastFetched))
+	}
+	w.Flush()
+
+	fmt.Fprintf(os.Stderr, "%d versions\n", len(versions))
+}
+
+func printJobs(jobs []store.FetchJob) {
+	if len(jobs) == 0 {
+		return
+	}
+
+	w := newTable()
+	fmt.Fprintln(w, "JOB\tPRODUCT\tSTATUS\tCREATED\tERROR")
+	for _, j := range jobs {
+		errMsg := j.Error
+		if errMsg == "" {
+			errMsg = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", j.ID, j.ProductID, j.Status, formatTime(&j.CreatedAt), errMsg)
+	}
+	w.Flush()
+}
+
+func printQueueStatus(status *queueStatus) {
+	fmt.Printf("pending:    %d\n", status.Pending)
+	fmt.Printf("processing: %d\n", status.Processing)
+	if len(status.Workers) == 0 {
+		fmt.Println("workers:    none")
+		return
+	}
+
+	fmt.Println()
+	w := newTable()
+	fmt.Fprintln(w, "WORKER\tLAST HEARTBEAT")
+	for _, worker := range status.Workers {
+		fmt.Fprintf(w, "%s\t%s ago\n", worker.ID, time.Since(worker.LastHeartbeat).Round(time.Second))
+	}
+	w.Flush()
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil || t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04")
+}
+
+func formatSize(bytes int64) string {
+	if bytes <= 0 {
+		return "-"
+	}
+
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+func shortChecksum(checksum string) string {
+	if checksum == "" {
+		return "-"
+	}
+	if len(checksum) > 12 {
+		return checksum[:12] + "…"
+	}
+	return checksum
+}
@@ -2,25 +2,40 @@ package main
 
 import (
 	"context"
+	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus"
 	"go.uber.org/zap"
 
 	"github.com/your-username/alldownloads/internal/api"
+	"github.com/your-username/alldownloads/internal/auth"
+	"github.com/your-username/alldownloads/internal/cache"
 	"github.com/your-username/alldownloads/internal/config"
+	"github.com/your-username/alldownloads/internal/events"
 	"github.com/your-username/alldownloads/internal/jobs"
 	"github.com/your-username/alldownloads/internal/middleware"
+	"github.com/your-username/alldownloads/internal/migrate"
+	"github.com/your-username/alldownloads/internal/ratelimit"
 	"github.com/your-username/alldownloads/internal/store"
+	"github.com/your-username/alldownloads/internal/tracing"
+	"github.com/your-username/alldownloads/internal/version"
 )
 
 func main() {
+	migrateSchema := flag.Bool("migrate", false, "apply pending database migrations before starting")
+	flag.Parse()
+
 	if err := godotenv.Load(".env"); err != nil {
 		log.Println("No .env file found, using environment variables")
 	}
@@ -33,11 +48,25 @@ func main() {
 	}
 	defer logger.Sync()
 
-	postgresStore, err := store.NewPostgresStore(cfg.DatabaseURL)
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Endpoint:    cfg.OTLPEndpoint,
+		ServiceName: "alldownloads-api",
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		logger.Fatal("Failed to set up tracing", zap.Error(err))
+	}
+
+	// Refuse to run on a schema this binary was not built for.
+	if err := migrate.Startup(context.Background(), cfg.DatabaseURL, *migrateSchema, logger); err != nil {
+		logger.Fatal("Database schema is not usable", zap.Error(err))
+	}
+
+	dataStore, err := store.Open(cfg.DatabaseURL)
 	if err != nil {
 		logger.Fatal("Failed to connect to database", zap.Error(err))
 	}
-	defer postgresStore.Close()
+	defer dataStore.Close()
 
 	jobQueue, err := jobs.NewQueue(cfg.RedisURL, logger)
 	if err != nil {
@@ -45,7 +74,68 @@ func main() {
 	}
 	defer jobQueue.Close()
 
-	handler := api.NewHandler(postgresStore, jobQueue, logger, cfg.AuthToken)
+	limiter, closeLimiter, err := newRateLimiter(cfg, logger)
+	if err != nil {
+		logger.Fatal("Failed to create rate limiter", zap.Error(err))
+	}
+	defer closeLimiter()
+
+	policies, err := ratelimit.ParsePolicies(cfg.RateLimitPolicies, ratelimit.Policy{
+		Requests: cfg.RateLimitRequestsPerMinute,
+		Window:   time.Minute,
+	})
+	if err != nil {
+		logger.Fatal("Invalid RATE_LIMIT_POLICIES", zap.Error(err))
+	}
+	policies.Rebase("/api/", apiPrefix+"/")
+
+	var productCache *cache.ProductCache
+	if cfg.CacheTTL > 0 {
+		productCache, err = cache.NewProductCache(cfg.RedisURL, cfg.CacheTTL, logger)
+		if err != nil {
+			logger.Fatal("Failed to create product cache", zap.Error(err))
+		}
+		defer productCache.Close()
+	}
+
+	eventBus, err := events.NewBus(cfg.RedisURL, logger)
+	if err != nil {
+		logger.Fatal("Failed to create event bus", zap.Error(err))
+	}
+	defer eventBus.Close()
+
+	// Cancelled on shutdown so that long-lived event streams end and
+	// srv.Shutdown does not wait for them to time out.
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+	defer cancelBase()
+
+	eventHub := events.NewHub(eventBus, logger)
+	go eventHub.Run(baseCtx)
+
+	handler := api.NewHandler(dataStore, jobQueue, productCache, eventBus, logger)
+	if !cfg.PublicReadAPI {
+		handler.SetCacheControl("private, max-age=60, must-revalidate")
+	}
+	handler.SetReadinessThresholds(cfg.ReadyMaxQueueDepth, cfg.WorkerHeartbeatMaxAge)
+	authMiddleware := auth.NewAuthMiddleware(dataStore, cfg.AuthToken, limiter, logger)
+	if cfg.AuthToken != "" {
+		logger.Warn("AUTH_TOKEN is set and grants admin access; create API keys and unset it")
+	}
+
+	var oidcLogin *auth.OIDC
+	if cfg.OIDCIssuerURL != "" {
+		sessionStore, err := auth.NewRedisSessionStore(cfg.RedisURL)
+		if err != nil {
+			logger.Fatal("Failed to create session store", zap.Error(err))
+		}
+		defer sessionStore.Close()
+
+		oidcLogin, err = newOIDC(cfg, sessionStore, logger)
+		if err != nil {
+			logger.Fatal("Failed to configure OIDC login", zap.Error(err))
+		}
+		authMiddleware.UseSessions(oidcLogin)
+	}
 
 	if cfg.LogFormat == "json" {
 		gin.SetMode(gin.ReleaseMode)
@@ -55,35 +145,44 @@ func main() {
 
 	router := gin.New()
 
-	rateLimiter := middleware.NewIPRateLimiter(cfg.RateLimitRequestsPerMinute)
+	// Client addresses are resolved by middleware.RealIP, which supports
+	// the Forwarded header as well; gin must take RemoteAddr at face value.
+	if err := router.SetTrustedProxies(nil); err != nil {
+		logger.Fatal("Failed to configure trusted proxies", zap.Error(err))
+	}
+	trustedProxies, err := middleware.ParseTrustedProxies(cfg.TrustedProxies)
+	if err != nil {
+		logger.Fatal("Invalid TRUSTED_PROXIES", zap.Error(err))
+	}
 
-	router.Use(middleware.RequestID())
-	router.Use(middleware.Logger(logger))
-	router.Use(middleware.CORS(cfg.CorsOrigins))
-	router.Use(rateLimiter.RateLimit())
-	router.Use(api.PrometheusMetrics())
+	httpMetrics := api.NewHTTPMetrics(prometheus.DefaultRegisterer, cfg.HTTPDurationBuckets, cfg.HTTPSizeBuckets)
+	prometheus.MustRegister(api.NewCatalogCollector(dataStore, logger))
+	useMiddleware(router, cfg, trustedProxies, limiter, policies, httpMetrics, logger)
 
-	v1 := router.Group("/api")
-	{
-		v1.GET("/health", handler.HealthCheck)
-		v1.GET("/products", handler.GetProducts)
-		v1.GET("/products/:id", handler.GetProduct)
-		v1.POST("/refresh", handler.RefreshProducts)
+	openapiDoc, err := api.LoadOpenAPI()
+	if err != nil {
+		logger.Fatal("Failed to load OpenAPI document", zap.Error(err))
+	}
+	if err := registerRoutes(router.Group(apiPrefix), cfg, handler, authMiddleware, oidcLogin, eventHub, openapiDoc); err != nil {
+		logger.Fatal("Failed to register routes", zap.Error(err))
 	}
 
 	router.GET("/metrics", api.MetricsHandler())
+	handleUnmatched(router)
 
 	srv := &http.Server{
 		Addr:           ":" + cfg.Port,
-		Handler:        router,
+		Handler:        unversionedAPIAlias(router),
 		ReadTimeout:    30 * time.Second,
 		WriteTimeout:   30 * time.Second,
 		IdleTimeout:    120 * time.Second,
 		MaxHeaderBytes: 1 << 20,
+		BaseContext:    func(net.Listener) context.Context { return baseCtx },
 	}
+	srv.RegisterOnShutdown(cancelBase)
 
 	go func() {
-		logger.Info("Starting server", zap.String("port", cfg.Port))
+		logger.Info("Starting server", zap.String("port", cfg.Port), zap.String("version", version.Get().Version))
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 			logger.Fatal("Failed to start server", zap.Error(err))
 		}
@@ -102,9 +201,70 @@ func main() {
 		logger.Fatal("Server forced to shutdown", zap.Error(err))
 	}
 
+	if err := shutdownTracing(ctx); err != nil {
+		logger.Error("failed to flush traces", zap.Error(err))
+	}
+
 	logger.Info("Server exited")
 }
 
+// newRateLimiter shares limits across replicas through Redis, falling back
+// to per-process limits while Redis is unreachable.
+func newRateLimiter(cfg *config.Config, logger *zap.Logger) (ratelimit.Limiter, func(), error) {
+	memory := ratelimit.NewMemoryLimiter()
+
+	switch cfg.RateLimitBackend {
+	case "memory":
+		return memory, func() {}, nil
+	case "redis":
+		redisLimiter, err := ratelimit.NewRedisLimiter(cfg.RedisURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		return ratelimit.NewFallbackLimiter(redisLimiter, memory, logger), func() { redisLimiter.Close() }, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown RATE_LIMIT_BACKEND %q, must be redis or memory", cfg.RateLimitBackend)
+	}
+}
+
+func newOIDC(cfg *config.Config, sessionStore auth.SessionStore, logger *zap.Logger) (*auth.OIDC, error) {
+	roleMapping, err := auth.ParseRoleMapping(cfg.OIDCRoleMapping)
+	if err != nil {
+		return nil, err
+	}
+	if len(roleMapping) == 0 {
+		logger.Warn("OIDC_ROLE_MAPPING is empty; no user will be able to log in")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	return auth.NewOIDC(ctx, auth.OIDCConfig{
+		IssuerURL:     cfg.OIDCIssuerURL,
+		ClientID:      cfg.OIDCClientID,
+		ClientSecret:  cfg.OIDCClientSecret,
+		RedirectURL:   cfg.OIDCRedirectURL,
+		Scopes:        splitList(cfg.OIDCScopes),
+		GroupsClaim:   cfg.OIDCGroupsClaim,
+		RoleMapping:   roleMapping,
+		SessionSecret: cfg.SessionSecret,
+		SessionTTL:    cfg.SessionTTL,
+		SessionStore:  sessionStore,
+		PostLoginURL:  cfg.OIDCPostLoginURL,
+		SecureCookies: strings.HasPrefix(cfg.BaseURL, "https://"),
+	}, logger)
+}
+
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func createLogger(level string, format string) (*zap.Logger, error) {
 	var config zap.Config
 
@@ -128,4 +288,4 @@ func createLogger(level string, format string) (*zap.Logger, error) {
 	}
 
 	return config.Build()
-}
\ No newline at end of file
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/your-username/alldownloads/internal/api"
+	"github.com/your-username/alldownloads/internal/auth"
+	"github.com/your-username/alldownloads/internal/config"
+	"github.com/your-username/alldownloads/internal/events"
+	"github.com/your-username/alldownloads/internal/middleware"
+	"github.com/your-username/alldownloads/internal/problem"
+	"github.com/your-username/alldownloads/internal/ratelimit"
+)
+
+// apiPrefix is where the current API version is mounted. The paths under
+// /api without a version keep working as aliases, see unversionedAPIAlias.
+const apiPrefix = "/api/v1"
+
+// useMiddleware installs the middleware every request passes through. The
+// metrics middleware wraps Recovery, CORS and rate limiting so that panics,
+// rejected preflights and 429s are counted like any other response.
+func useMiddleware(router *gin.Engine, cfg *config.Config, trustedProxies []*net.IPNet, limiter ratelimit.Limiter, policies *ratelimit.Policies, metrics *api.HTTPMetrics, logger *zap.Logger) {
+	router.Use(middleware.RealIP(trustedProxies, splitList(cfg.ClientIPHeaders)))
+	router.Use(middleware.RequestID())
+	router.Use(middleware.Tracing())
+	router.Use(metrics.Middleware())
+	router.Use(middleware.Logger(logger))
+	router.Use(middleware.Recovery(logger))
+	router.Use(middleware.CORS(cfg.CorsOrigins))
+	router.Use(ratelimit.Middleware(limiter, policies, logger))
+}
+
+// registerRoutes mounts the API on group. Every route must be described in
+// openapi.yaml; routes_test.go checks both ways.
+func registerRoutes(group *gin.RouterGroup, cfg *config.Config, handler *api.Handler, authMiddleware *auth.AuthMiddleware, oidcLogin *auth.OIDC, eventHub *events.Hub, openapiDoc *openapi3.T) error {
+	group.GET("/health", handler.HealthCheck)
+	group.GET("/health/live", handler.HealthCheck)
+	group.GET("/health/ready", handler.ReadinessCheck)
+
+	spec, err := api.OpenAPISpec(openapiDoc)
+	if err != nil {
+		return err
+	}
+	group.GET("/openapi.json", spec)
+	group.GET("/docs", api.APIDocs(apiPrefix+"/openapi.json"))
+
+	read := group.Group("")
+	if !cfg.PublicReadAPI {
+		read.Use(authMiddleware.RequireScope(auth.ScopeRead))
+	}
+	{
+		read.GET("/products", handler.GetProducts)
+		read.GET("/products/:id", handler.GetProduct)
+		read.GET("/versions", handler.GetVersions)
+		read.GET("/events", handler.StreamEvents(eventHub))
+	}
+
+	// GraphQL mutations check for the admin scope themselves, so callers
+	// are identified even when reads are public.
+	graphqlAuth := authMiddleware.Authenticate()
+	if !cfg.PublicReadAPI {
+		graphqlAuth = authMiddleware.RequireScope(auth.ScopeRead)
+	}
+	group.POST("/graphql", graphqlAuth, handler.GraphQL())
+
+	group.POST("/refresh", authMiddleware.RequireScope(auth.ScopeRefresh), handler.RefreshProducts)
+
+	admin := group.Group("/admin", authMiddleware.RequireScope(auth.ScopeAdmin))
+	{
+		admin.GET("/audit", handler.ListAuditLog)
+
+		admin.GET("/queue", handler.GetQueueStatus)
+		admin.POST("/queue/requeue-dead", handler.RequeueDeadJobs)
+
+		admin.GET("/keys", handler.ListAPIKeys)
+		admin.POST("/keys", handler.CreateAPIKey)
+		admin.DELETE("/keys/:id", handler.RevokeAPIKey)
+
+		admin.POST("/products", handler.CreateProduct)
+		admin.PUT("/products/:id", handler.ReplaceProduct)
+		admin.PATCH("/products/:id", handler.PatchProduct)
+		admin.DELETE("/products/:id", handler.DeleteProduct)
+
+		admin.GET("/products/:id/versions", handler.ListProductVersionsAdmin)
+		admin.POST("/products/:id/versions", handler.CreateProductVersion)
+		admin.PATCH("/versions/:versionId", handler.UpdateProductVersion)
+		admin.DELETE("/versions/:versionId", handler.DeleteProductVersion)
+	}
+
+	if oidcLogin != nil {
+		authRoutes := group.Group("/auth")
+		{
+			authRoutes.GET("/login", oidcLogin.Login)
+			authRoutes.GET("/callback", oidcLogin.Callback)
+			authRoutes.POST("/logout", oidcLogin.Logout)
+			authRoutes.GET("/me", oidcLogin.Me)
+		}
+	}
+
+	return nil
+}
+
+// handleUnmatched answers requests that match no route, or match one only
+// under another method, with problem responses.
+func handleUnmatched(router *gin.Engine) {
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(func(c *gin.Context) {
+		problem.NotFound(c, "No such endpoint")
+	})
+	router.NoMethod(func(c *gin.Context) {
+		problem.Abort(c, http.StatusMethodNotAllowed, problem.CodeMethodNotAllowed, c.Request.Method+" is not supported on this endpoint")
+	})
+}
+
+// unversionedAPIAlias serves /api/... as /api/v1/... by rewriting the path
+// before routing, so that the aliases share routes, rate limit policies and
+// metrics with the versioned paths.
+func unversionedAPIAlias(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if rest, ok := strings.CutPrefix(r.URL.Path, "/api/"); ok && rest != "v1" && !strings.HasPrefix(rest, "v1/") {
+			r2 := r.Clone(r.Context())
+			r2.URL.Path = apiPrefix + "/" + rest
+			if r.URL.RawPath != "" {
+				r2.URL.RawPath = apiPrefix + "/" + strings.TrimPrefix(r.URL.RawPath, "/api/")
+			}
+			r = r2
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.uber.org/zap"
+
+	"github.com/your-username/alldownloads/internal/api"
+	"github.com/your-username/alldownloads/internal/auth"
+	"github.com/your-username/alldownloads/internal/auth/oidctest"
+	"github.com/your-username/alldownloads/internal/config"
+	"github.com/your-username/alldownloads/internal/events"
+	"github.com/your-username/alldownloads/internal/ratelimit"
+)
+
+// newTestRouter mounts the real routes with every optional feature
+// enabled. There is no database behind it, so only requests that are
+// answered before the store is reached can be served.
+func newTestRouter(t *testing.T) (*gin.Engine, *openapi3.T) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	doc, err := api.LoadOpenAPI()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	provider, err := oidctest.NewProvider("alldl", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(provider.Close)
+
+	sessionStore, err := auth.NewRedisSessionStore("redis://" + miniredis.RunT(t).Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sessionStore.Close() })
+
+	oidcLogin, err := auth.NewOIDC(context.Background(), auth.OIDCConfig{
+		IssuerURL:     provider.Issuer(),
+		ClientID:      provider.ClientID,
+		ClientSecret:  provider.ClientSecret,
+		RedirectURL:   "http://localhost/api/v1/auth/callback",
+		RoleMapping:   map[string][]string{"admins": {auth.ScopeAdmin}},
+		SessionSecret: "test-session-secret",
+		SessionTTL:    time.Hour,
+		SessionStore:  sessionStore,
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	logger := zap.NewNop()
+	handler := api.NewHandler(nil, nil, nil, nil, logger)
+	authMiddleware := auth.NewAuthMiddleware(nil, "", ratelimit.NewMemoryLimiter(), logger)
+	authMiddleware.UseSessions(oidcLogin)
+
+	router := gin.New()
+	cfg := &config.Config{PublicReadAPI: true}
+	if err := registerRoutes(router.Group(apiPrefix), cfg, handler, authMiddleware, oidcLogin, events.NewHub(nil, logger), doc); err != nil {
+		t.Fatal(err)
+	}
+	handleUnmatched(router)
+
+	return router, doc
+}
+
+var ginParam = regexp.MustCompile(`:([A-Za-z]+)`)
+
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	router, doc := newTestRouter(t)
+
+	routes := make(map[string]bool)
+	for _, route := range router.Routes() {
+		path, ok := strings.CutPrefix(route.Path, apiPrefix)
+		if !ok {
+			continue
+		}
+		path = ginParam.ReplaceAllString(path, "{$1}")
+		routes[route.Method+" "+path] = true
+
+		item := doc.Paths.Value(path)
+		if item == nil || item.GetOperation(route.Method) == nil {
+			t.Errorf("route %s %s is not documented in openapi.yaml", route.Method, path)
+		}
+	}
+
+	for path, item := range doc.Paths.Map() {
+		for method := range item.Operations() {
+			if !routes[method+" "+path] {
+				t.Errorf("openapi.yaml documents %s %s, which has no route", method, path)
+			}
+		}
+	}
+}
+
+func TestResponsesMatchOpenAPI(t *testing.T) {
+	router, doc := newTestRouter(t)
+	handler := unversionedAPIAlias(router)
+
+	// The document's server is relative; route requests as if served
+	// from localhost.
+	doc.Servers = openapi3.Servers{{URL: "http://localhost" + apiPrefix}}
+	openapi3filter.RegisterBodyDecoder("text/html", openapi3filter.FileBodyDecoder)
+	specRouter, err := legacy.NewRouter(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{http.MethodGet, "/api/v1/health", "", http.StatusOK},
+		{http.MethodGet, "/api/v1/health/live", "", http.StatusOK},
+		{http.MethodGet, "/api/v1/openapi.json", "", http.StatusOK},
+		{http.MethodGet, "/api/v1/docs", "", http.StatusOK},
+		{http.MethodGet, "/api/v1/products?category=bogus", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/products/Not_A_Slug", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/versions?latest=maybe", "", http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/events?last_event_id=nope", "", http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/graphql", "{}", http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/graphql", `{"query": "mutation { refresh { id } }"}`, http.StatusOK},
+		{http.MethodPost, "/api/v1/refresh", "", http.StatusUnauthorized},
+		{http.MethodGet, "/api/v1/admin/keys", "", http.StatusUnauthorized},
+		{http.MethodPatch, "/api/v1/admin/versions/1", "{}", http.StatusUnauthorized},
+		{http.MethodGet, "/api/v1/auth/login", "", http.StatusFound},
+		{http.MethodGet, "/api/v1/auth/callback?state=x", "", http.StatusBadRequest},
+		{http.MethodPost, "/api/v1/auth/logout", "", http.StatusForbidden},
+		{http.MethodGet, "/api/v1/auth/me", "", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "http://localhost"+tt.path, strings.NewReader(tt.body))
+			if tt.body != "" {
+				req.Header.Set("Content-Type", "application/json")
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.status, rec.Body.String())
+			}
+
+			route, pathParams, err := specRouter.FindRoute(req)
+			if err != nil {
+				t.Fatalf("no operation in openapi.yaml: %v", err)
+			}
+			input := &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: &openapi3filter.RequestValidationInput{
+					Request:    req,
+					PathParams: pathParams,
+					Route:      route,
+				},
+				Status: rec.Code,
+				Header: rec.Header(),
+				Body:   rec.Result().Body,
+				Options: &openapi3filter.Options{
+					IncludeResponseStatus: true,
+				},
+			}
+			if err := openapi3filter.ValidateResponse(context.Background(), input); err != nil {
+				t.Errorf("response does not match openapi.yaml: %v", err)
+			}
+		})
+	}
+}
+
+func TestUnversionedAPIAlias(t *testing.T) {
+	router, _ := newTestRouter(t)
+	handler := unversionedAPIAlias(router)
+
+	for path, status := range map[string]int{
+		"/api/health":           http.StatusOK,
+		"/api/v1/health":        http.StatusOK,
+		"/api/products?limit=0": http.StatusBadRequest,
+		"/api/v2/health":        http.StatusNotFound,
+		"/health":               http.StatusNotFound,
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != status {
+			t.Errorf("GET %s = %d, want %d", path, rec.Code, status)
+		}
+	}
+}
+
+func TestMiddlewareCountsRejectedAndPanickingRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := zap.NewNop()
+
+	policies, err := ratelimit.ParsePolicies("", ratelimit.Policy{Requests: 2, Window: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := prometheus.NewRegistry()
+
+	router := gin.New()
+	useMiddleware(router, &config.Config{}, nil, ratelimit.NewMemoryLimiter(), policies, api.NewHTTPMetrics(reg, nil, nil), logger)
+	router.GET("/ok", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	router.GET("/panic", func(c *gin.Context) { panic("boom") })
+
+	for _, tt := range []struct {
+		path   string
+		status int
+	}{
+		{"/ok", http.StatusNoContent},
+		{"/panic", http.StatusInternalServerError},
+		{"/ok", http.StatusTooManyRequests},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if rec.Code != tt.status {
+			t.Fatalf("GET %s = %d, want %d", tt.path, rec.Code, tt.status)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	for _, want := range []string{
+		`http_requests_total{endpoint="/ok",method="GET",status="204"} 1`,
+		`http_requests_total{endpoint="/ok",method="GET",status="429"} 1`,
+		`http_requests_total{endpoint="/panic",method="GET",status="500"} 1`,
+		`http_requests_in_flight 0`,
+	} {
+		if !strings.Contains(rec.Body.String(), want) {
+			t.Errorf("scrape is missing %s", want)
+		}
+	}
+}
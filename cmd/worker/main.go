@@ -2,22 +2,35 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
+	"net/http"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/robfig/cron/v3"
 	"go.uber.org/zap"
 
+	"github.com/your-username/alldownloads/internal/alerts"
+	"github.com/your-username/alldownloads/internal/cache"
 	"github.com/your-username/alldownloads/internal/config"
+	"github.com/your-username/alldownloads/internal/events"
 	"github.com/your-username/alldownloads/internal/jobs"
+	"github.com/your-username/alldownloads/internal/migrate"
 	"github.com/your-username/alldownloads/internal/store"
+	"github.com/your-username/alldownloads/internal/tracing"
 )
 
 func main() {
+	migrateSchema := flag.Bool("migrate", false, "apply pending database migrations before starting")
+	flag.Parse()
+
 	if err := godotenv.Load(".env"); err != nil {
 		log.Println("No .env file found, using environment variables")
 	}
@@ -30,11 +43,25 @@ func main() {
 	}
 	defer logger.Sync()
 
-	postgresStore, err := store.NewPostgresStore(cfg.DatabaseURL)
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Endpoint:    cfg.OTLPEndpoint,
+		ServiceName: "alldownloads-worker",
+		SampleRatio: cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		logger.Fatal("Failed to set up tracing", zap.Error(err))
+	}
+
+	// Refuse to run on a schema this binary was not built for.
+	if err := migrate.Startup(context.Background(), cfg.DatabaseURL, *migrateSchema, logger); err != nil {
+		logger.Fatal("Database schema is not usable", zap.Error(err))
+	}
+
+	dataStore, err := store.Open(cfg.DatabaseURL)
 	if err != nil {
 		logger.Fatal("Failed to connect to database", zap.Error(err))
 	}
-	defer postgresStore.Close()
+	defer dataStore.Close()
 
 	jobQueue, err := jobs.NewQueue(cfg.RedisURL, logger)
 	if err != nil {
@@ -42,7 +69,37 @@ func main() {
 	}
 	defer jobQueue.Close()
 
-	worker := jobs.NewWorker(postgresStore, jobQueue, logger, cfg.MaxConcurrentFetches)
+	var productCache *cache.ProductCache
+	if cfg.CacheTTL > 0 {
+		productCache, err = cache.NewProductCache(cfg.RedisURL, cfg.CacheTTL, logger)
+		if err != nil {
+			logger.Fatal("Failed to create product cache", zap.Error(err))
+		}
+		defer productCache.Close()
+	}
+
+	eventBus, err := events.NewBus(cfg.RedisURL, logger)
+	if err != nil {
+		logger.Fatal("Failed to create event bus", zap.Error(err))
+	}
+	defer eventBus.Close()
+
+	jobs.RegisterMetrics(prometheus.DefaultRegisterer)
+	metricsServer := startMetricsServer(cfg.WorkerMetricsPort, logger)
+
+	notifier, err := alerts.New(alerts.Config{
+		WebhookURL:      cfg.AlertWebhookURL,
+		SlackWebhookURL: cfg.AlertSlackWebhookURL,
+		SMTPAddr:        cfg.AlertSMTPAddr,
+		SMTPFrom:        cfg.AlertSMTPFrom,
+		SMTPTo:          cfg.AlertSMTPTo,
+	})
+	if err != nil {
+		logger.Fatal("Invalid alert configuration", zap.Error(err))
+	}
+
+	worker := jobs.NewWorker(dataStore, jobQueue, productCache, eventBus, logger, cfg.MaxConcurrentFetches)
+	worker.SetFreshness(cfg.FreshnessMaxAge, notifier)
 
 	ctx, cancel := context.WithCancel(context.Background())
 	var wg sync.WaitGroup
@@ -60,30 +117,41 @@ func main() {
 	_, err = scheduler.AddFunc(cfg.RefreshCron, func() {
 		logger.Info("scheduled refresh triggered")
 
-		products, err := postgresStore.GetProducts(context.Background())
+		ctx, span := tracing.Tracer().Start(context.Background(), "scheduled refresh")
+		defer span.End()
+
+		products, err := dataStore.GetProducts(ctx)
 		if err != nil {
 			logger.Error("failed to get products for scheduled refresh", zap.Error(err))
 			return
 		}
 
+		queued := 0
 		for _, product := range products {
+			if !product.Scheduled() {
+				continue
+			}
+
 			job := &store.FetchJob{
 				ProductID: product.ID,
 				Status:    store.JobStatusPending,
 			}
 
-			if err := postgresStore.CreateFetchJob(context.Background(), job); err != nil {
+			if err := dataStore.CreateFetchJob(ctx, job); err != nil {
 				logger.Error("failed to create scheduled fetch job", zap.Error(err), zap.String("product_id", product.ID))
 				continue
 			}
 
-			if err := jobQueue.Enqueue(context.Background(), job.ID); err != nil {
+			if err := jobQueue.Enqueue(ctx, job.ID); err != nil {
 				logger.Error("failed to enqueue scheduled job", zap.Error(err), zap.String("job_id", job.ID))
 				continue
 			}
+
+			eventBus.Publish(ctx, events.Event{Type: events.JobQueued, JobID: job.ID, ProductID: product.ID})
+			queued++
 		}
 
-		logger.Info("scheduled refresh completed", zap.Int("jobs_queued", len(products)))
+		logger.Info("scheduled refresh completed", zap.Int("jobs_queued", queued))
 	})
 
 	if err != nil {
@@ -103,9 +171,39 @@ func main() {
 	cancel()
 	wg.Wait()
 
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+		logger.Error("failed to stop metrics server", zap.Error(err))
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("failed to flush traces", zap.Error(err))
+	}
+
 	logger.Info("worker exited")
 }
 
+// startMetricsServer serves Prometheus metrics on port in the background.
+func startMetricsServer(port string, logger *zap.Logger) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           mux,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+
+	go func() {
+		logger.Info("metrics server started", zap.String("port", port))
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Error("metrics server failed", zap.Error(err))
+		}
+	}()
+
+	return srv
+}
+
 func createLogger(level string, format string) (*zap.Logger, error) {
 	var config zap.Config
 
@@ -0,0 +1,130 @@
+// Package alerts tells operators when a product stops updating. Alerts go
+// to any combination of a generic JSON webhook, a Slack-compatible incoming
+// webhook and e-mail through an SMTP relay.
+package alerts
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	KindStale     = "product.stale"
+	KindRecovered = "product.recovered"
+)
+
+// Alert describes a product crossing its freshness SLO in either direction.
+type Alert struct {
+	Kind          string     `json:"kind"`
+	ProductID     string     `json:"product_id"`
+	ProductName   string     `json:"product_name"`
+	MaxAgeSeconds int64      `json:"max_age_seconds"`
+	LastSuccessAt *time.Time `json:"last_success_at"`
+	StaleSince    *time.Time `json:"stale_since,omitempty"`
+	At            time.Time  `json:"at"`
+}
+
+// Summary is a one-line description for chat messages and e-mail subjects.
+func (a Alert) Summary() string {
+	maxAge := time.Duration(a.MaxAgeSeconds) * time.Second
+
+	if a.Kind == KindRecovered {
+		summary := fmt.Sprintf("%s is updating again", a.ProductName)
+		if a.StaleSince != nil {
+			summary += fmt.Sprintf(" after being stale for %s", a.At.Sub(*a.StaleSince).Round(time.Minute))
+		}
+		return summary
+	}
+
+	if a.LastSuccessAt == nil {
+		return fmt.Sprintf("%s has never updated successfully (freshness SLO %s)", a.ProductName, maxAge)
+	}
+	return fmt.Sprintf("%s has not updated successfully for %s (freshness SLO %s)",
+		a.ProductName, a.At.Sub(*a.LastSuccessAt).Round(time.Minute), maxAge)
+}
+
+// Notifier delivers an alert to one destination.
+type Notifier interface {
+	Notify(ctx context.Context, alert Alert) error
+}
+
+// Config selects the destinations; empty fields are skipped.
+type Config struct {
+	WebhookURL      string
+	SlackWebhookURL string
+	SMTPAddr        string
+	SMTPFrom        string
+	// SMTPTo is a comma-separated list of recipients.
+	SMTPTo string
+}
+
+// New returns a notifier fanning out to every destination in cfg. It
+// notifies nobody when cfg is empty.
+func New(cfg Config) (Notifier, error) {
+	var notifiers Multi
+	if cfg.WebhookURL != "" {
+		notifiers = append(notifiers, NewWebhook(cfg.WebhookURL))
+	}
+	if cfg.SlackWebhookURL != "" {
+		notifiers = append(notifiers, NewSlack(cfg.SlackWebhookURL))
+	}
+	if cfg.SMTPAddr != "" {
+		var to []string
+		for _, addr := range strings.Split(cfg.SMTPTo, ",") {
+			if addr = strings.TrimSpace(addr); addr != "" {
+				to = append(to, addr)
+			}
+		}
+		if cfg.SMTPFrom == "" || len(to) == 0 {
+			return nil, errors.New("SMTP alerts need a sender and at least one recipient")
+		}
+		notifiers = append(notifiers, &SMTP{Addr: cfg.SMTPAddr, From: cfg.SMTPFrom, To: to})
+	}
+	return notifiers, nil
+}
+
+// Multi notifies every notifier in turn, so that one failing destination
+// does not silence the others.
+type Multi []Notifier
+
+func (m Multi) Notify(ctx context.Context, alert Alert) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(ctx, alert); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+func postJSON(ctx context.Context, url string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal alert: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create alert request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send alert: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusMultipleChoices {
+		return fmt.Errorf("alert endpoint answered %s", resp.Status)
+	}
+	return nil
+}
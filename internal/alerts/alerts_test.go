@@ -0,0 +1,257 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	lastSuccess = time.Date(2024, 3, 1, 6, 0, 0, 0, time.UTC)
+	staleSince  = lastSuccess.Add(24 * time.Hour)
+
+	staleAlert = Alert{
+		Kind:          KindStale,
+		ProductID:     "ubuntu",
+		ProductName:   "Ubuntü",
+		MaxAgeSeconds: 86400,
+		LastSuccessAt: &lastSuccess,
+		StaleSince:    &staleSince,
+		At:            lastSuccess.Add(25*time.Hour + 20*time.Minute),
+	}
+	recoveredAlert = Alert{
+		Kind:          KindRecovered,
+		ProductID:     "ubuntu",
+		ProductName:   "Ubuntü",
+		MaxAgeSeconds: 86400,
+		LastSuccessAt: &lastSuccess,
+		StaleSince:    &staleSince,
+		At:            staleSince.Add(90 * time.Minute),
+	}
+)
+
+func TestSummary(t *testing.T) {
+	never := staleAlert
+	never.LastSuccessAt = nil
+	recoveredUnknown := recoveredAlert
+	recoveredUnknown.StaleSince = nil
+
+	for _, tt := range []struct {
+		alert Alert
+		want  string
+	}{
+		{staleAlert, "Ubuntü has not updated successfully for 25h20m0s (freshness SLO 24h0m0s)"},
+		{never, "Ubuntü has never updated successfully (freshness SLO 24h0m0s)"},
+		{recoveredAlert, "Ubuntü is updating again after being stale for 1h30m0s"},
+		{recoveredUnknown, "Ubuntü is updating again"},
+	} {
+		if got := tt.alert.Summary(); got != tt.want {
+			t.Errorf("Summary() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+// receiver serves as an alert endpoint, recording each request body.
+func receiver(t *testing.T, status int) (*httptest.Server, <-chan map[string]interface{}) {
+	t.Helper()
+	bodies := make(chan map[string]interface{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("alert sent as %s with Content-Type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("alert body is not JSON: %v", err)
+		}
+		bodies <- body
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, bodies
+}
+
+func TestWebhook(t *testing.T) {
+	server, bodies := receiver(t, http.StatusNoContent)
+
+	if err := NewWebhook(server.URL).Notify(context.Background(), staleAlert); err != nil {
+		t.Fatal(err)
+	}
+	body := <-bodies
+	for key, want := range map[string]interface{}{
+		"kind":            KindStale,
+		"product_id":      "ubuntu",
+		"product_name":    "Ubuntü",
+		"max_age_seconds": float64(86400),
+		"last_success_at": "2024-03-01T06:00:00Z",
+		"stale_since":     "2024-03-02T06:00:00Z",
+		"at":              "2024-03-02T07:20:00Z",
+	} {
+		if body[key] != want {
+			t.Errorf("webhook %s = %v, want %v", key, body[key], want)
+		}
+	}
+
+	server, _ = receiver(t, http.StatusInternalServerError)
+	if err := NewWebhook(server.URL).Notify(context.Background(), staleAlert); err == nil || !strings.Contains(err.Error(), "500") {
+		t.Fatalf("Notify to a failing webhook = %v, want the status", err)
+	}
+}
+
+func TestSlack(t *testing.T) {
+	server, bodies := receiver(t, http.StatusOK)
+	slack := NewSlack(server.URL)
+
+	for _, tt := range []struct {
+		alert Alert
+		want  string
+	}{
+		{staleAlert, ":warning: " + staleAlert.Summary()},
+		{recoveredAlert, ":white_check_mark: " + recoveredAlert.Summary()},
+	} {
+		if err := slack.Notify(context.Background(), tt.alert); err != nil {
+			t.Fatal(err)
+		}
+		if body := <-bodies; len(body) != 1 || body["text"] != tt.want {
+			t.Fatalf("slack payload = %v, want text %q", body, tt.want)
+		}
+	}
+}
+
+// smtpRelay accepts one message on a local port and sends its envelope and
+// data on the returned channel.
+func smtpRelay(t *testing.T) (string, <-chan []string) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+
+		var lines []string
+		tp.PrintfLine("220 relay ready")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch command := strings.ToUpper(strings.Fields(line)[0]); command {
+			case "EHLO", "HELO":
+				tp.PrintfLine("250 relay")
+			case "MAIL", "RCPT":
+				lines = append(lines, line)
+				tp.PrintfLine("250 OK")
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				data, err := tp.ReadDotLines()
+				if err != nil {
+					return
+				}
+				lines = append(lines, data...)
+				tp.PrintfLine("250 queued")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				received <- lines
+				return
+			default:
+				tp.PrintfLine("502 %s not implemented", command)
+			}
+		}
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestSMTP(t *testing.T) {
+	addr, received := smtpRelay(t)
+	notifier := &SMTP{Addr: addr, From: "alerts@example.com", To: []string{"ops@example.com", "dev@example.com"}}
+
+	if err := notifier.Notify(context.Background(), staleAlert); err != nil {
+		t.Fatal(err)
+	}
+	message := strings.Join(<-received, "\n")
+	for _, want := range []string{
+		"MAIL FROM:<alerts@example.com>",
+		"RCPT TO:<ops@example.com>",
+		"RCPT TO:<dev@example.com>",
+		"To: ops@example.com, dev@example.com",
+		"Subject: =?utf-8?q?[alldownloads]_Ubunt=C3=BC_has_not_updated",
+		"Date: Sat, 02 Mar 2024 07:20:00 +0000",
+		"Product: Ubuntü (ubuntu)",
+		"Freshness SLO: 24h0m0s",
+		"Last successful fetch: 2024-03-01T06:00:00Z",
+	} {
+		if !strings.Contains(message, want) {
+			t.Errorf("message is missing %q:\n%s", want, message)
+		}
+	}
+}
+
+func TestSMTPGivesUpWithTheContext(t *testing.T) {
+	// The relay accepts the connection but never greets.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(5 * time.Second)
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	notifier := &SMTP{Addr: listener.Addr().String(), From: "alerts@example.com", To: []string{"ops@example.com"}}
+
+	start := time.Now()
+	if err := notifier.Notify(ctx, staleAlert); err == nil {
+		t.Fatal("Notify succeeded without a greeting")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("Notify took %v, want it to stop at the context deadline", elapsed)
+	}
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New(Config{SMTPAddr: "localhost:25", SMTPTo: "ops@example.com"}); err == nil {
+		t.Fatal("New accepted SMTP without a sender")
+	}
+	if _, err := New(Config{SMTPAddr: "localhost:25", SMTPFrom: "alerts@example.com", SMTPTo: " , "}); err == nil {
+		t.Fatal("New accepted SMTP without recipients")
+	}
+
+	notifier, err := New(Config{})
+	if err != nil || len(notifier.(Multi)) != 0 {
+		t.Fatalf("New(empty) = %v, %v, want no destinations", notifier, err)
+	}
+
+	// One failing destination does not keep the alert from the others.
+	failing, _ := receiver(t, http.StatusBadGateway)
+	working, bodies := receiver(t, http.StatusOK)
+	notifier, err = New(Config{WebhookURL: failing.URL, SlackWebhookURL: working.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := notifier.Notify(context.Background(), staleAlert); err == nil || !strings.Contains(err.Error(), "webhook") {
+		t.Fatalf("Notify = %v, want the webhook's error", err)
+	}
+	if body := <-bodies; body["text"] == nil {
+		t.Fatalf("slack got %v", body)
+	}
+}
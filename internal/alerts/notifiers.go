@@ -0,0 +1,132 @@
+package alerts
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Webhook posts the alert as JSON.
+type Webhook struct {
+	URL string
+}
+
+func NewWebhook(url string) *Webhook {
+	return &Webhook{URL: url}
+}
+
+func (w *Webhook) Notify(ctx context.Context, alert Alert) error {
+	if err := postJSON(ctx, w.URL, alert); err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	return nil
+}
+
+// Slack posts the alert's summary to an incoming webhook. Mattermost,
+// Rocket.Chat and Discord's /slack endpoints accept the same payload.
+type Slack struct {
+	URL string
+}
+
+func NewSlack(url string) *Slack {
+	return &Slack{URL: url}
+}
+
+func (s *Slack) Notify(ctx context.Context, alert Alert) error {
+	icon := ":warning:"
+	if alert.Kind == KindRecovered {
+		icon = ":white_check_mark:"
+	}
+
+	if err := postJSON(ctx, s.URL, map[string]string{"text": icon + " " + alert.Summary()}); err != nil {
+		return fmt.Errorf("slack: %w", err)
+	}
+	return nil
+}
+
+// SMTP mails the alert through a relay that accepts unauthenticated
+// submissions, such as a local Postfix.
+type SMTP struct {
+	Addr string
+	From string
+	To   []string
+}
+
+func (s *SMTP) Notify(ctx context.Context, alert Alert) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "From: %s\r\n", s.From)
+	fmt.Fprintf(&body, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&body, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", "[alldownloads] "+alert.Summary()))
+	fmt.Fprintf(&body, "Date: %s\r\n", alert.At.Format(time.RFC1123Z))
+	body.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+
+	fmt.Fprintf(&body, "%s\r\n\r\n", alert.Summary())
+	fmt.Fprintf(&body, "Product: %s (%s)\r\n", alert.ProductName, alert.ProductID)
+	fmt.Fprintf(&body, "Freshness SLO: %s\r\n", time.Duration(alert.MaxAgeSeconds)*time.Second)
+	if alert.LastSuccessAt != nil {
+		fmt.Fprintf(&body, "Last successful fetch: %s\r\n", alert.LastSuccessAt.UTC().Format(time.RFC3339))
+	} else {
+		body.WriteString("Last successful fetch: never\r\n")
+	}
+
+	if err := s.send(ctx, []byte(body.String())); err != nil {
+		return fmt.Errorf("smtp: %w", err)
+	}
+	return nil
+}
+
+// send does what smtp.SendMail does, on a connection that is dialled with
+// ctx and closed when ctx ends, since net/smtp itself takes no context.
+func (s *SMTP) send(ctx context.Context, msg []byte) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", s.Addr)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	host, _, err := net.SplitHostPort(s.Addr)
+	if err != nil {
+		return err
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(s.From); err != nil {
+		return err
+	}
+	for _, to := range s.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
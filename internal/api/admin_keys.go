@@ -0,0 +1,146 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/alldownloads/internal/auth"
+	"github.com/your-username/alldownloads/internal/problem"
+	"github.com/your-username/alldownloads/internal/store"
+	"go.uber.org/zap"
+)
+
+type createKeyRequest struct {
+	Name               string     `json:"name"`
+	Scopes             []string   `json:"scopes"`
+	RateLimitPerMinute int        `json:"rate_limit_per_minute"`
+	ExpiresAt          *time.Time `json:"expires_at"`
+}
+
+type createKeyResponse struct {
+	store.APIKey
+	Key string `json:"key"`
+}
+
+func (h *Handler) CreateAPIKey(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req createKeyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	var errs []problem.FieldError
+	if req.Name == "" {
+		errs = append(errs, problem.Field("name", problem.FieldRequired, "is required"))
+	}
+	if len(req.Scopes) == 0 {
+		errs = append(errs, problem.Field("scopes", problem.FieldRequired, "must contain at least one scope"))
+	}
+	for _, scope := range req.Scopes {
+		if !auth.ValidScope(scope) {
+			errs = append(errs, problem.Field("scopes", problem.FieldInvalid, "unknown scope "+scope))
+		}
+	}
+	if req.RateLimitPerMinute < 0 {
+		errs = append(errs, problem.Field("rate_limit_per_minute", problem.FieldOutOfRange, "must not be negative"))
+	}
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		errs = append(errs, problem.Field("expires_at", problem.FieldOutOfRange, "must be in the future"))
+	}
+	if len(errs) > 0 {
+		problem.Validation(c, errs...)
+		return
+	}
+
+	plaintext, prefix, hash, err := auth.GenerateKey()
+	if err != nil {
+		h.logger.Error("failed to generate api key", zap.Error(err))
+		problem.Internal(c, "Failed to create API key")
+		return
+	}
+
+	key := store.APIKey{
+		Name:               req.Name,
+		Prefix:             prefix,
+		KeyHash:            hash,
+		Scopes:             req.Scopes,
+		RateLimitPerMinute: req.RateLimitPerMinute,
+		ExpiresAt:          req.ExpiresAt,
+	}
+	if err := h.store.CreateAPIKey(ctx, &key); err != nil {
+		h.logger.Error("failed to store api key", zap.Error(err))
+		problem.Internal(c, "Failed to create API key")
+		return
+	}
+
+	h.logger.Info("api key created", zap.String("key_id", key.ID), zap.String("name", key.Name), zap.Strings("scopes", key.Scopes))
+	if err := h.audit(c, AuditActionAPIKeyCreate, "api_key", key.ID, nil, key); err != nil {
+		// The plaintext is never handed out, so the key cannot be used;
+		// revoke it so that it does not linger as active.
+		if err := h.store.RevokeAPIKey(ctx, key.ID); err != nil {
+			h.logger.Error("failed to revoke unaudited api key", zap.Error(err), zap.String("key_id", key.ID))
+		}
+		problem.Internal(c, "Failed to create API key")
+		return
+	}
+
+	// The plaintext key is only ever returned here.
+	c.JSON(http.StatusCreated, createKeyResponse{APIKey: key, Key: plaintext})
+}
+
+func (h *Handler) ListAPIKeys(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	keys, err := h.store.ListAPIKeys(ctx)
+	if err != nil {
+		h.logger.Error("failed to list api keys", zap.Error(err))
+		problem.Internal(c, "Failed to fetch API keys")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
+func (h *Handler) RevokeAPIKey(c *gin.Context) {
+	ctx := c.Request.Context()
+	keyID, ok := uuidParam(c, "id")
+	if !ok {
+		return
+	}
+
+	before, err := h.store.GetAPIKey(ctx, keyID)
+	if err != nil {
+		h.logger.Error("failed to get api key", zap.Error(err), zap.String("key_id", keyID))
+		problem.Internal(c, "Failed to revoke API key")
+		return
+	}
+	if before == nil {
+		problem.NotFound(c, "API key not found or already revoked")
+		return
+	}
+
+	if err := h.store.RevokeAPIKey(ctx, keyID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			problem.NotFound(c, "API key not found or already revoked")
+			return
+		}
+		h.logger.Error("failed to revoke api key", zap.Error(err), zap.String("key_id", keyID))
+		problem.Internal(c, "Failed to revoke API key")
+		return
+	}
+
+	h.logger.Info("api key revoked", zap.String("key_id", keyID))
+
+	after, err := h.store.GetAPIKey(ctx, keyID)
+	if err != nil {
+		h.logger.Warn("failed to reload revoked api key", zap.Error(err), zap.String("key_id", keyID))
+	}
+	if err := h.audit(c, AuditActionAPIKeyRevoke, "api_key", keyID, before, after); err != nil {
+		problem.Internal(c, auditFailed)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
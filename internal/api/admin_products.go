@@ -0,0 +1,270 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"regexp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/alldownloads/internal/cache"
+	"github.com/your-username/alldownloads/internal/problem"
+	"github.com/your-username/alldownloads/internal/sources"
+	"github.com/your-username/alldownloads/internal/store"
+	"go.uber.org/zap"
+)
+
+var productIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,62}$`)
+
+const productIDMessage = "must be a lowercase slug (a-z, 0-9, -)"
+
+// minMaxAgeSeconds keeps freshness SLOs above the worker's evaluation
+// interval; 0 selects the default.
+const (
+	minMaxAgeSeconds = 300
+	maxAgeMessage    = "must be 0 for the default or at least 300"
+)
+
+type productRequest struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Vendor      string `json:"vendor"`
+	Category    string `json:"category"`
+	Description string `json:"description"`
+	IconURL     string `json:"icon_url"`
+	WebsiteURL  string `json:"website_url"`
+	Fetcher     string `json:"fetcher"`
+	MaxAge      int    `json:"max_age_seconds"`
+}
+
+type productPatchRequest struct {
+	Name        *string `json:"name"`
+	Vendor      *string `json:"vendor"`
+	Category    *string `json:"category"`
+	Description *string `json:"description"`
+	IconURL     *string `json:"icon_url"`
+	WebsiteURL  *string `json:"website_url"`
+	Fetcher     *string `json:"fetcher"`
+	MaxAge      *int    `json:"max_age_seconds"`
+}
+
+func (r productRequest) toProduct() store.Product {
+	return store.Product{
+		ID:            r.ID,
+		Name:          r.Name,
+		Vendor:        r.Vendor,
+		Category:      r.Category,
+		Description:   r.Description,
+		IconURL:       r.IconURL,
+		WebsiteURL:    r.WebsiteURL,
+		Fetcher:       r.Fetcher,
+		MaxAgeSeconds: r.MaxAge,
+	}
+}
+
+func (r productPatchRequest) apply(p *store.Product) {
+	if r.Name != nil {
+		p.Name = *r.Name
+	}
+	if r.Vendor != nil {
+		p.Vendor = *r.Vendor
+	}
+	if r.Category != nil {
+		p.Category = *r.Category
+	}
+	if r.Description != nil {
+		p.Description = *r.Description
+	}
+	if r.IconURL != nil {
+		p.IconURL = *r.IconURL
+	}
+	if r.WebsiteURL != nil {
+		p.WebsiteURL = *r.WebsiteURL
+	}
+	if r.Fetcher != nil {
+		p.Fetcher = *r.Fetcher
+	}
+	if r.MaxAge != nil {
+		p.MaxAgeSeconds = *r.MaxAge
+	}
+}
+
+// validateProduct checks p and fills in defaults, returning every field
+// that is invalid.
+func validateProduct(p *store.Product) []problem.FieldError {
+	var errs []problem.FieldError
+	if !productIDPattern.MatchString(p.ID) {
+		errs = append(errs, problem.Field("id", problem.FieldInvalid, productIDMessage))
+	}
+	if p.Name == "" {
+		errs = append(errs, problem.Field("name", problem.FieldRequired, "is required"))
+	}
+	if p.Vendor == "" {
+		errs = append(errs, problem.Field("vendor", problem.FieldRequired, "is required"))
+	}
+	if !oneOf(p.Category, validCategories...) {
+		errs = append(errs, problem.Choice("category", validCategories))
+	}
+	if p.Fetcher == "" {
+		p.Fetcher = p.ID
+	}
+	if !sources.IsRegistered(p.Fetcher) {
+		errs = append(errs, problem.Choice("fetcher", sources.Names()))
+	}
+	if p.MaxAgeSeconds != 0 && p.MaxAgeSeconds < minMaxAgeSeconds {
+		errs = append(errs, problem.Field("max_age_seconds", problem.FieldOutOfRange, maxAgeMessage))
+	}
+	return errs
+}
+
+func (h *Handler) CreateProduct(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var req productRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	product := req.toProduct()
+	if errs := validateProduct(&product); len(errs) > 0 {
+		problem.Validation(c, errs...)
+		return
+	}
+
+	if err := h.store.CreateProduct(ctx, &product); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			problem.Conflict(c, "Product with this id already exists")
+			return
+		}
+		h.logger.Error("failed to create product", zap.Error(err), zap.String("product_id", product.ID))
+		problem.Internal(c, "Failed to create product")
+		return
+	}
+
+	h.logger.Info("product created", zap.String("product_id", product.ID), zap.String("fetcher", product.Fetcher))
+	h.enqueueFetch(ctx, product.ID)
+	if err := h.audit(c, AuditActionProductCreate, "product", product.ID, nil, product); err != nil {
+		problem.Internal(c, auditFailed)
+		return
+	}
+
+	c.JSON(http.StatusCreated, product)
+}
+
+func (h *Handler) ReplaceProduct(c *gin.Context) {
+	var req productRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.ID != "" && req.ID != c.Param("id") {
+		problem.Validation(c, problem.Field("id", problem.FieldInvalid, "does not match the URL"))
+		return
+	}
+
+	h.updateProduct(c, func(p *store.Product) {
+		replacement := req.toProduct()
+		replacement.ID = p.ID
+		replacement.CreatedAt = p.CreatedAt
+		replacement.LastSuccessAt = p.LastSuccessAt
+		replacement.Stale = p.Stale
+		replacement.StaleSince = p.StaleSince
+		*p = replacement
+	})
+}
+
+func (h *Handler) PatchProduct(c *gin.Context) {
+	var req productPatchRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	h.updateProduct(c, req.apply)
+}
+
+func (h *Handler) updateProduct(c *gin.Context, mutate func(p *store.Product)) {
+	ctx := c.Request.Context()
+	productID, ok := productIDParam(c)
+	if !ok {
+		return
+	}
+
+	product, err := h.store.GetProduct(ctx, productID)
+	if err != nil {
+		h.logger.Error("failed to get product", zap.Error(err), zap.String("product_id", productID))
+		problem.Internal(c, "Failed to update product")
+		return
+	}
+	if product == nil {
+		problem.NotFound(c, "Product not found")
+		return
+	}
+
+	before := *product
+	mutate(product)
+
+	if errs := validateProduct(product); len(errs) > 0 {
+		problem.Validation(c, errs...)
+		return
+	}
+
+	if err := h.store.UpdateProduct(ctx, product); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			problem.NotFound(c, "Product not found")
+			return
+		}
+		h.logger.Error("failed to update product", zap.Error(err), zap.String("product_id", productID))
+		problem.Internal(c, "Failed to update product")
+		return
+	}
+
+	h.logger.Info("product updated", zap.String("product_id", product.ID), zap.String("fetcher", product.Fetcher))
+	h.cache.Invalidate(ctx, product.ID, cache.ReasonAdmin)
+
+	// The worker resolves the fetcher per job, so a new reference takes effect
+	// on the next fetch; queue one now instead of waiting for the schedule.
+	if product.Fetcher != before.Fetcher {
+		h.enqueueFetch(ctx, product.ID)
+	}
+	if err := h.audit(c, AuditActionProductUpdate, "product", product.ID, before, product); err != nil {
+		problem.Internal(c, auditFailed)
+		return
+	}
+
+	c.JSON(http.StatusOK, product)
+}
+
+func (h *Handler) DeleteProduct(c *gin.Context) {
+	ctx := c.Request.Context()
+	productID, ok := productIDParam(c)
+	if !ok {
+		return
+	}
+
+	product, err := h.store.GetProduct(ctx, productID)
+	if err != nil {
+		h.logger.Error("failed to get product", zap.Error(err), zap.String("product_id", productID))
+		problem.Internal(c, "Failed to delete product")
+		return
+	}
+	if product == nil {
+		problem.NotFound(c, "Product not found")
+		return
+	}
+
+	if err := h.store.DeleteProduct(ctx, productID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			problem.NotFound(c, "Product not found")
+			return
+		}
+		h.logger.Error("failed to delete product", zap.Error(err), zap.String("product_id", productID))
+		problem.Internal(c, "Failed to delete product")
+		return
+	}
+
+	h.logger.Info("product deleted", zap.String("product_id", productID))
+	h.cache.Invalidate(ctx, productID, cache.ReasonAdmin)
+	if err := h.audit(c, AuditActionProductDelete, "product", productID, product, nil); err != nil {
+		problem.Internal(c, auditFailed)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/your-username/alldownloads/internal/auth"
+	"github.com/your-username/alldownloads/internal/cache"
+	"github.com/your-username/alldownloads/internal/jobs"
+	"github.com/your-username/alldownloads/internal/problem"
+	"github.com/your-username/alldownloads/internal/ratelimit"
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+// adminTest serves the product routes as cmd/api mounts them, backed by a
+// seeded SQLite store and a Redis cache and queue.
+type adminTest struct {
+	router *gin.Engine
+	store  *store.SQLiteStore
+	cache  *cache.ProductCache
+	queue  *jobs.Queue
+	keys   map[string]string
+}
+
+func newAdminTest(t *testing.T) *adminTest {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	logger := zap.NewNop()
+	s := newSQLiteStore(t)
+	redisURL := "redis://" + miniredis.RunT(t).Addr()
+
+	productCache, err := cache.NewProductCache(redisURL, time.Minute, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { productCache.Close() })
+	queue, err := jobs.NewQueue(redisURL, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { queue.Close() })
+
+	// One key per scope, named after it.
+	keys := make(map[string]string)
+	for _, scope := range auth.Scopes {
+		plaintext, prefix, hash, err := auth.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		key := &store.APIKey{Name: scope, Prefix: prefix, KeyHash: hash, Scopes: []string{scope}}
+		if err := s.CreateAPIKey(context.Background(), key); err != nil {
+			t.Fatal(err)
+		}
+		keys[scope] = plaintext
+	}
+
+	h := NewHandler(s, queue, productCache, nil, logger)
+	authMiddleware := auth.NewAuthMiddleware(s, "", ratelimit.NewMemoryLimiter(), logger)
+
+	router := gin.New()
+	router.GET("/products/:id", h.GetProduct)
+	admin := router.Group("/admin", authMiddleware.RequireScope(auth.ScopeAdmin))
+	admin.POST("/products", h.CreateProduct)
+	admin.PUT("/products/:id", h.ReplaceProduct)
+	admin.PATCH("/products/:id", h.PatchProduct)
+	admin.DELETE("/products/:id", h.DeleteProduct)
+
+	return &adminTest{router: router, store: s, cache: productCache, queue: queue, keys: keys}
+}
+
+// do sends body as the holder of the key for scope; an empty scope sends
+// no credentials.
+func (at *adminTest) do(scope, method, path, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	r.Header.Set("Content-Type", "application/json")
+	if scope != "" {
+		r.Header.Set("Authorization", "Bearer "+at.keys[scope])
+	}
+	w := httptest.NewRecorder()
+	at.router.ServeHTTP(w, r)
+	return w
+}
+
+func TestAdminProductsRequireAdmin(t *testing.T) {
+	at := newAdminTest(t)
+	body := `{"id": "scoped", "name": "Scoped", "vendor": "Test", "category": "app", "fetcher": "ubuntu"}`
+
+	for _, tt := range []struct {
+		method, path string
+	}{
+		{http.MethodPost, "/admin/products"},
+		{http.MethodPut, "/admin/products/ubuntu"},
+		{http.MethodPatch, "/admin/products/ubuntu"},
+		{http.MethodDelete, "/admin/products/ubuntu"},
+	} {
+		t.Run(tt.method, func(t *testing.T) {
+			assertProblem(t, at.do("", tt.method, tt.path, body), http.StatusUnauthorized, problem.CodeUnauthorized)
+			for _, scope := range []string{auth.ScopeRead, auth.ScopeRefresh} {
+				assertProblem(t, at.do(scope, tt.method, tt.path, body), http.StatusForbidden, problem.CodeInsufficientScope)
+			}
+		})
+	}
+
+	// Nothing was changed by the refused requests.
+	if p, err := at.store.GetProduct(context.Background(), "scoped"); err != nil || p != nil {
+		t.Fatalf("GetProduct(scoped) = %v, %v, want no product", p, err)
+	}
+	if p, err := at.store.GetProduct(context.Background(), "ubuntu"); err != nil || p == nil || p.Name != "Ubuntu" {
+		t.Fatalf("GetProduct(ubuntu) = %+v, %v, want it untouched", p, err)
+	}
+}
+
+func TestAdminProductsValidation(t *testing.T) {
+	at := newAdminTest(t)
+
+	for _, tt := range []struct {
+		name, method, path, body string
+		status                   int
+		code                     string
+		fields                   []string
+	}{
+		{
+			"empty product", http.MethodPost, "/admin/products", `{}`,
+			http.StatusBadRequest, problem.CodeValidationFailed, []string{"id", "name", "vendor", "category", "fetcher"},
+		},
+		{
+			"bad slug", http.MethodPost, "/admin/products", `{"id": "Not A Slug", "name": "X", "vendor": "Y", "category": "app", "fetcher": "ubuntu"}`,
+			http.StatusBadRequest, problem.CodeValidationFailed, []string{"id"},
+		},
+		{
+			"unknown fetcher", http.MethodPost, "/admin/products", `{"id": "unfetched", "name": "X", "vendor": "Y", "category": "app"}`,
+			http.StatusBadRequest, problem.CodeValidationFailed, []string{"fetcher"},
+		},
+		{
+			"freshness SLO below the minimum", http.MethodPost, "/admin/products", `{"id": "hasty", "name": "X", "vendor": "Y", "category": "app", "fetcher": "ubuntu", "max_age_seconds": 60}`,
+			http.StatusBadRequest, problem.CodeValidationFailed, []string{"max_age_seconds"},
+		},
+		{
+			"wrong type", http.MethodPost, "/admin/products", `{"id": 42}`,
+			http.StatusBadRequest, problem.CodeValidationFailed, []string{"id"},
+		},
+		{
+			"not JSON", http.MethodPost, "/admin/products", `{"id":`,
+			http.StatusBadRequest, problem.CodeInvalidRequest, nil,
+		},
+		{
+			"existing ID", http.MethodPost, "/admin/products", `{"id": "ubuntu", "name": "X", "vendor": "Y", "category": "os"}`,
+			http.StatusConflict, problem.CodeConflict, nil,
+		},
+		{
+			"ID that does not match the URL", http.MethodPut, "/admin/products/ubuntu", `{"id": "debian", "name": "X", "vendor": "Y", "category": "os"}`,
+			http.StatusBadRequest, problem.CodeValidationFailed, []string{"id"},
+		},
+		{
+			"replacement missing fields", http.MethodPut, "/admin/products/ubuntu", `{"name": "Ubuntu"}`,
+			http.StatusBadRequest, problem.CodeValidationFailed, []string{"vendor", "category"},
+		},
+		{
+			"patch to an unknown category", http.MethodPatch, "/admin/products/ubuntu", `{"category": "games"}`,
+			http.StatusBadRequest, problem.CodeValidationFailed, []string{"category"},
+		},
+		{
+			"patch clearing a required field", http.MethodPatch, "/admin/products/ubuntu", `{"name": ""}`,
+			http.StatusBadRequest, problem.CodeValidationFailed, []string{"name"},
+		},
+		{
+			"bad slug in the URL", http.MethodDelete, "/admin/products/Not_A_Slug", ``,
+			http.StatusBadRequest, problem.CodeValidationFailed, []string{"id"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			assertProblem(t, at.do(auth.ScopeAdmin, tt.method, tt.path, tt.body), tt.status, tt.code, tt.fields...)
+		})
+	}
+
+	if p, err := at.store.GetProduct(context.Background(), "ubuntu"); err != nil || p.Name != "Ubuntu" || p.Category != store.CategoryOS {
+		t.Fatalf("GetProduct(ubuntu) = %+v, %v, want it untouched", p, err)
+	}
+}
+
+func TestAdminProductsUnknownID(t *testing.T) {
+	at := newAdminTest(t)
+	replacement := `{"name": "Ghost", "vendor": "Nobody", "category": "app", "fetcher": "ubuntu"}`
+
+	for _, tt := range []struct {
+		method, body string
+	}{
+		{http.MethodPut, replacement},
+		{http.MethodPatch, `{"name": "Ghost"}`},
+		{http.MethodDelete, ``},
+	} {
+		t.Run(tt.method, func(t *testing.T) {
+			assertProblem(t, at.do(auth.ScopeAdmin, tt.method, "/admin/products/ghost", tt.body), http.StatusNotFound, problem.CodeNotFound)
+		})
+	}
+}
+
+func TestAdminProductsLifecycle(t *testing.T) {
+	at := newAdminTest(t)
+	ctx := context.Background()
+
+	w := at.do(auth.ScopeAdmin, http.MethodPost, "/admin/products",
+		`{"id": "newcomer", "name": "Newcomer", "vendor": "Test", "category": "app", "fetcher": "ubuntu"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("POST = %d: %s", w.Code, w.Body)
+	}
+	// A new product is fetched straight away.
+	if pending, err := at.queue.GetQueueLength(ctx); err != nil || pending != 1 {
+		t.Fatalf("queue length after create = %d, %v, want 1", pending, err)
+	}
+
+	// Reading the product fills the cache; every edit must empty it.
+	for _, tt := range []struct {
+		method, body, name string
+	}{
+		{http.MethodPatch, `{"name": "Renamed"}`, "Renamed"},
+		{http.MethodPut, `{"name": "Replaced", "vendor": "Test", "category": "tool", "fetcher": "ubuntu"}`, "Replaced"},
+	} {
+		var before store.ProductWithVersions
+		decode(t, request(at.router, http.MethodGet, "/products/newcomer", nil), &before)
+		if cached, _ := at.cache.Get(ctx, "newcomer"); cached == nil {
+			t.Fatalf("%s: product was not cached by GET", tt.method)
+		}
+
+		var edited store.Product
+		decode(t, at.do(auth.ScopeAdmin, tt.method, "/admin/products/newcomer", tt.body), &edited)
+		if edited.Name != tt.name {
+			t.Fatalf("%s returned %+v, want name %q", tt.method, edited, tt.name)
+		}
+		if cached, _ := at.cache.Get(ctx, "newcomer"); cached != nil {
+			t.Fatalf("%s left the cached product %+v", tt.method, cached.Product.Product)
+		}
+
+		var after store.ProductWithVersions
+		decode(t, request(at.router, http.MethodGet, "/products/newcomer", nil), &after)
+		if after.Product.Name != tt.name {
+			t.Fatalf("GET after %s = %q, want %q", tt.method, after.Product.Name, tt.name)
+		}
+	}
+
+	// Only a change of fetcher queues another fetch.
+	if pending, _ := at.queue.GetQueueLength(ctx); pending != 1 {
+		t.Fatalf("queue length after edits = %d, want 1", pending)
+	}
+	decode(t, at.do(auth.ScopeAdmin, http.MethodPatch, "/admin/products/newcomer", `{"fetcher": "debian"}`), &store.Product{})
+	if pending, _ := at.queue.GetQueueLength(ctx); pending != 2 {
+		t.Fatalf("queue length after changing the fetcher = %d, want 2", pending)
+	}
+
+	decode(t, request(at.router, http.MethodGet, "/products/newcomer", nil), &store.ProductWithVersions{})
+	if w := at.do(auth.ScopeAdmin, http.MethodDelete, "/admin/products/newcomer", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("DELETE = %d: %s", w.Code, w.Body)
+	}
+	if cached, _ := at.cache.Get(ctx, "newcomer"); cached != nil {
+		t.Fatal("DELETE left the product cached")
+	}
+	assertProblem(t, request(at.router, http.MethodGet, "/products/newcomer", nil), http.StatusNotFound, problem.CodeNotFound)
+
+	entries, err := at.store.ListAuditEntries(ctx, store.AuditFilter{TargetID: "newcomer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries.Entries) != 5 {
+		t.Fatalf("audit log has %d entries for the product, want 5", len(entries.Entries))
+	}
+}
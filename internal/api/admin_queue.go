@@ -0,0 +1,123 @@
+package api
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/alldownloads/internal/events"
+	"github.com/your-username/alldownloads/internal/jobs"
+	"github.com/your-username/alldownloads/internal/problem"
+	"github.com/your-username/alldownloads/internal/store"
+	"go.uber.org/zap"
+)
+
+type workerHeartbeat struct {
+	ID            string    `json:"id"`
+	LastHeartbeat time.Time `json:"last_heartbeat"`
+}
+
+type queueStatus struct {
+	Pending    int64             `json:"pending"`
+	Processing int64             `json:"processing"`
+	Workers    []workerHeartbeat `json:"workers"`
+}
+
+type requeueResult struct {
+	DryRun bool             `json:"dry_run"`
+	Jobs   []store.FetchJob `json:"jobs"`
+}
+
+func (h *Handler) GetQueueStatus(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var status queueStatus
+	var err error
+	if status.Pending, err = h.jobQueue.GetQueueLength(ctx); err != nil {
+		h.logger.Error("failed to get queue length", zap.Error(err))
+		problem.Internal(c, "Failed to fetch queue status")
+		return
+	}
+	if status.Processing, err = h.jobQueue.GetProcessingCount(ctx); err != nil {
+		h.logger.Error("failed to get processing count", zap.Error(err))
+		problem.Internal(c, "Failed to fetch queue status")
+		return
+	}
+
+	heartbeats, err := h.jobQueue.GetHeartbeats(ctx)
+	if err != nil {
+		h.logger.Error("failed to get worker heartbeats", zap.Error(err))
+		problem.Internal(c, "Failed to fetch queue status")
+		return
+	}
+	status.Workers = make([]workerHeartbeat, 0, len(heartbeats))
+	for id, at := range heartbeats {
+		status.Workers = append(status.Workers, workerHeartbeat{ID: id, LastHeartbeat: at.UTC()})
+	}
+	sort.Slice(status.Workers, func(i, j int) bool { return status.Workers[i].ID < status.Workers[j].ID })
+
+	c.JSON(http.StatusOK, status)
+}
+
+// RequeueDeadJobs queues again the jobs that failed for good or whose
+// worker died, see jobs.FindDeadJobs. With dry_run it only lists them.
+func (h *Handler) RequeueDeadJobs(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	var errs []problem.FieldError
+	stuckAfter := jobs.DefaultStuckAfter
+	if value := c.Query("stuck_after"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			errs = append(errs, problem.Field("stuck_after", problem.FieldInvalid, "must be a positive duration like 30m"))
+		}
+		stuckAfter = d
+	}
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			errs = append(errs, problem.Field("dry_run", problem.FieldInvalid, "must be a boolean"))
+		}
+	}
+	if len(errs) > 0 {
+		problem.Validation(c, errs...)
+		return
+	}
+
+	dead, err := jobs.FindDeadJobs(ctx, h.store, h.jobQueue, stuckAfter)
+	if err != nil {
+		h.logger.Error("failed to find dead jobs", zap.Error(err))
+		problem.Internal(c, "Failed to find dead jobs")
+		return
+	}
+	if dryRun {
+		c.JSON(http.StatusOK, requeueResult{DryRun: true, Jobs: dead})
+		return
+	}
+
+	requeued := []store.FetchJob{}
+	var jobIDs []string
+	for i := range dead {
+		job := &dead[i]
+		if err := jobs.RequeueJob(ctx, h.store, h.jobQueue, job); err != nil {
+			h.logger.Error("failed to requeue job", zap.Error(err), zap.String("job_id", job.ID))
+			continue
+		}
+		h.events.Publish(ctx, events.Event{Type: events.JobQueued, JobID: job.ID, ProductID: job.ProductID})
+		requeued = append(requeued, *job)
+		jobIDs = append(jobIDs, job.ID)
+	}
+
+	h.logger.Info("dead jobs requeued", zap.Int("jobs_requeued", len(requeued)))
+	if len(requeued) > 0 {
+		if err := h.audit(c, AuditActionJobRequeue, "fetch_job", "*", nil, gin.H{"jobs_requeued": len(requeued), "job_ids": jobIDs}); err != nil {
+			problem.Internal(c, auditFailed)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, requeueResult{Jobs: requeued})
+}
@@ -0,0 +1,288 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/alldownloads/internal/cache"
+	"github.com/your-username/alldownloads/internal/problem"
+	"github.com/your-username/alldownloads/internal/store"
+	"go.uber.org/zap"
+)
+
+var validChecksumTypes = []string{"", "sha256", "sha512", "sha1", "md5"}
+
+type versionRequest struct {
+	Version      *string `json:"version"`
+	Platform     *string `json:"platform"`
+	Architecture *string `json:"architecture"`
+	Channel      *string `json:"channel"`
+	DownloadURL  *string `json:"download_url"`
+	Checksum     *string `json:"checksum"`
+	ChecksumType *string `json:"checksum_type"`
+	FileSize     *int64  `json:"file_size"`
+	Filename     *string `json:"filename"`
+	Pinned       *bool   `json:"pinned"`
+	Hidden       *bool   `json:"hidden"`
+}
+
+// apply copies the set fields onto v and reports whether any of the
+// download details changed, as opposed to only the pinned/hidden flags.
+func (r versionRequest) apply(v *store.ProductVersion) bool {
+	contentChanged := false
+	setString := func(dst *string, src *string) {
+		if src != nil && *dst != *src {
+			*dst = *src
+			contentChanged = true
+		}
+	}
+
+	setString(&v.Version, r.Version)
+	setString(&v.Platform, r.Platform)
+	setString(&v.Architecture, r.Architecture)
+	setString(&v.Channel, r.Channel)
+	setString(&v.DownloadURL, r.DownloadURL)
+	setString(&v.Checksum, r.Checksum)
+	setString(&v.ChecksumType, r.ChecksumType)
+	setString(&v.Filename, r.Filename)
+	if r.FileSize != nil && v.FileSize != *r.FileSize {
+		v.FileSize = *r.FileSize
+		contentChanged = true
+	}
+	if r.Pinned != nil {
+		v.Pinned = *r.Pinned
+	}
+	if r.Hidden != nil {
+		v.Hidden = *r.Hidden
+	}
+
+	return contentChanged
+}
+
+// validateVersion checks v and fills in defaults, returning every field
+// that is invalid.
+func validateVersion(v *store.ProductVersion) []problem.FieldError {
+	var errs []problem.FieldError
+	if v.Version == "" {
+		errs = append(errs, problem.Field("version", problem.FieldRequired, "is required"))
+	}
+	if !oneOf(v.Platform, validPlatforms...) {
+		errs = append(errs, problem.Choice("platform", validPlatforms))
+	}
+	if !oneOf(v.Architecture, validArchitectures...) {
+		errs = append(errs, problem.Choice("architecture", validArchitectures))
+	}
+	if v.Channel == "" {
+		v.Channel = store.ChannelStable
+	}
+	if !oneOf(v.Channel, validChannels...) {
+		errs = append(errs, problem.Choice("channel", validChannels))
+	}
+	u, err := url.Parse(v.DownloadURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		errs = append(errs, problem.Field("download_url", problem.FieldInvalid, "must be an absolute http(s) URL"))
+	}
+	if !oneOf(v.ChecksumType, validChecksumTypes...) {
+		errs = append(errs, problem.Choice("checksum_type", validChecksumTypes[1:]))
+	} else if v.Checksum != "" && v.ChecksumType == "" {
+		errs = append(errs, problem.Field("checksum_type", problem.FieldRequired, "is required when checksum is set"))
+	}
+	if v.FileSize < 0 {
+		errs = append(errs, problem.Field("file_size", problem.FieldOutOfRange, "must not be negative"))
+	}
+	return errs
+}
+
+func (h *Handler) ListProductVersionsAdmin(c *gin.Context) {
+	ctx := c.Request.Context()
+	productID, ok := productIDParam(c)
+	if !ok {
+		return
+	}
+
+	limit, err := parseLimit(c.Query("limit"))
+	if err != nil {
+		problem.Validation(c, problem.Field("limit", problem.FieldOutOfRange, err.Error()))
+		return
+	}
+
+	page, err := h.store.ListVersions(ctx, store.VersionFilter{
+		ProductID:     productID,
+		IncludeHidden: true,
+		Sort:          "product",
+		Cursor:        c.Query("cursor"),
+		Limit:         limit,
+	})
+	if err != nil {
+		if field, ok := pageFieldError(err); ok {
+			problem.Validation(c, field)
+			return
+		}
+		h.logger.Error("failed to list versions", zap.Error(err), zap.String("product_id", productID))
+		problem.Internal(c, "Failed to fetch versions")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *Handler) CreateProductVersion(c *gin.Context) {
+	ctx := c.Request.Context()
+	productID, ok := productIDParam(c)
+	if !ok {
+		return
+	}
+
+	var req versionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	product, err := h.store.GetProduct(ctx, productID)
+	if err != nil {
+		h.logger.Error("failed to get product", zap.Error(err), zap.String("product_id", productID))
+		problem.Internal(c, "Failed to create version")
+		return
+	}
+	if product == nil {
+		problem.NotFound(c, "Product not found")
+		return
+	}
+
+	version := &store.ProductVersion{ProductID: productID}
+	req.apply(version)
+	if errs := validateVersion(version); len(errs) > 0 {
+		problem.Validation(c, errs...)
+		return
+	}
+
+	if err := h.store.CreateManualVersion(ctx, version); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			problem.Conflict(c, "A version with this version, platform and architecture already exists")
+			return
+		}
+		h.logger.Error("failed to create version", zap.Error(err), zap.String("product_id", productID))
+		problem.Internal(c, "Failed to create version")
+		return
+	}
+
+	h.remarkLatest(c, version)
+	h.logger.Info("manual version created", zap.String("product_id", productID), zap.String("version_id", version.ID))
+	h.cache.Invalidate(ctx, productID, cache.ReasonAdmin)
+	if err := h.audit(c, AuditActionVersionCreate, "version", version.ID, nil, version); err != nil {
+		problem.Internal(c, auditFailed)
+		return
+	}
+
+	c.JSON(http.StatusCreated, version)
+}
+
+func (h *Handler) UpdateProductVersion(c *gin.Context) {
+	ctx := c.Request.Context()
+	versionID, ok := uuidParam(c, "versionId")
+	if !ok {
+		return
+	}
+
+	var req versionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	version, err := h.store.GetProductVersion(ctx, versionID)
+	if err != nil {
+		h.logger.Error("failed to get version", zap.Error(err), zap.String("version_id", versionID))
+		problem.Internal(c, "Failed to update version")
+		return
+	}
+	if version == nil {
+		problem.NotFound(c, "Version not found")
+		return
+	}
+
+	before := *version
+	if req.apply(version) {
+		version.Source = store.VersionSourceManual
+	}
+	if errs := validateVersion(version); len(errs) > 0 {
+		problem.Validation(c, errs...)
+		return
+	}
+
+	if err := h.store.UpdateProductVersion(ctx, version); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			problem.NotFound(c, "Version not found")
+		case errors.Is(err, store.ErrConflict):
+			problem.Conflict(c, "A version with this version, platform and architecture already exists")
+		default:
+			h.logger.Error("failed to update version", zap.Error(err), zap.String("version_id", versionID))
+			problem.Internal(c, "Failed to update version")
+		}
+		return
+	}
+
+	h.remarkLatest(c, version)
+	h.logger.Info("version updated", zap.String("version_id", version.ID), zap.String("source", version.Source),
+		zap.Bool("pinned", version.Pinned), zap.Bool("hidden", version.Hidden))
+	h.cache.Invalidate(ctx, version.ProductID, cache.ReasonAdmin)
+	if err := h.audit(c, AuditActionVersionUpdate, "version", version.ID, before, version); err != nil {
+		problem.Internal(c, auditFailed)
+		return
+	}
+
+	c.JSON(http.StatusOK, version)
+}
+
+func (h *Handler) DeleteProductVersion(c *gin.Context) {
+	ctx := c.Request.Context()
+	versionID, ok := uuidParam(c, "versionId")
+	if !ok {
+		return
+	}
+
+	version, err := h.store.GetProductVersion(ctx, versionID)
+	if err != nil {
+		h.logger.Error("failed to get version", zap.Error(err), zap.String("version_id", versionID))
+		problem.Internal(c, "Failed to delete version")
+		return
+	}
+	if version == nil {
+		problem.NotFound(c, "Version not found")
+		return
+	}
+
+	if err := h.store.DeleteProductVersion(ctx, versionID); err != nil && !errors.Is(err, store.ErrNotFound) {
+		h.logger.Error("failed to delete version", zap.Error(err), zap.String("version_id", versionID))
+		problem.Internal(c, "Failed to delete version")
+		return
+	}
+
+	h.remarkLatest(c, version)
+	h.logger.Info("version deleted", zap.String("version_id", versionID))
+	h.cache.Invalidate(ctx, version.ProductID, cache.ReasonAdmin)
+	if err := h.audit(c, AuditActionVersionDelete, "version", versionID, version, nil); err != nil {
+		problem.Internal(c, auditFailed)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// remarkLatest recomputes is_latest after an admin change so that pins and
+// hidden entries take effect without waiting for the next fetch. It reloads
+// the version afterwards so the response shows the resulting flag.
+func (h *Handler) remarkLatest(c *gin.Context, version *store.ProductVersion) {
+	ctx := c.Request.Context()
+
+	if err := h.store.MarkLatestVersions(ctx, version.ProductID); err != nil {
+		h.logger.Error("failed to mark latest versions", zap.Error(err), zap.String("product_id", version.ProductID))
+		return
+	}
+
+	if reloaded, err := h.store.GetProductVersion(ctx, version.ID); err == nil && reloaded != nil {
+		*version = *reloaded
+	}
+}
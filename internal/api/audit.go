@@ -0,0 +1,231 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/alldownloads/internal/auth"
+	"github.com/your-username/alldownloads/internal/problem"
+	"github.com/your-username/alldownloads/internal/store"
+	"go.uber.org/zap"
+)
+
+const (
+	AuditActionRefresh       = "refresh"
+	AuditActionProductCreate = "product.create"
+	AuditActionProductUpdate = "product.update"
+	AuditActionProductDelete = "product.delete"
+	AuditActionVersionCreate = "version.create"
+	AuditActionVersionUpdate = "version.update"
+	AuditActionVersionDelete = "version.delete"
+	AuditActionAPIKeyCreate  = "api_key.create"
+	AuditActionAPIKeyRevoke  = "api_key.revoke"
+	AuditActionJobRequeue    = "job.requeue"
+)
+
+// auditFailed is the detail of the error answered when a change was made
+// but its audit entry could not be written.
+const auditFailed = "The change was applied but could not be recorded in the audit log"
+
+// audit records a mutation made by the authenticated caller. before and
+// after are snapshots of the target (nil when it did not exist); only the
+// fields that differ are stored. The change has already happened, so
+// callers finish its side effects first and then answer with auditFailed
+// when audit returns an error: an unaudited change must not look like a
+// success.
+func (h *Handler) audit(c *gin.Context, action, targetType, targetID string, before, after interface{}) error {
+	// Values are cut to their columns so that a long name or ID cannot make
+	// the insert fail.
+	entry := &store.AuditEntry{
+		ActorType:  "anonymous",
+		ActorID:    "anonymous",
+		Action:     clip(action, 100),
+		TargetType: clip(targetType, 50),
+		TargetID:   clip(targetID, 255),
+		RequestID:  clip(c.GetString("request_id"), 100),
+		ClientIP:   clip(c.ClientIP(), 64),
+	}
+	if principal := auth.GetPrincipal(c); principal != nil {
+		entry.ActorType = clip(principal.Type, 50)
+		entry.ActorID = clip(principal.ID, 255)
+		entry.ActorName = clip(principal.Name, 255)
+	}
+
+	changes, err := diff(before, after)
+	if err != nil {
+		h.logger.Error("failed to diff audit snapshots", zap.Error(err), zap.String("action", action))
+	}
+	entry.Changes = changes
+
+	if err := h.store.CreateAuditEntry(c.Request.Context(), entry); err != nil {
+		h.logger.Error("failed to write audit entry", zap.Error(err), zap.String("action", action),
+			zap.String("target_id", targetID), zap.String("actor_id", entry.ActorID))
+		return err
+	}
+	return nil
+}
+
+// clip cuts s to at most n characters.
+func clip(s string, n int) string {
+	for i := range s {
+		if n == 0 {
+			return s[:i]
+		}
+		n--
+	}
+	return s
+}
+
+// diff compares the JSON representations of before and after field by
+// field, so it sees exactly what the API exposes and never fields hidden
+// from JSON such as key hashes.
+func diff(before, after interface{}) (map[string]store.AuditChange, error) {
+	beforeFields, err := jsonFields(before)
+	if err != nil {
+		return nil, err
+	}
+	afterFields, err := jsonFields(after)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := make(map[string]store.AuditChange)
+	for field, value := range beforeFields {
+		if !reflect.DeepEqual(value, afterFields[field]) {
+			changes[field] = store.AuditChange{Before: value, After: afterFields[field]}
+		}
+	}
+	for field, value := range afterFields {
+		if _, seen := beforeFields[field]; !seen && value != nil {
+			changes[field] = store.AuditChange{After: value}
+		}
+	}
+	return changes, nil
+}
+
+func jsonFields(v interface{}) (map[string]interface{}, error) {
+	if v == nil || (reflect.ValueOf(v).Kind() == reflect.Ptr && reflect.ValueOf(v).IsNil()) {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func (h *Handler) ListAuditLog(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	filter := store.AuditFilter{
+		ActorID:    c.Query("actor_id"),
+		Action:     c.Query("action"),
+		TargetType: c.Query("target_type"),
+		TargetID:   c.Query("target_id"),
+		RequestID:  c.Query("request_id"),
+		Cursor:     c.Query("cursor"),
+	}
+
+	var errs []problem.FieldError
+	now := time.Now()
+	if value := c.Query("since"); value != "" {
+		since, err := parseSince(value, now)
+		if err != nil {
+			errs = append(errs, problem.Field("since", problem.FieldInvalid, err.Error()))
+		}
+		filter.Since = &since
+	}
+	if value := c.Query("until"); value != "" {
+		until, err := parseSince(value, now)
+		if err != nil {
+			errs = append(errs, problem.Field("until", problem.FieldInvalid, err.Error()))
+		}
+		filter.Until = &until
+	}
+
+	limit, err := parseLimit(c.Query("limit"))
+	if err != nil {
+		errs = append(errs, problem.Field("limit", problem.FieldOutOfRange, err.Error()))
+	}
+	filter.Limit = limit
+
+	format := c.DefaultQuery("format", "json")
+	if !oneOf(format, "json", "jsonl") {
+		errs = append(errs, problem.Choice("format", []string{"json", "jsonl"}))
+	}
+
+	if len(errs) > 0 {
+		problem.Validation(c, errs...)
+		return
+	}
+	if format == "jsonl" {
+		h.exportAuditLog(c, filter)
+		return
+	}
+
+	page, err := h.store.ListAuditEntries(ctx, filter)
+	if err != nil {
+		if field, ok := pageFieldError(err); ok {
+			problem.Validation(c, field)
+			return
+		}
+		h.logger.Error("failed to list audit log", zap.Error(err))
+		problem.Internal(c, "Failed to fetch audit log")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+// exportAuditLog streams every matching entry as JSON lines, newest first,
+// walking the pages itself so that exports are not capped by limit.
+func (h *Handler) exportAuditLog(c *gin.Context, filter store.AuditFilter) {
+	ctx := c.Request.Context()
+	filter.Limit = store.MaxPageSize
+
+	page, err := h.store.ListAuditEntries(ctx, filter)
+	if err != nil {
+		if field, ok := pageFieldError(err); ok {
+			problem.Validation(c, field)
+			return
+		}
+		h.logger.Error("failed to export audit log", zap.Error(err))
+		problem.Internal(c, "Failed to export audit log")
+		return
+	}
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Header("Content-Disposition", `attachment; filename="audit-log.jsonl"`)
+	c.Status(http.StatusOK)
+
+	encoder := json.NewEncoder(c.Writer)
+	for {
+		for _, entry := range page.Entries {
+			if err := encoder.Encode(entry); err != nil {
+				return
+			}
+		}
+		c.Writer.Flush()
+
+		if page.NextCursor == "" {
+			return
+		}
+
+		filter.Cursor = page.NextCursor
+		page, err = h.store.ListAuditEntries(ctx, filter)
+		if err != nil {
+			// Headers are already sent; all we can do is cut the stream short.
+			h.logger.Error("failed to export audit log", zap.Error(err))
+			return
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/your-username/alldownloads/internal/auth"
+	"github.com/your-username/alldownloads/internal/problem"
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+// failingAuditStore refuses every audit entry.
+type failingAuditStore struct {
+	store.Store
+}
+
+func (s failingAuditStore) CreateAuditEntry(ctx context.Context, entry *store.AuditEntry) error {
+	return errors.New("value too long for type character varying(100)")
+}
+
+func newSQLiteStore(t *testing.T) *store.SQLiteStore {
+	t.Helper()
+	s, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "api.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(s.Close)
+	return s
+}
+
+func TestClip(t *testing.T) {
+	for _, tt := range []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"abc", 5, "abc"},
+		{"abc", 3, "abc"},
+		{"abcdef", 3, "abc"},
+		{"äöüß", 2, "äö"},
+		{"abc", 0, ""},
+		{"", 3, ""},
+	} {
+		if got := clip(tt.s, tt.n); got != tt.want {
+			t.Errorf("clip(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestAuditClipsLongFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sqlite := newSQLiteStore(t)
+	h := NewHandler(sqlite, nil, nil, nil, zap.NewNop())
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodDelete, "/", nil)
+	c.Set("request_id", strings.Repeat("r", 300))
+	c.Set(auth.PrincipalKey, &auth.Principal{Type: auth.PrincipalTypeUser, ID: strings.Repeat("i", 300), Name: strings.Repeat("ñ", 300)})
+
+	if err := h.audit(c, AuditActionProductDelete, "product", strings.Repeat("p", 300), nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	page, err := sqlite.ListAuditEntries(context.Background(), store.AuditFilter{Limit: 10})
+	if err != nil || len(page.Entries) != 1 {
+		t.Fatalf("ListAuditEntries = %v, %v", page, err)
+	}
+	entry := page.Entries[0]
+	for field, got := range map[string]int{
+		"request_id": len([]rune(entry.RequestID)),
+		"actor_id":   len([]rune(entry.ActorID)),
+		"actor_name": len([]rune(entry.ActorName)),
+		"target_id":  len([]rune(entry.TargetID)),
+	} {
+		want := 255
+		if field == "request_id" {
+			want = 100
+		}
+		if got != want {
+			t.Errorf("%s has %d characters, want %d", field, got, want)
+		}
+	}
+}
+
+func TestUnauditedChangeIsAnError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sqlite := newSQLiteStore(t)
+	ctx := context.Background()
+	if err := sqlite.CreateProduct(ctx, &store.Product{ID: "audited", Name: "Audited", Vendor: "Test", Category: store.CategoryApp}); err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set(auth.PrincipalKey, &auth.Principal{Type: auth.PrincipalTypeAPIKey, ID: "admin", Scopes: []string{auth.ScopeAdmin}})
+	})
+	h := NewHandler(failingAuditStore{sqlite}, nil, nil, nil, zap.NewNop())
+	router.DELETE("/products/:id", h.DeleteProduct)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/products/audited", nil))
+
+	var body problem.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusInternalServerError || body.Code != problem.CodeInternal || body.Detail != auditFailed {
+		t.Fatalf("DELETE = %d %+v, want a 500 saying the change is unaudited", w.Code, body)
+	}
+	if product, err := sqlite.GetProduct(ctx, "audited"); err != nil || product != nil {
+		t.Fatalf("GetProduct = %v, %v, want the delete to have happened", product, err)
+	}
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+// DefaultCacheControl lets clients and shared caches reuse a response for a
+// minute and then revalidate it, which is cheap thanks to the validators.
+const DefaultCacheControl = "public, max-age=60, must-revalidate"
+
+// notModified sets ETag, Last-Modified and Cache-Control for a response
+// derived from v and answers 304 when the request's validators match. It
+// reports whether the response has been written. resource identifies the
+// representation, including any query parameters that shape it.
+func (h *Handler) notModified(c *gin.Context, resource string, v *store.Validator) bool {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%d", resource, v.LastModified.UnixNano(), v.Rows)))
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+	lastModified := v.LastModified.UTC().Truncate(time.Second)
+
+	c.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.Format(http.TimeFormat))
+	}
+	c.Header("Cache-Control", h.cacheControl)
+	c.Header("Vary", "Authorization")
+
+	// If-None-Match takes precedence over If-Modified-Since (RFC 9110 13.2.2).
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		if etagMatches(inm, etag) {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+		return false
+	}
+
+	if ims := c.GetHeader("If-Modified-Since"); ims != "" && !lastModified.IsZero() {
+		if t, err := http.ParseTime(ims); err == nil && !lastModified.After(t) {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+
+	return false
+}
+
+// etagMatches implements the weak comparison If-None-Match calls for.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
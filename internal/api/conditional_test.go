@@ -0,0 +1,171 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+func TestETagMatches(t *testing.T) {
+	const etag = `"abc"`
+	for header, want := range map[string]bool{
+		`"abc"`:               true,
+		`W/"abc"`:             true,
+		`"xyz", "abc"`:        true,
+		`"xyz",W/"abc"`:       true,
+		`*`:                   true,
+		`"xyz"`:               false,
+		`abc`:                 false,
+		`"ABC"`:               false,
+		`"abc`:                false,
+		`w/"abc"`:             false,
+		`"xyz", "abc-suffix"`: false,
+	} {
+		if got := etagMatches(header, etag); got != want {
+			t.Errorf("etagMatches(%s) = %v, want %v", header, got, want)
+		}
+	}
+}
+
+// newConditionalRouter answers GET /resource with a body unless the
+// request's validators match v.
+func newConditionalRouter(v *store.Validator) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	h := NewHandler(nil, nil, nil, nil, zap.NewNop())
+	router := gin.New()
+	router.GET("/resource", func(c *gin.Context) {
+		if !h.notModified(c, "resource", v) {
+			c.String(http.StatusOK, "body")
+		}
+	})
+	return router
+}
+
+func conditionalGet(router http.Handler, headers map[string]string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodGet, "/resource", nil)
+	for name, value := range headers {
+		r.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func TestNotModified(t *testing.T) {
+	modified := time.Date(2024, 1, 15, 10, 30, 0, 500_000_000, time.UTC)
+	router := newConditionalRouter(&store.Validator{LastModified: modified, Rows: 3})
+
+	fresh := conditionalGet(router, nil)
+	etag := fresh.Header().Get("ETag")
+	if fresh.Code != http.StatusOK || len(etag) != 34 || etag[0] != '"' {
+		t.Fatalf("unconditional GET = %d with ETag %q", fresh.Code, etag)
+	}
+	for name, want := range map[string]string{
+		"Last-Modified": "Mon, 15 Jan 2024 10:30:00 GMT",
+		"Cache-Control": DefaultCacheControl,
+		"Vary":          "Authorization",
+	} {
+		if got := fresh.Header().Get(name); got != want {
+			t.Errorf("%s = %q, want %q", name, got, want)
+		}
+	}
+
+	lastModified := fresh.Header().Get("Last-Modified")
+	before := modified.Add(-time.Hour).Format(http.TimeFormat)
+	after := modified.Add(time.Hour).Format(http.TimeFormat)
+
+	for _, tt := range []struct {
+		name    string
+		headers map[string]string
+		status  int
+	}{
+		{"matching ETag", map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"weak ETag", map[string]string{"If-None-Match": "W/" + etag}, http.StatusNotModified},
+		{"ETag in a list", map[string]string{"If-None-Match": `"stale", ` + etag}, http.StatusNotModified},
+		{"any ETag", map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{"other ETag", map[string]string{"If-None-Match": `"stale"`}, http.StatusOK},
+		{"same Last-Modified", map[string]string{"If-Modified-Since": lastModified}, http.StatusNotModified},
+		{"later date", map[string]string{"If-Modified-Since": after}, http.StatusNotModified},
+		{"earlier date", map[string]string{"If-Modified-Since": before}, http.StatusOK},
+		{"unparseable date", map[string]string{"If-Modified-Since": "yesterday"}, http.StatusOK},
+		// If-None-Match decides on its own when both are sent.
+		{"ETag mismatch overrides a date match", map[string]string{"If-None-Match": `"stale"`, "If-Modified-Since": after}, http.StatusOK},
+		{"ETag match overrides a date mismatch", map[string]string{"If-None-Match": etag, "If-Modified-Since": before}, http.StatusNotModified},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w := conditionalGet(router, tt.headers)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if tt.status == http.StatusNotModified && w.Body.Len() != 0 {
+				t.Fatalf("304 has a body: %q", w.Body)
+			}
+			// Validators are sent either way, so a cache can refresh them.
+			if w.Header().Get("ETag") != etag || w.Header().Get("Last-Modified") != lastModified {
+				t.Fatalf("validators = %q, %q", w.Header().Get("ETag"), w.Header().Get("Last-Modified"))
+			}
+		})
+	}
+
+	// The ETag changes with the data behind the representation.
+	for _, v := range []*store.Validator{
+		{LastModified: modified, Rows: 4},
+		{LastModified: modified.Add(time.Millisecond), Rows: 3},
+	} {
+		if w := conditionalGet(newConditionalRouter(v), map[string]string{"If-None-Match": etag}); w.Code != http.StatusOK {
+			t.Errorf("ETag of %+v matched the old one", v)
+		}
+	}
+}
+
+func TestNotModifiedWithoutLastModified(t *testing.T) {
+	router := newConditionalRouter(&store.Validator{})
+
+	w := conditionalGet(router, map[string]string{"If-Modified-Since": time.Now().Format(http.TimeFormat)})
+	if w.Code != http.StatusOK || w.Header().Get("Last-Modified") != "" {
+		t.Fatalf("empty resource = %d with Last-Modified %q, want 200 without one", w.Code, w.Header().Get("Last-Modified"))
+	}
+	if w := conditionalGet(router, map[string]string{"If-None-Match": w.Header().Get("ETag")}); w.Code != http.StatusNotModified {
+		t.Fatalf("ETag of an empty resource = %d, want 304", w.Code)
+	}
+}
+
+func TestGetProductsNotModified(t *testing.T) {
+	router, s := newReadRouter(t)
+
+	first := request(router, http.MethodGet, "/products?category=os", nil)
+	etag := first.Header().Get("ETag")
+
+	get := func(path string) int {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("If-None-Match", etag)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+		return w.Code
+	}
+	if code := get("/products?category=os"); code != http.StatusNotModified {
+		t.Fatalf("revalidation = %d, want 304", code)
+	}
+	// The query is part of the representation.
+	if code := get("/products?category=app"); code != http.StatusOK {
+		t.Fatalf("other query = %d, want 200", code)
+	}
+
+	time.Sleep(time.Millisecond)
+	ctx := context.Background()
+	product, _ := s.GetProduct(ctx, "ubuntu")
+	product.Description = "Edited"
+	if err := s.UpdateProduct(ctx, product); err != nil {
+		t.Fatal(err)
+	}
+	if code := get("/products?category=os"); code != http.StatusOK {
+		t.Fatalf("revalidation after an edit = %d, want 200", code)
+	}
+}
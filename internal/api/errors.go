@@ -0,0 +1,63 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/your-username/alldownloads/internal/problem"
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+// bindJSON decodes the request body into v. It answers with a problem and
+// returns false when the body is not JSON or a field has the wrong type.
+func bindJSON(c *gin.Context, v interface{}) bool {
+	err := c.ShouldBindJSON(v)
+	if err == nil {
+		return true
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		problem.Validation(c, problem.Field(typeErr.Field, problem.FieldInvalid, fmt.Sprintf("must not be a %s", typeErr.Value)))
+		return false
+	}
+	problem.BadRequest(c, "Request body is not valid JSON")
+	return false
+}
+
+// pageFieldError maps the errors a list query returns for a bad cursor or
+// sort onto the parameter at fault.
+func pageFieldError(err error) (problem.FieldError, bool) {
+	switch {
+	case errors.Is(err, store.ErrInvalidCursor):
+		return problem.Field("cursor", problem.FieldInvalid, "is not a cursor returned by this endpoint"), true
+	case errors.Is(err, store.ErrInvalidSort):
+		return problem.Field("sort", problem.FieldInvalid, "is not a supported sort order"), true
+	}
+	return problem.FieldError{}, false
+}
+
+// productIDParam returns the :id path parameter, answering with a problem
+// when it cannot be a product ID.
+func productIDParam(c *gin.Context) (string, bool) {
+	id := c.Param("id")
+	if !productIDPattern.MatchString(id) {
+		problem.Validation(c, problem.Field("id", problem.FieldInvalid, productIDMessage))
+		return "", false
+	}
+	return id, true
+}
+
+// uuidParam returns the named path parameter, answering with a problem
+// when it is not a UUID.
+func uuidParam(c *gin.Context, name string) (string, bool) {
+	id := c.Param(name)
+	if _, err := uuid.Parse(id); err != nil {
+		problem.Validation(c, problem.Field(name, problem.FieldInvalid, "must be a UUID"))
+		return "", false
+	}
+	return id, true
+}
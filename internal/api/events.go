@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/alldownloads/internal/events"
+	"github.com/your-username/alldownloads/internal/problem"
+	"go.uber.org/zap"
+)
+
+const eventsHeartbeat = 15 * time.Second
+
+// StreamEvents serves job and version events as Server-Sent Events. Clients
+// resume after a disconnect with the Last-Event-ID header (sent by
+// EventSource automatically) or the last_event_id query parameter. Events
+// can be narrowed with product_id and a comma-separated types list.
+func (h *Handler) StreamEvents(hub *events.Hub) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+
+		lastID := c.GetHeader("Last-Event-ID")
+		if lastID == "" {
+			lastID = c.Query("last_event_id")
+		}
+		if lastID != "" && !events.ValidID(lastID) {
+			problem.Validation(c, problem.Field("last_event_id", problem.FieldInvalid, "must be an event ID from this stream"))
+			return
+		}
+
+		productID := c.Query("product_id")
+		types := map[string]bool{}
+		for _, t := range strings.Split(c.Query("types"), ",") {
+			if t = strings.TrimSpace(t); t != "" {
+				types[t] = true
+			}
+		}
+		wanted := func(e events.Event) bool {
+			return (productID == "" || e.ProductID == productID) && (len(types) == 0 || types[e.Type])
+		}
+
+		// Subscribe before replaying so nothing published in between is lost;
+		// duplicates are dropped by comparing stream IDs below.
+		live := hub.Subscribe()
+		defer hub.Unsubscribe(live)
+
+		var backlog []events.Event
+		if lastID != "" {
+			var err error
+			backlog, err = h.events.Since(ctx, lastID)
+			if err != nil {
+				h.logger.Error("failed to replay events", zap.Error(err), zap.String("last_event_id", lastID))
+				problem.Internal(c, "Failed to replay events")
+				return
+			}
+		}
+
+		// The server's write timeout would otherwise end every stream.
+		if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil {
+			h.logger.Warn("failed to clear write deadline for event stream", zap.Error(err))
+		}
+
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+		c.Status(http.StatusOK)
+		fmt.Fprint(c.Writer, "retry: 3000\n\n")
+		c.Writer.Flush()
+
+		send := func(e events.Event) bool {
+			if lastID != "" && !events.After(e.ID, lastID) {
+				return true
+			}
+			lastID = e.ID
+			if !wanted(e) {
+				return true
+			}
+
+			data, err := json.Marshal(e)
+			if err != nil {
+				return true
+			}
+			if _, err := fmt.Fprintf(c.Writer, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data); err != nil {
+				return false
+			}
+			c.Writer.Flush()
+			return true
+		}
+
+		for _, e := range backlog {
+			if !send(e) {
+				return
+			}
+		}
+
+		heartbeat := time.NewTicker(eventsHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e, ok := <-live:
+				if !ok {
+					// Too slow to keep up; the client reconnects and resumes.
+					return
+				}
+				if !send(e) {
+					return
+				}
+			case <-heartbeat.C:
+				if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+					return
+				}
+				c.Writer.Flush()
+			}
+		}
+	}
+}
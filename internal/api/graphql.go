@@ -0,0 +1,598 @@
+package api
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/your-username/alldownloads/internal/auth"
+	"github.com/your-username/alldownloads/internal/cache"
+	"github.com/your-username/alldownloads/internal/problem"
+	"github.com/your-username/alldownloads/internal/store"
+	"go.uber.org/zap"
+)
+
+//go:embed schema.graphql
+var graphqlSchema string
+
+const (
+	graphqlMaxDepth = 8
+
+	// maxProductJobs bounds Product.jobs so that one query can load the
+	// recent jobs of a whole page of products.
+	maxProductJobs = 20
+)
+
+var validJobStatuses = []string{store.JobStatusPending, store.JobStatusRunning, store.JobStatusCompleted, store.JobStatusFailed}
+
+// graphqlError carries a problem code in the error's extensions so that
+// GraphQL clients can branch on the same codes as REST clients.
+type graphqlError struct {
+	code string
+	msg  string
+}
+
+func (e *graphqlError) Error() string { return e.msg }
+
+func (e *graphqlError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.code}
+}
+
+func graphqlErrorf(code, format string, args ...interface{}) error {
+	return &graphqlError{code: code, msg: fmt.Sprintf(format, args...)}
+}
+
+var errGraphQLInternal = graphqlErrorf(problem.CodeInternal, "internal error")
+
+type graphqlParams struct {
+	Query         string                 `json:"query" binding:"required"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// graphqlRequest carries the per-request state that resolvers share: the
+// gin context for auditing and the loaders that batch nested lookups.
+type graphqlRequest struct {
+	c        *gin.Context
+	products *batchLoader[string, *store.Product]
+	versions *batchLoader[string, []store.ProductVersion]
+	jobs     *batchLoader[string, []store.FetchJob]
+}
+
+type graphqlRequestKey struct{}
+
+// GraphQL serves POST /api/v1/graphql. Queries run with the caller's read
+// access; mutations additionally require the admin scope.
+func (h *Handler) GraphQL() gin.HandlerFunc {
+	schema := graphql.MustParseSchema(graphqlSchema, &graphqlResolver{h: h}, graphql.MaxDepth(graphqlMaxDepth))
+
+	return func(c *gin.Context) {
+		var params graphqlParams
+		if !bindJSON(c, &params) {
+			return
+		}
+
+		ctx := context.WithValue(c.Request.Context(), graphqlRequestKey{}, h.newGraphQLRequest(c))
+		c.JSON(http.StatusOK, schema.Exec(ctx, params.Query, params.OperationName, params.Variables))
+	}
+}
+
+func (h *Handler) newGraphQLRequest(c *gin.Context) *graphqlRequest {
+	req := &graphqlRequest{c: c}
+
+	req.versions = newBatchLoader(func(ctx context.Context, productIDs []string) (map[string][]store.ProductVersion, error) {
+		return h.store.GetVersionsByProductIDs(ctx, productIDs)
+	})
+	req.jobs = newBatchLoader(func(ctx context.Context, productIDs []string) (map[string][]store.FetchJob, error) {
+		return h.store.GetRecentFetchJobs(ctx, productIDs, maxProductJobs)
+	})
+	req.products = newBatchLoader(func(ctx context.Context, ids []string) (map[string]*store.Product, error) {
+		products, err := h.store.GetProductsByIDs(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		for id := range products {
+			req.versions.Prime(id)
+			req.jobs.Prime(id)
+		}
+		return products, nil
+	})
+
+	return req
+}
+
+func graphqlRequestFrom(ctx context.Context) *graphqlRequest {
+	return ctx.Value(graphqlRequestKey{}).(*graphqlRequest)
+}
+
+// internalError logs err and hides it from the client.
+func (h *Handler) internalError(msg string, err error, fields ...zap.Field) error {
+	h.logger.Error(msg, append(fields, zap.Error(err))...)
+	return errGraphQLInternal
+}
+
+func (h *Handler) storeError(msg string, err error, fields ...zap.Field) error {
+	if field, ok := pageFieldError(err); ok {
+		return graphqlErrorf(problem.CodeValidationFailed, "%s %s", field.Field, field.Message)
+	}
+	return h.internalError(msg, err, fields...)
+}
+
+func requireAdmin(ctx context.Context) error {
+	principal := auth.GetPrincipal(graphqlRequestFrom(ctx).c)
+	if principal == nil {
+		return graphqlErrorf(problem.CodeUnauthorized, "authentication required")
+	}
+	if !auth.HasScope(principal.Scopes, auth.ScopeAdmin) {
+		return graphqlErrorf(problem.CodeInsufficientScope, "admin scope required")
+	}
+	return nil
+}
+
+func checkArg(name string, value *string, allowed []string) (string, error) {
+	if value == nil || *value == "" {
+		return "", nil
+	}
+	if !oneOf(*value, allowed...) {
+		return "", graphqlErrorf(problem.CodeValidationFailed, "%s must be one of %v", name, allowed)
+	}
+	return *value, nil
+}
+
+func graphqlLimit(first *int32) (int, error) {
+	if first == nil {
+		return 0, nil
+	}
+	if *first < 1 || *first > store.MaxPageSize {
+		return 0, graphqlErrorf(problem.CodeValidationFailed, "first must be between 1 and %d", store.MaxPageSize)
+	}
+	return int(*first), nil
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func idValue(id *graphql.ID) string {
+	if id == nil {
+		return ""
+	}
+	return string(*id)
+}
+
+type graphqlResolver struct {
+	h *Handler
+}
+
+type productsArgs struct {
+	Category     *string
+	Vendor       *string
+	Platform     *string
+	Architecture *string
+	Search       *string
+	Sort         *string
+	First        *int32
+	After        *string
+}
+
+func (r *graphqlResolver) Products(ctx context.Context, args productsArgs) (*productConnectionResolver, error) {
+	filter := store.ProductFilter{
+		Vendor: stringValue(args.Vendor),
+		Search: stringValue(args.Search),
+		Sort:   stringValue(args.Sort),
+		Cursor: stringValue(args.After),
+	}
+
+	var err error
+	if filter.Category, err = checkArg("category", args.Category, validCategories); err != nil {
+		return nil, err
+	}
+	if filter.Platform, err = checkArg("platform", args.Platform, validPlatforms); err != nil {
+		return nil, err
+	}
+	if filter.Architecture, err = checkArg("architecture", args.Architecture, validArchitectures); err != nil {
+		return nil, err
+	}
+	if filter.Limit, err = graphqlLimit(args.First); err != nil {
+		return nil, err
+	}
+
+	page, err := r.h.store.ListProducts(ctx, filter)
+	if err != nil {
+		return nil, r.h.storeError("failed to get products", err)
+	}
+
+	req := graphqlRequestFrom(ctx)
+	nodes := make([]*productResolver, len(page.Products))
+	for i, p := range page.Products {
+		req.products.Set(p.ID, &page.Products[i])
+		req.versions.Prime(p.ID)
+		req.jobs.Prime(p.ID)
+		nodes[i] = &productResolver{h: r.h, p: p}
+	}
+
+	return &productConnectionResolver{nodes: nodes, nextCursor: page.NextCursor}, nil
+}
+
+func (r *graphqlResolver) Product(ctx context.Context, args struct{ ID graphql.ID }) (*productResolver, error) {
+	if !productIDPattern.MatchString(string(args.ID)) {
+		return nil, nil
+	}
+	return r.h.loadProduct(ctx, string(args.ID))
+}
+
+type versionsArgs struct {
+	ProductID    *graphql.ID
+	Platform     *string
+	Architecture *string
+	Channel      *string
+	LatestOnly   *bool
+	Sort         *string
+	First        *int32
+	After        *string
+}
+
+func (r *graphqlResolver) Versions(ctx context.Context, args versionsArgs) (*versionConnectionResolver, error) {
+	filter := store.VersionFilter{
+		ProductID:  idValue(args.ProductID),
+		LatestOnly: args.LatestOnly != nil && *args.LatestOnly,
+		Sort:       stringValue(args.Sort),
+		Cursor:     stringValue(args.After),
+	}
+
+	var err error
+	if filter.Platform, err = checkArg("platform", args.Platform, validPlatforms); err != nil {
+		return nil, err
+	}
+	if filter.Architecture, err = checkArg("architecture", args.Architecture, validArchitectures); err != nil {
+		return nil, err
+	}
+	if filter.Channel, err = checkArg("channel", args.Channel, validChannels); err != nil {
+		return nil, err
+	}
+	if filter.Limit, err = graphqlLimit(args.First); err != nil {
+		return nil, err
+	}
+
+	page, err := r.h.store.ListVersions(ctx, filter)
+	if err != nil {
+		return nil, r.h.storeError("failed to get versions", err)
+	}
+
+	req := graphqlRequestFrom(ctx)
+	nodes := make([]*versionResolver, len(page.Versions))
+	for i, v := range page.Versions {
+		req.products.Prime(v.ProductID)
+		nodes[i] = &versionResolver{h: r.h, v: v}
+	}
+
+	return &versionConnectionResolver{nodes: nodes, nextCursor: page.NextCursor}, nil
+}
+
+type jobsArgs struct {
+	ProductID *graphql.ID
+	Status    *string
+	First     *int32
+	After     *string
+}
+
+func (r *graphqlResolver) Jobs(ctx context.Context, args jobsArgs) (*jobConnectionResolver, error) {
+	filter := store.JobFilter{
+		ProductID: idValue(args.ProductID),
+		Cursor:    stringValue(args.After),
+	}
+
+	var err error
+	if filter.Status, err = checkArg("status", args.Status, validJobStatuses); err != nil {
+		return nil, err
+	}
+	if filter.Limit, err = graphqlLimit(args.First); err != nil {
+		return nil, err
+	}
+
+	page, err := r.h.store.ListFetchJobs(ctx, filter)
+	if err != nil {
+		return nil, r.h.storeError("failed to get fetch jobs", err)
+	}
+
+	req := graphqlRequestFrom(ctx)
+	nodes := make([]*jobResolver, len(page.Jobs))
+	for i, job := range page.Jobs {
+		req.products.Prime(job.ProductID)
+		nodes[i] = &jobResolver{h: r.h, job: job}
+	}
+
+	return &jobConnectionResolver{nodes: nodes, nextCursor: page.NextCursor}, nil
+}
+
+func (r *graphqlResolver) Job(ctx context.Context, args struct{ ID graphql.ID }) (*jobResolver, error) {
+	if _, err := uuid.Parse(string(args.ID)); err != nil {
+		return nil, nil
+	}
+	job, err := r.h.store.GetFetchJob(ctx, string(args.ID))
+	if err != nil {
+		return nil, r.h.internalError("failed to get fetch job", err, zap.String("job_id", string(args.ID)))
+	}
+	if job == nil {
+		return nil, nil
+	}
+	return &jobResolver{h: r.h, job: *job}, nil
+}
+
+func (r *graphqlResolver) Refresh(ctx context.Context, args struct{ ProductIDs *[]graphql.ID }) ([]*jobResolver, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	c := graphqlRequestFrom(ctx).c
+
+	var productIDs []string
+	if args.ProductIDs == nil {
+		products, err := r.h.store.GetProducts(ctx)
+		if err != nil {
+			return nil, r.h.internalError("failed to get products for refresh", err)
+		}
+		for _, p := range products {
+			if p.Scheduled() {
+				productIDs = append(productIDs, p.ID)
+			}
+		}
+	} else {
+		for _, id := range *args.ProductIDs {
+			if !productIDPattern.MatchString(string(id)) {
+				return nil, graphqlErrorf(problem.CodeNotFound, "product %s not found", id)
+			}
+			productIDs = append(productIDs, string(id))
+		}
+		products, err := r.h.store.GetProductsByIDs(ctx, productIDs)
+		if err != nil {
+			return nil, r.h.internalError("failed to get products for refresh", err)
+		}
+		for _, id := range productIDs {
+			if products[id] == nil {
+				return nil, graphqlErrorf(problem.CodeNotFound, "product %s not found", id)
+			}
+		}
+	}
+
+	resolvers := []*jobResolver{}
+	var queuedJobs []string
+	for _, productID := range productIDs {
+		job, err := r.h.enqueueFetch(ctx, productID)
+		if err != nil {
+			continue
+		}
+		queuedJobs = append(queuedJobs, job.ID)
+		resolvers = append(resolvers, &jobResolver{h: r.h, job: *job})
+	}
+
+	r.h.logger.Info("refresh initiated", zap.Int("jobs_queued", len(queuedJobs)))
+	target := "*"
+	if args.ProductIDs != nil && len(productIDs) == 1 {
+		target = productIDs[0]
+	}
+	if err := r.h.audit(c, AuditActionRefresh, "product", target, nil, gin.H{"jobs_queued": len(queuedJobs), "job_ids": queuedJobs}); err != nil {
+		return nil, errGraphQLInternal
+	}
+
+	return resolvers, nil
+}
+
+type versionFlagsArgs struct {
+	ID     graphql.ID
+	Pinned *bool
+	Hidden *bool
+}
+
+func (r *graphqlResolver) SetVersionFlags(ctx context.Context, args versionFlagsArgs) (*versionResolver, error) {
+	if err := requireAdmin(ctx); err != nil {
+		return nil, err
+	}
+	c := graphqlRequestFrom(ctx).c
+	versionID := string(args.ID)
+	if _, err := uuid.Parse(versionID); err != nil {
+		return nil, graphqlErrorf(problem.CodeNotFound, "version not found")
+	}
+
+	version, err := r.h.store.GetProductVersion(ctx, versionID)
+	if err != nil {
+		return nil, r.h.internalError("failed to get version", err, zap.String("version_id", versionID))
+	}
+	if version == nil {
+		return nil, graphqlErrorf(problem.CodeNotFound, "version not found")
+	}
+
+	before := *version
+	versionRequest{Pinned: args.Pinned, Hidden: args.Hidden}.apply(version)
+
+	if err := r.h.store.UpdateProductVersion(ctx, version); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, graphqlErrorf(problem.CodeNotFound, "version not found")
+		}
+		return nil, r.h.internalError("failed to update version", err, zap.String("version_id", versionID))
+	}
+
+	r.h.remarkLatest(c, version)
+	r.h.logger.Info("version updated", zap.String("version_id", version.ID), zap.String("source", version.Source),
+		zap.Bool("pinned", version.Pinned), zap.Bool("hidden", version.Hidden))
+	r.h.cache.Invalidate(ctx, version.ProductID, cache.ReasonAdmin)
+	if err := r.h.audit(c, AuditActionVersionUpdate, "version", version.ID, before, version); err != nil {
+		return nil, errGraphQLInternal
+	}
+
+	return &versionResolver{h: r.h, v: *version}, nil
+}
+
+func (h *Handler) loadProduct(ctx context.Context, id string) (*productResolver, error) {
+	product, err := graphqlRequestFrom(ctx).products.Load(ctx, id)
+	if err != nil {
+		return nil, h.internalError("failed to get product", err, zap.String("product_id", id))
+	}
+	if product == nil {
+		return nil, nil
+	}
+	return &productResolver{h: h, p: *product}, nil
+}
+
+type productConnectionResolver struct {
+	nodes      []*productResolver
+	nextCursor string
+}
+
+func (r *productConnectionResolver) Nodes() []*productResolver { return r.nodes }
+func (r *productConnectionResolver) NextCursor() *string       { return cursorValue(r.nextCursor) }
+
+type versionConnectionResolver struct {
+	nodes      []*versionResolver
+	nextCursor string
+}
+
+func (r *versionConnectionResolver) Nodes() []*versionResolver { return r.nodes }
+func (r *versionConnectionResolver) NextCursor() *string       { return cursorValue(r.nextCursor) }
+
+type jobConnectionResolver struct {
+	nodes      []*jobResolver
+	nextCursor string
+}
+
+func (r *jobConnectionResolver) Nodes() []*jobResolver { return r.nodes }
+func (r *jobConnectionResolver) NextCursor() *string   { return cursorValue(r.nextCursor) }
+
+func cursorValue(cursor string) *string {
+	if cursor == "" {
+		return nil
+	}
+	return &cursor
+}
+
+type productResolver struct {
+	h *Handler
+	p store.Product
+}
+
+func (r *productResolver) ID() graphql.ID               { return graphql.ID(r.p.ID) }
+func (r *productResolver) Name() string                 { return r.p.Name }
+func (r *productResolver) Vendor() string               { return r.p.Vendor }
+func (r *productResolver) Category() string             { return r.p.Category }
+func (r *productResolver) Description() string          { return r.p.Description }
+func (r *productResolver) IconUrl() string              { return r.p.IconURL }
+func (r *productResolver) WebsiteUrl() string           { return r.p.WebsiteURL }
+func (r *productResolver) Fetcher() string              { return r.p.Fetcher }
+func (r *productResolver) MaxAgeSeconds() int32         { return int32(r.p.MaxAgeSeconds) }
+func (r *productResolver) LastSuccessAt() *graphql.Time { return timeValue(r.p.LastSuccessAt) }
+func (r *productResolver) Stale() bool                  { return r.p.Stale }
+func (r *productResolver) StaleSince() *graphql.Time    { return timeValue(r.p.StaleSince) }
+func (r *productResolver) CreatedAt() graphql.Time      { return graphql.Time{Time: r.p.CreatedAt} }
+func (r *productResolver) UpdatedAt() graphql.Time      { return graphql.Time{Time: r.p.UpdatedAt} }
+
+type productVersionsArgs struct {
+	Platform     *string
+	Architecture *string
+	Channel      *string
+	LatestOnly   *bool
+}
+
+// Versions filters the product's visible versions in memory, so that the
+// versions of every product on a page come from one batched query whatever
+// the arguments.
+func (r *productResolver) Versions(ctx context.Context, args productVersionsArgs) ([]*versionResolver, error) {
+	versions, err := graphqlRequestFrom(ctx).versions.Load(ctx, r.p.ID)
+	if err != nil {
+		return nil, r.h.internalError("failed to get product versions", err, zap.String("product_id", r.p.ID))
+	}
+
+	resolvers := []*versionResolver{}
+	for _, v := range versions {
+		if args.Platform != nil && v.Platform != *args.Platform ||
+			args.Architecture != nil && v.Architecture != *args.Architecture ||
+			args.Channel != nil && v.Channel != *args.Channel ||
+			args.LatestOnly != nil && *args.LatestOnly && !v.IsLatest {
+			continue
+		}
+		resolvers = append(resolvers, &versionResolver{h: r.h, v: v})
+	}
+
+	return resolvers, nil
+}
+
+func (r *productResolver) Jobs(ctx context.Context, args struct{ First int32 }) ([]*jobResolver, error) {
+	if args.First < 1 || args.First > maxProductJobs {
+		return nil, graphqlErrorf(problem.CodeValidationFailed, "first must be between 1 and %d", maxProductJobs)
+	}
+	first := int(args.First)
+
+	jobs, err := graphqlRequestFrom(ctx).jobs.Load(ctx, r.p.ID)
+	if err != nil {
+		return nil, r.h.internalError("failed to get fetch jobs", err, zap.String("product_id", r.p.ID))
+	}
+	if len(jobs) > first {
+		jobs = jobs[:first]
+	}
+
+	resolvers := make([]*jobResolver, len(jobs))
+	for i, job := range jobs {
+		resolvers[i] = &jobResolver{h: r.h, job: job}
+	}
+	return resolvers, nil
+}
+
+type versionResolver struct {
+	h *Handler
+	v store.ProductVersion
+}
+
+func (r *versionResolver) ID() graphql.ID { return graphql.ID(r.v.ID) }
+
+func (r *versionResolver) Product(ctx context.Context) (*productResolver, error) {
+	return r.h.loadProduct(ctx, r.v.ProductID)
+}
+
+func (r *versionResolver) Version() string           { return r.v.Version }
+func (r *versionResolver) Platform() string          { return r.v.Platform }
+func (r *versionResolver) Architecture() string      { return r.v.Architecture }
+func (r *versionResolver) Channel() string           { return r.v.Channel }
+func (r *versionResolver) DownloadUrl() string       { return r.v.DownloadURL }
+func (r *versionResolver) Checksum() string          { return r.v.Checksum }
+func (r *versionResolver) ChecksumType() string      { return r.v.ChecksumType }
+func (r *versionResolver) FileSize() float64         { return float64(r.v.FileSize) }
+func (r *versionResolver) Filename() string          { return r.v.Filename }
+func (r *versionResolver) IsLatest() bool            { return r.v.IsLatest }
+func (r *versionResolver) Source() string            { return r.v.Source }
+func (r *versionResolver) Pinned() bool              { return r.v.Pinned }
+func (r *versionResolver) Hidden() bool              { return r.v.Hidden }
+func (r *versionResolver) LastFetched() graphql.Time { return graphql.Time{Time: r.v.LastFetched} }
+func (r *versionResolver) CreatedAt() graphql.Time   { return graphql.Time{Time: r.v.CreatedAt} }
+func (r *versionResolver) UpdatedAt() graphql.Time   { return graphql.Time{Time: r.v.UpdatedAt} }
+
+type jobResolver struct {
+	h   *Handler
+	job store.FetchJob
+}
+
+func (r *jobResolver) ID() graphql.ID { return graphql.ID(r.job.ID) }
+
+func (r *jobResolver) Product(ctx context.Context) (*productResolver, error) {
+	return r.h.loadProduct(ctx, r.job.ProductID)
+}
+
+func (r *jobResolver) Status() string             { return r.job.Status }
+func (r *jobResolver) StartedAt() *graphql.Time   { return timeValue(r.job.StartedAt) }
+func (r *jobResolver) CompletedAt() *graphql.Time { return timeValue(r.job.CompletedAt) }
+func (r *jobResolver) Error() string              { return r.job.Error }
+func (r *jobResolver) CreatedAt() graphql.Time    { return graphql.Time{Time: r.job.CreatedAt} }
+func (r *jobResolver) UpdatedAt() graphql.Time    { return graphql.Time{Time: r.job.UpdatedAt} }
+
+func timeValue(t *time.Time) *graphql.Time {
+	if t == nil {
+		return nil
+	}
+	return &graphql.Time{Time: *t}
+}
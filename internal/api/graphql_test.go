@@ -0,0 +1,253 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/your-username/alldownloads/internal/auth"
+	"github.com/your-username/alldownloads/internal/problem"
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+// countingStore counts the calls the resolvers make for lists and nested
+// fields, so that tests can tell batched loads from one query per node.
+type countingStore struct {
+	store.Store
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (s *countingStore) count(method string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[method]++
+}
+
+func (s *countingStore) reset() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls := s.calls
+	s.calls = make(map[string]int)
+	return calls
+}
+
+func (s *countingStore) GetProduct(ctx context.Context, id string) (*store.Product, error) {
+	s.count("GetProduct")
+	return s.Store.GetProduct(ctx, id)
+}
+
+func (s *countingStore) GetProductsByIDs(ctx context.Context, ids []string) (map[string]*store.Product, error) {
+	s.count("GetProductsByIDs")
+	return s.Store.GetProductsByIDs(ctx, ids)
+}
+
+func (s *countingStore) GetVersionsByProductIDs(ctx context.Context, productIDs []string) (map[string][]store.ProductVersion, error) {
+	s.count("GetVersionsByProductIDs")
+	return s.Store.GetVersionsByProductIDs(ctx, productIDs)
+}
+
+func (s *countingStore) GetRecentFetchJobs(ctx context.Context, productIDs []string, perProduct int) (map[string][]store.FetchJob, error) {
+	s.count("GetRecentFetchJobs")
+	return s.Store.GetRecentFetchJobs(ctx, productIDs, perProduct)
+}
+
+func (s *countingStore) UpdateProductVersion(ctx context.Context, version *store.ProductVersion) error {
+	s.count("UpdateProductVersion")
+	return s.Store.UpdateProductVersion(ctx, version)
+}
+
+func (s *countingStore) CreateFetchJob(ctx context.Context, job *store.FetchJob) error {
+	s.count("CreateFetchJob")
+	return s.Store.CreateFetchJob(ctx, job)
+}
+
+type graphqlTest struct {
+	t       *testing.T
+	store   *countingStore
+	router  *gin.Engine
+	version []*store.ProductVersion
+}
+
+// newGraphQLTest serves the GraphQL endpoint from a fresh SQLite database
+// holding three products with two versions and a fetch job each.
+func newGraphQLTest(t *testing.T) *graphqlTest {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+
+	sqlite, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "graphql.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sqlite.Close)
+	gt := &graphqlTest{t: t, store: &countingStore{Store: sqlite, calls: make(map[string]int)}}
+
+	for i := 1; i <= 3; i++ {
+		p := &store.Product{ID: fmt.Sprintf("graphql-%d", i), Name: fmt.Sprintf("GraphQL %d", i), Vendor: "GraphQL Test", Category: store.CategoryApp, Fetcher: "github"}
+		if err := sqlite.CreateProduct(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, arch := range []string{store.ArchAMD64, store.ArchARM64} {
+			v := &store.ProductVersion{ProductID: p.ID, Version: "1.0", Platform: store.PlatformLinux, Architecture: arch,
+				DownloadURL: "https://example.com/" + p.ID + "/" + arch, Filename: p.ID + ".tar.gz"}
+			if _, err := sqlite.CreateOrUpdateProductVersion(ctx, v); err != nil {
+				t.Fatal(err)
+			}
+			gt.version = append(gt.version, v)
+		}
+		if err := sqlite.CreateFetchJob(ctx, &store.FetchJob{ProductID: p.ID, Status: store.JobStatusCompleted}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	gt.router = gin.New()
+	gt.router.Use(func(c *gin.Context) {
+		if scopes := c.GetHeader("X-Test-Scopes"); scopes != "" {
+			c.Set(auth.PrincipalKey, &auth.Principal{Type: auth.PrincipalTypeAPIKey, ID: "test", Scopes: []string{scopes}})
+		}
+	})
+	gt.router.POST("/graphql", NewHandler(gt.store, nil, nil, nil, zap.NewNop()).GraphQL())
+
+	return gt
+}
+
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string            `json:"message"`
+		Extensions map[string]string `json:"extensions"`
+	} `json:"errors"`
+}
+
+// exec runs query as a caller with scope, or anonymously when scope is
+// empty, and returns the response and the store calls it made.
+func (gt *graphqlTest) exec(scope, query string) (graphqlResponse, map[string]int) {
+	gt.t.Helper()
+	gt.store.reset()
+
+	body, _ := json.Marshal(map[string]string{"query": query})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if scope != "" {
+		req.Header.Set("X-Test-Scopes", scope)
+	}
+	w := httptest.NewRecorder()
+	gt.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		gt.t.Fatalf("POST /graphql = %d: %s", w.Code, w.Body)
+	}
+
+	var resp graphqlResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		gt.t.Fatalf("decoding response: %v", err)
+	}
+	return resp, gt.store.reset()
+}
+
+func TestGraphQLBatchesNestedFields(t *testing.T) {
+	gt := newGraphQLTest(t)
+
+	tests := []struct {
+		name  string
+		query string
+		calls map[string]int
+	}{
+		{
+			name:  "versions and jobs of a page of products",
+			query: `{ products(vendor: "GraphQL Test") { nodes { id versions { id } jobs { id } } } }`,
+			calls: map[string]int{"GetVersionsByProductIDs": 1, "GetRecentFetchJobs": 1},
+		},
+		{
+			name:  "products of the versions of a page of products",
+			query: `{ products(vendor: "GraphQL Test") { nodes { versions { product { id } } } } }`,
+			calls: map[string]int{"GetVersionsByProductIDs": 1},
+		},
+		{
+			name:  "products of a page of versions, and their jobs",
+			query: `{ versions(platform: "linux") { nodes { id product { id jobs { id } } } } }`,
+			calls: map[string]int{"GetProductsByIDs": 1, "GetRecentFetchJobs": 1},
+		},
+		{
+			name:  "products of a page of jobs",
+			query: `{ jobs { nodes { id product { name versions { id } } } } }`,
+			calls: map[string]int{"GetProductsByIDs": 1, "GetVersionsByProductIDs": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, calls := gt.exec("", tt.query)
+			if len(resp.Errors) > 0 {
+				t.Fatalf("errors: %+v", resp.Errors)
+			}
+			if fmt.Sprint(calls) != fmt.Sprint(tt.calls) {
+				t.Fatalf("store calls = %v, want %v", calls, tt.calls)
+			}
+		})
+	}
+
+	// Batching must not mix up which rows belong to which node.
+	resp, _ := gt.exec("", `{ product(id: "graphql-2") { versions { product { id } } jobs { product { id } } } }`)
+	var data struct {
+		Product struct {
+			Versions []struct{ Product struct{ ID string } }
+			Jobs     []struct{ Product struct{ ID string } }
+		}
+	}
+	if err := json.Unmarshal(resp.Data, &data); err != nil {
+		t.Fatal(err)
+	}
+	if len(data.Product.Versions) != 2 || len(data.Product.Jobs) != 1 {
+		t.Fatalf("product = %+v, want 2 versions and 1 job", data.Product)
+	}
+	for _, v := range data.Product.Versions {
+		if v.Product.ID != "graphql-2" {
+			t.Fatalf("version of graphql-2 resolves to product %q", v.Product.ID)
+		}
+	}
+}
+
+func TestGraphQLMutationsRequireAdmin(t *testing.T) {
+	gt := newGraphQLTest(t)
+	setFlags := fmt.Sprintf(`mutation { setVersionFlags(id: %q, pinned: true) { pinned } }`, gt.version[0].ID)
+	refresh := `mutation { refresh(productIds: ["graphql-1"]) { id } }`
+
+	for _, tt := range []struct {
+		scope string
+		code  string
+	}{
+		{"", problem.CodeUnauthorized},
+		{auth.ScopeRead, problem.CodeInsufficientScope},
+		{auth.ScopeRefresh, problem.CodeInsufficientScope},
+	} {
+		for _, query := range []string{setFlags, refresh} {
+			resp, calls := gt.exec(tt.scope, query)
+			if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != tt.code {
+				t.Fatalf("%s with scope %q: errors = %+v, want %s", query, tt.scope, resp.Errors, tt.code)
+			}
+			if len(calls) != 0 {
+				t.Fatalf("%s with scope %q reached the store: %v", query, tt.scope, calls)
+			}
+		}
+	}
+
+	resp, calls := gt.exec(auth.ScopeAdmin, setFlags)
+	if len(resp.Errors) > 0 || string(resp.Data) != `{"setVersionFlags":{"pinned":true}}` {
+		t.Fatalf("setVersionFlags as admin = %s, %+v", resp.Data, resp.Errors)
+	}
+	if calls["UpdateProductVersion"] != 1 {
+		t.Fatalf("setVersionFlags as admin made store calls %v, want one update", calls)
+	}
+}
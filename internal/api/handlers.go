@@ -1,93 +1,255 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/your-username/alldownloads/internal/cache"
+	"github.com/your-username/alldownloads/internal/events"
 	"github.com/your-username/alldownloads/internal/jobs"
+	"github.com/your-username/alldownloads/internal/problem"
 	"github.com/your-username/alldownloads/internal/store"
 	"go.uber.org/zap"
 )
 
 type Handler struct {
-	store     *store.PostgresStore
-	jobQueue  *jobs.Queue
-	logger    *zap.Logger
-	authToken string
+	store        store.Store
+	jobQueue     *jobs.Queue
+	queueProbe   queueProbe
+	cache        *cache.ProductCache
+	events       *events.Bus
+	logger       *zap.Logger
+	cacheControl string
+
+	maxQueueDepth   int
+	maxHeartbeatAge time.Duration
 }
 
-func NewHandler(store *store.PostgresStore, jobQueue *jobs.Queue, logger *zap.Logger, authToken string) *Handler {
+// NewHandler creates the API handlers. productCache may be nil when
+// response caching is disabled.
+func NewHandler(store store.Store, jobQueue *jobs.Queue, productCache *cache.ProductCache, bus *events.Bus, logger *zap.Logger) *Handler {
 	return &Handler{
-		store:     store,
-		jobQueue:  jobQueue,
-		logger:    logger,
-		authToken: authToken,
+		store:        store,
+		jobQueue:     jobQueue,
+		queueProbe:   jobQueue,
+		cache:        productCache,
+		events:       bus,
+		logger:       logger,
+		cacheControl: DefaultCacheControl,
+
+		maxQueueDepth:   DefaultMaxQueueDepth,
+		maxHeartbeatAge: DefaultMaxHeartbeatAge,
 	}
 }
 
+// SetCacheControl overrides the Cache-Control header of cacheable reads,
+// e.g. to "private" when the read API requires authentication.
+func (h *Handler) SetCacheControl(value string) {
+	h.cacheControl = value
+}
+
 func (h *Handler) GetProducts(c *gin.Context) {
 	ctx := c.Request.Context()
 
-	products, err := h.store.GetProducts(ctx)
+	filter := store.ProductFilter{
+		Category:     c.Query("category"),
+		Vendor:       c.Query("vendor"),
+		Platform:     c.Query("platform"),
+		Architecture: c.Query("architecture"),
+		Search:       c.Query("q"),
+		Sort:         c.Query("sort"),
+		Cursor:       c.Query("cursor"),
+	}
+
+	var errs []problem.FieldError
+	if filter.Category != "" && !oneOf(filter.Category, validCategories...) {
+		errs = append(errs, problem.Choice("category", validCategories))
+	}
+	if filter.Platform != "" && !oneOf(filter.Platform, validPlatforms...) {
+		errs = append(errs, problem.Choice("platform", validPlatforms))
+	}
+	if filter.Architecture != "" && !oneOf(filter.Architecture, validArchitectures...) {
+		errs = append(errs, problem.Choice("architecture", validArchitectures))
+	}
+
+	limit, err := parseLimit(c.Query("limit"))
+	if err != nil {
+		errs = append(errs, problem.Field("limit", problem.FieldOutOfRange, err.Error()))
+	}
+	filter.Limit = limit
+
+	if len(errs) > 0 {
+		problem.Validation(c, errs...)
+		return
+	}
+
+	validator, err := h.store.GetProductsValidator(ctx)
 	if err != nil {
+		h.logger.Error("failed to get products validator", zap.Error(err))
+		problem.Internal(c, "Failed to fetch products")
+		return
+	}
+	if h.notModified(c, "products?"+c.Request.URL.Query().Encode(), validator) {
+		return
+	}
+
+	page, err := h.store.ListProducts(ctx, filter)
+	if err != nil {
+		if field, ok := pageFieldError(err); ok {
+			problem.Validation(c, field)
+			return
+		}
 		h.logger.Error("failed to get products", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		problem.Internal(c, "Failed to fetch products")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"products": products})
+	c.JSON(http.StatusOK, page)
 }
 
 func (h *Handler) GetProduct(c *gin.Context) {
 	ctx := c.Request.Context()
-	productID := c.Param("id")
+	productID, ok := productIDParam(c)
+	if !ok {
+		return
+	}
+
+	cached, generation := h.cache.Get(ctx, productID)
+	if cached != nil {
+		if !h.notModified(c, "product/"+productID, &cached.Validator) {
+			c.JSON(http.StatusOK, cached.Product)
+		}
+		return
+	}
+
+	validator, err := h.store.GetProductValidator(ctx, productID)
+	if err != nil {
+		h.logger.Error("failed to get product validator", zap.Error(err), zap.String("product_id", productID))
+		problem.Internal(c, "Failed to fetch product")
+		return
+	}
+	if validator == nil {
+		problem.NotFound(c, "Product not found")
+		return
+	}
+	if h.notModified(c, "product/"+productID, validator) {
+		return
+	}
 
 	productWithVersions, err := h.store.GetProductWithVersions(ctx, productID)
 	if err != nil {
 		h.logger.Error("failed to get product", zap.Error(err), zap.String("product_id", productID))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch product"})
+		problem.Internal(c, "Failed to fetch product")
 		return
 	}
 
 	if productWithVersions == nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		problem.NotFound(c, "Product not found")
 		return
 	}
 
+	// Set is skipped if a fetch or edit invalidated the product since Get,
+	// since the reads above may predate it.
+	h.cache.Set(ctx, productID, generation, &cache.CachedProduct{Product: productWithVersions, Validator: *validator})
+
 	c.JSON(http.StatusOK, productWithVersions)
 }
 
-func (h *Handler) RefreshProducts(c *gin.Context) {
-	authHeader := c.GetHeader("Authorization")
-	if authHeader != "Bearer "+h.authToken {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid authorization"})
+func (h *Handler) GetVersions(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	filter := store.VersionFilter{
+		ProductID:    c.Query("product_id"),
+		Platform:     c.Query("platform"),
+		Architecture: c.Query("architecture"),
+		Channel:      c.Query("channel"),
+		Sort:         c.Query("sort"),
+		Cursor:       c.Query("cursor"),
+	}
+
+	var errs []problem.FieldError
+	if filter.Platform != "" && !oneOf(filter.Platform, validPlatforms...) {
+		errs = append(errs, problem.Choice("platform", validPlatforms))
+	}
+	if filter.Architecture != "" && !oneOf(filter.Architecture, validArchitectures...) {
+		errs = append(errs, problem.Choice("architecture", validArchitectures))
+	}
+	if filter.Channel != "" && !oneOf(filter.Channel, validChannels...) {
+		errs = append(errs, problem.Choice("channel", validChannels))
+	}
+
+	if latest := c.Query("latest"); latest != "" {
+		latestOnly, err := strconv.ParseBool(latest)
+		if err != nil {
+			errs = append(errs, problem.Field("latest", problem.FieldInvalid, "must be a boolean"))
+		}
+		filter.LatestOnly = latestOnly
+	}
+
+	if hasChecksum := c.Query("has_checksum"); hasChecksum != "" {
+		value, err := strconv.ParseBool(hasChecksum)
+		if err != nil {
+			errs = append(errs, problem.Field("has_checksum", problem.FieldInvalid, "must be a boolean"))
+		}
+		filter.HasChecksum = &value
+	}
+
+	if since := c.Query("updated_since"); since != "" {
+		updatedSince, err := parseSince(since, time.Now())
+		if err != nil {
+			errs = append(errs, problem.Field("updated_since", problem.FieldInvalid, err.Error()))
+		}
+		filter.UpdatedSince = &updatedSince
+	}
+
+	limit, err := parseLimit(c.Query("limit"))
+	if err != nil {
+		errs = append(errs, problem.Field("limit", problem.FieldOutOfRange, err.Error()))
+	}
+	filter.Limit = limit
+
+	if len(errs) > 0 {
+		problem.Validation(c, errs...)
 		return
 	}
 
+	page, err := h.store.ListVersions(ctx, filter)
+	if err != nil {
+		if field, ok := pageFieldError(err); ok {
+			problem.Validation(c, field)
+			return
+		}
+		h.logger.Error("failed to get versions", zap.Error(err))
+		problem.Internal(c, "Failed to fetch versions")
+		return
+	}
+
+	c.JSON(http.StatusOK, page)
+}
+
+func (h *Handler) RefreshProducts(c *gin.Context) {
 	ctx := c.Request.Context()
 
 	products, err := h.store.GetProducts(ctx)
 	if err != nil {
 		h.logger.Error("failed to get products for refresh", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh products"})
+		problem.Internal(c, "Failed to refresh products")
 		return
 	}
 
 	var queuedJobs []string
 	for _, product := range products {
-		job := &store.FetchJob{
-			ProductID: product.ID,
-			Status:    store.JobStatusPending,
-		}
-
-		if err := h.store.CreateFetchJob(ctx, job); err != nil {
-			h.logger.Error("failed to create fetch job", zap.Error(err), zap.String("product_id", product.ID))
+		if !product.Scheduled() {
 			continue
 		}
-
-		if err := h.jobQueue.Enqueue(ctx, job.ID); err != nil {
-			h.logger.Error("failed to enqueue job", zap.Error(err), zap.String("job_id", job.ID))
+		job, err := h.enqueueFetch(ctx, product.ID)
+		if err != nil {
 			continue
 		}
 
@@ -95,6 +257,10 @@ func (h *Handler) RefreshProducts(c *gin.Context) {
 	}
 
 	h.logger.Info("refresh initiated", zap.Int("jobs_queued", len(queuedJobs)))
+	if err := h.audit(c, AuditActionRefresh, "product", "*", nil, gin.H{"jobs_queued": len(queuedJobs), "job_ids": queuedJobs}); err != nil {
+		problem.Internal(c, auditFailed)
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":     "Refresh initiated",
@@ -103,10 +269,70 @@ func (h *Handler) RefreshProducts(c *gin.Context) {
 	})
 }
 
-func (h *Handler) HealthCheck(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{
-		"status":    "healthy",
-		"timestamp": time.Now().UTC(),
-		"version":   "0.1.0",
-	})
-}
\ No newline at end of file
+func (h *Handler) enqueueFetch(ctx context.Context, productID string) (*store.FetchJob, error) {
+	job := &store.FetchJob{
+		ProductID: productID,
+		Status:    store.JobStatusPending,
+	}
+
+	if err := h.store.CreateFetchJob(ctx, job); err != nil {
+		h.logger.Error("failed to create fetch job", zap.Error(err), zap.String("product_id", productID))
+		return nil, err
+	}
+
+	if err := h.jobQueue.Enqueue(ctx, job.ID); err != nil {
+		h.logger.Error("failed to enqueue job", zap.Error(err), zap.String("job_id", job.ID))
+		return nil, err
+	}
+
+	h.events.Publish(ctx, events.Event{Type: events.JobQueued, JobID: job.ID, ProductID: productID})
+
+	return job, nil
+}
+
+var (
+	validCategories    = []string{store.CategoryOS, store.CategoryApp, store.CategoryTool}
+	validPlatforms     = []string{store.PlatformWindows, store.PlatformLinux, store.PlatformMacOS, store.PlatformWeb}
+	validArchitectures = []string{store.ArchAMD64, store.ArchARM64, store.Arch386, store.ArchARM}
+	validChannels      = []string{store.ChannelStable, store.ChannelLTS, store.ChannelBeta}
+)
+
+func parseLimit(value string) (int, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 1 || limit > store.MaxPageSize {
+		return 0, fmt.Errorf("must be between 1 and %d", store.MaxPageSize)
+	}
+	return limit, nil
+}
+
+// parseSince accepts either an RFC 3339 timestamp or a relative age such as
+// "7d", "36h" or "90m".
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err == nil && days >= 0 {
+			return now.AddDate(0, 0, -days), nil
+		}
+	} else if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+		return now.Add(-d), nil
+	}
+
+	return time.Time{}, errors.New("must be an RFC 3339 timestamp or an age like 7d or 24h")
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,308 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/your-username/alldownloads/internal/problem"
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+// newReadRouter serves the public read endpoints from a seeded SQLite store.
+func newReadRouter(t *testing.T) (*gin.Engine, *store.SQLiteStore) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	s := newSQLiteStore(t)
+	h := NewHandler(s, nil, nil, nil, zap.NewNop())
+
+	router := gin.New()
+	router.GET("/products", h.GetProducts)
+	router.GET("/versions", h.GetVersions)
+	return router, s
+}
+
+func request(router http.Handler, method, path string, body io.Reader) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, body)
+	if body != nil {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+// decode unmarshals a successful response into v.
+func decode(t *testing.T, w *httptest.ResponseRecorder, v interface{}) {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d, want 200: %s", w.Code, w.Body)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// assertProblem checks that w is a problem with the given status and code
+// whose field errors name exactly fields, in order.
+func assertProblem(t *testing.T, w *httptest.ResponseRecorder, status int, code string, fields ...string) problem.Problem {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status = %d, want %d: %s", w.Code, status, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != problem.ContentType {
+		t.Fatalf("Content-Type = %q, want %q", ct, problem.ContentType)
+	}
+	var body problem.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Code != code || body.Status != status {
+		t.Fatalf("problem = %+v, want code %q and status %d", body, code, status)
+	}
+	var got []string
+	for _, e := range body.Errors {
+		got = append(got, e.Field)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(fields) {
+		t.Fatalf("field errors = %+v, want %v", body.Errors, fields)
+	}
+	return body
+}
+
+func TestGetProductsPages(t *testing.T) {
+	router, _ := newReadRouter(t)
+
+	for _, sort := range []string{"", "-name", "updated_at"} {
+		t.Run("sort="+sort, func(t *testing.T) {
+			var all store.ProductPage
+			decode(t, request(router, http.MethodGet, "/products?sort="+sort, nil), &all)
+			if len(all.Products) != 16 || all.NextCursor != "" {
+				t.Fatalf("unpaged = %d products, cursor %q, want the 16 seeded", len(all.Products), all.NextCursor)
+			}
+
+			// Walking the pages yields every product once, in the same order.
+			var walked []string
+			query := url.Values{"sort": {sort}, "limit": {"5"}}
+			for pages := 1; ; pages++ {
+				var page store.ProductPage
+				decode(t, request(router, http.MethodGet, "/products?"+query.Encode(), nil), &page)
+				if pages < 4 && len(page.Products) != 5 || pages == 4 && len(page.Products) != 1 {
+					t.Fatalf("page %d has %d products", pages, len(page.Products))
+				}
+				for _, p := range page.Products {
+					walked = append(walked, p.ID)
+				}
+				if page.NextCursor == "" {
+					if pages != 4 {
+						t.Fatalf("ran out of pages after %d, want 4", pages)
+					}
+					break
+				}
+				query.Set("cursor", page.NextCursor)
+			}
+
+			var want []string
+			for _, p := range all.Products {
+				want = append(want, p.ID)
+			}
+			if fmt.Sprint(walked) != fmt.Sprint(want) {
+				t.Fatalf("pages = %v, want %v", walked, want)
+			}
+		})
+	}
+}
+
+func TestGetProductsRejectsBadQueries(t *testing.T) {
+	router, _ := newReadRouter(t)
+
+	var first store.ProductPage
+	decode(t, request(router, http.MethodGet, "/products?sort=name&limit=2", nil), &first)
+	if first.NextCursor == "" {
+		t.Fatal("first page has no cursor")
+	}
+	// Cursors are not signed: an edited value that still fits its column
+	// merely moves the page. Edits that break the cursor are refused.
+	forged := func(values ...string) string {
+		data, _ := json.Marshal(values)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		query  url.Values
+		fields []string
+	}{
+		{"truncated cursor", url.Values{"sort": {"name"}, "cursor": {first.NextCursor[:len(first.NextCursor)-4]}}, []string{"cursor"}},
+		{"cursor missing a value", url.Values{"sort": {"name"}, "cursor": {forged("name", "Debian")}}, []string{"cursor"}},
+		{"cursor with a bad timestamp", url.Values{"sort": {"updated_at"}, "cursor": {forged("updated_at", "yesterday", "debian")}}, []string{"cursor"}},
+		{"garbage cursor", url.Values{"cursor": {"not a cursor"}}, []string{"cursor"}},
+		{"cursor for another sort", url.Values{"sort": {"-updated_at"}, "cursor": {first.NextCursor}}, []string{"cursor"}},
+		{"cursor for the other direction", url.Values{"sort": {"-name"}, "cursor": {first.NextCursor}}, []string{"cursor"}},
+		{"cursor for the default sort", url.Values{"cursor": {first.NextCursor}}, []string{"cursor"}},
+		{"unknown sort", url.Values{"sort": {"price"}}, []string{"sort"}},
+		{"zero limit", url.Values{"limit": {"0"}}, []string{"limit"}},
+		{"negative limit", url.Values{"limit": {"-5"}}, []string{"limit"}},
+		{"limit above the maximum", url.Values{"limit": {fmt.Sprint(store.MaxPageSize + 1)}}, []string{"limit"}},
+		{"limit that is not a number", url.Values{"limit": {"ten"}}, []string{"limit"}},
+		{"unknown category", url.Values{"category": {"games"}}, []string{"category"}},
+		{"unknown platform", url.Values{"platform": {"amiga"}}, []string{"platform"}},
+		{"unknown architecture", url.Values{"architecture": {"mips"}}, []string{"architecture"}},
+		{"enums are case-sensitive", url.Values{"platform": {"Linux"}}, []string{"platform"}},
+		{
+			"every bad filter at once",
+			url.Values{"category": {"games"}, "platform": {"amiga"}, "architecture": {"mips"}, "limit": {"1000"}},
+			[]string{"category", "platform", "architecture", "limit"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(router, http.MethodGet, "/products?"+tt.query.Encode(), nil)
+			body := assertProblem(t, w, http.StatusBadRequest, problem.CodeValidationFailed, tt.fields...)
+			for _, e := range body.Errors {
+				want := problem.FieldInvalid
+				if e.Field == "limit" {
+					want = problem.FieldOutOfRange
+				}
+				if e.Code != want || e.Message == "" {
+					t.Errorf("field error = %+v, want code %q and a message", e, want)
+				}
+			}
+		})
+	}
+
+	// The limit bounds themselves are accepted.
+	for _, limit := range []string{"1", fmt.Sprint(store.MaxPageSize)} {
+		var page store.ProductPage
+		decode(t, request(router, http.MethodGet, "/products?limit="+limit, nil), &page)
+	}
+}
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		value string
+		want  time.Time
+	}{
+		{"2024-01-15T10:30:00Z", time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC)},
+		{"2024-01-15T10:30:00+02:00", time.Date(2024, 1, 15, 8, 30, 0, 0, time.UTC)},
+		{"7d", time.Date(2024, 3, 3, 12, 0, 0, 0, time.UTC)},
+		{"0d", now},
+		{"36h", time.Date(2024, 3, 9, 0, 0, 0, 0, time.UTC)},
+		{"90m", time.Date(2024, 3, 10, 10, 30, 0, 0, time.UTC)},
+		{"1h30m", time.Date(2024, 3, 10, 10, 30, 0, 0, time.UTC)},
+		{"0s", now},
+	} {
+		got, err := parseSince(tt.value, now)
+		if err != nil || !got.Equal(tt.want) {
+			t.Errorf("parseSince(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+
+	for _, value := range []string{
+		"", "-7d", "-1h", "7", "d", "7w", "1.5d", "7 d", "yesterday", "2024-01-15", "2024-01-15 10:30:00",
+	} {
+		if got, err := parseSince(value, now); err == nil {
+			t.Errorf("parseSince(%q) = %v, want an error", value, got)
+		}
+	}
+}
+
+func TestGetVersionsFilters(t *testing.T) {
+	router, s := newReadRouter(t)
+	ctx := context.Background()
+
+	var created []*store.ProductVersion
+	for _, spec := range []struct{ version, platform, checksum string }{
+		{"23.10", store.PlatformLinux, "aaa"},
+		{"24.04", store.PlatformLinux, ""},
+		{"24.04", store.PlatformWindows, "bbb"},
+	} {
+		v := &store.ProductVersion{
+			ProductID:    "ubuntu",
+			Version:      spec.version,
+			Platform:     spec.platform,
+			Architecture: store.ArchAMD64,
+			Channel:      store.ChannelStable,
+			Checksum:     spec.checksum,
+			DownloadURL:  "https://example.com/ubuntu-" + spec.version + "-" + spec.platform,
+		}
+		if _, err := s.CreateOrUpdateProductVersion(ctx, v); err != nil {
+			t.Fatal(err)
+		}
+		created = append(created, v)
+		time.Sleep(2 * time.Millisecond)
+	}
+	if err := s.MarkLatestVersions(ctx, "ubuntu"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Versions come most recently updated first.
+	list := func(query string) []string {
+		t.Helper()
+		var page store.VersionPage
+		decode(t, request(router, http.MethodGet, "/versions?product_id=ubuntu&"+query, nil), &page)
+		var got []string
+		for _, v := range page.Versions {
+			got = append(got, v.Version+"/"+v.Platform)
+		}
+		return got
+	}
+	future := url.QueryEscape(time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+
+	for _, tt := range []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"24.04/windows", "24.04/linux", "23.10/linux"}},
+		{"latest=true", []string{"24.04/windows", "24.04/linux"}},
+		{"latest=1", []string{"24.04/windows", "24.04/linux"}},
+		{"latest=false", []string{"24.04/windows", "24.04/linux", "23.10/linux"}},
+		{"has_checksum=true", []string{"24.04/windows", "23.10/linux"}},
+		{"has_checksum=false", []string{"24.04/linux"}},
+		{"latest=true&has_checksum=true", []string{"24.04/windows"}},
+		{"updated_since=1h", []string{"24.04/windows", "24.04/linux", "23.10/linux"}},
+		{"updated_since=7d&platform=windows", []string{"24.04/windows"}},
+		{"updated_since=" + future, nil},
+	} {
+		if got := list(tt.query); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("GET /versions?%s = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	// Timestamps may carry fractional seconds.
+	since := url.QueryEscape(created[2].UpdatedAt.Add(-time.Millisecond).Format(time.RFC3339Nano))
+	if got := list("updated_since=" + since); fmt.Sprint(got) != "[24.04/windows]" {
+		t.Errorf("versions updated since the last one = %v", got)
+	}
+
+	for _, tt := range []struct {
+		query  string
+		fields []string
+	}{
+		{"latest=yes", []string{"latest"}},
+		{"has_checksum=maybe", []string{"has_checksum"}},
+		{"updated_since=yesterday", []string{"updated_since"}},
+		{"updated_since=-7d", []string{"updated_since"}},
+		{"channel=nightly", []string{"channel"}},
+		{"latest=yes&has_checksum=maybe&updated_since=soon&limit=0", []string{"latest", "has_checksum", "updated_since", "limit"}},
+	} {
+		w := request(router, http.MethodGet, "/versions?"+tt.query, nil)
+		body := assertProblem(t, w, http.StatusBadRequest, problem.CodeValidationFailed, tt.fields...)
+		for _, e := range body.Errors {
+			if e.Field != "limit" && e.Code != problem.FieldInvalid {
+				t.Errorf("%s: field error = %+v, want %q", tt.query, e, problem.FieldInvalid)
+			}
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/alldownloads/internal/version"
+	"go.uber.org/zap"
+)
+
+const (
+	DefaultMaxQueueDepth   = 500
+	DefaultMaxHeartbeatAge = time.Minute
+
+	readinessCheckTimeout = 2 * time.Second
+)
+
+const (
+	checkUp       = "up"
+	checkDegraded = "degraded"
+	checkDown     = "down"
+)
+
+// dependencyCheck is one entry of the readiness report.
+type dependencyCheck struct {
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+	Details   gin.H   `json:"details,omitempty"`
+
+	name string
+	// critical checks take the instance out of rotation when down; the
+	// others only degrade it.
+	critical bool
+}
+
+// queueProbe is the part of the job queue that readiness reports on.
+type queueProbe interface {
+	Ping(ctx context.Context) error
+	GetQueueLength(ctx context.Context) (int64, error)
+	GetProcessingCount(ctx context.Context) (int64, error)
+	GetHeartbeats(ctx context.Context) (map[string]time.Time, error)
+}
+
+// SetReadinessThresholds sets the queue backlog and worker heartbeat age
+// beyond which readiness reports the instance as degraded.
+func (h *Handler) SetReadinessThresholds(maxQueueDepth int, maxHeartbeatAge time.Duration) {
+	h.maxQueueDepth = maxQueueDepth
+	h.maxHeartbeatAge = maxHeartbeatAge
+}
+
+// HealthCheck reports that the process is up. It checks no dependencies,
+// so a failing database does not get the API restarted.
+func (h *Handler) HealthCheck(c *gin.Context) {
+	info := version.Get()
+	body := gin.H{
+		"status":    "healthy",
+		"timestamp": time.Now().UTC(),
+		"version":   info.Version,
+	}
+	if info.Commit != "" {
+		body["commit"] = info.Commit
+	}
+	c.JSON(http.StatusOK, body)
+}
+
+// ReadinessCheck reports whether the instance can serve traffic. It answers
+// 503 when the database or Redis is unreachable; a queue backlog or missing
+// worker heartbeats are reported as degraded but keep the instance ready,
+// since reads are still served.
+func (h *Handler) ReadinessCheck(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	probes := []struct {
+		name     string
+		critical bool
+		probe    func(ctx context.Context, check *dependencyCheck)
+	}{
+		{h.store.Driver(), true, h.checkDatabase},
+		{"redis", true, h.checkRedis},
+		{"queue", false, h.checkQueue},
+		{"worker", false, h.checkWorkers},
+	}
+
+	checks := make(map[string]*dependencyCheck, len(probes))
+	var wg sync.WaitGroup
+	for _, p := range probes {
+		check := &dependencyCheck{Status: checkUp, name: p.name, critical: p.critical}
+		checks[p.name] = check
+
+		wg.Add(1)
+		go func(probe func(context.Context, *dependencyCheck)) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+			defer cancel()
+
+			start := time.Now()
+			probe(ctx, check)
+			check.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+		}(p.probe)
+	}
+	wg.Wait()
+
+	status, code := "ready", http.StatusOK
+	for _, check := range checks {
+		switch {
+		case check.Status == checkDown && check.critical:
+			status, code = "not_ready", http.StatusServiceUnavailable
+		case check.Status != checkUp && status == "ready":
+			status = "degraded"
+		}
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.JSON(code, gin.H{
+		"status":    status,
+		"timestamp": time.Now().UTC(),
+		"version":   version.Get().Version,
+		"checks":    checks,
+	})
+}
+
+func (h *Handler) checkDatabase(ctx context.Context, check *dependencyCheck) {
+	if err := h.store.Ping(ctx); err != nil {
+		h.failCheck(check, err)
+	}
+
+	stat := h.store.Stats()
+	check.Details = gin.H{
+		"total_conns":    stat.TotalConns,
+		"acquired_conns": stat.AcquiredConns,
+		"idle_conns":     stat.IdleConns,
+		"max_conns":      stat.MaxConns,
+	}
+	if check.Status == checkUp && stat.AcquiredConns >= stat.MaxConns {
+		check.Status, check.Error = checkDegraded, "connection pool exhausted"
+	}
+}
+
+func (h *Handler) checkRedis(ctx context.Context, check *dependencyCheck) {
+	if err := h.queueProbe.Ping(ctx); err != nil {
+		h.failCheck(check, err)
+	}
+}
+
+func (h *Handler) checkQueue(ctx context.Context, check *dependencyCheck) {
+	pending, err := h.queueProbe.GetQueueLength(ctx)
+	if err != nil {
+		h.failCheck(check, err)
+		return
+	}
+	processing, err := h.queueProbe.GetProcessingCount(ctx)
+	if err != nil {
+		h.failCheck(check, err)
+		return
+	}
+
+	check.Details = gin.H{"pending": pending, "processing": processing, "max_pending": h.maxQueueDepth}
+	if pending > int64(h.maxQueueDepth) {
+		check.Status, check.Error = checkDegraded, "queue backlog above threshold"
+	}
+}
+
+func (h *Handler) checkWorkers(ctx context.Context, check *dependencyCheck) {
+	heartbeats, err := h.queueProbe.GetHeartbeats(ctx)
+	if err != nil {
+		h.failCheck(check, err)
+		return
+	}
+
+	now := time.Now()
+	alive := 0
+	var last time.Time
+	for _, at := range heartbeats {
+		if now.Sub(at) <= h.maxHeartbeatAge {
+			alive++
+		}
+		if at.After(last) {
+			last = at
+		}
+	}
+
+	check.Details = gin.H{"alive": alive, "max_heartbeat_age_seconds": h.maxHeartbeatAge.Seconds()}
+	if !last.IsZero() {
+		check.Details["last_heartbeat"] = last.UTC()
+	}
+	if alive == 0 {
+		check.Status, check.Error = checkDown, "no worker heartbeat within threshold"
+	}
+}
+
+// failCheck marks check as down. The cause is logged rather than reported,
+// since the endpoint is public and errors can reveal internal addresses.
+func (h *Handler) failCheck(check *dependencyCheck, err error) {
+	h.logger.Warn("readiness check failed", zap.String("check", check.name), zap.Error(err))
+	check.Status, check.Error = checkDown, "unavailable"
+}
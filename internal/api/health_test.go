@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+// stubStore answers the database probe of the readiness check.
+type stubStore struct {
+	store.Store
+	pingErr error
+	stats   store.PoolStats
+}
+
+func (s *stubStore) Driver() string                 { return "postgres" }
+func (s *stubStore) Ping(ctx context.Context) error { return s.pingErr }
+func (s *stubStore) Stats() store.PoolStats         { return s.stats }
+
+// stubQueue answers the Redis, queue and worker probes.
+type stubQueue struct {
+	pingErr    error
+	queueErr   error
+	pending    int64
+	heartbeats map[string]time.Time
+}
+
+func (q *stubQueue) Ping(ctx context.Context) error { return q.pingErr }
+
+func (q *stubQueue) GetQueueLength(ctx context.Context) (int64, error) {
+	return q.pending, q.queueErr
+}
+
+func (q *stubQueue) GetProcessingCount(ctx context.Context) (int64, error) {
+	return 0, q.queueErr
+}
+
+func (q *stubQueue) GetHeartbeats(ctx context.Context) (map[string]time.Time, error) {
+	return q.heartbeats, q.queueErr
+}
+
+func TestReadinessCheck(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	healthyPool := store.PoolStats{TotalConns: 2, AcquiredConns: 1, IdleConns: 1, MaxConns: 10}
+	alive := map[string]time.Time{"worker-1": time.Now()}
+	failure := errors.New("dial tcp 10.0.0.5:5432: connection refused")
+
+	tests := []struct {
+		name   string
+		store  *stubStore
+		queue  *stubQueue
+		code   int
+		status string
+		checks map[string]string
+	}{
+		{
+			name:   "everything up",
+			store:  &stubStore{stats: healthyPool},
+			queue:  &stubQueue{heartbeats: alive},
+			code:   http.StatusOK,
+			status: "ready",
+			checks: map[string]string{"postgres": checkUp, "redis": checkUp, "queue": checkUp, "worker": checkUp},
+		},
+		{
+			name:   "database down",
+			store:  &stubStore{pingErr: failure, stats: healthyPool},
+			queue:  &stubQueue{heartbeats: alive},
+			code:   http.StatusServiceUnavailable,
+			status: "not_ready",
+			checks: map[string]string{"postgres": checkDown, "redis": checkUp},
+		},
+		{
+			name:   "redis down",
+			store:  &stubStore{stats: healthyPool},
+			queue:  &stubQueue{pingErr: failure, queueErr: failure},
+			code:   http.StatusServiceUnavailable,
+			status: "not_ready",
+			checks: map[string]string{"postgres": checkUp, "redis": checkDown, "queue": checkDown, "worker": checkDown},
+		},
+		{
+			name:   "critical down outweighs degraded",
+			store:  &stubStore{pingErr: failure, stats: healthyPool},
+			queue:  &stubQueue{pending: 11},
+			code:   http.StatusServiceUnavailable,
+			status: "not_ready",
+			checks: map[string]string{"postgres": checkDown, "queue": checkDegraded, "worker": checkDown},
+		},
+		{
+			name:   "pool exhausted",
+			store:  &stubStore{stats: store.PoolStats{TotalConns: 10, AcquiredConns: 10, MaxConns: 10}},
+			queue:  &stubQueue{heartbeats: alive},
+			code:   http.StatusOK,
+			status: "degraded",
+			checks: map[string]string{"postgres": checkDegraded},
+		},
+		{
+			name:   "queue backlog",
+			store:  &stubStore{stats: healthyPool},
+			queue:  &stubQueue{pending: 11, heartbeats: alive},
+			code:   http.StatusOK,
+			status: "degraded",
+			checks: map[string]string{"queue": checkDegraded, "worker": checkUp},
+		},
+		{
+			name:   "backlog at the threshold",
+			store:  &stubStore{stats: healthyPool},
+			queue:  &stubQueue{pending: 10, heartbeats: alive},
+			code:   http.StatusOK,
+			status: "ready",
+			checks: map[string]string{"queue": checkUp},
+		},
+		{
+			name:   "no workers",
+			store:  &stubStore{stats: healthyPool},
+			queue:  &stubQueue{},
+			code:   http.StatusOK,
+			status: "degraded",
+			checks: map[string]string{"redis": checkUp, "worker": checkDown},
+		},
+		{
+			name:   "stale heartbeat",
+			store:  &stubStore{stats: healthyPool},
+			queue:  &stubQueue{heartbeats: map[string]time.Time{"worker-1": time.Now().Add(-2 * time.Minute)}},
+			code:   http.StatusOK,
+			status: "degraded",
+			checks: map[string]string{"worker": checkDown},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHandler(tt.store, nil, nil, nil, zap.NewNop())
+			h.queueProbe = tt.queue
+			h.SetReadinessThresholds(10, time.Minute)
+
+			router := gin.New()
+			router.GET("/ready", h.ReadinessCheck)
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ready", nil))
+
+			var body struct {
+				Status string
+				Checks map[string]dependencyCheck
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tt.code || body.Status != tt.status {
+				t.Fatalf("readiness = %d %q, want %d %q: %s", w.Code, body.Status, tt.code, tt.status, w.Body)
+			}
+			if len(body.Checks) != 4 {
+				t.Fatalf("checks = %v, want all four", body.Checks)
+			}
+			for name, want := range tt.checks {
+				check := body.Checks[name]
+				if check.Status != want {
+					t.Errorf("%s = %q, want %q", name, check.Status, want)
+				}
+				// Causes are logged, not served: they can name internal hosts.
+				if check.Status == checkDown && name != "worker" && check.Error != "unavailable" {
+					t.Errorf("%s error = %q, want it redacted", name, check.Error)
+				}
+			}
+		})
+	}
+}
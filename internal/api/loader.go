@@ -0,0 +1,94 @@
+package api
+
+import (
+	"context"
+	"sync"
+)
+
+// batchLoader loads values by key for the duration of one request. Keys
+// announced with Prime are fetched together with the first key that is
+// actually loaded, so resolving a field on every element of a list costs
+// one query instead of one per element. Results are remembered, and
+// concurrent loads of a key wait for the fetch already in flight.
+type batchLoader[K comparable, V any] struct {
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu      sync.Mutex
+	pending []K
+	batches map[K]*loaderBatch[K, V]
+}
+
+type loaderBatch[K comparable, V any] struct {
+	done   chan struct{}
+	values map[K]V
+	err    error
+}
+
+func newBatchLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *batchLoader[K, V] {
+	return &batchLoader[K, V]{
+		fetch:   fetch,
+		batches: make(map[K]*loaderBatch[K, V]),
+	}
+}
+
+// Prime queues keys for the next fetch.
+func (l *batchLoader[K, V]) Prime(keys ...K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		if _, ok := l.batches[key]; !ok {
+			l.pending = append(l.pending, key)
+		}
+	}
+}
+
+// Set records value for key, for values that a list query has already
+// returned. A key that has been loaded keeps its value.
+func (l *batchLoader[K, V]) Set(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, ok := l.batches[key]; ok {
+		return
+	}
+	batch := &loaderBatch[K, V]{done: make(chan struct{}), values: map[K]V{key: value}}
+	close(batch.done)
+	l.batches[key] = batch
+}
+
+// Load returns the value for key, or the zero value when fetch did not
+// return one.
+func (l *batchLoader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	batch, ok := l.batches[key]
+	if !ok {
+		batch = &loaderBatch[K, V]{done: make(chan struct{})}
+		keys := []K{key}
+		l.batches[key] = batch
+		for _, k := range l.pending {
+			if _, seen := l.batches[k]; !seen {
+				l.batches[k] = batch
+				keys = append(keys, k)
+			}
+		}
+		l.pending = nil
+		l.mu.Unlock()
+
+		batch.values, batch.err = l.fetch(ctx, keys)
+		close(batch.done)
+	} else {
+		l.mu.Unlock()
+	}
+
+	var zero V
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+	if batch.err != nil {
+		return zero, batch.err
+	}
+	return batch.values[key], nil
+}
@@ -0,0 +1,161 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"sync"
+	"testing"
+)
+
+// recordingFetch returns the square of each key and records the batches it
+// was asked for.
+type recordingFetch struct {
+	mu      sync.Mutex
+	batches [][]int
+	err     error
+	release chan struct{}
+}
+
+func (f *recordingFetch) fetch(ctx context.Context, keys []int) (map[int]int, error) {
+	f.mu.Lock()
+	batch := append([]int(nil), keys...)
+	sort.Ints(batch)
+	f.batches = append(f.batches, batch)
+	f.mu.Unlock()
+
+	if f.release != nil {
+		<-f.release
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	values := make(map[int]int, len(keys))
+	for _, key := range keys {
+		if key >= 0 {
+			values[key] = key * key
+		}
+	}
+	return values, nil
+}
+
+func (f *recordingFetch) calls() [][]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.batches
+}
+
+func TestBatchLoaderFetchesPrimedKeysTogether(t *testing.T) {
+	ctx := context.Background()
+	f := &recordingFetch{}
+	loader := newBatchLoader(f.fetch)
+
+	loader.Prime(2, 3, 2)
+	for _, key := range []int{1, 2, 3} {
+		got, err := loader.Load(ctx, key)
+		if err != nil || got != key*key {
+			t.Fatalf("Load(%d) = %d, %v, want %d", key, got, err, key*key)
+		}
+	}
+	if got := fmt.Sprint(f.calls()); got != "[[1 2 3]]" {
+		t.Fatalf("fetches = %s, want one batch of every primed key", got)
+	}
+
+	// Keys already loaded are not fetched again, even when primed.
+	loader.Prime(1, 4)
+	if got, _ := loader.Load(ctx, 3); got != 9 {
+		t.Fatalf("Load(3) again = %d, want 9", got)
+	}
+	if got, err := loader.Load(ctx, -1); err != nil || got != 0 {
+		t.Fatalf("Load of a key fetch did not return = %d, %v, want the zero value", got, err)
+	}
+	if got := fmt.Sprint(f.calls()); got != "[[1 2 3] [-1 4]]" {
+		t.Fatalf("fetches = %s", got)
+	}
+
+	// Set values are served without a fetch and never replace loaded ones.
+	loader.Set(10, 1000)
+	loader.Set(3, 1000)
+	if got, _ := loader.Load(ctx, 10); got != 1000 {
+		t.Fatalf("Load(10) after Set = %d, want 1000", got)
+	}
+	if got, _ := loader.Load(ctx, 3); got != 9 {
+		t.Fatalf("Load(3) after Set = %d, want the loaded 9", got)
+	}
+	if len(f.calls()) != 2 {
+		t.Fatalf("fetches = %v, want no more after Set", f.calls())
+	}
+}
+
+func TestBatchLoaderConcurrentLoadsShareAFetch(t *testing.T) {
+	f := &recordingFetch{release: make(chan struct{})}
+	loader := newBatchLoader(f.fetch)
+	loader.Prime(5, 6)
+
+	// The first load holds the fetch open until every waiter has joined it.
+	first := make(chan error, 1)
+	go func() {
+		_, err := loader.Load(context.Background(), 5)
+		first <- err
+	}()
+	for len(f.calls()) == 0 {
+		runtime.Gosched()
+	}
+
+	var wg sync.WaitGroup
+	results := make([]int, 10)
+	errs := make([]error, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i], errs[i] = loader.Load(context.Background(), 5+i%2)
+		}(i)
+	}
+	close(f.release)
+	wg.Wait()
+
+	if err := <-first; err != nil {
+		t.Fatalf("first Load: %v", err)
+	}
+	for i, got := range results {
+		if want := (5 + i%2) * (5 + i%2); errs[i] != nil || got != want {
+			t.Fatalf("waiter %d got %d, %v, want %d", i, got, errs[i], want)
+		}
+	}
+	if got := fmt.Sprint(f.calls()); got != "[[5 6]]" {
+		t.Fatalf("fetches = %s, want a single batch", got)
+	}
+}
+
+func TestBatchLoaderErrors(t *testing.T) {
+	errFetch := errors.New("database is down")
+	f := &recordingFetch{err: errFetch}
+	loader := newBatchLoader(f.fetch)
+
+	loader.Prime(1, 2)
+	for _, key := range []int{1, 2} {
+		if _, err := loader.Load(context.Background(), key); !errors.Is(err, errFetch) {
+			t.Fatalf("Load(%d) = %v, want the fetch error", key, err)
+		}
+	}
+	if len(f.calls()) != 1 {
+		t.Fatalf("fetched %d times, want the failed batch to be remembered", len(f.calls()))
+	}
+
+	// A waiter whose request ends stops waiting for someone else's fetch.
+	f = &recordingFetch{release: make(chan struct{})}
+	loader = newBatchLoader(f.fetch)
+	go loader.Load(context.Background(), 7)
+	for len(f.calls()) == 0 {
+		runtime.Gosched()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := loader.Load(ctx, 7); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Load with a cancelled context = %v, want context.Canceled", err)
+	}
+	close(f.release)
+}
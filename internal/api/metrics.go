@@ -1,80 +1,179 @@
 package api
 
 import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"time"
+	"go.uber.org/zap"
+
+	"github.com/your-username/alldownloads/internal/store"
 )
 
-var (
-	httpRequestsTotal = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "http_requests_total",
-			Help: "Total number of HTTP requests",
-		},
-		[]string{"method", "endpoint", "status"},
-	)
+// UnmatchedEndpoint labels requests that matched no route, so that probes
+// for arbitrary paths cannot create new series.
+const UnmatchedEndpoint = "unmatched"
 
-	httpRequestDuration = prometheus.NewHistogramVec(
-		prometheus.HistogramOpts{
-			Name:    "http_request_duration_seconds",
-			Help:    "Duration of HTTP requests in seconds",
-			Buckets: prometheus.DefBuckets,
-		},
-		[]string{"method", "endpoint"},
-	)
+// DefaultSizeBuckets spans 100 B to 10 MB.
+var DefaultSizeBuckets = prometheus.ExponentialBuckets(100, 10, 6)
 
-	fetchJobsTotal = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Name: "fetch_jobs_total",
-			Help: "Total number of fetch jobs",
-		},
-		[]string{"status"},
-	)
+// HTTPMetrics records requests by method, route template and status code.
+type HTTPMetrics struct {
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	size     *prometheus.HistogramVec
+	inFlight prometheus.Gauge
+}
 
-	productsTotal = prometheus.NewGauge(
-		prometheus.GaugeOpts{
-			Name: "products_total",
-			Help: "Total number of products",
-		},
-	)
+// NewHTTPMetrics registers the HTTP collectors with reg. Nil buckets fall
+// back to prometheus.DefBuckets for durations and DefaultSizeBuckets for
+// response sizes.
+func NewHTTPMetrics(reg prometheus.Registerer, durationBuckets, sizeBuckets []float64) *HTTPMetrics {
+	if durationBuckets == nil {
+		durationBuckets = prometheus.DefBuckets
+	}
+	if sizeBuckets == nil {
+		sizeBuckets = DefaultSizeBuckets
+	}
 
-	productVersionsTotal = prometheus.NewGaugeVec(
-		prometheus.GaugeOpts{
-			Name: "product_versions_total",
-			Help: "Total number of product versions",
-		},
-		[]string{"product_id"},
-	)
-)
+	m := &HTTPMetrics{
+		requests: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "http_requests_total",
+				Help: "Total number of HTTP requests",
+			},
+			[]string{"method", "endpoint", "status"},
+		),
+		duration: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_request_duration_seconds",
+				Help:    "Duration of HTTP requests in seconds",
+				Buckets: durationBuckets,
+			},
+			[]string{"method", "endpoint", "status"},
+		),
+		size: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{
+				Name:    "http_response_size_bytes",
+				Help:    "Size of HTTP response bodies in bytes",
+				Buckets: sizeBuckets,
+			},
+			[]string{"method", "endpoint", "status"},
+		),
+		inFlight: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Name: "http_requests_in_flight",
+				Help: "HTTP requests currently being served",
+			},
+		),
+	}
+	reg.MustRegister(m.requests, m.duration, m.size, m.inFlight)
 
-func init() {
-	prometheus.MustRegister(httpRequestsTotal)
-	prometheus.MustRegister(httpRequestDuration)
-	prometheus.MustRegister(fetchJobsTotal)
-	prometheus.MustRegister(productsTotal)
-	prometheus.MustRegister(productVersionsTotal)
+	return m
 }
 
-func PrometheusMetrics() gin.HandlerFunc {
+// Middleware observes every request passing through the router. Endpoints
+// are labelled with the route template, e.g. /api/v1/products/:id.
+func (m *HTTPMetrics) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
+		m.inFlight.Inc()
+		defer m.inFlight.Dec()
 
 		c.Next()
 
-		duration := time.Since(start).Seconds()
+		endpoint := c.FullPath()
+		if endpoint == "" {
+			endpoint = UnmatchedEndpoint
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		size := c.Writer.Size()
+		if size < 0 {
+			size = 0
+		}
+
+		method := metricMethod(c.Request.Method)
+
+		m.requests.WithLabelValues(method, endpoint, status).Inc()
+		m.duration.WithLabelValues(method, endpoint, status).Observe(time.Since(start).Seconds())
+		m.size.WithLabelValues(method, endpoint, status).Observe(float64(size))
+	}
+}
+
+// metricMethod folds non-standard methods into one label value.
+func metricMethod(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodOptions, http.MethodConnect, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
 
-		httpRequestsTotal.WithLabelValues(
-			c.Request.Method,
-			c.FullPath(),
-			string(rune(c.Writer.Status())),
-		).Inc()
+// catalogScrapeTimeout bounds the queries behind CatalogCollector, so that
+// a slow database cannot stall a scrape.
+const catalogScrapeTimeout = 5 * time.Second
 
-		httpRequestDuration.WithLabelValues(
-			c.Request.Method,
-			c.FullPath(),
-		).Observe(duration)
+var (
+	productsTotalDesc = prometheus.NewDesc(
+		"products_total",
+		"Total number of products",
+		nil, nil,
+	)
+	productVersionsTotalDesc = prometheus.NewDesc(
+		"product_versions_total",
+		"Total number of product versions",
+		[]string{"product_id"}, nil,
+	)
+)
+
+// CatalogCollector reports products_total and product_versions_total from
+// the store at scrape time, so that they agree with the database whichever
+// process changed it.
+type CatalogCollector struct {
+	store  store.Store
+	logger *zap.Logger
+}
+
+// NewCatalogCollector returns a collector for the product catalogue.
+func NewCatalogCollector(store store.Store, logger *zap.Logger) *CatalogCollector {
+	return &CatalogCollector{store: store, logger: logger}
+}
+
+// Describe implements prometheus.Collector.
+func (c *CatalogCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- productsTotalDesc
+	ch <- productVersionsTotalDesc
+}
+
+// Collect implements prometheus.Collector. When the store cannot be read
+// the series are left out of the scrape rather than failing it.
+func (c *CatalogCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), catalogScrapeTimeout)
+	defer cancel()
+
+	products, err := c.store.GetProducts(ctx)
+	if err != nil {
+		c.logger.Warn("failed to count products for metrics", zap.Error(err))
+		return
+	}
+	ids := make([]string, len(products))
+	for i, product := range products {
+		ids[i] = product.ID
+	}
+	versions, err := c.store.GetVersionsByProductIDs(ctx, ids)
+	if err != nil {
+		c.logger.Warn("failed to count product versions for metrics", zap.Error(err))
+		return
+	}
+
+	ch <- prometheus.MustNewConstMetric(productsTotalDesc, prometheus.GaugeValue, float64(len(products)))
+	for _, id := range ids {
+		ch <- prometheus.MustNewConstMetric(productVersionsTotalDesc, prometheus.GaugeValue, float64(len(versions[id])), id)
 	}
 }
 
@@ -84,15 +183,3 @@ func MetricsHandler() gin.HandlerFunc {
 		handler.ServeHTTP(c.Writer, c.Request)
 	}
 }
-
-func IncrementFetchJobMetric(status string) {
-	fetchJobsTotal.WithLabelValues(status).Inc()
-}
-
-func SetProductsMetric(count float64) {
-	productsTotal.Set(count)
-}
-
-func SetProductVersionsMetric(productID string, count float64) {
-	productVersionsTotal.WithLabelValues(productID).Set(count)
-}
\ No newline at end of file
@@ -0,0 +1,163 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+// newMetricsRouter serves a parameterised route behind the metrics
+// middleware, recording into a registry of its own.
+func newMetricsRouter(t *testing.T, durationBuckets, sizeBuckets []float64) (*gin.Engine, *prometheus.Registry, *HTTPMetrics) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	reg := prometheus.NewRegistry()
+	metrics := NewHTTPMetrics(reg, durationBuckets, sizeBuckets)
+
+	router := gin.New()
+	router.Use(metrics.Middleware())
+	router.GET("/api/v1/products/:id", func(c *gin.Context) {
+		if c.Param("id") == "missing" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+			return
+		}
+		c.String(http.StatusOK, strings.Repeat("x", 250))
+	})
+	return router, reg, metrics
+}
+
+func serve(router http.Handler, method, path string) {
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, path, nil))
+}
+
+// scrape renders reg in the exposition format, as Prometheus would read it.
+func scrape(t *testing.T, reg *prometheus.Registry) string {
+	t.Helper()
+	w := httptest.NewRecorder()
+	promhttp.HandlerFor(reg, promhttp.HandlerOpts{}).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("scrape returned %d", w.Code)
+	}
+	return w.Body.String()
+}
+
+func TestHTTPMetricsLabels(t *testing.T) {
+	router, reg, metrics := newMetricsRouter(t, nil, nil)
+
+	serve(router, http.MethodGet, "/api/v1/products/ubuntu")
+	serve(router, http.MethodGet, "/api/v1/products/debian")
+	serve(router, http.MethodGet, "/api/v1/products/missing")
+	serve(router, http.MethodGet, "/wp-login.php")
+	serve(router, "PROPFIND", "/api/v1/products/ubuntu")
+
+	body := scrape(t, reg)
+	for _, want := range []string{
+		`http_requests_total{endpoint="/api/v1/products/:id",method="GET",status="200"} 2`,
+		`http_requests_total{endpoint="/api/v1/products/:id",method="GET",status="404"} 1`,
+		`http_requests_total{endpoint="unmatched",method="GET",status="404"} 1`,
+		`http_requests_total{endpoint="unmatched",method="OTHER",status="404"} 1`,
+		`http_request_duration_seconds_count{endpoint="/api/v1/products/:id",method="GET",status="200"} 2`,
+		`http_response_size_bytes_sum{endpoint="/api/v1/products/:id",method="GET",status="200"} 500`,
+		`http_response_size_bytes_bucket{endpoint="/api/v1/products/:id",method="GET",status="200",le="100"} 0`,
+		`http_response_size_bytes_bucket{endpoint="/api/v1/products/:id",method="GET",status="200",le="1000"} 2`,
+		`http_requests_in_flight 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("scrape is missing %s", want)
+		}
+	}
+	for _, unwanted := range []string{`status="OK"`, `/wp-login.php`, `/api/v1/products/ubuntu`} {
+		if strings.Contains(body, unwanted) {
+			t.Errorf("scrape contains %s", unwanted)
+		}
+	}
+
+	if got := testutil.ToFloat64(metrics.inFlight); got != 0 {
+		t.Fatalf("in-flight gauge is %v after requests finished", got)
+	}
+}
+
+func TestHTTPMetricsInFlight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	reg := prometheus.NewRegistry()
+	metrics := NewHTTPMetrics(reg, nil, nil)
+
+	var during float64
+	router := gin.New()
+	router.Use(metrics.Middleware())
+	router.GET("/slow", func(c *gin.Context) {
+		during = testutil.ToFloat64(metrics.inFlight)
+		c.Status(http.StatusNoContent)
+	})
+
+	serve(router, http.MethodGet, "/slow")
+	if during != 1 {
+		t.Fatalf("in-flight gauge was %v while serving, want 1", during)
+	}
+	if got := testutil.ToFloat64(metrics.inFlight); got != 0 {
+		t.Fatalf("in-flight gauge is %v after serving, want 0", got)
+	}
+}
+
+func TestHTTPMetricsCustomBuckets(t *testing.T) {
+	router, reg, _ := newMetricsRouter(t, []float64{0.25, 5}, []float64{10, 300})
+
+	serve(router, http.MethodGet, "/api/v1/products/ubuntu")
+
+	body := scrape(t, reg)
+	for _, want := range []string{
+		`http_request_duration_seconds_bucket{endpoint="/api/v1/products/:id",method="GET",status="200",le="0.25"}`,
+		`http_request_duration_seconds_bucket{endpoint="/api/v1/products/:id",method="GET",status="200",le="5"}`,
+		`http_response_size_bytes_bucket{endpoint="/api/v1/products/:id",method="GET",status="200",le="10"} 0`,
+		`http_response_size_bytes_bucket{endpoint="/api/v1/products/:id",method="GET",status="200",le="300"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("scrape is missing %s", want)
+		}
+	}
+	if strings.Contains(body, `le="0.005"`) || strings.Contains(body, `le="1000"`) {
+		t.Errorf("default buckets are still exposed:\n%s", body)
+	}
+}
+
+func TestCatalogCollector(t *testing.T) {
+	s := newSQLiteStore(t)
+	ctx := context.Background()
+	for _, platform := range []string{"linux", "windows"} {
+		version := &store.ProductVersion{
+			ProductID:    "ubuntu",
+			Version:      "24.04",
+			Platform:     platform,
+			Architecture: "amd64",
+			DownloadURL:  "https://example.com/ubuntu-24.04-" + platform,
+		}
+		if _, err := s.CreateOrUpdateProductVersion(ctx, version); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(NewCatalogCollector(s, zap.NewNop()))
+
+	body := scrape(t, reg)
+	for _, want := range []string{
+		"products_total 16",
+		`product_versions_total{product_id="ubuntu"} 2`,
+		`product_versions_total{product_id="debian"} 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("scrape is missing %s:\n%s", want, body)
+		}
+	}
+}
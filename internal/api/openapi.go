@@ -0,0 +1,66 @@
+package api
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed openapi.yaml
+var openapiYAML []byte
+
+// LoadOpenAPI parses and validates the embedded OpenAPI document.
+func LoadOpenAPI() (*openapi3.T, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(openapiYAML)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load OpenAPI document: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI document: %w", err)
+	}
+	return doc, nil
+}
+
+// OpenAPISpec serves doc as JSON.
+func OpenAPISpec(doc *openapi3.T) (gin.HandlerFunc, error) {
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode OpenAPI document: %w", err)
+	}
+
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", data)
+	}, nil
+}
+
+// APIDocs serves an interactive page that renders the OpenAPI document
+// found at specURL.
+func APIDocs(specURL string) gin.HandlerFunc {
+	page := fmt.Sprintf(docsPage, specURL)
+	return func(c *gin.Context) {
+		c.Data(http.StatusOK, "text/html; charset=utf-8", []byte(page))
+	}
+}
+
+const docsPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>AllDownloads API</title>
+  <link rel="stylesheet" href="https://cdn.jsdelivr.net/npm/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://cdn.jsdelivr.net/npm/swagger-ui-dist@5/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: %q, dom_id: "#swagger-ui", withCredentials: true });
+  </script>
+</body>
+</html>
+`
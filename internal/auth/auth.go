@@ -1,39 +1,220 @@
 package auth
 
 import (
+	"context"
 	"crypto/subtle"
 	"net/http"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/alldownloads/internal/problem"
+	"github.com/your-username/alldownloads/internal/ratelimit"
+	"github.com/your-username/alldownloads/internal/store"
+	"go.uber.org/zap"
+)
+
+const (
+	PrincipalKey = "auth_principal"
+
+	PrincipalTypeAPIKey    = "api_key"
+	PrincipalTypeBootstrap = "bootstrap"
+
+	// lastUsedInterval bounds how often last_used_at is written per key.
+	lastUsedInterval = time.Minute
 )
 
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Type   string   `json:"type"`
+	ID     string   `json:"id"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+func GetPrincipal(c *gin.Context) *Principal {
+	if v, ok := c.Get(PrincipalKey); ok {
+		if p, ok := v.(*Principal); ok {
+			return p
+		}
+	}
+	return nil
+}
+
 type AuthMiddleware struct {
-	token string
+	store          store.Store
+	bootstrapToken string
+	logger         *zap.Logger
+	oidc           *OIDC
+	limiter        ratelimit.Limiter
+
+	mu       sync.Mutex
+	lastUsed map[string]time.Time
+}
+
+// NewAuthMiddleware authenticates requests with API keys stored in Postgres.
+// bootstrapToken, when set, is accepted as an admin credential so that the
+// first keys can be created; leave it empty once real keys exist. limiter
+// enforces each key's own rate_limit_per_minute.
+func NewAuthMiddleware(store store.Store, bootstrapToken string, limiter ratelimit.Limiter, logger *zap.Logger) *AuthMiddleware {
+	return &AuthMiddleware{
+		store:          store,
+		bootstrapToken: bootstrapToken,
+		limiter:        limiter,
+		logger:         logger,
+		lastUsed:       make(map[string]time.Time),
+	}
+}
+
+// UseSessions additionally accepts OIDC session cookies from browsers.
+func (a *AuthMiddleware) UseSessions(o *OIDC) {
+	a.oidc = o
 }
 
-func NewAuthMiddleware(token string) *AuthMiddleware {
-	return &AuthMiddleware{token: token}
+// RequireScope rejects requests without a valid key (401), with a key that
+// lacks scope (403) or that has exhausted its own rate limit (429).
+func (a *AuthMiddleware) RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		a.identify(c, scope, true)
+	}
 }
 
-func (a *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			http.Error(w, "Authorization header required", http.StatusUnauthorized)
+// Authenticate identifies the caller when credentials are present but lets
+// anonymous requests through, for endpoints that decide per operation what
+// a caller may do. Invalid credentials are still rejected.
+func (a *AuthMiddleware) Authenticate() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		a.identify(c, "", false)
+	}
+}
+
+// identify sets the principal of the request and checks it for scope,
+// unless scope is empty.
+func (a *AuthMiddleware) identify(c *gin.Context, scope string, required bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" && a.oidc != nil {
+		if principal := a.oidc.SessionPrincipal(c.Request); principal != nil {
+			a.requireSessionScope(c, principal, scope)
 			return
 		}
+	}
+	if authHeader == "" && !required {
+		c.Next()
+		return
+	}
 
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			http.Error(w, "Authorization header format must be Bearer {token}", http.StatusUnauthorized)
-			return
+	principal, key, status, msg := a.authenticate(c.Request.Context(), authHeader)
+	if principal == nil {
+		code := problem.CodeUnauthorized
+		if status == http.StatusInternalServerError {
+			code = problem.CodeInternal
 		}
+		problem.Abort(c, status, code, msg)
+		return
+	}
+
+	if scope != "" && !HasScope(principal.Scopes, scope) {
+		problem.Abort(c, http.StatusForbidden, problem.CodeInsufficientScope, "API key lacks the "+scope+" scope")
+		return
+	}
 
-		token := parts[1]
-		if subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) != 1 {
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
+	if key != nil && key.RateLimitPerMinute > 0 {
+		policy := ratelimit.Policy{Name: "api_key", Requests: key.RateLimitPerMinute, Window: time.Minute}
+		if !ratelimit.Enforce(c, a.limiter, "key:"+key.ID, policy, a.logger) {
 			return
 		}
+	}
 
-		next.ServeHTTP(w, r)
-	})
-}
\ No newline at end of file
+	c.Set(PrincipalKey, principal)
+	c.Next()
+}
+
+// requireSessionScope authorizes a cookie-authenticated request. Unsafe
+// methods must carry X-Requested-With, which browsers only send cross-site
+// after a CORS preflight, so a forged form post cannot ride on the cookie.
+func (a *AuthMiddleware) requireSessionScope(c *gin.Context, principal *Principal, scope string) {
+	if !requireCSRFHeader(c) {
+		return
+	}
+
+	if scope != "" && !HasScope(principal.Scopes, scope) {
+		problem.Abort(c, http.StatusForbidden, problem.CodeInsufficientScope, "Your account lacks the "+scope+" scope")
+		return
+	}
+
+	c.Set(PrincipalKey, principal)
+	c.Next()
+}
+
+// requireCSRFHeader aborts unsafe cookie-authenticated requests without an
+// X-Requested-With header. Browsers only send custom headers cross-origin
+// after a CORS preflight, which CORS answers for allowed origins only.
+func requireCSRFHeader(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	if c.GetHeader("X-Requested-With") == "" {
+		problem.Abort(c, http.StatusForbidden, problem.CodeForbidden, "X-Requested-With header required for cookie-authenticated requests")
+		return false
+	}
+	return true
+}
+
+func (a *AuthMiddleware) authenticate(ctx context.Context, authHeader string) (*Principal, *store.APIKey, int, string) {
+	if authHeader == "" {
+		return nil, nil, http.StatusUnauthorized, "Authorization header required"
+	}
+
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, nil, http.StatusUnauthorized, "Authorization header format must be Bearer {token}"
+	}
+	token := parts[1]
+
+	if a.bootstrapToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(a.bootstrapToken)) == 1 {
+		return &Principal{Type: PrincipalTypeBootstrap, ID: "bootstrap", Name: "bootstrap token", Scopes: []string{ScopeAdmin}}, nil, 0, ""
+	}
+
+	prefix, ok := ParseKeyPrefix(token)
+	if !ok {
+		return nil, nil, http.StatusUnauthorized, "Invalid token"
+	}
+
+	key, err := a.store.GetAPIKeyByPrefix(ctx, prefix)
+	if err != nil {
+		a.logger.Error("failed to look up api key", zap.Error(err))
+		return nil, nil, http.StatusInternalServerError, "Failed to verify token"
+	}
+	if key == nil || subtle.ConstantTimeCompare([]byte(HashKey(token)), []byte(key.KeyHash)) != 1 {
+		return nil, nil, http.StatusUnauthorized, "Invalid token"
+	}
+
+	now := time.Now()
+	if key.RevokedAt != nil {
+		return nil, nil, http.StatusUnauthorized, "API key has been revoked"
+	}
+	if key.ExpiresAt != nil && now.After(*key.ExpiresAt) {
+		return nil, nil, http.StatusUnauthorized, "API key has expired"
+	}
+
+	a.touch(ctx, key.ID, now)
+
+	return &Principal{Type: PrincipalTypeAPIKey, ID: key.ID, Name: key.Name, Scopes: key.Scopes}, key, 0, ""
+}
+
+func (a *AuthMiddleware) touch(ctx context.Context, keyID string, now time.Time) {
+	a.mu.Lock()
+	last, seen := a.lastUsed[keyID]
+	if seen && now.Sub(last) < lastUsedInterval {
+		a.mu.Unlock()
+		return
+	}
+	a.lastUsed[keyID] = now
+	a.mu.Unlock()
+
+	if err := a.store.TouchAPIKey(ctx, keyID, now); err != nil {
+		a.logger.Warn("failed to record api key use", zap.Error(err), zap.String("key_id", keyID))
+	}
+}
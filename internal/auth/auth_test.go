@@ -0,0 +1,206 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/your-username/alldownloads/internal/problem"
+	"github.com/your-username/alldownloads/internal/ratelimit"
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+// keyStore serves API keys from memory and records when they are used.
+type keyStore struct {
+	store.Store
+	keys    map[string]*store.APIKey
+	err     error
+	touched []string
+}
+
+func (s *keyStore) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*store.APIKey, error) {
+	return s.keys[prefix], s.err
+}
+
+func (s *keyStore) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	s.touched = append(s.touched, id)
+	return nil
+}
+
+// add stores a new key with scopes and returns its plaintext.
+func (s *keyStore) add(t *testing.T, name string, scopes ...string) (string, *store.APIKey) {
+	t.Helper()
+	plaintext, prefix, hash, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := &store.APIKey{ID: name, Name: name, Prefix: prefix, KeyHash: hash, Scopes: scopes}
+	s.keys[prefix] = key
+	return plaintext, key
+}
+
+func newKeyRouter(s store.Store, bootstrapToken string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	middleware := NewAuthMiddleware(s, bootstrapToken, ratelimit.NewMemoryLimiter(), zap.NewNop())
+
+	router := gin.New()
+	ok := func(c *gin.Context) { c.String(http.StatusOK, GetPrincipal(c).Type+":"+GetPrincipal(c).ID) }
+	router.GET("/products", middleware.RequireScope(ScopeRead), ok)
+	router.POST("/refresh", middleware.RequireScope(ScopeRefresh), ok)
+	router.POST("/admin/keys", middleware.RequireScope(ScopeAdmin), ok)
+	return router
+}
+
+func serveAuth(router http.Handler, method, path, authorization string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, path, nil)
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func TestRequireScope(t *testing.T) {
+	keys := &keyStore{keys: map[string]*store.APIKey{}}
+	reader, _ := keys.add(t, "reader", ScopeRead)
+	refresher, _ := keys.add(t, "refresher", ScopeRefresh)
+	admin, _ := keys.add(t, "admin", ScopeAdmin)
+	revoked, revokedKey := keys.add(t, "revoked", ScopeAdmin)
+	revokedKey.RevokedAt = &[]time.Time{time.Now().Add(-time.Minute)}[0]
+	expired, expiredKey := keys.add(t, "expired", ScopeAdmin)
+	expiredKey.ExpiresAt = &[]time.Time{time.Now().Add(-time.Second)}[0]
+	expiring, expiringKey := keys.add(t, "expiring", ScopeRead)
+	expiringKey.ExpiresAt = &[]time.Time{time.Now().Add(time.Hour)}[0]
+
+	// Same prefix, different secret: found by the lookup, refused by the hash.
+	prefix, _ := ParseKeyPrefix(admin)
+	forged := "adl_" + prefix + "_" + strings.Repeat("0", 64)
+
+	router := newKeyRouter(keys, "")
+
+	for _, tt := range []struct {
+		name          string
+		method, path  string
+		authorization string
+		status        int
+		code          string
+		detail        string
+	}{
+		{"reader reads", http.MethodGet, "/products", "Bearer " + reader, http.StatusOK, "", "api_key:reader"},
+		{"reader refreshes", http.MethodPost, "/refresh", "Bearer " + reader, http.StatusForbidden, problem.CodeInsufficientScope, "API key lacks the refresh scope"},
+		{"refresher refreshes", http.MethodPost, "/refresh", "Bearer " + refresher, http.StatusOK, "", "api_key:refresher"},
+		{"refresher reads", http.MethodGet, "/products", "Bearer " + refresher, http.StatusForbidden, problem.CodeInsufficientScope, "API key lacks the read scope"},
+		{"refresher administers", http.MethodPost, "/admin/keys", "Bearer " + refresher, http.StatusForbidden, problem.CodeInsufficientScope, "API key lacks the admin scope"},
+		{"admin reads", http.MethodGet, "/products", "Bearer " + admin, http.StatusOK, "", "api_key:admin"},
+		{"admin refreshes", http.MethodPost, "/refresh", "Bearer " + admin, http.StatusOK, "", "api_key:admin"},
+		{"admin administers", http.MethodPost, "/admin/keys", "Bearer " + admin, http.StatusOK, "", "api_key:admin"},
+		{"key before its expiry", http.MethodGet, "/products", "Bearer " + expiring, http.StatusOK, "", "api_key:expiring"},
+		{"revoked key", http.MethodGet, "/products", "Bearer " + revoked, http.StatusUnauthorized, problem.CodeUnauthorized, "API key has been revoked"},
+		{"expired key", http.MethodGet, "/products", "Bearer " + expired, http.StatusUnauthorized, problem.CodeUnauthorized, "API key has expired"},
+		{"forged secret", http.MethodGet, "/products", "Bearer " + forged, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid token"},
+		{"unknown prefix", http.MethodGet, "/products", "Bearer adl_ffffffffffff_" + strings.Repeat("0", 64), http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid token"},
+		{"not a key", http.MethodGet, "/products", "Bearer nonsense", http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid token"},
+		{"no credentials", http.MethodGet, "/products", "", http.StatusUnauthorized, problem.CodeUnauthorized, "Authorization header required"},
+		{"wrong scheme", http.MethodGet, "/products", "Basic " + admin, http.StatusUnauthorized, problem.CodeUnauthorized, "Authorization header format must be Bearer {token}"},
+		{"bare key", http.MethodGet, "/products", admin, http.StatusUnauthorized, problem.CodeUnauthorized, "Authorization header format must be Bearer {token}"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w := serveAuth(router, tt.method, tt.path, tt.authorization)
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d: %s", w.Code, tt.status, w.Body)
+			}
+			if tt.code == "" {
+				if w.Body.String() != tt.detail {
+					t.Fatalf("principal = %q, want %q", w.Body, tt.detail)
+				}
+				return
+			}
+			var body problem.Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != tt.code || body.Detail != tt.detail {
+				t.Fatalf("problem = %+v, want %q: %q", body, tt.code, tt.detail)
+			}
+		})
+	}
+
+	// Use is recorded at most once a minute per key, and never for
+	// refused keys.
+	for _, id := range keys.touched {
+		if id == "revoked" || id == "expired" {
+			t.Errorf("refused key %s was marked as used", id)
+		}
+	}
+	if len(keys.touched) != 4 {
+		t.Errorf("touched = %v, want each accepted key once", keys.touched)
+	}
+}
+
+func TestRequireScopeStoreFailure(t *testing.T) {
+	keys := &keyStore{keys: map[string]*store.APIKey{}, err: errors.New("connection refused")}
+	router := newKeyRouter(keys, "")
+
+	w := serveAuth(router, http.MethodGet, "/products", "Bearer adl_0123456789ab_secret")
+	var body problem.Problem
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusInternalServerError || body.Code != problem.CodeInternal {
+		t.Fatalf("lookup failure = %d %+v, want a 500", w.Code, body)
+	}
+}
+
+func TestRequireScopeKeyRateLimit(t *testing.T) {
+	keys := &keyStore{keys: map[string]*store.APIKey{}}
+	limited, key := keys.add(t, "limited", ScopeRead)
+	key.RateLimitPerMinute = 2
+	unlimited, _ := keys.add(t, "unlimited", ScopeRead)
+	router := newKeyRouter(keys, "")
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if w := serveAuth(router, http.MethodGet, "/products", "Bearer "+limited); w.Code != want {
+			t.Fatalf("request %d = %d, want %d", i+1, w.Code, want)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		if w := serveAuth(router, http.MethodGet, "/products", "Bearer "+unlimited); w.Code != http.StatusOK {
+			t.Fatalf("key without a limit got %d", w.Code)
+		}
+	}
+}
+
+// The shared AUTH_TOKEN is gone: it only works, as an admin bootstrap
+// credential, while it is set, and its old default is just another bad key.
+func TestBootstrapToken(t *testing.T) {
+	keys := &keyStore{keys: map[string]*store.APIKey{}}
+
+	router := newKeyRouter(keys, "first-run-secret")
+	for _, path := range []string{"/refresh", "/admin/keys"} {
+		if w := serveAuth(router, http.MethodPost, path, "Bearer first-run-secret"); w.Code != http.StatusOK || w.Body.String() != "bootstrap:bootstrap" {
+			t.Fatalf("bootstrap token on %s = %d %s", path, w.Code, w.Body)
+		}
+	}
+	if w := serveAuth(router, http.MethodPost, "/admin/keys", "Bearer first-run-secreT"); w.Code != http.StatusUnauthorized {
+		t.Fatalf("near miss of the bootstrap token = %d, want 401", w.Code)
+	}
+	if len(keys.touched) != 0 {
+		t.Fatalf("bootstrap token touched keys %v", keys.touched)
+	}
+
+	router = newKeyRouter(keys, "")
+	for _, token := range []string{"Bearer change-me", "Bearer ", "Bearer first-run-secret"} {
+		if w := serveAuth(router, http.MethodPost, "/admin/keys", token); w.Code != http.StatusUnauthorized {
+			t.Errorf("%q without a bootstrap token = %d, want 401", token, w.Code)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestGenerateKey(t *testing.T) {
+	plaintext, prefix, hash, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^adl_[0-9a-f]{12}_[0-9a-f]{64}$`).MatchString(plaintext) {
+		t.Fatalf("key %q does not look like adl_<prefix>_<secret>", plaintext)
+	}
+	if parsed, ok := ParseKeyPrefix(plaintext); !ok || parsed != prefix {
+		t.Fatalf("ParseKeyPrefix = %q, %v, want %q", parsed, ok, prefix)
+	}
+	// Only the hash is stored, so it must be reproducible from the key and
+	// reveal nothing of it.
+	if HashKey(plaintext) != hash || len(hash) != 64 || strings.Contains(hash, plaintext[len("adl_"+prefix+"_"):]) {
+		t.Fatalf("hash %q does not match the key", hash)
+	}
+	if HashKey(plaintext+"x") == hash {
+		t.Fatal("different keys share a hash")
+	}
+
+	other, otherPrefix, _, err := GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == plaintext || otherPrefix == prefix {
+		t.Fatal("GenerateKey repeated itself")
+	}
+}
+
+func TestParseKeyPrefix(t *testing.T) {
+	for token, want := range map[string]string{
+		"adl_0123456789ab_secret": "0123456789ab",
+		"adl__secret":             "",
+		"adl_0123456789ab_":       "",
+		"adl_0123456789ab":        "",
+		"xyz_0123456789ab_secret": "",
+		"adl_a_b_c":               "",
+		"change-me":               "",
+		"":                        "",
+	} {
+		prefix, ok := ParseKeyPrefix(token)
+		if prefix != want || ok != (want != "") {
+			t.Errorf("ParseKeyPrefix(%q) = %q, %v, want %q", token, prefix, ok, want)
+		}
+	}
+}
+
+func TestHasScope(t *testing.T) {
+	for _, tt := range []struct {
+		granted  []string
+		required string
+		want     bool
+	}{
+		{[]string{ScopeRead}, ScopeRead, true},
+		{[]string{ScopeRead}, ScopeRefresh, false},
+		{[]string{ScopeRead}, ScopeAdmin, false},
+		{[]string{ScopeRefresh}, ScopeRead, false},
+		{[]string{ScopeRead, ScopeRefresh}, ScopeRefresh, true},
+		{[]string{ScopeAdmin}, ScopeRead, true},
+		{[]string{ScopeAdmin}, ScopeRefresh, true},
+		{nil, ScopeRead, false},
+		{[]string{"Admin"}, ScopeRead, false},
+	} {
+		if got := HasScope(tt.granted, tt.required); got != tt.want {
+			t.Errorf("HasScope(%v, %q) = %v, want %v", tt.granted, tt.required, got, tt.want)
+		}
+	}
+
+	for _, scope := range Scopes {
+		if !ValidScope(scope) {
+			t.Errorf("ValidScope(%q) = false", scope)
+		}
+	}
+	for _, scope := range []string{"", "write", "ADMIN", "admin "} {
+		if ValidScope(scope) {
+			t.Errorf("ValidScope(%q) = true", scope)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/alldownloads/internal/problem"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+const (
+	PrincipalTypeUser = "user"
+
+	SessionCookie    = "alldl_session"
+	loginStateCookie = "alldl_oidc_login"
+	loginStateTTL    = 10 * time.Minute
+)
+
+type OIDCConfig struct {
+	IssuerURL    string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// GroupsClaim names the ID token claim holding the user's groups. Nested
+	// claims such as Keycloak's realm_access.roles use a dotted path.
+	GroupsClaim string
+	// RoleMapping maps a group to the scopes it grants.
+	RoleMapping   map[string][]string
+	SessionSecret string
+	SessionTTL    time.Duration
+	// SessionStore tracks live sessions so that Logout revokes them on every
+	// replica rather than only clearing the cookie in one browser.
+	SessionStore  SessionStore
+	PostLoginURL  string
+	SecureCookies bool
+}
+
+type OIDC struct {
+	cfg      OIDCConfig
+	verifier *oidc.IDTokenVerifier
+	oauth2   oauth2.Config
+	sessions *SessionCodec
+	logger   *zap.Logger
+}
+
+// session is the payload of the session cookie. ID names the session in the
+// SessionStore.
+type session struct {
+	ID string `json:"sid"`
+	Principal
+}
+
+type loginState struct {
+	State    string `json:"state"`
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+	ReturnTo string `json:"return_to"`
+}
+
+// NewOIDC discovers the provider at cfg.IssuerURL. It fails if the provider
+// cannot be reached so that a misconfiguration is caught at startup.
+func NewOIDC(ctx context.Context, cfg OIDCConfig, logger *zap.Logger) (*OIDC, error) {
+	if cfg.SessionSecret == "" {
+		return nil, fmt.Errorf("a session secret is required for OIDC login")
+	}
+	if cfg.SessionStore == nil {
+		return nil, fmt.Errorf("a session store is required for OIDC login")
+	}
+	if cfg.GroupsClaim == "" {
+		cfg.GroupsClaim = "groups"
+	}
+	if cfg.SessionTTL <= 0 {
+		cfg.SessionTTL = 12 * time.Hour
+	}
+	if cfg.PostLoginURL == "" {
+		cfg.PostLoginURL = "/"
+	}
+
+	provider, err := oidc.NewProvider(ctx, cfg.IssuerURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider: %w", err)
+	}
+
+	scopes := cfg.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{oidc.ScopeOpenID, "profile", "email"}
+	}
+
+	return &OIDC{
+		cfg:      cfg,
+		verifier: provider.Verifier(&oidc.Config{ClientID: cfg.ClientID}),
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Endpoint:     provider.Endpoint(),
+			Scopes:       scopes,
+		},
+		sessions: NewSessionCodec(cfg.SessionSecret),
+		logger:   logger,
+	}, nil
+}
+
+// ParseRoleMapping parses "group=scope,group=scope" into a mapping. A group
+// may appear several times to grant several scopes.
+func ParseRoleMapping(value string) (map[string][]string, error) {
+	mapping := make(map[string][]string)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		group, scope, ok := strings.Cut(pair, "=")
+		group, scope = strings.TrimSpace(group), strings.TrimSpace(scope)
+		if !ok || group == "" || !ValidScope(scope) {
+			return nil, fmt.Errorf("invalid role mapping %q, expected group=%s", pair, strings.Join(Scopes, "|"))
+		}
+		mapping[group] = append(mapping[group], scope)
+	}
+	return mapping, nil
+}
+
+func (o *OIDC) Login(c *gin.Context) {
+	state := loginState{
+		State:    randomString(),
+		Nonce:    randomString(),
+		Verifier: oauth2.GenerateVerifier(),
+		ReturnTo: safeReturnTo(c.Query("return_to")),
+	}
+
+	value, err := o.sessions.Encode(state, loginStateTTL)
+	if err != nil {
+		o.logger.Error("failed to encode login state", zap.Error(err))
+		problem.Internal(c, "Failed to start login")
+		return
+	}
+	o.setCookie(c, loginStateCookie, value, loginStateTTL)
+
+	authURL := o.oauth2.AuthCodeURL(state.State, oidc.Nonce(state.Nonce), oauth2.S256ChallengeOption(state.Verifier))
+	c.Redirect(http.StatusFound, authURL)
+}
+
+func (o *OIDC) Callback(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	cookie, err := c.Cookie(loginStateCookie)
+	if err != nil {
+		problem.Abort(c, http.StatusBadRequest, problem.CodeLoginFailed, "Login session expired, start again")
+		return
+	}
+	o.clearCookie(c, loginStateCookie)
+
+	var state loginState
+	if err := o.sessions.Decode(cookie, &state); err != nil || c.Query("state") != state.State {
+		problem.Abort(c, http.StatusBadRequest, problem.CodeLoginFailed, "Invalid login state")
+		return
+	}
+
+	if errParam := c.Query("error"); errParam != "" {
+		problem.Abort(c, http.StatusUnauthorized, problem.CodeLoginFailed, "Login failed: "+errParam)
+		return
+	}
+
+	token, err := o.oauth2.Exchange(ctx, c.Query("code"), oauth2.VerifierOption(state.Verifier))
+	if err != nil {
+		o.logger.Warn("oidc code exchange failed", zap.Error(err))
+		problem.Abort(c, http.StatusUnauthorized, problem.CodeLoginFailed, "Login failed")
+		return
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok {
+		problem.Abort(c, http.StatusUnauthorized, problem.CodeLoginFailed, "Login failed: no id_token in response")
+		return
+	}
+
+	idToken, err := o.verifier.Verify(ctx, rawIDToken)
+	if err != nil || idToken.Nonce != state.Nonce {
+		o.logger.Warn("oidc id token rejected", zap.Error(err))
+		problem.Abort(c, http.StatusUnauthorized, problem.CodeLoginFailed, "Login failed: invalid id_token")
+		return
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		problem.Abort(c, http.StatusUnauthorized, problem.CodeLoginFailed, "Login failed: unreadable claims")
+		return
+	}
+
+	principal := o.principalFromClaims(idToken.Subject, claims)
+	if len(principal.Scopes) == 0 {
+		o.logger.Info("oidc login denied, no mapped groups", zap.String("subject", principal.ID))
+		problem.Abort(c, http.StatusForbidden, problem.CodeForbidden, "Your account is not in any group that grants access")
+		return
+	}
+
+	sess := session{ID: randomString(), Principal: *principal}
+	if err := o.cfg.SessionStore.Create(ctx, sess.ID, principal.ID, o.cfg.SessionTTL); err != nil {
+		o.logger.Error("failed to store session", zap.Error(err))
+		problem.Internal(c, "Failed to create session")
+		return
+	}
+	value, err := o.sessions.Encode(sess, o.cfg.SessionTTL)
+	if err != nil {
+		o.logger.Error("failed to encode session", zap.Error(err))
+		problem.Internal(c, "Failed to create session")
+		return
+	}
+	o.setCookie(c, SessionCookie, value, o.cfg.SessionTTL)
+
+	o.logger.Info("oidc login", zap.String("subject", principal.ID), zap.String("name", principal.Name), zap.Strings("scopes", principal.Scopes))
+
+	returnTo := state.ReturnTo
+	if returnTo == "" {
+		returnTo = o.cfg.PostLoginURL
+	}
+	c.Redirect(http.StatusFound, returnTo)
+}
+
+// Logout revokes the session so that a copy of the cookie stops working too.
+// It is guarded like other cookie-authenticated writes, so that a cross-site
+// form cannot log users out.
+func (o *OIDC) Logout(c *gin.Context) {
+	if !requireCSRFHeader(c) {
+		return
+	}
+	o.clearCookie(c, SessionCookie)
+
+	if sess := o.decodeSession(c.Request); sess != nil {
+		if err := o.cfg.SessionStore.Revoke(c.Request.Context(), sess.ID); err != nil {
+			o.logger.Error("failed to revoke session", zap.String("subject", sess.Principal.ID), zap.Error(err))
+			problem.Internal(c, "Failed to end session")
+			return
+		}
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (o *OIDC) Me(c *gin.Context) {
+	principal := o.SessionPrincipal(c.Request)
+	if principal == nil {
+		problem.Abort(c, http.StatusUnauthorized, problem.CodeUnauthorized, "Not logged in")
+		return
+	}
+	c.JSON(http.StatusOK, principal)
+}
+
+// SessionPrincipal returns the user of a valid, unrevoked session cookie, or
+// nil. Sessions are refused while the session store is unreachable.
+func (o *OIDC) SessionPrincipal(r *http.Request) *Principal {
+	sess := o.decodeSession(r)
+	if sess == nil {
+		return nil
+	}
+
+	live, err := o.cfg.SessionStore.Exists(r.Context(), sess.ID)
+	if err != nil {
+		o.logger.Warn("failed to check session", zap.Error(err))
+		return nil
+	}
+	if !live {
+		return nil
+	}
+	return &sess.Principal
+}
+
+func (o *OIDC) decodeSession(r *http.Request) *session {
+	cookie, err := r.Cookie(SessionCookie)
+	if err != nil {
+		return nil
+	}
+
+	var sess session
+	if err := o.sessions.Decode(cookie.Value, &sess); err != nil || sess.ID == "" {
+		return nil
+	}
+	return &sess
+}
+
+func (o *OIDC) principalFromClaims(subject string, claims map[string]interface{}) *Principal {
+	name, _ := claims["name"].(string)
+	if name == "" {
+		name, _ = claims["email"].(string)
+	}
+	if name == "" {
+		name, _ = claims["preferred_username"].(string)
+	}
+
+	granted := make(map[string]bool)
+	for _, group := range claimStrings(claims, o.cfg.GroupsClaim) {
+		for _, scope := range o.cfg.RoleMapping[group] {
+			granted[scope] = true
+		}
+	}
+
+	scopes := make([]string, 0, len(granted))
+	for scope := range granted {
+		scopes = append(scopes, scope)
+	}
+	sort.Strings(scopes)
+
+	return &Principal{Type: PrincipalTypeUser, ID: subject, Name: name, Scopes: scopes}
+}
+
+// claimStrings reads a string or list-of-strings claim at a dotted path.
+func claimStrings(claims map[string]interface{}, path string) []string {
+	var current interface{} = claims
+	for _, part := range strings.Split(path, ".") {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		current = m[part]
+	}
+
+	switch v := current.(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+func (o *OIDC) setCookie(c *gin.Context, name, value string, ttl time.Duration) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   int(ttl.Seconds()),
+		HttpOnly: true,
+		Secure:   o.cfg.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (o *OIDC) clearCookie(c *gin.Context, name string) {
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     name,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   o.cfg.SecureCookies,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// safeReturnTo only allows local paths so the login flow cannot be used as
+// an open redirect.
+func safeReturnTo(returnTo string) string {
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.Contains(returnTo, "\\") {
+		return ""
+	}
+	return returnTo
+}
+
+func randomString() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(fmt.Sprintf("crypto/rand failed: %v", err))
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,256 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/your-username/alldownloads/internal/auth/oidctest"
+	"github.com/your-username/alldownloads/internal/ratelimit"
+)
+
+type oidcFixture struct {
+	provider *oidctest.Provider
+	redis    *miniredis.Miniredis
+	app      *httptest.Server
+	client   *http.Client
+}
+
+func newOIDCFixture(t *testing.T) *oidcFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	provider, err := oidctest.NewProvider("alldl", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(provider.Close)
+
+	router := gin.New()
+	app := httptest.NewServer(router)
+	t.Cleanup(app.Close)
+
+	roles, err := ParseRoleMapping("alldl-admins=admin, alldl-ops=refresh")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	redisServer := miniredis.RunT(t)
+	sessionStore, err := NewRedisSessionStore("redis://" + redisServer.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sessionStore.Close() })
+
+	login, err := NewOIDC(context.Background(), OIDCConfig{
+		IssuerURL:     provider.Issuer(),
+		ClientID:      provider.ClientID,
+		ClientSecret:  provider.ClientSecret,
+		RedirectURL:   app.URL + "/api/auth/callback",
+		RoleMapping:   roles,
+		SessionSecret: "test-session-secret",
+		SessionTTL:    time.Hour,
+		SessionStore:  sessionStore,
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	middleware := NewAuthMiddleware(nil, "", ratelimit.NewMemoryLimiter(), zap.NewNop())
+	middleware.UseSessions(login)
+
+	router.GET("/api/auth/login", login.Login)
+	router.GET("/api/auth/callback", login.Callback)
+	router.POST("/api/auth/logout", login.Logout)
+	router.GET("/api/auth/me", login.Me)
+	router.GET("/done", func(c *gin.Context) { c.String(http.StatusOK, "done") })
+
+	ok := func(c *gin.Context) { c.String(http.StatusOK, GetPrincipal(c).Name) }
+	router.GET("/api/admin/thing", middleware.RequireScope(ScopeAdmin), ok)
+	router.POST("/api/admin/thing", middleware.RequireScope(ScopeAdmin), ok)
+	router.POST("/api/refresh", middleware.RequireScope(ScopeRefresh), ok)
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &oidcFixture{provider: provider, redis: redisServer, app: app, client: &http.Client{Jar: jar}}
+}
+
+func (f *oidcFixture) do(t *testing.T, method, path string, headers map[string]string) *http.Response {
+	t.Helper()
+	req, err := http.NewRequest(method, f.app.URL+path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp
+}
+
+func TestOIDCLoginGrantsMappedScopes(t *testing.T) {
+	f := newOIDCFixture(t)
+	f.provider.SetClaims(map[string]interface{}{
+		"sub":    "user-1",
+		"name":   "Ada Admin",
+		"groups": []string{"alldl-admins", "unrelated"},
+	})
+
+	resp := f.do(t, http.MethodGet, "/api/auth/login?return_to=/done", nil)
+	if resp.StatusCode != http.StatusOK || resp.Request.URL.Path != "/done" {
+		t.Fatalf("login ended at %s with %d, want /done with 200", resp.Request.URL.Path, resp.StatusCode)
+	}
+
+	if resp := f.do(t, http.MethodGet, "/api/auth/me", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("me returned %d, want 200", resp.StatusCode)
+	}
+	if resp := f.do(t, http.MethodGet, "/api/admin/thing", nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("admin GET returned %d, want 200", resp.StatusCode)
+	}
+
+	// Cookie-authenticated writes need the CSRF header.
+	if resp := f.do(t, http.MethodPost, "/api/admin/thing", nil); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("admin POST without X-Requested-With returned %d, want 403", resp.StatusCode)
+	}
+	if resp := f.do(t, http.MethodPost, "/api/admin/thing", map[string]string{"X-Requested-With": "fetch"}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("admin POST returned %d, want 200", resp.StatusCode)
+	}
+
+	f.do(t, http.MethodPost, "/api/auth/logout", map[string]string{"X-Requested-With": "fetch"})
+	if resp := f.do(t, http.MethodGet, "/api/admin/thing", nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("admin GET after logout returned %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestOIDCLogoutRevokesCopiedCookie(t *testing.T) {
+	f := newOIDCFixture(t)
+	f.provider.SetClaims(map[string]interface{}{"sub": "user-1", "groups": []string{"alldl-admins"}})
+
+	f.do(t, http.MethodGet, "/api/auth/login?return_to=/done", nil)
+	appURL, _ := url.Parse(f.app.URL)
+	cookies := f.client.Jar.Cookies(appURL)
+	if len(cookies) != 1 || cookies[0].Name != SessionCookie {
+		t.Fatalf("cookies after login = %v, want the session cookie", cookies)
+	}
+	copied := map[string]string{"Cookie": cookies[0].String()}
+
+	// A second client replaying the cookie is logged in until the session
+	// is ended from anywhere.
+	other := &oidcFixture{app: f.app, client: &http.Client{}}
+	if resp := other.do(t, http.MethodGet, "/api/auth/me", copied); resp.StatusCode != http.StatusOK {
+		t.Fatalf("me with the copied cookie returned %d, want 200", resp.StatusCode)
+	}
+
+	// A cross-site form cannot send the CSRF header, so it cannot log out.
+	if resp := other.do(t, http.MethodPost, "/api/auth/logout", copied); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("logout without X-Requested-With returned %d, want 403", resp.StatusCode)
+	}
+	if resp := other.do(t, http.MethodGet, "/api/auth/me", copied); resp.StatusCode != http.StatusOK {
+		t.Fatalf("me after a refused logout returned %d, want 200", resp.StatusCode)
+	}
+
+	f.do(t, http.MethodPost, "/api/auth/logout", map[string]string{"X-Requested-With": "fetch"})
+	if resp := other.do(t, http.MethodGet, "/api/auth/me", copied); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("me with the copied cookie after logout returned %d, want 401", resp.StatusCode)
+	}
+	if resp := other.do(t, http.MethodGet, "/api/admin/thing", copied); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("admin GET with the copied cookie after logout returned %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestOIDCSessionsNeedTheStore(t *testing.T) {
+	f := newOIDCFixture(t)
+	f.provider.SetClaims(map[string]interface{}{"sub": "user-1", "groups": []string{"alldl-admins"}})
+	f.do(t, http.MethodGet, "/api/auth/login?return_to=/done", nil)
+
+	keys := f.redis.Keys()
+	if len(keys) != 1 || !strings.HasPrefix(keys[0], sessionKeyPrefix) {
+		t.Fatalf("Redis keys after login = %v, want one session", keys)
+	}
+	if ttl := f.redis.TTL(keys[0]); ttl != time.Hour {
+		t.Fatalf("session TTL = %v, want the session TTL", ttl)
+	}
+	if got, _ := f.redis.Get(keys[0]); got != "user-1" {
+		t.Fatalf("session subject = %q, want user-1", got)
+	}
+
+	// Sessions are refused rather than trusted while Redis is down.
+	f.redis.Close()
+	if resp := f.do(t, http.MethodGet, "/api/auth/me", nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("me with Redis down returned %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestOIDCLoginLimitsScopesToGroups(t *testing.T) {
+	f := newOIDCFixture(t)
+	f.provider.SetClaims(map[string]interface{}{"sub": "user-2", "groups": []string{"alldl-ops"}})
+
+	f.do(t, http.MethodGet, "/api/auth/login?return_to=/done", nil)
+
+	if resp := f.do(t, http.MethodPost, "/api/refresh", map[string]string{"X-Requested-With": "fetch"}); resp.StatusCode != http.StatusOK {
+		t.Fatalf("refresh returned %d, want 200", resp.StatusCode)
+	}
+	if resp := f.do(t, http.MethodGet, "/api/admin/thing", nil); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("admin GET returned %d, want 403", resp.StatusCode)
+	}
+}
+
+func TestOIDCLoginRejectsUnmappedUsers(t *testing.T) {
+	f := newOIDCFixture(t)
+	f.provider.SetClaims(map[string]interface{}{"sub": "user-3", "groups": []string{"everyone"}})
+
+	resp := f.do(t, http.MethodGet, "/api/auth/login", nil)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("callback returned %d, want 403", resp.StatusCode)
+	}
+	if resp := f.do(t, http.MethodGet, "/api/auth/me", nil); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("me returned %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestOIDCCallbackRejectsForgedState(t *testing.T) {
+	f := newOIDCFixture(t)
+
+	resp := f.do(t, http.MethodGet, "/api/auth/callback?code=x&state=forged", nil)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("callback returned %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestClaimStringsNestedPath(t *testing.T) {
+	claims := map[string]interface{}{
+		"realm_access": map[string]interface{}{"roles": []interface{}{"a", "b"}},
+	}
+	if got := claimStrings(claims, "realm_access.roles"); strings.Join(got, ",") != "a,b" {
+		t.Fatalf("claimStrings = %v, want [a b]", got)
+	}
+}
+
+func TestSafeReturnTo(t *testing.T) {
+	for input, want := range map[string]string{
+		"/admin":               "/admin",
+		"//evil.example":       "",
+		"https://evil.example": "",
+		"/\\evil.example":      "",
+	} {
+		if got := safeReturnTo(input); got != want {
+			t.Errorf("safeReturnTo(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
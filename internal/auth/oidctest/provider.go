@@ -0,0 +1,215 @@
+// Package oidctest runs an in-process OpenID Connect provider for tests.
+// It implements discovery, JWKS, an authorize endpoint that approves every
+// request immediately and a token endpoint that checks PKCE.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const keyID = "oidctest"
+
+type Provider struct {
+	Server       *httptest.Server
+	ClientID     string
+	ClientSecret string
+
+	key *rsa.PrivateKey
+
+	mu     sync.Mutex
+	claims map[string]interface{}
+	codes  map[string]authRequest
+}
+
+type authRequest struct {
+	nonce       string
+	challenge   string
+	redirectURI string
+}
+
+func NewProvider(clientID, clientSecret string) (*Provider, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Provider{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		key:          key,
+		claims:       map[string]interface{}{"sub": "user-1"},
+		codes:        make(map[string]authRequest),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/authorize", p.authorize)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/keys", p.keys)
+	p.Server = httptest.NewServer(mux)
+
+	return p, nil
+}
+
+func (p *Provider) Close() {
+	p.Server.Close()
+}
+
+func (p *Provider) Issuer() string {
+	return p.Server.URL
+}
+
+// SetClaims sets the claims of the next ID tokens issued. iss, aud, iat,
+// exp and nonce are filled in by the provider.
+func (p *Provider) SetClaims(claims map[string]interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.claims = claims
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.Issuer(),
+		"authorization_endpoint":                p.Issuer() + "/authorize",
+		"token_endpoint":                        p.Issuer() + "/token",
+		"jwks_uri":                              p.Issuer() + "/keys",
+		"response_types_supported":              []string{"code"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("client_id") != p.ClientID || q.Get("response_type") != "code" || q.Get("code_challenge_method") != "S256" {
+		http.Error(w, "invalid authorization request", http.StatusBadRequest)
+		return
+	}
+
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil {
+		http.Error(w, "invalid redirect_uri", http.StatusBadRequest)
+		return
+	}
+
+	code := randomHex()
+	p.mu.Lock()
+	p.codes[code] = authRequest{nonce: q.Get("nonce"), challenge: q.Get("code_challenge"), redirectURI: q.Get("redirect_uri")}
+	p.mu.Unlock()
+
+	params := redirect.Query()
+	params.Set("code", code)
+	params.Set("state", q.Get("state"))
+	redirect.RawQuery = params.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != p.ClientID || clientSecret != p.ClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	p.mu.Lock()
+	req, found := p.codes[r.PostForm.Get("code")]
+	delete(p.codes, r.PostForm.Get("code"))
+	claims := make(map[string]interface{}, len(p.claims))
+	for k, v := range p.claims {
+		claims[k] = v
+	}
+	p.mu.Unlock()
+
+	verifierHash := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !found || req.redirectURI != r.PostForm.Get("redirect_uri") ||
+		base64.RawURLEncoding.EncodeToString(verifierHash[:]) != req.challenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	now := time.Now()
+	claims["iss"] = p.Issuer()
+	claims["aud"] = p.ClientID
+	claims["iat"] = now.Unix()
+	claims["exp"] = now.Add(time.Hour).Unix()
+	claims["nonce"] = req.nonce
+
+	idToken, err := p.sign(claims)
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "server_error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": randomHex(),
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     idToken,
+	})
+}
+
+func (p *Provider) keys(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": keyID,
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *Provider) sign(claims map[string]interface{}) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "kid": keyID, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func randomHex() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
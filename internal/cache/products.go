@@ -0,0 +1,184 @@
+// Package cache is a read-through cache in Redis for product reads. Writers
+// (the worker after a fetch, the admin API after an edit) invalidate the
+// affected product and publish the change on UpdatesChannel.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+	"github.com/your-username/alldownloads/internal/store"
+	"go.uber.org/zap"
+)
+
+const (
+	productKeyPrefix    = "cache:product:"
+	generationKeyPrefix = "cache:product-generation:"
+
+	// generationTTL keeps a product's generation far longer than any read
+	// that could still be holding an older one.
+	generationTTL = 24 * time.Hour
+
+	// UpdatesChannel carries a ProductUpdate for every invalidation.
+	UpdatesChannel = "alldl:product-updates"
+
+	ReasonFetch     = "fetch"
+	ReasonAdmin     = "admin"
+	ReasonFreshness = "freshness"
+)
+
+var cacheRequestsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "cache_requests_total",
+		Help: "Total number of cache lookups by result (hit, miss, error)",
+	},
+	[]string{"cache", "result"},
+)
+
+func init() {
+	prometheus.MustRegister(cacheRequestsTotal)
+}
+
+// ProductUpdate is published when a product's data changes.
+type ProductUpdate struct {
+	ProductID string    `json:"product_id"`
+	Reason    string    `json:"reason"`
+	At        time.Time `json:"at"`
+}
+
+// CachedProduct is a product response together with the validator it was
+// built from, so cache hits can answer conditional requests without
+// touching Postgres.
+type CachedProduct struct {
+	Product   *store.ProductWithVersions `json:"product"`
+	Validator store.Validator            `json:"validator"`
+}
+
+// setIfCurrent stores a product only if its generation is still the one
+// the caller read before loading it, so that an entry built from data read
+// before an invalidation cannot replace the invalidation.
+var setIfCurrent = redis.NewScript(`
+local generation = redis.call('GET', KEYS[2]) or '0'
+if generation ~= ARGV[1] then
+    return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+// ProductCache methods are safe to call on a nil *ProductCache, which
+// behaves as an always-empty cache. That keeps callers free of checks when
+// caching is disabled.
+type ProductCache struct {
+	client *redis.Client
+	ttl    time.Duration
+	logger *zap.Logger
+}
+
+func NewProductCache(redisURL string, ttl time.Duration, logger *zap.Logger) (*ProductCache, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+
+	return &ProductCache{
+		client: redis.NewClient(opts),
+		ttl:    ttl,
+		logger: logger,
+	}, nil
+}
+
+func (c *ProductCache) Close() error {
+	if c == nil {
+		return nil
+	}
+	return c.client.Close()
+}
+
+// Get returns the cached product, or nil on a miss. Redis errors count as
+// misses so that reads fall through to Postgres. It also returns the
+// product's generation, which a caller that loads the product after a miss
+// passes to Set.
+func (c *ProductCache) Get(ctx context.Context, productID string) (*CachedProduct, string) {
+	if c == nil {
+		return nil, ""
+	}
+
+	values, err := c.client.MGet(ctx, productKeyPrefix+productID, generationKeyPrefix+productID).Result()
+	if err != nil {
+		cacheRequestsTotal.WithLabelValues("product", "error").Inc()
+		c.logger.Warn("product cache read failed", zap.Error(err), zap.String("product_id", productID))
+		return nil, ""
+	}
+
+	generation := "0"
+	if value, ok := values[1].(string); ok {
+		generation = value
+	}
+
+	data, ok := values[0].(string)
+	if !ok {
+		cacheRequestsTotal.WithLabelValues("product", "miss").Inc()
+		return nil, generation
+	}
+
+	var cached CachedProduct
+	if err := json.Unmarshal([]byte(data), &cached); err != nil || cached.Product == nil {
+		cacheRequestsTotal.WithLabelValues("product", "error").Inc()
+		return nil, generation
+	}
+
+	cacheRequestsTotal.WithLabelValues("product", "hit").Inc()
+	return &cached, generation
+}
+
+// Set stores a product loaded after Get returned generation. It does
+// nothing when the product has been invalidated since, as the data may
+// predate the change, or when generation is empty because Get failed.
+func (c *ProductCache) Set(ctx context.Context, productID, generation string, cached *CachedProduct) {
+	if c == nil || generation == "" {
+		return
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		c.logger.Error("failed to encode cached product", zap.Error(err), zap.String("product_id", productID))
+		return
+	}
+
+	keys := []string{productKeyPrefix + productID, generationKeyPrefix + productID}
+	if err := setIfCurrent.Run(ctx, c.client, keys, generation, data, c.ttl.Milliseconds()).Err(); err != nil {
+		c.logger.Warn("product cache write failed", zap.Error(err), zap.String("product_id", productID))
+	}
+}
+
+// Invalidate drops the cached product, moves it to a new generation so that
+// reads already in flight do not store it again, and announces the change.
+// A failure leaves the entry to expire after the TTL.
+func (c *ProductCache) Invalidate(ctx context.Context, productID, reason string) {
+	if c == nil {
+		return
+	}
+
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Incr(ctx, generationKeyPrefix+productID)
+		pipe.Expire(ctx, generationKeyPrefix+productID, generationTTL)
+		pipe.Del(ctx, productKeyPrefix+productID)
+		return nil
+	})
+	if err != nil {
+		c.logger.Error("failed to invalidate cached product", zap.Error(err), zap.String("product_id", productID))
+	}
+
+	message, err := json.Marshal(ProductUpdate{ProductID: productID, Reason: reason, At: time.Now().UTC()})
+	if err != nil {
+		return
+	}
+	if err := c.client.Publish(ctx, UpdatesChannel, message).Err(); err != nil {
+		c.logger.Warn("failed to publish product update", zap.Error(err), zap.String("product_id", productID))
+	}
+}
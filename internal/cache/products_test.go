@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"go.uber.org/zap"
+
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+func newTestCache(t *testing.T) (*ProductCache, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	c, err := NewProductCache("redis://"+server.Addr(), time.Minute, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c, server
+}
+
+func cachedProduct(name string) *CachedProduct {
+	return &CachedProduct{
+		Product:   &store.ProductWithVersions{Product: store.Product{ID: "ubuntu", Name: name}},
+		Validator: store.Validator{Rows: 1, LastModified: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+}
+
+func TestProductCacheReadThrough(t *testing.T) {
+	c, server := newTestCache(t)
+	ctx := context.Background()
+
+	cached, generation := c.Get(ctx, "ubuntu")
+	if cached != nil || generation == "" {
+		t.Fatalf("Get of an empty cache = %v, %q, want a miss with a generation", cached, generation)
+	}
+
+	c.Set(ctx, "ubuntu", generation, cachedProduct("Ubuntu"))
+	cached, _ = c.Get(ctx, "ubuntu")
+	if cached == nil || cached.Product.Product.Name != "Ubuntu" || cached.Validator.Rows != 1 {
+		t.Fatalf("Get after Set = %+v", cached)
+	}
+	if ttl := server.TTL(productKeyPrefix + "ubuntu"); ttl != time.Minute {
+		t.Fatalf("entry TTL = %v, want the cache TTL", ttl)
+	}
+
+	c.Invalidate(ctx, "ubuntu", ReasonAdmin)
+	if cached, _ := c.Get(ctx, "ubuntu"); cached != nil {
+		t.Fatalf("Get after Invalidate = %+v, want a miss", cached)
+	}
+}
+
+func TestProductCacheSetAfterInvalidate(t *testing.T) {
+	c, _ := newTestCache(t)
+	ctx := context.Background()
+
+	// A reader misses and loads the product while a fetch finishes and
+	// invalidates it. Its write must not restore what it read.
+	_, generation := c.Get(ctx, "ubuntu")
+	c.Invalidate(ctx, "ubuntu", ReasonFetch)
+	c.Set(ctx, "ubuntu", generation, cachedProduct("Stale"))
+	if cached, _ := c.Get(ctx, "ubuntu"); cached != nil {
+		t.Fatalf("Get = %+v, want the stale write to be dropped", cached)
+	}
+
+	// The next reader starts from the new generation and may store.
+	cached, generation := c.Get(ctx, "ubuntu")
+	if cached != nil {
+		t.Fatalf("Get = %+v, want a miss", cached)
+	}
+	c.Set(ctx, "ubuntu", generation, cachedProduct("Fresh"))
+	if cached, _ := c.Get(ctx, "ubuntu"); cached == nil || cached.Product.Product.Name != "Fresh" {
+		t.Fatalf("Get = %+v, want the fresh entry", cached)
+	}
+
+	// Generations are per product.
+	_, other := c.Get(ctx, "debian")
+	c.Invalidate(ctx, "ubuntu", ReasonFetch)
+	c.Set(ctx, "debian", other, cachedProduct("Debian"))
+	if cached, _ := c.Get(ctx, "debian"); cached == nil {
+		t.Fatal("invalidating one product dropped another's write")
+	}
+}
+
+func TestProductCacheInvalidatePublishes(t *testing.T) {
+	c, server := newTestCache(t)
+	ctx := context.Background()
+
+	pubsub := c.client.Subscribe(ctx, UpdatesChannel)
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	c.Invalidate(ctx, "ubuntu", ReasonFreshness)
+	message, err := pubsub.ReceiveMessage(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var update ProductUpdate
+	if err := json.Unmarshal([]byte(message.Payload), &update); err != nil || update.ProductID != "ubuntu" || update.Reason != ReasonFreshness {
+		t.Fatalf("update = %+v, %v", update, err)
+	}
+	if ttl := server.TTL(generationKeyPrefix + "ubuntu"); ttl != generationTTL {
+		t.Fatalf("generation TTL = %v, want %v", ttl, generationTTL)
+	}
+}
+
+func TestProductCacheUnavailable(t *testing.T) {
+	c, server := newTestCache(t)
+	ctx := context.Background()
+	server.Close()
+
+	cached, generation := c.Get(ctx, "ubuntu")
+	if cached != nil || generation != "" {
+		t.Fatalf("Get with Redis down = %v, %q, want a miss without a generation", cached, generation)
+	}
+	c.Set(ctx, "ubuntu", generation, cachedProduct("Ubuntu"))
+	c.Invalidate(ctx, "ubuntu", ReasonAdmin)
+
+	var nilCache *ProductCache
+	if cached, _ := nilCache.Get(ctx, "ubuntu"); cached != nil {
+		t.Fatal("nil cache returned a product")
+	}
+	nilCache.Set(ctx, "ubuntu", "0", cachedProduct("Ubuntu"))
+	nilCache.Invalidate(ctx, "ubuntu", ReasonAdmin)
+}
@@ -0,0 +1,164 @@
+// Package events carries job and version events from the worker to API
+// replicas. Every event is appended to a capped Redis stream, which gives it
+// an ID and lets reconnecting clients catch up, and published on a pub/sub
+// channel for live delivery.
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	StreamKey = "alldl:events"
+	Channel   = "alldl:events:live"
+
+	// streamLength bounds how far back a reconnecting client can resume.
+	streamLength = 1000
+
+	JobQueued    = "job.queued"
+	JobRunning   = "job.running"
+	JobCompleted = "job.completed"
+	JobFailed    = "job.failed"
+	VersionNew   = "version.new"
+	ProductStale = "product.stale"
+	ProductFresh = "product.fresh"
+)
+
+// Event fields other than ID, Type and At are set where they apply: job
+// events carry JobID, version events the version's coordinates, and
+// job.completed the number of fetched and newly found versions. The
+// product events mark a product crossing its freshness SLO.
+type Event struct {
+	// ID is the Redis stream ID, assigned when the event is published.
+	ID           string    `json:"id"`
+	Type         string    `json:"type"`
+	At           time.Time `json:"at"`
+	JobID        string    `json:"job_id,omitempty"`
+	ProductID    string    `json:"product_id,omitempty"`
+	Version      string    `json:"version,omitempty"`
+	Platform     string    `json:"platform,omitempty"`
+	Architecture string    `json:"architecture,omitempty"`
+	Versions     int       `json:"versions,omitempty"`
+	NewVersions  int       `json:"new_versions,omitempty"`
+	Error        string    `json:"error,omitempty"`
+}
+
+// publish appends to the stream and publishes in one step, so live
+// subscribers never see an event that a replay would not return.
+var publish = redis.NewScript(`
+local id = redis.call('XADD', KEYS[1], 'MAXLEN', '~', ARGV[1], '*', 'event', ARGV[2])
+redis.call('PUBLISH', KEYS[2], id .. ' ' .. ARGV[2])
+return id
+`)
+
+// Bus methods are safe to call on a nil *Bus, which drops every event.
+type Bus struct {
+	client *redis.Client
+	logger *zap.Logger
+}
+
+func NewBus(redisURL string, logger *zap.Logger) (*Bus, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+
+	return &Bus{client: redis.NewClient(opts), logger: logger}, nil
+}
+
+func (b *Bus) Close() error {
+	if b == nil {
+		return nil
+	}
+	return b.client.Close()
+}
+
+// Publish emits an event. Events are advisory, so failures are logged and
+// never interrupt the job that produced them.
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	if b == nil {
+		return
+	}
+	if event.At.IsZero() {
+		event.At = time.Now().UTC()
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		b.logger.Error("failed to encode event", zap.Error(err), zap.String("type", event.Type))
+		return
+	}
+
+	if err := publish.Run(ctx, b.client, []string{StreamKey, Channel}, streamLength, payload).Err(); err != nil {
+		b.logger.Warn("failed to publish event", zap.Error(err), zap.String("type", event.Type))
+	}
+}
+
+// Since returns the retained events after lastID, oldest first.
+func (b *Bus) Since(ctx context.Context, lastID string) ([]Event, error) {
+	if b == nil {
+		return nil, nil
+	}
+
+	messages, err := b.client.XRange(ctx, StreamKey, "("+lastID, "+").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event stream: %w", err)
+	}
+
+	events := make([]Event, 0, len(messages))
+	for _, message := range messages {
+		payload, _ := message.Values["event"].(string)
+		event, err := decode(message.ID, payload)
+		if err != nil {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+func decode(id, payload string) (Event, error) {
+	var event Event
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		return Event{}, err
+	}
+	event.ID = id
+	return event, nil
+}
+
+// ValidID reports whether id looks like a Redis stream ID ("ms-seq").
+func ValidID(id string) bool {
+	_, _, ok := parseID(id)
+	return ok
+}
+
+// After reports whether stream ID a comes after b.
+func After(a, b string) bool {
+	aMs, aSeq, _ := parseID(a)
+	bMs, bSeq, _ := parseID(b)
+	return aMs > bMs || (aMs == bMs && aSeq > bSeq)
+}
+
+func parseID(id string) (uint64, uint64, bool) {
+	msPart, seqPart, ok := strings.Cut(id, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	ms, err := strconv.ParseUint(msPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	seq, err := strconv.ParseUint(seqPart, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	return ms, seq, true
+}
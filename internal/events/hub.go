@@ -0,0 +1,113 @@
+package events
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const subscriberBuffer = 64
+
+// Hub holds the one pub/sub subscription of an API replica and fans events
+// out to its SSE clients.
+type Hub struct {
+	bus    *Bus
+	logger *zap.Logger
+
+	mu          sync.Mutex
+	subscribers map[chan Event]struct{}
+}
+
+func NewHub(bus *Bus, logger *zap.Logger) *Hub {
+	return &Hub{
+		bus:         bus,
+		logger:      logger,
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Run relays events until ctx is cancelled. Once subscribed, go-redis
+// reconnects and resubscribes by itself after connection errors; Run only
+// retries when the initial subscription fails.
+func (h *Hub) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		h.relay(ctx)
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+func (h *Hub) relay(ctx context.Context) {
+	pubsub := h.bus.client.Subscribe(ctx, Channel)
+	defer pubsub.Close()
+
+	if _, err := pubsub.Receive(ctx); err != nil {
+		if ctx.Err() == nil {
+			h.logger.Warn("failed to subscribe to events", zap.Error(err))
+		}
+		return
+	}
+
+	messages := pubsub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case message, ok := <-messages:
+			if !ok {
+				return
+			}
+			id, payload, ok := strings.Cut(message.Payload, " ")
+			if !ok {
+				continue
+			}
+			event, err := decode(id, payload)
+			if err != nil {
+				continue
+			}
+			h.broadcast(event)
+		}
+	}
+}
+
+func (h *Hub) broadcast(event Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// A client that cannot keep up is cut off; it reconnects with
+			// Last-Event-ID and catches up from the stream.
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe returns a channel of live events. It is closed when the client
+// falls behind or after Unsubscribe.
+func (h *Hub) Subscribe() chan Event {
+	ch := make(chan Event, subscriberBuffer)
+	h.mu.Lock()
+	h.subscribers[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *Hub) Unsubscribe(ch chan Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
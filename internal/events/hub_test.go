@@ -0,0 +1,54 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"go.uber.org/zap"
+)
+
+func TestHubRelaysUntilCancelled(t *testing.T) {
+	server := miniredis.RunT(t)
+	bus, err := NewBus("redis://"+server.Addr(), zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+
+	hub := NewHub(bus, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		hub.Run(ctx)
+		close(done)
+	}()
+
+	events := hub.Subscribe()
+	defer hub.Unsubscribe(events)
+
+	// Publish until the hub's subscription is in place.
+	deadline := time.After(5 * time.Second)
+	for received := false; !received; {
+		bus.Publish(context.Background(), Event{Type: JobQueued, JobID: "job-1"})
+		select {
+		case event := <-events:
+			if event.Type != JobQueued || event.JobID != "job-1" || !ValidID(event.ID) {
+				t.Fatalf("relayed event = %+v", event)
+			}
+			received = true
+		case <-time.After(50 * time.Millisecond):
+		case <-deadline:
+			t.Fatal("no event was relayed")
+		}
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Run did not return after its context was cancelled")
+	}
+}
@@ -0,0 +1,52 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+// DefaultStuckAfter is how long a job may run before it is presumed to have
+// been abandoned by a worker that died, far beyond the slowest fetch.
+const DefaultStuckAfter = 30 * time.Minute
+
+// FindDeadJobs returns the jobs nothing is going to run any more: a
+// product's newest job when it failed and no retry is queued, or when it
+// has been running for longer than stuckAfter.
+func FindDeadJobs(ctx context.Context, st store.Store, queue *Queue, stuckAfter time.Duration) ([]store.FetchJob, error) {
+	candidates, err := st.ListDeadFetchJobs(ctx, time.Now().Add(-stuckAfter))
+	if err != nil {
+		return nil, err
+	}
+
+	queued, err := queue.QueuedJobIDs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	dead := []store.FetchJob{}
+	for _, job := range candidates {
+		if !queued[job.ID] {
+			dead = append(dead, job)
+		}
+	}
+	return dead, nil
+}
+
+// RequeueJob resets job to pending and queues it again.
+func RequeueJob(ctx context.Context, st store.Store, queue *Queue, job *store.FetchJob) error {
+	job.Status = store.JobStatusPending
+	job.StartedAt = nil
+	job.CompletedAt = nil
+	job.Error = ""
+	if err := st.UpdateFetchJob(ctx, job); err != nil {
+		return err
+	}
+
+	if err := queue.Requeue(ctx, job.ID); err != nil {
+		return fmt.Errorf("failed to requeue job %s: %w", job.ID, err)
+	}
+	return nil
+}
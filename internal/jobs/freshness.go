@@ -0,0 +1,169 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/your-username/alldownloads/internal/alerts"
+	"github.com/your-username/alldownloads/internal/cache"
+	"github.com/your-username/alldownloads/internal/events"
+	"github.com/your-username/alldownloads/internal/store"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultMaxAge is the freshness SLO of products without one of their
+	// own: several missed runs of the default six-hourly schedule.
+	DefaultMaxAge = 24 * time.Hour
+
+	freshnessCheckInterval = time.Minute
+	alertTimeout           = 30 * time.Second
+)
+
+// errNoVersions fails fetches that parse an unexpected page, such as an
+// upstream error or rate-limit response, into nothing. The manual fetcher
+// never returns versions and is exempt.
+var errNoVersions = errors.New("fetcher returned no versions")
+
+var productStale = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "fetch_product_stale",
+		Help: "1 when a product's last successful fetch is older than its freshness SLO",
+	},
+	[]string{"product_id"},
+)
+
+// SetFreshness sets the SLO for products without their own max age, and
+// where alerts about products crossing it are sent. notifier may be nil.
+func (w *Worker) SetFreshness(defaultMaxAge time.Duration, notifier alerts.Notifier) {
+	w.defaultMaxAge = defaultMaxAge
+	w.notifier = notifier
+}
+
+func (w *Worker) maxAge(p *store.Product) time.Duration {
+	if p.MaxAgeSeconds > 0 {
+		return time.Duration(p.MaxAgeSeconds) * time.Second
+	}
+	return w.defaultMaxAge
+}
+
+// freshnessLoop evaluates the freshness SLO until ctx is cancelled.
+func (w *Worker) freshnessLoop(ctx context.Context) {
+	ticker := time.NewTicker(freshnessCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		w.checkFreshness(ctx, time.Now())
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// checkFreshness marks products stale whose last successful fetch, or
+// creation if they never had one, is older than their max age. Manually
+// maintained products are never fetched and so are skipped. Products
+// become fresh again when a fetch succeeds, in recordSuccess.
+func (w *Worker) checkFreshness(ctx context.Context, now time.Time) {
+	products, err := w.store.GetProducts(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			w.logger.Warn("failed to load products for freshness check", zap.Error(err))
+		}
+		return
+	}
+
+	for i := range products {
+		p := &products[i]
+		if !p.Scheduled() {
+			productStale.DeleteLabelValues(p.ID)
+			continue
+		}
+		if p.Stale {
+			productStale.WithLabelValues(p.ID).Set(1)
+			continue
+		}
+
+		reference := p.CreatedAt
+		if p.LastSuccessAt != nil {
+			reference = *p.LastSuccessAt
+		}
+		staleSince := reference.Add(w.maxAge(p))
+		if !now.After(staleSince) {
+			productStale.WithLabelValues(p.ID).Set(0)
+			continue
+		}
+
+		// Only the worker whose update flips the flag raises the alert.
+		marked, err := w.store.MarkProductStale(ctx, p.ID, staleSince)
+		if err != nil {
+			w.logger.Warn("failed to mark product stale", zap.Error(err), zap.String("product_id", p.ID))
+			continue
+		}
+		productStale.WithLabelValues(p.ID).Set(1)
+		if !marked {
+			continue
+		}
+
+		w.logger.Warn("product is stale", zap.String("product_id", p.ID), zap.Timep("last_success_at", p.LastSuccessAt))
+		w.cache.Invalidate(ctx, p.ID, cache.ReasonFreshness)
+		w.events.Publish(ctx, events.Event{Type: events.ProductStale, ProductID: p.ID})
+		w.alert(alerts.Alert{
+			Kind:          alerts.KindStale,
+			ProductID:     p.ID,
+			ProductName:   p.Name,
+			MaxAgeSeconds: int64(w.maxAge(p).Seconds()),
+			LastSuccessAt: p.LastSuccessAt,
+			StaleSince:    &staleSince,
+			At:            now,
+		})
+	}
+}
+
+// recordSuccess notes a successful fetch of p, alerting when it ends a
+// stale period.
+func (w *Worker) recordSuccess(ctx context.Context, p *store.Product, at time.Time) {
+	wasStale, err := w.store.RecordFetchSuccess(ctx, p.ID, at)
+	if err != nil {
+		w.logger.Error("failed to record fetch success", zap.Error(err), zap.String("product_id", p.ID))
+		return
+	}
+	productStale.WithLabelValues(p.ID).Set(0)
+	if !wasStale {
+		return
+	}
+
+	w.logger.Info("product is fresh again", zap.String("product_id", p.ID))
+	w.events.Publish(ctx, events.Event{Type: events.ProductFresh, ProductID: p.ID})
+	w.alert(alerts.Alert{
+		Kind:          alerts.KindRecovered,
+		ProductID:     p.ID,
+		ProductName:   p.Name,
+		MaxAgeSeconds: int64(w.maxAge(p).Seconds()),
+		LastSuccessAt: &at,
+		StaleSince:    p.StaleSince,
+		At:            at,
+	})
+}
+
+// alert notifies in the background, so that a slow destination does not
+// hold up fetches.
+func (w *Worker) alert(alert alerts.Alert) {
+	if w.notifier == nil {
+		return
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+		defer cancel()
+
+		if err := w.notifier.Notify(ctx, alert); err != nil {
+			w.logger.Error("failed to send alert", zap.Error(err), zap.String("kind", alert.Kind), zap.String("product_id", alert.ProductID))
+		}
+	}()
+}
@@ -0,0 +1,127 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/your-username/alldownloads/internal/sources"
+	"github.com/your-username/alldownloads/internal/store"
+	"go.uber.org/zap"
+)
+
+// queueSampleInterval is how often the queue gauges are refreshed.
+const queueSampleInterval = 15 * time.Second
+
+var (
+	fetchDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "fetch_duration_seconds",
+			Help:    "Duration of product fetches by result (success, failure)",
+			Buckets: []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120},
+		},
+		[]string{"product_id", "result"},
+	)
+
+	// fetchJobsTotal keeps the name and label of the series the API used
+	// to define; the per-product breakdown is fetchResultsTotal.
+	fetchJobsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "fetch_jobs_total",
+			Help: "Total number of fetch jobs",
+		},
+		[]string{"status"},
+	)
+
+	fetchResultsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "fetch_results_total",
+			Help: "Fetches per product by result (success, failure)",
+		},
+		[]string{"product_id", "result"},
+	)
+
+	fetchVersionsDiscovered = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "fetch_versions_discovered_total",
+			Help: "Versions seen for the first time by fetches",
+		},
+		[]string{"product_id"},
+	)
+
+	fetchLastSuccess = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "fetch_last_success_timestamp_seconds",
+			Help: "Unix time of the last successful fetch; subtract from time() for its age",
+		},
+		[]string{"product_id"},
+	)
+
+	queuePending = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "fetch_queue_pending",
+			Help: "Fetch jobs waiting in the queue",
+		},
+	)
+
+	queueProcessing = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "fetch_queue_processing",
+			Help: "Fetch jobs taken from the queue and not yet finished",
+		},
+	)
+)
+
+// RegisterMetrics registers the worker's collectors with reg. Only the
+// worker calls it, so that the API does not expose idle worker series.
+func RegisterMetrics(reg prometheus.Registerer) {
+	reg.MustRegister(fetchDuration, fetchJobsTotal, fetchResultsTotal, fetchVersionsDiscovered, fetchLastSuccess, queuePending, queueProcessing, productStale)
+	reg.MustRegister(sources.Collectors()...)
+}
+
+func observeFetch(productID string, start time.Time, err error) {
+	result, status := "success", store.JobStatusCompleted
+	if err != nil {
+		result, status = "failure", store.JobStatusFailed
+	}
+	fetchDuration.WithLabelValues(productID, result).Observe(time.Since(start).Seconds())
+	fetchJobsTotal.WithLabelValues(status).Inc()
+	fetchResultsTotal.WithLabelValues(productID, result).Inc()
+	if err == nil {
+		fetchLastSuccess.WithLabelValues(productID).Set(float64(time.Now().Unix()))
+	}
+}
+
+// loadLastSuccess seeds the last-success gauges from the job history, so
+// that a restarted worker does not report every product as never fetched.
+func (w *Worker) loadLastSuccess(ctx context.Context) {
+	fetches, err := w.store.GetLastSuccessfulFetches(ctx)
+	if err != nil {
+		w.logger.Warn("failed to load last successful fetches", zap.Error(err))
+		return
+	}
+	for productID, at := range fetches {
+		fetchLastSuccess.WithLabelValues(productID).Set(float64(at.Unix()))
+	}
+}
+
+// sampleQueue refreshes the queue gauges until ctx is cancelled.
+func (w *Worker) sampleQueue(ctx context.Context) {
+	ticker := time.NewTicker(queueSampleInterval)
+	defer ticker.Stop()
+
+	for {
+		if pending, err := w.queue.GetQueueLength(ctx); err == nil {
+			queuePending.Set(float64(pending))
+		}
+		if processing, err := w.queue.GetProcessingCount(ctx); err == nil {
+			queueProcessing.Set(float64(processing))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -4,17 +4,20 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/redis/go-redis/v9"
+	"github.com/your-username/alldownloads/internal/tracing"
 	"go.uber.org/zap"
 )
 
 const (
-	QueueName      = "fetch_jobs"
-	ProcessingSet  = "fetch_jobs:processing"
-	RetryLimit     = 3
-	RetryDelay     = 5 * time.Minute
+	QueueName     = "fetch_jobs"
+	ProcessingSet = "fetch_jobs:processing"
+	HeartbeatSet  = "workers:heartbeats"
+	RetryLimit    = 3
+	RetryDelay    = 5 * time.Minute
 )
 
 type Queue struct {
@@ -26,6 +29,9 @@ type JobMessage struct {
 	ID        string    `json:"id"`
 	Retries   int       `json:"retries"`
 	CreatedAt time.Time `json:"created_at"`
+	// TraceContext links the worker's spans to the request or schedule
+	// that enqueued the job. Retries keep the original trace.
+	TraceContext map[string]string `json:"trace_context,omitempty"`
 }
 
 func NewQueue(redisURL string, logger *zap.Logger) (*Queue, error) {
@@ -53,12 +59,13 @@ func (q *Queue) Close() error {
 	return q.client.Close()
 }
 
-func (q *Queue) Enqueue(ctx context.Context, jobID string) error {
-	message := JobMessage{
-		ID:        jobID,
-		Retries:   0,
-		CreatedAt: time.Now(),
-	}
+func (q *Queue) Ping(ctx context.Context) error {
+	return q.client.Ping(ctx).Err()
+}
+
+func (q *Queue) Enqueue(ctx context.Context, jobID string) (err error) {
+	message, span := newJobMessage(ctx, jobID)
+	defer func() { tracing.End(span, err) }()
 
 	data, err := json.Marshal(message)
 	if err != nil {
@@ -132,10 +139,76 @@ func (q *Queue) RetryJob(ctx context.Context, message *JobMessage) error {
 	return nil
 }
 
+// Requeue puts jobID back on the queue with a fresh retry count, taking it
+// out of the processing set if a worker that died left it there.
+func (q *Queue) Requeue(ctx context.Context, jobID string) error {
+	if err := q.client.SRem(ctx, ProcessingSet, jobID).Err(); err != nil {
+		return fmt.Errorf("failed to remove job from processing set: %w", err)
+	}
+	return q.Enqueue(ctx, jobID)
+}
+
+// QueuedJobIDs returns the IDs of the jobs waiting in the queue.
+func (q *Queue) QueuedJobIDs(ctx context.Context) (map[string]bool, error) {
+	entries, err := q.client.LRange(ctx, QueueName, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list queued jobs: %w", err)
+	}
+
+	ids := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		var message JobMessage
+		if err := json.Unmarshal([]byte(entry), &message); err != nil {
+			q.logger.Warn("skipping unreadable queue entry", zap.Error(err))
+			continue
+		}
+		ids[message.ID] = true
+	}
+	return ids, nil
+}
+
 func (q *Queue) GetQueueLength(ctx context.Context) (int64, error) {
 	return q.client.LLen(ctx, QueueName).Result()
 }
 
 func (q *Queue) GetProcessingCount(ctx context.Context) (int64, error) {
 	return q.client.SCard(ctx, ProcessingSet).Result()
-}
\ No newline at end of file
+}
+
+// Heartbeat records that workerID is alive. Entries not refreshed for an
+// hour are pruned so that departed workers do not accumulate.
+func (q *Queue) Heartbeat(ctx context.Context, workerID string) error {
+	now := time.Now()
+
+	pipe := q.client.TxPipeline()
+	pipe.ZAdd(ctx, HeartbeatSet, redis.Z{Score: float64(now.Unix()), Member: workerID})
+	pipe.ZRemRangeByScore(ctx, HeartbeatSet, "-inf", strconv.FormatInt(now.Add(-time.Hour).Unix(), 10))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to record heartbeat: %w", err)
+	}
+	return nil
+}
+
+// RemoveHeartbeat forgets workerID, e.g. when it shuts down cleanly.
+func (q *Queue) RemoveHeartbeat(ctx context.Context, workerID string) error {
+	if err := q.client.ZRem(ctx, HeartbeatSet, workerID).Err(); err != nil {
+		return fmt.Errorf("failed to remove heartbeat: %w", err)
+	}
+	return nil
+}
+
+// GetHeartbeats returns the last heartbeat of each known worker.
+func (q *Queue) GetHeartbeats(ctx context.Context) (map[string]time.Time, error) {
+	entries, err := q.client.ZRangeWithScores(ctx, HeartbeatSet, 0, -1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get heartbeats: %w", err)
+	}
+
+	heartbeats := make(map[string]time.Time, len(entries))
+	for _, entry := range entries {
+		if member, ok := entry.Member.(string); ok {
+			heartbeats[member] = time.Unix(int64(entry.Score), 0)
+		}
+	}
+	return heartbeats, nil
+}
@@ -0,0 +1,47 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/your-username/alldownloads/internal/tracing"
+)
+
+var messagingSystem = semconv.MessagingSystemKey.String("redis")
+
+// newJobMessage starts the producer span of a job and returns the message
+// that carries its trace context to the worker. The caller ends the span.
+func newJobMessage(ctx context.Context, jobID string) (JobMessage, trace.Span) {
+	ctx, span := tracing.Tracer().Start(ctx, "publish "+QueueName,
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(
+			messagingSystem,
+			semconv.MessagingDestinationName(QueueName),
+			semconv.MessagingMessageID(jobID),
+		),
+	)
+
+	return JobMessage{
+		ID:           jobID,
+		Retries:      0,
+		CreatedAt:    time.Now(),
+		TraceContext: tracing.Inject(ctx),
+	}, span
+}
+
+// startProcessSpan continues the trace of whoever enqueued message.
+func startProcessSpan(ctx context.Context, message *JobMessage) (context.Context, trace.Span) {
+	return tracing.Tracer().Start(tracing.Extract(ctx, message.TraceContext), "process "+QueueName,
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(
+			messagingSystem,
+			semconv.MessagingDestinationName(QueueName),
+			semconv.MessagingMessageID(message.ID),
+			attribute.Int("job.retries", message.Retries),
+		),
+	)
+}
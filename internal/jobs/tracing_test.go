@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/your-username/alldownloads/internal/tracing"
+	"github.com/your-username/alldownloads/internal/tracing/tracingtest"
+)
+
+func TestJobMessageCarriesTrace(t *testing.T) {
+	exporter := tracingtest.NewRecorder(t)
+
+	ctx, request := tracing.Tracer().Start(context.Background(), "POST /api/v1/refresh")
+	message, publish := newJobMessage(ctx, "job-1")
+	publish.End()
+	request.End()
+
+	// The message crosses Redis as JSON.
+	data, err := json.Marshal(message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var received JobMessage
+	if err := json.Unmarshal(data, &received); err != nil {
+		t.Fatal(err)
+	}
+
+	_, process := startProcessSpan(context.Background(), &received)
+	process.End()
+
+	publishSpan, ok := tracingtest.Find(exporter, "publish "+QueueName)
+	if !ok {
+		t.Fatal("no publish span recorded")
+	}
+	processSpan, ok := tracingtest.Find(exporter, "process "+QueueName)
+	if !ok {
+		t.Fatal("no process span recorded")
+	}
+
+	if publishSpan.SpanKind != trace.SpanKindProducer || processSpan.SpanKind != trace.SpanKindConsumer {
+		t.Fatalf("span kinds are %v and %v", publishSpan.SpanKind, processSpan.SpanKind)
+	}
+	if got, want := processSpan.SpanContext.TraceID(), request.SpanContext().TraceID(); got != want {
+		t.Fatalf("worker span is in trace %s, want the request's %s", got, want)
+	}
+	if got, want := processSpan.Parent.SpanID(), publishSpan.SpanContext.SpanID(); got != want {
+		t.Fatalf("worker span's parent is %s, want the publish span %s", got, want)
+	}
+	if !processSpan.Parent.IsRemote() {
+		t.Fatal("worker span's parent is not marked remote")
+	}
+}
+
+func TestJobMessageWithoutTrace(t *testing.T) {
+	tracingtest.NewRecorder(t)
+
+	var message JobMessage
+	if err := json.Unmarshal([]byte(`{"id":"job-1","retries":0}`), &message); err != nil {
+		t.Fatal(err)
+	}
+
+	_, span := startProcessSpan(context.Background(), &message)
+	defer span.End()
+	if !span.SpanContext().IsValid() {
+		t.Fatal("messages enqueued before tracing should start a new trace")
+	}
+}
@@ -3,56 +3,78 @@ package jobs
 import (
 	"context"
 	"fmt"
+	"os"
 	"sync"
 	"time"
 
+	"github.com/your-username/alldownloads/internal/alerts"
+	"github.com/your-username/alldownloads/internal/cache"
+	"github.com/your-username/alldownloads/internal/events"
 	"github.com/your-username/alldownloads/internal/sources"
 	"github.com/your-username/alldownloads/internal/store"
+	"github.com/your-username/alldownloads/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/zap"
 )
 
+// HeartbeatInterval is how often a running worker records a heartbeat.
+const HeartbeatInterval = 15 * time.Second
+
 type Worker struct {
-	store      *store.PostgresStore
+	id         string
+	store      store.Store
 	queue      *Queue
+	cache      *cache.ProductCache
+	events     *events.Bus
 	fetchers   map[string]sources.Fetcher
 	logger     *zap.Logger
 	maxWorkers int
+
+	defaultMaxAge time.Duration
+	notifier      alerts.Notifier
 }
 
-func NewWorker(store *store.PostgresStore, queue *Queue, logger *zap.Logger, maxWorkers int) *Worker {
-	fetchers := map[string]sources.Fetcher{
-		"ubuntu":             sources.NewUbuntuFetcher(),
-		"debian":             sources.NewDebianFetcher(),
-		"arch":               sources.NewArchFetcher(),
-		"kali":               sources.NewKaliFetcher(),
-		"windows":            sources.NewWindowsFetcher(),
-		"termius":            sources.NewTermiusFetcher(),
-		"telegram":           sources.NewTelegramFetcher(),
-		"whatsapp":           sources.NewWhatsAppFetcher(),
-		"tailscale":          sources.NewTailscaleFetcher(),
-		"nextcloud":          sources.NewNextcloudFetcher(),
-		"chrome":             sources.NewChromeFetcher(),
-		"firefox":            sources.NewFirefoxFetcher(),
-		"brave":              sources.NewBraveFetcher(),
-		"vscode":             sources.NewVSCodeFetcher(),
-		"notepadplusplus":    sources.NewNotepadPlusPlusFetcher(),
-		"powershell":         sources.NewPowerShellFetcher(),
-	}
+// NewWorker creates a worker pool. productCache may be nil when response
+// caching is disabled, and bus nil when no events should be emitted.
+func NewWorker(store store.Store, queue *Queue, productCache *cache.ProductCache, bus *events.Bus, logger *zap.Logger, maxWorkers int) *Worker {
+	hostname, _ := os.Hostname()
 
 	return &Worker{
+		id:         fmt.Sprintf("%s-%d", hostname, os.Getpid()),
 		store:      store,
 		queue:      queue,
-		fetchers:   fetchers,
+		cache:      productCache,
+		events:     bus,
+		fetchers:   sources.NewFetchers(),
 		logger:     logger,
 		maxWorkers: maxWorkers,
+
+		defaultMaxAge: DefaultMaxAge,
 	}
 }
 
 func (w *Worker) Start(ctx context.Context) error {
-	w.logger.Info("starting worker", zap.Int("max_workers", w.maxWorkers))
+	w.logger.Info("starting worker", zap.String("id", w.id), zap.Int("max_workers", w.maxWorkers))
 
 	var wg sync.WaitGroup
 
+	w.loadLastSuccess(ctx)
+
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		w.heartbeatLoop(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		w.sampleQueue(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		w.freshnessLoop(ctx)
+	}()
+
 	for i := 0; i < w.maxWorkers; i++ {
 		wg.Add(1)
 		go func(workerID int) {
@@ -66,6 +88,30 @@ func (w *Worker) Start(ctx context.Context) error {
 	return nil
 }
 
+// heartbeatLoop tells the API's readiness check that this worker is alive
+// until ctx is cancelled, and withdraws the heartbeat on the way out.
+func (w *Worker) heartbeatLoop(ctx context.Context) {
+	ticker := time.NewTicker(HeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := w.queue.Heartbeat(ctx, w.id); err != nil && ctx.Err() == nil {
+			w.logger.Warn("failed to record heartbeat", zap.Error(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			removeCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := w.queue.RemoveHeartbeat(removeCtx, w.id); err != nil {
+				w.logger.Warn("failed to remove heartbeat", zap.Error(err))
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
 func (w *Worker) workerLoop(ctx context.Context, workerID int) {
 	logger := w.logger.With(zap.Int("worker_id", workerID))
 	logger.Info("worker started")
@@ -94,22 +140,28 @@ func (w *Worker) workerLoop(ctx context.Context, workerID int) {
 				if retryErr := w.queue.RetryJob(ctx, message); retryErr != nil {
 					logger.Error("failed to retry job", zap.Error(retryErr), zap.String("job_id", message.ID))
 				}
-
-				// Note: metrics would be updated here
 			} else {
 				logger.Info("job completed successfully", zap.String("job_id", message.ID))
 
 				if err := w.queue.MarkCompleted(ctx, message.ID); err != nil {
 					logger.Error("failed to mark job as completed", zap.Error(err), zap.String("job_id", message.ID))
 				}
-
-				// Note: metrics would be updated here
 			}
 		}
 	}
 }
 
-func (w *Worker) processJob(ctx context.Context, message *JobMessage) error {
+func (w *Worker) processJob(ctx context.Context, message *JobMessage) (err error) {
+	ctx, span := startProcessSpan(ctx, message)
+	defer func() { tracing.End(span, err) }()
+
+	var productID string
+	defer func() {
+		if err != nil {
+			w.events.Publish(ctx, events.Event{Type: events.JobFailed, JobID: message.ID, ProductID: productID, Error: err.Error()})
+		}
+	}()
+
 	job := &store.FetchJob{
 		ID:        message.ID,
 		Status:    store.JobStatusRunning,
@@ -133,13 +185,27 @@ func (w *Worker) processJob(ctx context.Context, message *JobMessage) error {
 	if product == nil {
 		return fmt.Errorf("product not found: %s", jobFromDB.ProductID)
 	}
+	productID = product.ID
+	span.SetAttributes(attribute.String("product.id", product.ID))
+
+	start := time.Now()
+	defer func() {
+		observeFetch(productID, start, err)
+	}()
 
-	fetcher, exists := w.fetchers[product.ID]
+	w.events.Publish(ctx, events.Event{Type: events.JobRunning, JobID: message.ID, ProductID: product.ID})
+
+	fetcherName := product.Fetcher
+	if fetcherName == "" {
+		fetcherName = product.ID
+	}
+
+	fetcher, exists := w.fetchers[fetcherName]
 	if !exists {
-		return fmt.Errorf("no fetcher available for product: %s", product.ID)
+		return fmt.Errorf("no fetcher %q available for product: %s", fetcherName, product.ID)
 	}
 
-	versions, err := fetcher.Fetch(ctx)
+	versions, err := w.fetch(ctx, fetcher, fetcherName, product.ID)
 	if err != nil {
 		job.Status = store.JobStatusFailed
 		job.Error = err.Error()
@@ -153,28 +219,73 @@ func (w *Worker) processJob(ctx context.Context, message *JobMessage) error {
 		return fmt.Errorf("fetcher failed: %w", err)
 	}
 
+	newVersions := 0
 	for _, version := range versions {
 		version.ProductID = product.ID
-		if err := w.store.CreateOrUpdateProductVersion(ctx, version); err != nil {
+		inserted, err := w.store.CreateOrUpdateProductVersion(ctx, version)
+		if err != nil {
 			w.logger.Error("failed to save product version", zap.Error(err), zap.String("version", version.Version))
+			continue
+		}
+		if inserted {
+			newVersions++
+			w.events.Publish(ctx, events.Event{
+				Type:         events.VersionNew,
+				JobID:        message.ID,
+				ProductID:    product.ID,
+				Version:      version.Version,
+				Platform:     version.Platform,
+				Architecture: version.Architecture,
+			})
 		}
 	}
 
+	fetchVersionsDiscovered.WithLabelValues(product.ID).Add(float64(newVersions))
+
 	if err := w.store.MarkLatestVersions(ctx, product.ID); err != nil {
 		w.logger.Error("failed to mark latest versions", zap.Error(err), zap.String("product_id", product.ID))
 	}
 
-	job.Status = store.JobStatusCompleted
 	completedAt := time.Now()
+	w.recordSuccess(ctx, product, completedAt)
+
+	w.cache.Invalidate(ctx, product.ID, cache.ReasonFetch)
+
+	job.Status = store.JobStatusCompleted
 	job.CompletedAt = &completedAt
 
 	if err := w.store.UpdateFetchJob(ctx, job); err != nil {
 		return fmt.Errorf("failed to update job status to completed: %w", err)
 	}
 
-	// Note: metrics would be updated here
+	w.events.Publish(ctx, events.Event{
+		Type:        events.JobCompleted,
+		JobID:       message.ID,
+		ProductID:   product.ID,
+		Versions:    len(versions),
+		NewVersions: newVersions,
+	})
 
-	w.logger.Info("product updated", zap.String("product_id", product.ID), zap.Int("versions", len(versions)))
+	w.logger.Info("product updated", zap.String("product_id", product.ID), zap.Int("versions", len(versions)), zap.String("trace_id", tracing.TraceID(ctx)))
 
 	return nil
-}
\ No newline at end of file
+}
+
+// fetch runs fetcher in a span of its own, so that its upstream requests
+// are grouped apart from the job's database writes.
+func (w *Worker) fetch(ctx context.Context, fetcher sources.Fetcher, fetcherName, productID string) (versions []*store.ProductVersion, err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "fetch "+fetcherName, trace.WithAttributes(
+		attribute.String("fetcher", fetcherName),
+		attribute.String("product.id", productID),
+	))
+	defer func() {
+		span.SetAttributes(attribute.Int("fetch.versions", len(versions)))
+		tracing.End(span, err)
+	}()
+
+	versions, err = fetcher.Fetch(sources.WithProductID(ctx, productID))
+	if err == nil && len(versions) == 0 && fetcherName != store.FetcherManual {
+		err = errNoVersions
+	}
+	return versions, err
+}
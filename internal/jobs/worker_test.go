@@ -0,0 +1,189 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
+
+	"github.com/your-username/alldownloads/internal/alerts"
+	"github.com/your-username/alldownloads/internal/sources"
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+// emptyFetcher parses nothing, as a fetcher does when the vendor returns an
+// error page.
+type emptyFetcher struct{}
+
+func (emptyFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, error) {
+	return nil, nil
+}
+
+func newTestWorker(t *testing.T) (*Worker, store.Store) {
+	t.Helper()
+	st, err := store.NewSQLiteStore(filepath.Join(t.TempDir(), "alldownloads.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(st.Close)
+
+	return &Worker{
+		store: st,
+		fetchers: map[string]sources.Fetcher{
+			store.FetcherManual: sources.NewManualFetcher(),
+			"empty":             emptyFetcher{},
+		},
+		logger:        zap.NewNop(),
+		defaultMaxAge: DefaultMaxAge,
+	}, st
+}
+
+func runJob(t *testing.T, w *Worker, st store.Store, productID string) (*store.FetchJob, error) {
+	t.Helper()
+	ctx := context.Background()
+	job := &store.FetchJob{ProductID: productID, Status: store.JobStatusPending}
+	if err := st.CreateFetchJob(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	err := w.processJob(ctx, &JobMessage{ID: job.ID, CreatedAt: time.Now()})
+	stored, getErr := st.GetFetchJob(ctx, job.ID)
+	if getErr != nil {
+		t.Fatal(getErr)
+	}
+	return stored, err
+}
+
+func TestManualProductsAreNotFetchedOrHeldToTheSLO(t *testing.T) {
+	w, st := newTestWorker(t)
+	ctx := context.Background()
+
+	manual := &store.Product{ID: "handmade", Name: "Handmade", Vendor: "Test", Category: store.CategoryApp, Fetcher: store.FetcherManual}
+	scraped := &store.Product{ID: "scraped", Name: "Scraped", Vendor: "Test", Category: store.CategoryApp, Fetcher: "empty"}
+	for _, p := range []*store.Product{manual, scraped} {
+		if err := st.CreateProduct(ctx, p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if manual.Scheduled() || !scraped.Scheduled() {
+		t.Fatal("Scheduled does not tell manual products apart")
+	}
+
+	// A job queued for a manual product by hand completes without versions.
+	job, err := runJob(t, w, st, manual.ID)
+	if err != nil || job.Status != store.JobStatusCompleted {
+		t.Fatalf("manual product job = %s, %v, want completed", job.Status, err)
+	}
+
+	// Any other fetcher that comes back empty has failed.
+	job, err = runJob(t, w, st, scraped.ID)
+	if !errors.Is(err, errNoVersions) || job.Status != store.JobStatusFailed {
+		t.Fatalf("empty fetch = %s, %v, want failed with errNoVersions", job.Status, err)
+	}
+
+	w.checkFreshness(ctx, time.Now().Add(2*DefaultMaxAge))
+
+	if p, _ := st.GetProduct(ctx, manual.ID); p.Stale {
+		t.Fatal("manual product was marked stale")
+	}
+	if p, _ := st.GetProduct(ctx, scraped.ID); !p.Stale {
+		t.Fatal("product that never fetched was not marked stale")
+	}
+}
+
+// recordingNotifier hands alerts to the test, which the worker sends from a
+// goroutine of their own.
+type recordingNotifier chan alerts.Alert
+
+func (n recordingNotifier) Notify(ctx context.Context, alert alerts.Alert) error {
+	n <- alert
+	return nil
+}
+
+func (n recordingNotifier) next(t *testing.T) alerts.Alert {
+	t.Helper()
+	select {
+	case alert := <-n:
+		return alert
+	case <-time.After(5 * time.Second):
+		t.Fatal("no alert was sent")
+		return alerts.Alert{}
+	}
+}
+
+func (n recordingNotifier) none(t *testing.T) {
+	t.Helper()
+	select {
+	case alert := <-n:
+		t.Fatalf("unexpected alert %+v", alert)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestFreshnessTransitions(t *testing.T) {
+	w, st := newTestWorker(t)
+	notifier := make(recordingNotifier, 10)
+	w.SetFreshness(DefaultMaxAge, notifier)
+	ctx := context.Background()
+
+	p := &store.Product{ID: "flaky", Name: "Flaky", Vendor: "Test", Category: store.CategoryApp, Fetcher: "empty", MaxAgeSeconds: 3600}
+	if err := st.CreateProduct(ctx, p); err != nil {
+		t.Fatal(err)
+	}
+	gauge := func() float64 { return testutil.ToFloat64(productStale.WithLabelValues(p.ID)) }
+	stored := func() *store.Product {
+		t.Helper()
+		got, err := st.GetProduct(ctx, p.ID)
+		if err != nil || got == nil {
+			t.Fatalf("GetProduct = %v, %v", got, err)
+		}
+		return got
+	}
+
+	// Within its own max age, a product that never fetched is still fresh.
+	w.checkFreshness(ctx, p.CreatedAt.Add(30*time.Minute))
+	notifier.none(t)
+	if stored().Stale || gauge() != 0 {
+		t.Fatal("product went stale within its max age")
+	}
+
+	now := p.CreatedAt.Add(2 * time.Hour)
+	w.checkFreshness(ctx, now)
+	alert := notifier.next(t)
+	wantSince := p.CreatedAt.Add(time.Hour)
+	if alert.Kind != alerts.KindStale || alert.ProductID != p.ID || alert.ProductName != "Flaky" || alert.MaxAgeSeconds != 3600 ||
+		alert.LastSuccessAt != nil || alert.StaleSince == nil || !alert.StaleSince.Equal(wantSince) || !alert.At.Equal(now) {
+		t.Fatalf("stale alert = %+v", alert)
+	}
+	if got := stored(); !got.Stale || got.StaleSince == nil || gauge() != 1 {
+		t.Fatalf("product after going stale = %+v, gauge %v", got, gauge())
+	}
+
+	// Still stale on the next check, but the alert is not repeated.
+	w.checkFreshness(ctx, now.Add(time.Minute))
+	notifier.none(t)
+
+	succeeded := now.Add(10 * time.Minute)
+	w.recordSuccess(ctx, stored(), succeeded)
+	alert = notifier.next(t)
+	if alert.Kind != alerts.KindRecovered || alert.ProductID != p.ID || alert.LastSuccessAt == nil || !alert.LastSuccessAt.Equal(succeeded) ||
+		alert.StaleSince == nil || !alert.StaleSince.Equal(wantSince) || !alert.At.Equal(succeeded) {
+		t.Fatalf("recovered alert = %+v", alert)
+	}
+	if got := stored(); got.Stale || got.StaleSince != nil || got.LastSuccessAt == nil || gauge() != 0 {
+		t.Fatalf("product after recovering = %+v, gauge %v", got, gauge())
+	}
+
+	// Later successes are not recoveries, and the SLO now runs from the
+	// last success rather than creation.
+	w.recordSuccess(ctx, stored(), succeeded.Add(time.Minute))
+	w.checkFreshness(ctx, succeeded.Add(30*time.Minute))
+	notifier.none(t)
+	w.checkFreshness(ctx, succeeded.Add(2*time.Hour))
+	if alert := notifier.next(t); alert.Kind != alerts.KindStale || alert.LastSuccessAt == nil {
+		t.Fatalf("second stale alert = %+v", alert)
+	}
+}
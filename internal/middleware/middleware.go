@@ -2,20 +2,27 @@ package middleware
 
 import (
 	"net/http"
-	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/your-username/alldownloads/internal/problem"
+	"github.com/your-username/alldownloads/internal/tracing"
 	"go.uber.org/zap"
-	"golang.org/x/time/rate"
 )
 
+// maxRequestIDLength matches the audit log's request_id column.
+const maxRequestIDLength = 100
+
+// RequestID tags the request with the client's X-Request-ID, or a new one
+// when the client sent none or one that is too long or not made of
+// letters, digits and "-_.:". The ID ends up in logs and the audit log, so
+// a client must not be able to choose an arbitrary string.
 func RequestID() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		requestID := c.GetHeader("X-Request-ID")
-		if requestID == "" {
+		if !validRequestID(requestID) {
 			requestID = uuid.New().String()
 		}
 		c.Set("request_id", requestID)
@@ -24,6 +31,33 @@ func RequestID() gin.HandlerFunc {
 	}
 }
 
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// Recovery turns a panicking handler into a 500 problem response.
+func Recovery(logger *zap.Logger) gin.HandlerFunc {
+	return gin.CustomRecovery(func(c *gin.Context, err any) {
+		logger.Error("panic while handling request",
+			zap.Any("panic", err),
+			zap.String("request_id", c.GetString("request_id")),
+			zap.String("path", c.Request.URL.Path),
+		)
+		problem.Internal(c, "Internal server error")
+	})
+}
+
 func Logger(logger *zap.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
@@ -51,6 +85,7 @@ func Logger(logger *zap.Logger) gin.HandlerFunc {
 			zap.Int("status", statusCode),
 			zap.Int("body_size", bodySize),
 			zap.Duration("latency", latency),
+			zap.String("trace_id", tracing.TraceID(c.Request.Context())),
 		)
 	}
 }
@@ -64,19 +99,39 @@ func CORS(origins string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		origin := c.Request.Header.Get("Origin")
 
+		// Only listed origins may send credentials: with a wildcard any site
+		// could make cookie-authenticated calls on the visitor's behalf.
+		allowed, credentials := false, false
 		for _, allowedOrigin := range allowedOrigins {
-			if allowedOrigin == "*" || allowedOrigin == origin {
-				c.Header("Access-Control-Allow-Origin", origin)
+			if allowedOrigin == origin && origin != "" {
+				allowed, credentials = true, true
 				break
 			}
+			if allowedOrigin == "*" {
+				allowed = true
+			}
+		}
+		c.Header("Vary", "Origin")
+		switch {
+		case credentials:
+			c.Header("Access-Control-Allow-Origin", origin)
+			c.Header("Access-Control-Allow-Credentials", "true")
+		case allowed:
+			c.Header("Access-Control-Allow-Origin", "*")
 		}
 
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
-		c.Header("Access-Control-Allow-Credentials", "true")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID, X-Requested-With, traceparent, tracestate")
+		c.Header("Access-Control-Expose-Headers", "ETag, Last-Modified, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After, X-Request-ID")
 		c.Header("Access-Control-Max-Age", "86400")
 
 		if c.Request.Method == "OPTIONS" {
+			// Other requests from a foreign origin are still served; the
+			// browser withholds the response without the allow header.
+			if origin != "" && !allowed {
+				problem.Abort(c, http.StatusForbidden, problem.CodeOriginNotAllowed, "Origin "+origin+" is not allowed")
+				return
+			}
 			c.AbortWithStatus(http.StatusNoContent)
 			return
 		}
@@ -84,45 +139,3 @@ func CORS(origins string) gin.HandlerFunc {
 		c.Next()
 	}
 }
-
-type IPRateLimiter struct {
-	limiters map[string]*rate.Limiter
-	rate     rate.Limit
-	burst    int
-}
-
-func NewIPRateLimiter(requestsPerMinute int) *IPRateLimiter {
-	return &IPRateLimiter{
-		limiters: make(map[string]*rate.Limiter),
-		rate:     rate.Limit(float64(requestsPerMinute) / 60.0),
-		burst:    requestsPerMinute,
-	}
-}
-
-func (rl *IPRateLimiter) getLimiter(ip string) *rate.Limiter {
-	if limiter, exists := rl.limiters[ip]; exists {
-		return limiter
-	}
-
-	limiter := rate.NewLimiter(rl.rate, rl.burst)
-	rl.limiters[ip] = limiter
-	return limiter
-}
-
-func (rl *IPRateLimiter) RateLimit() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		ip := c.ClientIP()
-		limiter := rl.getLimiter(ip)
-
-		if !limiter.Allow() {
-			c.Header("Retry-After", strconv.Itoa(60))
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error": "Rate limit exceeded. Try again later.",
-			})
-			c.Abort()
-			return
-		}
-
-		c.Next()
-	}
-}
\ No newline at end of file
@@ -0,0 +1,109 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		header string
+		kept   bool
+	}{
+		{"missing", "", false},
+		{"uuid", "7d1ee18c-e753-4028-81e2-bb3f83410d50", true},
+		{"proxy style", "req_01HZX.abc:42", true},
+		{"at the limit", strings.Repeat("a", maxRequestIDLength), true},
+		{"too long", strings.Repeat("a", maxRequestIDLength+1), false},
+		{"spaces", "id with spaces", false},
+		{"control characters", "id\x1b[31m", false},
+		{"non-ascii", "idé", false},
+		{"quotes", `id"`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			router := gin.New()
+			router.Use(RequestID())
+			router.GET("/", func(c *gin.Context) { seen = c.GetString("request_id") })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.header != "" {
+				req.Header.Set("X-Request-ID", tt.header)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if got := w.Header().Get("X-Request-ID"); got != seen {
+				t.Fatalf("response X-Request-ID = %q, context has %q", got, seen)
+			}
+			if tt.kept {
+				if seen != tt.header {
+					t.Fatalf("request_id = %q, want the client's %q", seen, tt.header)
+				}
+			} else if _, err := uuid.Parse(seen); err != nil {
+				t.Fatalf("request_id = %q, want a generated UUID", seen)
+			}
+		})
+	}
+}
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name        string
+		origins     string
+		method      string
+		origin      string
+		status      int
+		allowOrigin string
+		credentials string
+	}{
+		{"listed origin", "https://app.example, https://admin.example", http.MethodGet, "https://admin.example", http.StatusNoContent, "https://admin.example", "true"},
+		{"listed origin preflight", "https://admin.example", http.MethodOptions, "https://admin.example", http.StatusNoContent, "https://admin.example", "true"},
+		{"unlisted origin", "https://admin.example", http.MethodGet, "https://evil.example", http.StatusNoContent, "", ""},
+		{"unlisted origin preflight", "https://admin.example", http.MethodOptions, "https://evil.example", http.StatusForbidden, "", ""},
+		{"wildcard never allows credentials", "*", http.MethodOptions, "https://evil.example", http.StatusNoContent, "*", ""},
+		{"listed origin beside a wildcard", "*, https://admin.example", http.MethodGet, "https://admin.example", http.StatusNoContent, "https://admin.example", "true"},
+		{"no origin", "*", http.MethodGet, "", http.StatusNoContent, "*", ""},
+		{"no origin with a list", "https://admin.example", http.MethodGet, "", http.StatusNoContent, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(CORS(tt.origins))
+			router.GET("/", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+			req := httptest.NewRequest(tt.method, "/", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Fatalf("status = %d, want %d", w.Code, tt.status)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.allowOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.allowOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tt.credentials {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, tt.credentials)
+			}
+			if got := w.Header().Get("Vary"); got != "Origin" {
+				t.Errorf("Vary = %q, want Origin", got)
+			}
+		})
+	}
+}
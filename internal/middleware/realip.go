@@ -0,0 +1,143 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ParseTrustedProxies parses a comma-separated list of CIDRs or bare IPs.
+func ParseTrustedProxies(value string) ([]*net.IPNet, error) {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		if !strings.Contains(entry, "/") {
+			ip := net.ParseIP(entry)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", entry)
+			}
+			bits := 128
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, network, err := net.ParseCIDR(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// RealIP resolves the client address of requests that arrive through a
+// trusted proxy and stores it in RemoteAddr, so c.ClientIP() returns the
+// real client everywhere (logging, rate limiting, audit log). headers are
+// consulted in order and the first one present is used; forwarding headers
+// from untrusted peers are ignored, so they cannot be spoofed. The engine
+// must not trust any proxies itself (SetTrustedProxies(nil)).
+func RealIP(trusted []*net.IPNet, headers []string) gin.HandlerFunc {
+	isTrusted := func(ip net.IP) bool {
+		for _, network := range trusted {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(c *gin.Context) {
+		host, port, err := net.SplitHostPort(c.Request.RemoteAddr)
+		if err != nil {
+			host, port = c.Request.RemoteAddr, "0"
+		}
+
+		peer := net.ParseIP(host)
+		if peer == nil || !isTrusted(peer) {
+			c.Next()
+			return
+		}
+
+		for _, header := range headers {
+			chain := forwardedChain(c.Request.Header, header)
+			if len(chain) == 0 {
+				continue
+			}
+
+			// Walk from the nearest hop outwards: the first address not
+			// belonging to a trusted proxy is the client. Anything to its
+			// left was supplied by the client and may be forged.
+			client := chain[0]
+			for i := len(chain) - 1; i >= 0; i-- {
+				if !isTrusted(chain[i]) {
+					client = chain[i]
+					break
+				}
+			}
+
+			c.Request.RemoteAddr = net.JoinHostPort(client.String(), port)
+			break
+		}
+
+		c.Next()
+	}
+}
+
+// forwardedChain returns the addresses recorded in header, client first.
+// Entries that are not IP addresses (such as "unknown" or obfuscated
+// identifiers) end the chain, since nothing beyond them can be trusted.
+func forwardedChain(h http.Header, header string) []net.IP {
+	var values []string
+	switch http.CanonicalHeaderKey(header) {
+	case "Forwarded":
+		for _, line := range h.Values("Forwarded") {
+			for _, element := range strings.Split(line, ",") {
+				for _, pair := range strings.Split(element, ";") {
+					key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+					if ok && strings.EqualFold(key, "for") {
+						values = append(values, value)
+					}
+				}
+			}
+		}
+	case "X-Real-Ip":
+		if value := h.Get(header); value != "" {
+			values = append(values, value)
+		}
+	default:
+		for _, line := range h.Values(header) {
+			values = append(values, strings.Split(line, ",")...)
+		}
+	}
+
+	var chain []net.IP
+	for i := len(values) - 1; i >= 0; i-- {
+		ip := parseForwardedAddr(values[i])
+		if ip == nil {
+			break
+		}
+		chain = append([]net.IP{ip}, chain...)
+	}
+	return chain
+}
+
+// parseForwardedAddr accepts "1.2.3.4", "1.2.3.4:80", "\"[2001:db8::1]:80\""
+// and similar forms.
+func parseForwardedAddr(value string) net.IP {
+	value = strings.Trim(strings.TrimSpace(value), `"`)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+	value = strings.TrimSuffix(strings.TrimPrefix(value, "["), "]")
+	return net.ParseIP(value)
+}
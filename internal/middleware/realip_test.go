@@ -0,0 +1,189 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseForwardedAddr(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"203.0.113.7", "203.0.113.7"},
+		{" 203.0.113.7 ", "203.0.113.7"},
+		{"203.0.113.7:4711", "203.0.113.7"},
+		{`"203.0.113.7:4711"`, "203.0.113.7"},
+		{"2001:db8::1", "2001:db8::1"},
+		{"[2001:db8::1]", "2001:db8::1"},
+		{"[2001:db8::1]:4711", "2001:db8::1"},
+		{`"[2001:db8::1]:4711"`, "2001:db8::1"},
+		{"::ffff:203.0.113.7", "203.0.113.7"},
+		{"unknown", "<nil>"},
+		{"_hidden", "<nil>"},
+		{"", "<nil>"},
+		{`"`, "<nil>"},
+		{"203.0.113", "<nil>"},
+		{"203.0.113.7:", "203.0.113.7"},
+		{"example.com:80", "<nil>"},
+	}
+
+	for _, tt := range tests {
+		if got := parseForwardedAddr(tt.value).String(); got != tt.want {
+			t.Errorf("parseForwardedAddr(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestForwardedChain(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		values []string
+		want   string
+	}{
+		{"x-forwarded-for", "X-Forwarded-For", []string{"203.0.113.7, 10.0.0.1"}, "[203.0.113.7 10.0.0.1]"},
+		{"repeated x-forwarded-for lines", "X-Forwarded-For", []string{"203.0.113.7", "10.0.0.1,10.0.0.2"}, "[203.0.113.7 10.0.0.1 10.0.0.2]"},
+		{"junk ends the chain", "X-Forwarded-For", []string{"203.0.113.7, garbage, 10.0.0.1"}, "[10.0.0.1]"},
+		{"junk at the nearest hop", "X-Forwarded-For", []string{"203.0.113.7, unknown"}, "[]"},
+		{"empty x-forwarded-for entry", "X-Forwarded-For", []string{"203.0.113.7,,10.0.0.1"}, "[10.0.0.1]"},
+		{"forwarded", "Forwarded", []string{`for=203.0.113.7;proto=https, for="[2001:db8::1]:4711";by=10.0.0.1`}, "[203.0.113.7 2001:db8::1]"},
+		{"forwarded key case", "forwarded", []string{`For=203.0.113.7, FOR="10.0.0.1"`}, "[203.0.113.7 10.0.0.1]"},
+		{"forwarded without for", "Forwarded", []string{"proto=https;host=example.com"}, "[]"},
+		{"obfuscated forwarded", "Forwarded", []string{`for=203.0.113.7, for=_gateway`}, "[]"},
+		{"x-real-ip", "X-Real-IP", []string{"203.0.113.7"}, "[203.0.113.7]"},
+		{"x-real-ip takes one address", "X-Real-IP", []string{"203.0.113.7, 10.0.0.1"}, "[]"},
+		{"missing header", "X-Forwarded-For", nil, "[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{}
+			for _, value := range tt.values {
+				h.Add(tt.header, value)
+			}
+			if got := fmt.Sprint(forwardedChain(h, tt.header)); got != tt.want {
+				t.Fatalf("forwardedChain = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRealIP(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	trusted, err := ParseTrustedProxies("10.0.0.0/8, fd00::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		peer    string
+		headers map[string]string
+		want    string
+	}{
+		{
+			name:    "untrusted peer's headers are ignored",
+			peer:    "198.51.100.1:5000",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.7"},
+			want:    "198.51.100.1",
+		},
+		{
+			name:    "trusted proxy",
+			peer:    "10.0.0.1:5000",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.7"},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "spoofed left-most entry",
+			peer:    "10.0.0.1:5000",
+			headers: map[string]string{"X-Forwarded-For": "1.1.1.1, 203.0.113.7, 10.0.0.2"},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "first untrusted hop from the right is the client",
+			peer:    "10.0.0.1:5000",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.7, 198.51.100.9"},
+			want:    "198.51.100.9",
+		},
+		{
+			name:    "every hop trusted",
+			peer:    "10.0.0.1:5000",
+			headers: map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"},
+			want:    "10.0.0.3",
+		},
+		{
+			name:    "ipv6 proxy and client",
+			peer:    "[fd00::1]:5000",
+			headers: map[string]string{"Forwarded": `for="[2001:db8::7]:4711"`},
+			want:    "2001:db8::7",
+		},
+		{
+			name:    "first configured header present wins",
+			peer:    "10.0.0.1:5000",
+			headers: map[string]string{"Forwarded": "for=203.0.113.7", "X-Forwarded-For": "198.51.100.9"},
+			want:    "203.0.113.7",
+		},
+		{
+			name:    "malformed header falls through to the next",
+			peer:    "10.0.0.1:5000",
+			headers: map[string]string{"Forwarded": "for=unknown", "X-Forwarded-For": "198.51.100.9"},
+			want:    "198.51.100.9",
+		},
+		{
+			name:    "nothing usable keeps the peer",
+			peer:    "10.0.0.1:5000",
+			headers: map[string]string{"X-Forwarded-For": "garbage"},
+			want:    "10.0.0.1",
+		},
+		{
+			name:    "peer without a port",
+			peer:    "10.0.0.1",
+			headers: map[string]string{"X-Forwarded-For": "203.0.113.7"},
+			want:    "203.0.113.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var clientIP string
+			router := gin.New()
+			if err := router.SetTrustedProxies(nil); err != nil {
+				t.Fatal(err)
+			}
+			router.Use(RealIP(trusted, []string{"Forwarded", "X-Forwarded-For"}))
+			router.GET("/", func(c *gin.Context) { clientIP = c.ClientIP() })
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.peer
+			for header, value := range tt.headers {
+				req.Header.Set(header, value)
+			}
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if clientIP != tt.want {
+				t.Fatalf("ClientIP = %s, want %s", clientIP, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies(t *testing.T) {
+	networks, err := ParseTrustedProxies(" 10.0.0.0/8, 192.0.2.1 ,, 2001:db8::/32, fd00::1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(networks); got != "[10.0.0.0/8 192.0.2.1/32 2001:db8::/32 fd00::1/128]" {
+		t.Fatalf("ParseTrustedProxies = %s", got)
+	}
+
+	for _, value := range []string{"10.0.0.0/33", "proxy.internal", "10.0.0"} {
+		if _, err := ParseTrustedProxies(value); err == nil {
+			t.Errorf("ParseTrustedProxies(%q) succeeded", value)
+		}
+	}
+}
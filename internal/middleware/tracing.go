@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/your-username/alldownloads/internal/tracing"
+)
+
+// Tracing records a server span for each request, continuing the caller's
+// trace when it sends a traceparent header. Spans are named after the route
+// template so that they group well.
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+
+		name := c.Request.Method
+		if route := c.FullPath(); route != "" {
+			name += " " + route
+		}
+
+		ctx, span := tracing.Tracer().Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(c.FullPath()),
+				semconv.URLPath(c.Request.URL.Path),
+				attribute.String("request.id", c.GetString("request_id")),
+			),
+		)
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/your-username/alldownloads/internal/tracing"
+	"github.com/your-username/alldownloads/internal/tracing/tracingtest"
+)
+
+func TestTracing(t *testing.T) {
+	exporter := tracingtest.NewRecorder(t)
+	gin.SetMode(gin.TestMode)
+
+	var handlerTraceID string
+	router := gin.New()
+	router.Use(Tracing())
+	router.GET("/api/v1/products/:id", func(c *gin.Context) {
+		handlerTraceID = tracing.TraceID(c.Request.Context())
+		c.Status(http.StatusOK)
+	})
+	router.GET("/boom", func(c *gin.Context) {
+		c.Status(http.StatusInternalServerError)
+	})
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/products/ubuntu", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	span, ok := tracingtest.Find(exporter, "GET /api/v1/products/:id")
+	if !ok {
+		t.Fatalf("no span named after the route; got %v", exporter.GetSpans())
+	}
+	if span.SpanKind != trace.SpanKindServer {
+		t.Fatalf("span kind is %v, want server", span.SpanKind)
+	}
+	if span.SpanContext.TraceID().String() != traceID || !span.Parent.IsRemote() {
+		t.Fatal("span does not continue the caller's trace")
+	}
+	if handlerTraceID != traceID {
+		t.Fatalf("handler saw trace %q, want %q", handlerTraceID, traceID)
+	}
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/boom", nil))
+	span, ok = tracingtest.Find(exporter, "GET /boom")
+	if !ok || span.Status.Code != codes.Error {
+		t.Fatal("5xx response did not mark the span as failed")
+	}
+}
@@ -0,0 +1,44 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// legacyBaseline is the version a database created by the old init-db.sh
+// script is taken to be at. The script created the tables and seeded the
+// products, which is migrations 001 and 002, but recorded no version. Every
+// later migration is idempotent, so applying them brings such a database up
+// to date however old the script that created it was.
+const legacyBaseline = 2
+
+// legacyVersion returns legacyBaseline when the schema predates
+// schema_migrations, and 0 for an empty database.
+func (m *Migrator) legacyVersion(ctx context.Context) (int64, error) {
+	var exists bool
+	if err := m.conn.QueryRow(ctx, `SELECT to_regclass('products') IS NOT NULL`).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("failed to inspect schema: %w", err)
+	}
+	if !exists {
+		return 0, nil
+	}
+	return legacyBaseline, nil
+}
+
+// baseline records legacyBaseline for a schema created by init-db.sh, so
+// that Up continues from there rather than failing on tables that exist.
+func (m *Migrator) baseline(ctx context.Context) (int64, error) {
+	version, err := m.legacyVersion(ctx)
+	if err != nil || version == 0 {
+		return version, err
+	}
+
+	if err := m.apply(ctx, "", version); err != nil {
+		return 0, err
+	}
+	m.logger.Warn("schema was created outside the migrations, probably by init-db.sh; continuing from the baseline version",
+		zap.Int64("version", version))
+	return version, nil
+}
@@ -0,0 +1,333 @@
+// Package migrate applies the SQL migrations in migrations/. The schema
+// version is kept in schema_migrations, laid out the way golang-migrate
+// keeps it, so that databases migrated with either tool stay compatible.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+
+	"github.com/your-username/alldownloads/migrations"
+)
+
+// Migration is one numbered pair of up and down scripts.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%03d_%s", m.Version, m.Name)
+}
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Embedded returns the migrations compiled into the binary.
+func Embedded() ([]Migration, error) {
+	return Load(migrations.FS)
+}
+
+// Load reads the migrations in fsys, named like 001_initial_schema.up.sql,
+// sorted by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d is named both %s and %s", version, m.Name, match[2])
+		}
+		if match[3] == "up" {
+			m.Up = string(data)
+		} else {
+			m.Down = string(data)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %s has no up script", m)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+var (
+	// ErrDirty means a migration failed halfway under a tool that does not
+	// run migrations in a transaction. The schema has to be repaired by hand
+	// and the version set with Force.
+	ErrDirty = errors.New("database schema is dirty after a failed migration")
+	// ErrTooNew means the database was migrated by a newer release. Running
+	// against it could corrupt data the older code does not understand.
+	ErrTooNew = errors.New("database schema is newer than this binary")
+	// ErrOutdated means migrations are pending.
+	ErrOutdated = errors.New("database schema is out of date")
+)
+
+// lockKey identifies the advisory lock that serializes migrations, so that
+// an API and a worker starting together do not both apply them.
+const lockKey int64 = 0x616c6c646c6d6967 // "alldlmig"
+
+// Migrator applies migrations over a single connection, which holds the
+// advisory lock while it works.
+type Migrator struct {
+	conn       *pgx.Conn
+	migrations []Migration
+	logger     *zap.Logger
+}
+
+// Open connects to databaseURL and prepares to apply migrations.
+func Open(ctx context.Context, databaseURL string, migrations []Migration, logger *zap.Logger) (*Migrator, error) {
+	conn, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return &Migrator{conn: conn, migrations: migrations, logger: logger}, nil
+}
+
+func (m *Migrator) Close(ctx context.Context) error {
+	return m.conn.Close(ctx)
+}
+
+// Latest returns the newest version this binary knows about.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// withLock runs fn holding the advisory lock, waiting for any other
+// migrator to finish first.
+func (m *Migrator) withLock(ctx context.Context, fn func() error) error {
+	if _, err := m.conn.Exec(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer func() {
+		// Unlock on a fresh context: ctx may be why fn returned.
+		if _, err := m.conn.Exec(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey); err != nil {
+			m.logger.Warn("failed to release migration lock", zap.Error(err))
+		}
+	}()
+
+	if _, err := m.conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (version BIGINT NOT NULL PRIMARY KEY, dirty BOOLEAN NOT NULL)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+	return fn()
+}
+
+// Version returns the applied schema version, 0 if none, and whether the
+// last migration failed halfway.
+func (m *Migrator) Version(ctx context.Context) (int64, bool, error) {
+	var exists bool
+	if err := m.conn.QueryRow(ctx, `SELECT to_regclass('schema_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return 0, false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if !exists {
+		return 0, false, nil
+	}
+
+	var version int64
+	var dirty bool
+	err := m.conn.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err == pgx.ErrNoRows {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, dirty, nil
+}
+
+// Pending returns the migrations newer than the applied version.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	version, err := m.usableVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if version == 0 {
+		if version, err = m.legacyVersion(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return m.after(version), nil
+}
+
+// Check reports whether the schema is exactly what this binary expects,
+// with ErrTooNew, ErrOutdated or ErrDirty when it is not.
+func (m *Migrator) Check(ctx context.Context) error {
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		return err
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("%w: %d migrations pending, up to %s", ErrOutdated, len(pending), pending[len(pending)-1])
+	}
+	return nil
+}
+
+// usableVersion returns the applied version unless the schema is dirty or
+// newer than the binary.
+func (m *Migrator) usableVersion(ctx context.Context) (int64, error) {
+	version, dirty, err := m.Version(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if dirty {
+		return 0, fmt.Errorf("%w at version %d", ErrDirty, version)
+	}
+	if version > m.Latest() {
+		return 0, fmt.Errorf("%w: schema is at version %d, this binary knows up to %d", ErrTooNew, version, m.Latest())
+	}
+	return version, nil
+}
+
+func (m *Migrator) after(version int64) []Migration {
+	var pending []Migration
+	for _, migration := range m.migrations {
+		if migration.Version > version {
+			pending = append(pending, migration)
+		}
+	}
+	return pending
+}
+
+// Up applies every pending migration in order and returns those it applied.
+// Each runs in a transaction together with the version bump.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.withLock(ctx, func() error {
+		version, err := m.usableVersion(ctx)
+		if err != nil {
+			return err
+		}
+		if version == 0 {
+			if version, err = m.baseline(ctx); err != nil {
+				return err
+			}
+		}
+
+		for _, migration := range m.after(version) {
+			if err := m.apply(ctx, migration.Up, migration.Version); err != nil {
+				return fmt.Errorf("migration %s failed: %w", migration, err)
+			}
+			m.logger.Info("migration applied", zap.String("migration", migration.String()))
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the newest steps applied migrations and returns them.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.withLock(ctx, func() error {
+		version, err := m.usableVersion(ctx)
+		if err != nil {
+			return err
+		}
+
+		for ; steps > 0 && version > 0; steps-- {
+			i := sort.Search(len(m.migrations), func(i int) bool { return m.migrations[i].Version >= version })
+			if i == len(m.migrations) || m.migrations[i].Version != version {
+				return fmt.Errorf("no migration %d to revert", version)
+			}
+			migration := m.migrations[i]
+			if migration.Down == "" {
+				return fmt.Errorf("migration %s cannot be reverted: it has no down script", migration)
+			}
+
+			var previous int64
+			if i > 0 {
+				previous = m.migrations[i-1].Version
+			}
+			if err := m.apply(ctx, migration.Down, previous); err != nil {
+				return fmt.Errorf("reverting migration %s failed: %w", migration, err)
+			}
+			m.logger.Info("migration reverted", zap.String("migration", migration.String()))
+			reverted = append(reverted, migration)
+			version = previous
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Force records version as applied and clean without running anything, to
+// recover from a dirty schema after repairing it by hand.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	return m.withLock(ctx, func() error {
+		return m.apply(ctx, "", version)
+	})
+}
+
+// apply runs script, if any, and records version in one transaction.
+func (m *Migrator) apply(ctx context.Context, script string, version int64) error {
+	tx, err := m.conn.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if script != "" {
+		if _, err := tx.Exec(ctx, script); err != nil {
+			return err
+		}
+	}
+	if err := setVersion(ctx, tx, version); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit migration: %w", err)
+	}
+	return nil
+}
+
+func setVersion(ctx context.Context, tx pgx.Tx, version int64) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM schema_migrations`); err != nil {
+		return fmt.Errorf("failed to update schema version: %w", err)
+	}
+	if version == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, FALSE)`, version); err != nil {
+		return fmt.Errorf("failed to update schema version: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,286 @@
+package migrate
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.uber.org/zap"
+)
+
+//go:embed testdata/init-db.sql
+var initDBSchema string
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(fstest.MapFS{
+		"002_seed.up.sql":           {Data: []byte("INSERT 2")},
+		"001_initial.up.sql":        {Data: []byte("CREATE 1")},
+		"001_initial.down.sql":      {Data: []byte("DROP 1")},
+		"README.md":                 {Data: []byte("not a migration")},
+		"010_later.up.sql/nested":   {Data: []byte("directories are skipped")},
+		"003_no_extension.up.sql~":  {Data: []byte("backup files are skipped")},
+		"embed.go":                  {Data: []byte("package migrations")},
+		"004_without_name.up":       {Data: []byte("no .sql suffix")},
+		"005_reversible.down.sql":   {Data: []byte("DROP 5")},
+		"005_reversible.up.sql":     {Data: []byte("CREATE 5")},
+		"0100_padded_wide.up.sql":   {Data: []byte("CREATE 100")},
+		"0100_padded_wide.down.sql": {Data: []byte("DROP 100")},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, m := range migrations {
+		got = append(got, fmt.Sprintf("%s up=%q down=%q", m, m.Up, m.Down))
+	}
+	want := []string{
+		`001_initial up="CREATE 1" down="DROP 1"`,
+		`002_seed up="INSERT 2" down=""`,
+		`005_reversible up="CREATE 5" down="DROP 5"`,
+		`100_padded_wide up="CREATE 100" down="DROP 100"`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("Load =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	for name, fsys := range map[string]fstest.MapFS{
+		"down without up": {"001_a.down.sql": {Data: []byte("DROP")}},
+		"name mismatch":   {"001_a.up.sql": {Data: []byte("CREATE")}, "001_b.down.sql": {Data: []byte("DROP")}},
+		"version zero":    {"000_a.up.sql": {Data: []byte("CREATE")}},
+	} {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("Load accepted %s", name)
+		}
+	}
+
+	embedded, err := Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range embedded {
+		if m.Down == "" {
+			t.Errorf("embedded migration %s has no down script", m)
+		}
+	}
+}
+
+// testDatabase creates a scratch database next to the one in
+// TEST_DATABASE_URL, whose role needs CREATEDB, and drops it after the test.
+func testDatabase(t *testing.T) string {
+	t.Helper()
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	ctx := context.Background()
+	admin, err := pgx.Connect(ctx, databaseURL)
+	if err != nil {
+		t.Fatalf("failed to connect to database: %v", err)
+	}
+	t.Cleanup(func() { admin.Close(context.Background()) })
+
+	name := fmt.Sprintf("alldl_migrate_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(ctx, "CREATE DATABASE "+name); err != nil {
+		t.Fatalf("failed to create scratch database: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(context.Background(), "DROP DATABASE IF EXISTS "+name+" WITH (FORCE)"); err != nil {
+			t.Errorf("failed to drop scratch database: %v", err)
+		}
+	})
+
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u.Path = "/" + name
+	return u.String()
+}
+
+func openMigrator(t *testing.T, databaseURL string, migrations []Migration) *Migrator {
+	t.Helper()
+	m, err := Open(context.Background(), databaseURL, migrations, zap.NewNop())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { m.Close(context.Background()) })
+	return m
+}
+
+func embedded(t *testing.T) []Migration {
+	t.Helper()
+	migrations, err := Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return migrations
+}
+
+func assertVersion(t *testing.T, m *Migrator, want int64) {
+	t.Helper()
+	version, dirty, err := m.Version(context.Background())
+	if err != nil || version != want || dirty {
+		t.Fatalf("Version = %d, %v, %v, want %d and clean", version, dirty, err, want)
+	}
+}
+
+func TestUpDownRoundTrip(t *testing.T) {
+	databaseURL := testDatabase(t)
+	ctx := context.Background()
+	migrations := embedded(t)
+	m := openMigrator(t, databaseURL, migrations)
+
+	if err := m.Check(ctx); !errors.Is(err, ErrOutdated) {
+		t.Fatalf("Check of an empty database = %v, want ErrOutdated", err)
+	}
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(applied) != len(migrations) {
+		t.Fatalf("Up applied %d migrations, want %d", len(applied), len(migrations))
+	}
+	assertVersion(t, m, m.Latest())
+	if err := m.Check(ctx); err != nil {
+		t.Fatalf("Check after Up = %v", err)
+	}
+	if applied, err := m.Up(ctx); err != nil || len(applied) != 0 {
+		t.Fatalf("second Up = %v, %v, want nothing to do", applied, err)
+	}
+
+	reverted, err := m.Down(ctx, 1)
+	if err != nil || len(reverted) != 1 || reverted[0].Version != m.Latest() {
+		t.Fatalf("Down(1) = %v, %v, want the latest migration", reverted, err)
+	}
+	assertVersion(t, m, migrations[len(migrations)-2].Version)
+	if err := m.Check(ctx); !errors.Is(err, ErrOutdated) {
+		t.Fatalf("Check after Down(1) = %v, want ErrOutdated", err)
+	}
+
+	// Reverting everything leaves no trace for the next Up to trip over.
+	if _, err := m.Down(ctx, len(migrations)); err != nil {
+		t.Fatal(err)
+	}
+	assertVersion(t, m, 0)
+	var products bool
+	if err := m.conn.QueryRow(ctx, `SELECT to_regclass('products') IS NOT NULL`).Scan(&products); err != nil || products {
+		t.Fatalf("products table exists after reverting everything: %v, %v", products, err)
+	}
+
+	applied, err = m.Up(ctx)
+	if err != nil || len(applied) != len(migrations) {
+		t.Fatalf("Up after Down = %d migrations, %v, want %d", len(applied), err, len(migrations))
+	}
+	if err := m.Check(ctx); err != nil {
+		t.Fatalf("Check after the round trip = %v", err)
+	}
+}
+
+func TestTooNewSchema(t *testing.T) {
+	databaseURL := testDatabase(t)
+	ctx := context.Background()
+	migrations := embedded(t)
+
+	if _, err := openMigrator(t, databaseURL, migrations).Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	// An older binary, one migration behind, refuses the schema.
+	old := openMigrator(t, databaseURL, migrations[:len(migrations)-1])
+	if err := old.Check(ctx); !errors.Is(err, ErrTooNew) {
+		t.Fatalf("Check = %v, want ErrTooNew", err)
+	}
+	if _, err := old.Up(ctx); !errors.Is(err, ErrTooNew) {
+		t.Fatalf("Up = %v, want ErrTooNew", err)
+	}
+	if _, err := old.Down(ctx, 1); !errors.Is(err, ErrTooNew) {
+		t.Fatalf("Down = %v, want ErrTooNew", err)
+	}
+	if err := Startup(ctx, databaseURL, true, zap.NewNop()); err != nil {
+		t.Fatalf("Startup of the binary that migrated = %v", err)
+	}
+}
+
+func TestDirtySchema(t *testing.T) {
+	databaseURL := testDatabase(t)
+	ctx := context.Background()
+	m := openMigrator(t, databaseURL, embedded(t))
+
+	if _, err := m.Up(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// golang-migrate leaves the row dirty when a migration fails halfway.
+	if _, err := m.conn.Exec(ctx, `UPDATE schema_migrations SET dirty = TRUE`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Check(ctx); !errors.Is(err, ErrDirty) {
+		t.Fatalf("Check = %v, want ErrDirty", err)
+	}
+	if _, err := m.Up(ctx); !errors.Is(err, ErrDirty) {
+		t.Fatalf("Up = %v, want ErrDirty", err)
+	}
+	if _, err := m.Down(ctx, 1); !errors.Is(err, ErrDirty) {
+		t.Fatalf("Down = %v, want ErrDirty", err)
+	}
+	if err := Startup(ctx, databaseURL, true, zap.NewNop()); !errors.Is(err, ErrDirty) {
+		t.Fatalf("Startup = %v, want ErrDirty", err)
+	}
+
+	if err := m.Force(ctx, m.Latest()); err != nil {
+		t.Fatal(err)
+	}
+	assertVersion(t, m, m.Latest())
+	if err := m.Check(ctx); err != nil {
+		t.Fatalf("Check after Force = %v", err)
+	}
+}
+
+func TestLegacySchema(t *testing.T) {
+	databaseURL := testDatabase(t)
+	ctx := context.Background()
+	migrations := embedded(t)
+	m := openMigrator(t, databaseURL, migrations)
+
+	if _, err := m.conn.Exec(ctx, initDBSchema); err != nil {
+		t.Fatalf("failed to create the init-db.sh schema: %v", err)
+	}
+
+	pending, err := m.Pending(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != len(migrations)-legacyBaseline || pending[0].Version != legacyBaseline+1 {
+		t.Fatalf("Pending = %v, want everything after the baseline", pending)
+	}
+	if err := Startup(ctx, databaseURL, false, zap.NewNop()); !errors.Is(err, ErrOutdated) {
+		t.Fatalf("Startup without --migrate = %v, want ErrOutdated", err)
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("Up over the init-db.sh schema: %v", err)
+	}
+	if len(applied) != len(pending) {
+		t.Fatalf("Up applied %v, want %v", applied, pending)
+	}
+	assertVersion(t, m, m.Latest())
+	if err := m.Check(ctx); err != nil {
+		t.Fatalf("Check after Up = %v", err)
+	}
+
+	var products int
+	if err := m.conn.QueryRow(ctx, `SELECT count(*) FROM products`).Scan(&products); err != nil || products != 16 {
+		t.Fatalf("products after Up = %d, %v, want the 16 seeded by init-db.sh", products, err)
+	}
+}
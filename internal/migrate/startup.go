@@ -0,0 +1,53 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+// Startup gets the schema ready for a binary that is starting: with apply
+// it first applies the pending embedded migrations, then it checks that the
+// schema is exactly the one the binary was built for. The binaries refuse
+// to start on any error. SQLite databases are left alone: the store creates
+// their schema itself.
+func Startup(ctx context.Context, databaseURL string, apply bool, logger *zap.Logger) error {
+	if store.IsSQLiteURL(databaseURL) {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	migrations, err := Embedded()
+	if err != nil {
+		return err
+	}
+
+	m, err := Open(ctx, databaseURL, migrations, logger)
+	if err != nil {
+		return err
+	}
+	defer m.Close(context.Background())
+
+	if apply {
+		applied, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) > 0 {
+			logger.Info("database schema migrated", zap.Int("migrations_applied", len(applied)), zap.Int64("version", m.Latest()))
+		}
+	}
+
+	err = m.Check(ctx)
+	if errors.Is(err, ErrOutdated) {
+		return fmt.Errorf("%w; start with --migrate or run alldl migrate", err)
+	}
+	return err
+}
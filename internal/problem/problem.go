@@ -0,0 +1,98 @@
+// Package problem writes error responses as RFC 7807 problem details.
+package problem
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const ContentType = "application/problem+json"
+
+// Codes identify error conditions. They are stable, so clients should
+// branch on them rather than on Detail, which is meant for people.
+const (
+	CodeInvalidRequest    = "invalid_request"
+	CodeValidationFailed  = "validation_failed"
+	CodeUnauthorized      = "unauthorized"
+	CodeInsufficientScope = "insufficient_scope"
+	CodeForbidden         = "forbidden"
+	CodeNotFound          = "not_found"
+	CodeMethodNotAllowed  = "method_not_allowed"
+	CodeConflict          = "conflict"
+	CodeRateLimited       = "rate_limited"
+	CodeOriginNotAllowed  = "origin_not_allowed"
+	CodeLoginFailed       = "login_failed"
+	CodeInternal          = "internal_error"
+)
+
+// Codes of individual field errors.
+const (
+	FieldRequired   = "required"
+	FieldInvalid    = "invalid"
+	FieldOutOfRange = "out_of_range"
+)
+
+// Problem is the body of every error response.
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError explains why one query parameter or body field was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func Field(field, code, message string) FieldError {
+	return FieldError{Field: field, Code: code, Message: message}
+}
+
+// Choice reports a value that is not one of allowed.
+func Choice(field string, allowed []string) FieldError {
+	return Field(field, FieldInvalid, "must be one of "+strings.Join(allowed, ", "))
+}
+
+// Abort answers the request with a problem and stops the handler chain.
+func Abort(c *gin.Context, status int, code, detail string, errs ...FieldError) {
+	c.Header("Content-Type", ContentType)
+	c.AbortWithStatusJSON(status, &Problem{
+		Type:      "urn:alldownloads:problem:" + code,
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  c.Request.URL.Path,
+		Code:      code,
+		RequestID: c.GetString("request_id"),
+		Errors:    errs,
+	})
+}
+
+func BadRequest(c *gin.Context, detail string) {
+	Abort(c, http.StatusBadRequest, CodeInvalidRequest, detail)
+}
+
+func Validation(c *gin.Context, errs ...FieldError) {
+	Abort(c, http.StatusBadRequest, CodeValidationFailed, "The request has invalid fields", errs...)
+}
+
+func NotFound(c *gin.Context, detail string) {
+	Abort(c, http.StatusNotFound, CodeNotFound, detail)
+}
+
+func Conflict(c *gin.Context, detail string) {
+	Abort(c, http.StatusConflict, CodeConflict, detail)
+}
+
+func Internal(c *gin.Context, detail string) {
+	Abort(c, http.StatusInternalServerError, CodeInternal, detail)
+}
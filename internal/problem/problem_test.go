@@ -0,0 +1,152 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// respond runs answer behind a request ID, as the middleware sets it, and
+// records whether the handler chain continued.
+func respond(t *testing.T, answer func(c *gin.Context)) (*httptest.ResponseRecorder, bool) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	continued := false
+	router := gin.New()
+	router.GET("/api/v1/products",
+		func(c *gin.Context) { c.Set("request_id", "req-123") },
+		answer,
+		func(c *gin.Context) { continued = true },
+	)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/products?limit=0", nil))
+	return w, continued
+}
+
+func TestAbort(t *testing.T) {
+	w, continued := respond(t, func(c *gin.Context) {
+		Abort(c, http.StatusBadRequest, CodeValidationFailed, "The request has invalid fields",
+			Field("limit", FieldOutOfRange, "must be between 1 and 200"),
+			Choice("platform", []string{"linux", "windows"}),
+		)
+	})
+
+	if continued {
+		t.Fatal("Abort let the handler chain continue")
+	}
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/problem+json" {
+		t.Fatalf("Content-Type = %q, want application/problem+json", ct)
+	}
+
+	// The wire format is the contract, so compare the raw JSON.
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"type":       "urn:alldownloads:problem:validation_failed",
+		"title":      "Bad Request",
+		"status":     float64(400),
+		"detail":     "The request has invalid fields",
+		"instance":   "/api/v1/products",
+		"code":       "validation_failed",
+		"request_id": "req-123",
+		"errors": []interface{}{
+			map[string]interface{}{"field": "limit", "code": "out_of_range", "message": "must be between 1 and 200"},
+			map[string]interface{}{"field": "platform", "code": "invalid", "message": "must be one of linux, windows"},
+		},
+	}
+	if !reflect.DeepEqual(body, want) {
+		t.Fatalf("body =\n%v\nwant\n%v", body, want)
+	}
+}
+
+func TestAbortOmitsEmptyFields(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/missing", func(c *gin.Context) { Abort(c, http.StatusNotFound, CodeNotFound, "") })
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"detail", "request_id", "errors"} {
+		if _, ok := body[key]; ok {
+			t.Errorf("empty %s is present in %s", key, w.Body)
+		}
+	}
+	for _, key := range []string{"type", "title", "status", "code", "instance"} {
+		if _, ok := body[key]; !ok {
+			t.Errorf("%s is missing from %s", key, w.Body)
+		}
+	}
+}
+
+func TestHelpers(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		answer func(c *gin.Context)
+		status int
+		code   string
+		detail string
+	}{
+		{"BadRequest", func(c *gin.Context) { BadRequest(c, "Request body is not valid JSON") }, http.StatusBadRequest, CodeInvalidRequest, "Request body is not valid JSON"},
+		{"Validation", func(c *gin.Context) { Validation(c, Field("id", FieldRequired, "is required")) }, http.StatusBadRequest, CodeValidationFailed, "The request has invalid fields"},
+		{"NotFound", func(c *gin.Context) { NotFound(c, "Product not found") }, http.StatusNotFound, CodeNotFound, "Product not found"},
+		{"Conflict", func(c *gin.Context) { Conflict(c, "Product with this id already exists") }, http.StatusConflict, CodeConflict, "Product with this id already exists"},
+		{"Internal", func(c *gin.Context) { Internal(c, "Failed to fetch products") }, http.StatusInternalServerError, CodeInternal, "Failed to fetch products"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			w, continued := respond(t, tt.answer)
+			if continued {
+				t.Fatal("the handler chain continued")
+			}
+			if w.Code != tt.status || w.Header().Get("Content-Type") != ContentType {
+				t.Fatalf("response = %d %q, want %d %q", w.Code, w.Header().Get("Content-Type"), tt.status, ContentType)
+			}
+			var body Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Code != tt.code || body.Type != "urn:alldownloads:problem:"+tt.code ||
+				body.Status != tt.status || body.Title != http.StatusText(tt.status) || body.Detail != tt.detail {
+				t.Fatalf("problem = %+v", body)
+			}
+		})
+	}
+}
+
+// Clients branch on these values, so changing one is a breaking change.
+func TestCodesAreStable(t *testing.T) {
+	for got, want := range map[string]string{
+		CodeInvalidRequest:    "invalid_request",
+		CodeValidationFailed:  "validation_failed",
+		CodeUnauthorized:      "unauthorized",
+		CodeInsufficientScope: "insufficient_scope",
+		CodeForbidden:         "forbidden",
+		CodeNotFound:          "not_found",
+		CodeMethodNotAllowed:  "method_not_allowed",
+		CodeConflict:          "conflict",
+		CodeRateLimited:       "rate_limited",
+		CodeOriginNotAllowed:  "origin_not_allowed",
+		CodeLoginFailed:       "login_failed",
+		CodeInternal:          "internal_error",
+		FieldRequired:         "required",
+		FieldInvalid:          "invalid",
+		FieldOutOfRange:       "out_of_range",
+	} {
+		if got != want {
+			t.Errorf("code %q changed to %q", want, got)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// FallbackLimiter uses primary and switches to fallback for requests where
+// primary fails, so a Redis outage degrades to per-replica limits instead
+// of rejecting or waving through all traffic.
+type FallbackLimiter struct {
+	primary  Limiter
+	fallback Limiter
+	logger   *zap.Logger
+
+	mu         sync.Mutex
+	lastWarned time.Time
+}
+
+func NewFallbackLimiter(primary, fallback Limiter, logger *zap.Logger) *FallbackLimiter {
+	return &FallbackLimiter{primary: primary, fallback: fallback, logger: logger}
+}
+
+func (f *FallbackLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	result, err := f.primary.Allow(ctx, key, limit, window)
+	if err == nil {
+		return result, nil
+	}
+
+	f.mu.Lock()
+	if time.Since(f.lastWarned) >= time.Minute {
+		f.lastWarned = time.Now()
+		f.logger.Warn("rate limiter backend failed, using in-memory limits", zap.Error(err))
+	}
+	f.mu.Unlock()
+
+	return f.fallback.Allow(ctx, key, limit, window)
+}
@@ -0,0 +1,181 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"go.uber.org/zap"
+)
+
+func TestMemoryLimiterWindow(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	// Each step is a request at start+at against a limit of 2 per minute.
+	tests := []struct {
+		name  string
+		steps []time.Duration
+		want  []Result
+	}{
+		{
+			name:  "limit reached",
+			steps: []time.Duration{0, time.Second, 2 * time.Second},
+			want: []Result{
+				{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Minute},
+				{Allowed: true, Limit: 2, Remaining: 0, Reset: 59 * time.Second},
+				{Allowed: false, Limit: 2, Remaining: 0, Reset: 58 * time.Second},
+			},
+		},
+		{
+			name:  "oldest request expires exactly one window later",
+			steps: []time.Duration{0, time.Second, time.Minute - time.Nanosecond, time.Minute},
+			want: []Result{
+				{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Minute},
+				{Allowed: true, Limit: 2, Remaining: 0, Reset: 59 * time.Second},
+				{Allowed: false, Limit: 2, Remaining: 0, Reset: time.Nanosecond},
+				{Allowed: true, Limit: 2, Remaining: 0, Reset: time.Second},
+			},
+		},
+		{
+			name:  "denied requests do not extend the window",
+			steps: []time.Duration{0, 0, 30 * time.Second, 59 * time.Second, time.Minute},
+			want: []Result{
+				{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Minute},
+				{Allowed: true, Limit: 2, Remaining: 0, Reset: time.Minute},
+				{Allowed: false, Limit: 2, Remaining: 0, Reset: 30 * time.Second},
+				{Allowed: false, Limit: 2, Remaining: 0, Reset: time.Second},
+				{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Minute},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMemoryLimiter()
+			for i, at := range tt.steps {
+				m.now = func() time.Time { return start.Add(at) }
+				got, err := m.Allow(context.Background(), "client", 2, time.Minute)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got != tt.want[i] {
+					t.Fatalf("request %d at +%v = %+v, want %+v", i, at, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryLimiterKeysAndSweep(t *testing.T) {
+	start := time.Now()
+	now := start
+	m := NewMemoryLimiter()
+	m.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	m.Allow(ctx, "a", 1, time.Second)
+	if got, _ := m.Allow(ctx, "b", 1, time.Second); !got.Allowed {
+		t.Fatal("a second key shares the first key's bucket")
+	}
+	if got, _ := m.Allow(ctx, "a", 1, time.Second); got.Allowed {
+		t.Fatal("key a was allowed past its limit")
+	}
+
+	// Idle buckets go on the next sweep, busy ones stay.
+	now = start.Add(sweepInterval)
+	m.Allow(ctx, "c", 1, 2*sweepInterval)
+	now = start.Add(2 * sweepInterval)
+	m.Allow(ctx, "d", 1, time.Second)
+	if _, ok := m.buckets["a"]; ok {
+		t.Fatal("idle bucket a survived a sweep")
+	}
+	if _, ok := m.buckets["c"]; !ok {
+		t.Fatal("bucket c was swept while its window was open")
+	}
+}
+
+func TestRedisLimiter(t *testing.T) {
+	server := miniredis.RunT(t)
+	limiter, err := NewRedisLimiter("redis://" + server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer limiter.Close()
+	ctx := context.Background()
+
+	for i, want := range []Result{
+		{Allowed: true, Limit: 2, Remaining: 1},
+		{Allowed: true, Limit: 2, Remaining: 0},
+		{Allowed: false, Limit: 2, Remaining: 0},
+	} {
+		got, err := limiter.Allow(ctx, "client", 2, time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Allowed != want.Allowed || got.Limit != want.Limit || got.Remaining != want.Remaining {
+			t.Fatalf("request %d = %+v, want %+v", i, got, want)
+		}
+		if got.Reset <= 0 || got.Reset > time.Minute {
+			t.Fatalf("request %d resets in %v, want within the window", i, got.Reset)
+		}
+	}
+	if ttl := server.TTL(keyPrefix + "client"); ttl <= 0 || ttl > time.Minute {
+		t.Fatalf("bucket TTL = %v, want it to expire with the window", ttl)
+	}
+
+	server.Close()
+	if _, err := limiter.Allow(ctx, "client", 2, time.Minute); err == nil {
+		t.Fatal("Allow succeeded with Redis down")
+	}
+}
+
+type failingLimiter struct {
+	calls int
+}
+
+func (f *failingLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	f.calls++
+	return Result{}, errors.New("connection refused")
+}
+
+func TestFallbackLimiter(t *testing.T) {
+	ctx := context.Background()
+
+	server := miniredis.RunT(t)
+	redisLimiter, err := NewRedisLimiter("redis://" + server.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer redisLimiter.Close()
+
+	memory := NewMemoryLimiter()
+	limiter := NewFallbackLimiter(redisLimiter, memory, zap.NewNop())
+	if got, err := limiter.Allow(ctx, "client", 1, time.Minute); err != nil || !got.Allowed {
+		t.Fatalf("Allow = %+v, %v", got, err)
+	}
+	if len(memory.buckets) != 0 {
+		t.Fatal("the fallback counted a request Redis answered")
+	}
+
+	// With Redis down the fallback applies the same limits on its own.
+	server.Close()
+	for i, allowed := range []bool{true, false} {
+		got, err := limiter.Allow(ctx, "client", 1, time.Minute)
+		if err != nil || got.Allowed != allowed {
+			t.Fatalf("request %d with Redis down = %+v, %v, want allowed %v", i, got, err, allowed)
+		}
+	}
+
+	primary := &failingLimiter{}
+	limiter = NewFallbackLimiter(primary, NewMemoryLimiter(), zap.NewNop())
+	for i := 0; i < 3; i++ {
+		if _, err := limiter.Allow(ctx, "client", 5, time.Minute); err != nil {
+			t.Fatalf("Allow returned the primary's error: %v", err)
+		}
+	}
+	if primary.calls != 3 {
+		t.Fatalf("primary was tried %d times, want every request", primary.calls)
+	}
+}
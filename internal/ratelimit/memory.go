@@ -0,0 +1,84 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+// MemoryLimiter keeps a sliding log of request times per key. Keys that
+// have been idle for a full window are evicted on a periodic sweep, so
+// memory stays bounded by the number of recently active clients.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+type memoryBucket struct {
+	hits   []time.Time
+	window time.Duration
+}
+
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		buckets:   make(map[string]*memoryBucket),
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+func (m *MemoryLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		m.sweep(now)
+	}
+
+	bucket, exists := m.buckets[key]
+	if !exists {
+		bucket = &memoryBucket{}
+		m.buckets[key] = bucket
+	}
+	bucket.window = window
+	bucket.expire(now)
+
+	result := Result{Limit: limit}
+	if len(bucket.hits) < limit {
+		bucket.hits = append(bucket.hits, now)
+		result.Allowed = true
+	}
+	result.Remaining = limit - len(bucket.hits)
+	if result.Remaining < 0 {
+		result.Remaining = 0
+	}
+	if len(bucket.hits) > 0 {
+		result.Reset = bucket.hits[0].Add(window).Sub(now)
+	}
+
+	return result, nil
+}
+
+func (m *MemoryLimiter) sweep(now time.Time) {
+	for key, bucket := range m.buckets {
+		bucket.expire(now)
+		if len(bucket.hits) == 0 {
+			delete(m.buckets, key)
+		}
+	}
+	m.lastSweep = now
+}
+
+func (b *memoryBucket) expire(now time.Time) {
+	cutoff := now.Add(-b.window)
+	i := 0
+	for i < len(b.hits) && !b.hits[i].After(cutoff) {
+		i++
+	}
+	b.hits = b.hits[i:]
+}
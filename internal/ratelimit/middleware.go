@@ -0,0 +1,64 @@
+package ratelimit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/your-username/alldownloads/internal/problem"
+	"go.uber.org/zap"
+)
+
+// Middleware limits requests per client IP according to the matching
+// policy. Each policy has its own bucket, so a tight limit on one route
+// does not eat into the quota for the rest of the API.
+func Middleware(limiter Limiter, policies *Policies, logger *zap.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		policy := policies.Match(c.Request.Method, c.FullPath(), c.Request.URL.Path)
+		if policy.Requests <= 0 {
+			c.Next()
+			return
+		}
+
+		key := "ip:" + policy.Name + ":" + c.ClientIP()
+		if !Enforce(c, limiter, key, policy, logger) {
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// Enforce counts the request against key, sets the RateLimit-* headers and
+// aborts with 429 when the policy is exhausted. It reports whether the
+// request may proceed. Limiter errors fail open.
+func Enforce(c *gin.Context, limiter Limiter, key string, policy Policy, logger *zap.Logger) bool {
+	result, err := limiter.Allow(c.Request.Context(), key, policy.Requests, policy.Window)
+	if err != nil {
+		logger.Error("rate limiter failed", zap.Error(err), zap.String("policy", policy.Name))
+		return true
+	}
+
+	reset := ceilSeconds(result.Reset)
+	c.Header("RateLimit-Limit", strconv.Itoa(result.Limit))
+	c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	c.Header("RateLimit-Reset", strconv.Itoa(reset))
+	c.Header("RateLimit-Policy", policy.Header())
+
+	if !result.Allowed {
+		if reset < 1 {
+			reset = 1
+		}
+		c.Header("Retry-After", strconv.Itoa(reset))
+		problem.Abort(c, http.StatusTooManyRequests, problem.CodeRateLimited, "Rate limit exceeded. Try again later.")
+		return false
+	}
+
+	return true
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
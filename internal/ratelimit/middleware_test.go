@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestEnforce(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy := Policy{Name: "test", Requests: 2, Window: 90 * time.Second}
+
+	type response struct {
+		status  int
+		headers map[string]string
+	}
+	allowed := func(remaining string) response {
+		return response{http.StatusNoContent, map[string]string{"RateLimit-Limit": "2", "RateLimit-Remaining": remaining,
+			"RateLimit-Reset": "90", "RateLimit-Policy": "2;w=90", "Retry-After": ""}}
+	}
+	failedOpen := response{http.StatusNoContent, map[string]string{"RateLimit-Limit": "", "Retry-After": ""}}
+
+	tests := []struct {
+		name    string
+		limiter Limiter
+		want    []response
+	}{
+		{
+			name:    "memory",
+			limiter: NewMemoryLimiter(),
+			want: []response{allowed("1"), allowed("0"), {http.StatusTooManyRequests, map[string]string{
+				"RateLimit-Remaining": "0", "RateLimit-Reset": "90", "Retry-After": "90"}}},
+		},
+		{
+			name:    "failing limiter fails open without headers",
+			limiter: &failingLimiter{},
+			want:    []response{failedOpen, failedOpen, failedOpen},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.GET("/", func(c *gin.Context) {
+				if Enforce(c, tt.limiter, "client", policy, zap.NewNop()) {
+					c.Status(http.StatusNoContent)
+				}
+			})
+
+			for i, want := range tt.want {
+				w := httptest.NewRecorder()
+				router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+				if w.Code != want.status {
+					t.Fatalf("request %d = %d, want %d", i, w.Code, want.status)
+				}
+				for header, value := range want.headers {
+					if got := w.Header().Get(header); got != value {
+						t.Fatalf("request %d: %s = %q, want %q", i, header, got, value)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMiddlewareSeparatesPolicies(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policies, err := ParsePolicies("/refresh=1/m, /open=0/m", Policy{Requests: 2, Window: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := gin.New()
+	router.Use(Middleware(NewMemoryLimiter(), policies, zap.NewNop()))
+	for _, path := range []string{"/refresh", "/products", "/open"} {
+		router.GET(path, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	}
+
+	for i, tt := range []struct {
+		path   string
+		status int
+	}{
+		{"/refresh", http.StatusNoContent},
+		{"/refresh", http.StatusTooManyRequests},
+		// The default bucket is untouched by the refresh policy.
+		{"/products", http.StatusNoContent},
+		{"/products", http.StatusNoContent},
+		{"/products", http.StatusTooManyRequests},
+		// A policy of 0 requests disables limiting.
+		{"/open", http.StatusNoContent},
+		{"/open", http.StatusNoContent},
+		{"/open", http.StatusNoContent},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		if w.Code != tt.status {
+			t.Fatalf("request %d to %s = %d, want %d", i, tt.path, w.Code, tt.status)
+		}
+	}
+}
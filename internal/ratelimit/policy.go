@@ -0,0 +1,148 @@
+package ratelimit
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy limits matching requests to Requests per Window. Requests <= 0
+// disables limiting.
+type Policy struct {
+	Name string
+	// Method restricts the policy to one HTTP method; empty matches any.
+	Method string
+	// Path is either a gin route template such as /api/products/:id, or a
+	// prefix ending in * such as /api/admin/*.
+	Path     string
+	Requests int
+	Window   time.Duration
+}
+
+// Header renders the policy for the RateLimit-Policy header.
+func (p Policy) Header() string {
+	return fmt.Sprintf("%d;w=%d", p.Requests, int(p.Window.Seconds()))
+}
+
+func (p Policy) matches(method, route, path string) bool {
+	if p.Method != "" && p.Method != method {
+		return false
+	}
+	if prefix, ok := strings.CutSuffix(p.Path, "*"); ok {
+		return strings.HasPrefix(path, prefix)
+	}
+	return p.Path == route || p.Path == path
+}
+
+type Policies struct {
+	Default Policy
+	routes  []Policy
+}
+
+// ParsePolicies parses a comma-separated list of route policies such as
+//
+//	POST /api/refresh=5/1m, /api/admin/*=120/1m, /api/versions=30/m
+//
+// Requests that match none of them fall back to def. When several match,
+// the longest path wins and a method-specific policy beats a generic one.
+func ParsePolicies(spec string, def Policy) (*Policies, error) {
+	def.Name = "default"
+	policies := &Policies{Default: def}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		route, limit, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid rate limit policy %q, expected [METHOD ]path=requests/window", entry)
+		}
+
+		policy := Policy{Name: strings.TrimSpace(route)}
+		fields := strings.Fields(route)
+		switch len(fields) {
+		case 1:
+			policy.Path = fields[0]
+		case 2:
+			policy.Method, policy.Path = strings.ToUpper(fields[0]), fields[1]
+		default:
+			return nil, fmt.Errorf("invalid rate limit route %q", route)
+		}
+		if !strings.HasPrefix(policy.Path, "/") {
+			return nil, fmt.Errorf("rate limit path %q must start with /", policy.Path)
+		}
+
+		requests, window, err := parseLimit(strings.TrimSpace(limit))
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit for %q: %w", route, err)
+		}
+		policy.Requests, policy.Window = requests, window
+
+		policies.routes = append(policies.routes, policy)
+	}
+
+	policies.sort()
+
+	return policies, nil
+}
+
+func (p *Policies) sort() {
+	sort.SliceStable(p.routes, func(i, j int) bool {
+		a, b := p.routes[i], p.routes[j]
+		if len(a.Path) != len(b.Path) {
+			return len(a.Path) > len(b.Path)
+		}
+		return a.Method != "" && b.Method == ""
+	})
+}
+
+// Rebase moves policies whose path starts with from to the same path under
+// to, for routes that are now mounted under a new prefix. Paths already
+// under to are left alone.
+func (p *Policies) Rebase(from, to string) {
+	for i, policy := range p.routes {
+		if rest, ok := strings.CutPrefix(policy.Path, from); ok && !strings.HasPrefix(policy.Path, to) {
+			p.routes[i].Path = to + rest
+		}
+	}
+	p.sort()
+}
+
+// parseLimit parses "requests/window" where window is a Go duration or a
+// bare unit such as "m" meaning one minute.
+func parseLimit(value string) (int, time.Duration, error) {
+	count, per, ok := strings.Cut(value, "/")
+	if !ok {
+		return 0, 0, fmt.Errorf("expected requests/window, got %q", value)
+	}
+
+	requests, err := strconv.Atoi(count)
+	if err != nil || requests < 0 {
+		return 0, 0, fmt.Errorf("requests must be a non-negative integer")
+	}
+
+	if per != "" && (per[0] < '0' || per[0] > '9') {
+		per = "1" + per
+	}
+	window, err := time.ParseDuration(per)
+	if err != nil || window < time.Second {
+		return 0, 0, fmt.Errorf("window must be a duration of at least 1s")
+	}
+
+	return requests, window, nil
+}
+
+// Match returns the policy for a request. route is the gin route template
+// (c.FullPath()), which is empty when no route matched.
+func (p *Policies) Match(method, route, path string) Policy {
+	for _, policy := range p.routes {
+		if policy.matches(method, route, path) {
+			return policy
+		}
+	}
+	return p.Default
+}
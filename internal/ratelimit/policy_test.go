@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePolicies(t *testing.T) {
+	def := Policy{Requests: 100, Window: time.Minute}
+
+	tests := []struct {
+		spec string
+		want []Policy
+		err  string
+	}{
+		{spec: "", want: nil},
+		{spec: " , ", want: nil},
+		{
+			spec: "POST /api/refresh=5/1m, /api/admin/*=120/1m, /api/versions=30/m",
+			want: []Policy{
+				{Name: "/api/versions", Path: "/api/versions", Requests: 30, Window: time.Minute},
+				{Name: "POST /api/refresh", Method: "POST", Path: "/api/refresh", Requests: 5, Window: time.Minute},
+				{Name: "/api/admin/*", Path: "/api/admin/*", Requests: 120, Window: time.Minute},
+			},
+		},
+		{spec: "get /api/x=0/30s", want: []Policy{{Name: "get /api/x", Method: "GET", Path: "/api/x", Requests: 0, Window: 30 * time.Second}}},
+		{spec: "/api/x=10/h", want: []Policy{{Name: "/api/x", Path: "/api/x", Requests: 10, Window: time.Hour}}},
+		{spec: "/api/x", err: "expected [METHOD ]path=requests/window"},
+		{spec: "GET /api/x extra=1/m", err: "invalid rate limit route"},
+		{spec: "api/x=1/m", err: "must start with /"},
+		{spec: "/api/x=1", err: "expected requests/window"},
+		{spec: "/api/x=many/m", err: "non-negative integer"},
+		{spec: "/api/x=-1/m", err: "non-negative integer"},
+		{spec: "/api/x=1/fortnight", err: "at least 1s"},
+		{spec: "/api/x=1/500ms", err: "at least 1s"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			policies, err := ParsePolicies(tt.spec, def)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("ParsePolicies(%q) = %v, want an error containing %q", tt.spec, err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ParsePolicies(%q): %v", tt.spec, err)
+			}
+			if len(policies.routes) != len(tt.want) {
+				t.Fatalf("policies = %+v, want %+v", policies.routes, tt.want)
+			}
+			for i := range tt.want {
+				if policies.routes[i] != tt.want[i] {
+					t.Fatalf("policy %d = %+v, want %+v", i, policies.routes[i], tt.want[i])
+				}
+			}
+			if policies.Default.Name != "default" || policies.Default.Requests != 100 {
+				t.Fatalf("default policy = %+v", policies.Default)
+			}
+		})
+	}
+}
+
+func TestPoliciesMatch(t *testing.T) {
+	policies, err := ParsePolicies("/api/*=1/m, /api/admin/*=2/m, POST /api/admin/*=3/m, /api/products/:id=4/m", Policy{Requests: 100, Window: time.Minute})
+	if err != nil {
+		t.Fatal(err)
+	}
+	policies.Rebase("/api/", "/api/v1/")
+
+	tests := []struct {
+		method, route, path string
+		want                string
+	}{
+		{"GET", "/api/v1/products/:id", "/api/v1/products/ubuntu", "/api/products/:id"},
+		{"GET", "/api/v1/products", "/api/v1/products", "/api/*"},
+		{"GET", "/api/v1/admin/keys", "/api/v1/admin/keys", "/api/admin/*"},
+		{"POST", "/api/v1/admin/keys", "/api/v1/admin/keys", "POST /api/admin/*"},
+		{"GET", "", "/api/v1/nowhere", "/api/*"},
+		{"GET", "/metrics", "/metrics", "default"},
+	}
+
+	for _, tt := range tests {
+		if got := policies.Match(tt.method, tt.route, tt.path); got.Name != tt.want {
+			t.Errorf("Match(%s %s) = %q, want %q", tt.method, tt.path, got.Name, tt.want)
+		}
+	}
+}
@@ -0,0 +1,26 @@
+// Package ratelimit enforces sliding-window request limits. The Redis
+// limiter shares its counters between all API replicas; the memory limiter
+// is used on its own for single-instance setups and as a fallback while
+// Redis is unavailable.
+package ratelimit
+
+import (
+	"context"
+	"time"
+)
+
+// Result describes the state of a bucket after a request was counted.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is how long until the oldest request in the window expires and
+	// frees up quota.
+	Reset time.Duration
+}
+
+type Limiter interface {
+	// Allow counts a request against key if fewer than limit requests were
+	// allowed during the last window.
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error)
+}
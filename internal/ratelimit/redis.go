@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "ratelimit:"
+
+// slidingWindow keeps one sorted-set member per allowed request, scored by
+// the Redis server clock so that replicas with skewed clocks agree.
+// It returns {allowed, count, ms until the oldest member expires}.
+var slidingWindow = redis.NewScript(`
+local key = KEYS[1]
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local member = ARGV[3]
+
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local count = redis.call('ZCARD', key)
+local allowed = 0
+if count < limit then
+    redis.call('ZADD', key, now, member)
+    count = count + 1
+    allowed = 1
+end
+redis.call('PEXPIRE', key, window)
+
+local reset = 0
+local oldest = redis.call('ZRANGE', key, 0, 0, 'WITHSCORES')
+if oldest[2] then
+    reset = tonumber(oldest[2]) + window - now
+end
+
+return {allowed, count, reset}
+`)
+
+type RedisLimiter struct {
+	client *redis.Client
+}
+
+func NewRedisLimiter(redisURL string) (*RedisLimiter, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse Redis URL: %w", err)
+	}
+
+	return &RedisLimiter{client: redis.NewClient(opts)}, nil
+}
+
+func (r *RedisLimiter) Close() error {
+	return r.client.Close()
+}
+
+func (r *RedisLimiter) Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error) {
+	member := make([]byte, 8)
+	if _, err := rand.Read(member); err != nil {
+		return Result{}, err
+	}
+
+	values, err := slidingWindow.Run(ctx, r.client, []string{keyPrefix + key},
+		window.Milliseconds(), limit, hex.EncodeToString(member)).Int64Slice()
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to run rate limit script: %w", err)
+	}
+	if len(values) != 3 {
+		return Result{}, fmt.Errorf("unexpected rate limit script result %v", values)
+	}
+
+	remaining := limit - int(values[1])
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return Result{
+		Allowed:   values[0] == 1,
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Duration(values[2]) * time.Millisecond,
+	}, nil
+}
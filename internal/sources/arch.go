@@ -11,12 +11,15 @@ import (
 )
 
 type ArchFetcher struct {
-	client *HTTPClient
+	client  *HTTPClient
+	baseURL string
 }
 
-func NewArchFetcher() *ArchFetcher {
+func NewArchFetcher(opts ...Option) *ArchFetcher {
+	baseURL, client := applyOptions(archBaseURL, opts)
 	return &ArchFetcher{
-		client: NewHTTPClient(),
+		client:  client,
+		baseURL: baseURL,
 	}
 }
 
@@ -24,9 +27,10 @@ func (f *ArchFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, error
 	var versions []*store.ProductVersion
 
 	// Use direct mirror URLs since main page has magnet links
-	mirrors := []string{
-		"https://mirror.rackspace.com/archlinux/iso/latest/archlinux-x86_64.iso",
-		"https://mirrors.kernel.org/archlinux/iso/latest/archlinux-x86_64.iso",
+	mirrors := []string{f.baseURL + "/iso/latest/archlinux-x86_64.iso"}
+	// kernel.org backs up the default mirror, not one set with WithBaseURL.
+	if f.baseURL == archBaseURL {
+		mirrors = append(mirrors, "https://mirrors.kernel.org/archlinux/iso/latest/archlinux-x86_64.iso")
 	}
 
 	var downloadURL string
@@ -10,7 +10,8 @@ import (
 )
 
 type ChromeFetcher struct {
-	client *HTTPClient
+	client  *HTTPClient
+	baseURL string
 }
 
 type ChromeVersionAPI struct {
@@ -21,16 +22,18 @@ type ChromeVersion struct {
 	Version string `json:"version"`
 }
 
-func NewChromeFetcher() *ChromeFetcher {
+func NewChromeFetcher(opts ...Option) *ChromeFetcher {
+	baseURL, client := applyOptions(chromeBaseURL, opts)
 	return &ChromeFetcher{
-		client: NewHTTPClient(),
+		client:  client,
+		baseURL: baseURL,
 	}
 }
 
 func (f *ChromeFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, error) {
 	var versions []*store.ProductVersion
 
-	apiURL := "https://versionhistory.googleapis.com/v1/chrome/platforms/win/channels/stable/versions"
+	apiURL := f.baseURL + "/v1/chrome/platforms/win/channels/stable/versions"
 	resp, err := f.client.GetJSON(ctx, apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch Chrome versions: %w", err)
@@ -11,19 +11,22 @@ import (
 )
 
 type DebianFetcher struct {
-	client *HTTPClient
+	client  *HTTPClient
+	baseURL string
 }
 
-func NewDebianFetcher() *DebianFetcher {
+func NewDebianFetcher(opts ...Option) *DebianFetcher {
+	baseURL, client := applyOptions(debianBaseURL, opts)
 	return &DebianFetcher{
-		client: NewHTTPClient(),
+		client:  client,
+		baseURL: baseURL,
 	}
 }
 
 func (f *DebianFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, error) {
 	var versions []*store.ProductVersion
 
-	baseURL := "https://cdimage.debian.org/debian-cd/current/amd64/iso-cd/"
+	baseURL := f.baseURL + "/"
 	resp, err := f.client.Get(ctx, baseURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch Debian ISOs: %w", err)
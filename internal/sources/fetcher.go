@@ -24,19 +24,62 @@ type HTTPClient struct {
 }
 
 func NewHTTPClient() *HTTPClient {
+	return newHTTPClient(&http.Transport{
+		MaxIdleConns:       10,
+		IdleConnTimeout:    90 * time.Second,
+		DisableCompression: false,
+	})
+}
+
+func newHTTPClient(transport http.RoundTripper) *HTTPClient {
 	return &HTTPClient{
 		client: &http.Client{
-			Timeout: 30 * time.Second,
-			Transport: &http.Transport{
-				MaxIdleConns:       10,
-				IdleConnTimeout:    90 * time.Second,
-				DisableCompression: false,
-			},
+			Timeout:   30 * time.Second,
+			Transport: &instrumentedTransport{next: transport},
 		},
 		userAgent: "AllDownloads/1.0 (+https://github.com/your-username/alldownloads)",
 	}
 }
 
+// Option configures a fetcher. They exist so that fetchers can run against
+// recorded vendor responses instead of the live sites.
+type Option func(*fetcherOptions)
+
+type fetcherOptions struct {
+	baseURL   string
+	transport http.RoundTripper
+}
+
+// WithBaseURL replaces the vendor URL a fetcher scrapes, such as
+// https://api.github.com, with one serving the same paths. Fetchers that only
+// link to fixed download URLs ignore it.
+func WithBaseURL(baseURL string) Option {
+	return func(o *fetcherOptions) {
+		o.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithTransport sends a fetcher's requests through transport.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(o *fetcherOptions) {
+		o.transport = transport
+	}
+}
+
+// applyOptions returns the base URL and client a fetcher uses by default
+// for defaultBaseURL, after applying opts.
+func applyOptions(defaultBaseURL string, opts []Option) (string, *HTTPClient) {
+	o := fetcherOptions{baseURL: defaultBaseURL}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.transport == nil {
+		return o.baseURL, NewHTTPClient()
+	}
+	return o.baseURL, newHTTPClient(o.transport)
+}
+
 func (h *HTTPClient) Get(ctx context.Context, url string) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
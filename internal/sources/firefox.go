@@ -10,7 +10,8 @@ import (
 )
 
 type FirefoxFetcher struct {
-	client *HTTPClient
+	client  *HTTPClient
+	baseURL string
 }
 
 type FirefoxRelease struct {
@@ -32,16 +33,18 @@ type FirefoxReleaseFile struct {
 	URL      string `json:"url"`
 }
 
-func NewFirefoxFetcher() *FirefoxFetcher {
+func NewFirefoxFetcher(opts ...Option) *FirefoxFetcher {
+	baseURL, client := applyOptions(firefoxBaseURL, opts)
 	return &FirefoxFetcher{
-		client: NewHTTPClient(),
+		client:  client,
+		baseURL: baseURL,
 	}
 }
 
 func (f *FirefoxFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, error) {
 	var versions []*store.ProductVersion
 
-	apiURL := "https://product-details.mozilla.org/1.0/firefox_versions.json"
+	apiURL := f.baseURL + "/1.0/firefox_versions.json"
 	resp, err := f.client.GetJSON(ctx, apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch Firefox versions: %w", err)
@@ -0,0 +1,144 @@
+package sources_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/your-username/alldownloads/internal/sources"
+	"github.com/your-username/alldownloads/internal/sources/sourcestest"
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+var record = flag.Bool("record", false, "re-record the fixtures in testdata from the live vendor sites and rewrite want.json")
+
+// goldenVersion holds the ProductVersion fields a fetcher fills in.
+type goldenVersion struct {
+	Version      string `json:"version"`
+	Platform     string `json:"platform"`
+	Architecture string `json:"architecture"`
+	Channel      string `json:"channel,omitempty"`
+	DownloadURL  string `json:"download_url"`
+	Checksum     string `json:"checksum,omitempty"`
+	ChecksumType string `json:"checksum_type,omitempty"`
+	FileSize     int64  `json:"file_size"`
+	Filename     string `json:"filename"`
+	IsLatest     bool   `json:"is_latest"`
+}
+
+// TestFetchers runs every registered fetcher against the responses recorded
+// in testdata/<name> and compares what it parsed with testdata/<name>/want.json.
+func TestFetchers(t *testing.T) {
+	for _, name := range sources.Names() {
+		name := name
+		t.Run(name, func(t *testing.T) {
+			dir := filepath.Join("testdata", name)
+			baseURL := sources.BaseURL(name)
+
+			var srv *sourcestest.Server
+			if *record && baseURL != "" {
+				if err := os.RemoveAll(dir); err != nil {
+					t.Fatal(err)
+				}
+				srv = sourcestest.Record(dir, baseURL)
+			} else {
+				srv = sourcestest.Replay(dir)
+			}
+			defer srv.Close()
+
+			fetcher, _ := sources.NewFetcher(name, sources.WithBaseURL(srv.URL), sources.WithTransport(srv.Transport()))
+			versions, err := fetcher.Fetch(context.Background())
+			if err != nil {
+				t.Fatalf("fetch failed: %v", err)
+			}
+			for _, request := range srv.Missing() {
+				t.Errorf("no fixture for %s", request)
+			}
+
+			got := golden(t, versions)
+			if baseURL != "" {
+				got = bytes.ReplaceAll(got, []byte(srv.URL), []byte(baseURL))
+			}
+
+			wantPath := filepath.Join(dir, "want.json")
+			if *record {
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(wantPath, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+
+			want, err := os.ReadFile(wantPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("fetched versions differ from %s\ngot:\n%s", wantPath, got)
+			}
+		})
+	}
+}
+
+func TestBraveReportsRateLimit(t *testing.T) {
+	srv := sourcestest.Replay(filepath.Join("testdata", "errors", "github-rate-limited"))
+	defer srv.Close()
+
+	fetcher := sources.NewBraveFetcher(sources.WithBaseURL(srv.URL), sources.WithTransport(srv.Transport()))
+	_, err := fetcher.Fetch(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "rate limit exceeded until 2025-06-01T12:00:00Z") {
+		t.Fatalf("got error %v, want the rate limit and its reset time", err)
+	}
+}
+
+// golden renders versions in a stable order, since several fetchers build
+// them from map iteration.
+func golden(t *testing.T, versions []*store.ProductVersion) []byte {
+	t.Helper()
+
+	rendered := make([]goldenVersion, 0, len(versions))
+	for _, v := range versions {
+		rendered = append(rendered, goldenVersion{
+			Version:      v.Version,
+			Platform:     v.Platform,
+			Architecture: v.Architecture,
+			Channel:      v.Channel,
+			DownloadURL:  v.DownloadURL,
+			Checksum:     v.Checksum,
+			ChecksumType: v.ChecksumType,
+			FileSize:     v.FileSize,
+			Filename:     v.Filename,
+			IsLatest:     v.IsLatest,
+		})
+	}
+	sort.Slice(rendered, func(i, j int) bool {
+		a, b := rendered[i], rendered[j]
+		if a.Platform != b.Platform {
+			return a.Platform < b.Platform
+		}
+		if a.Architecture != b.Architecture {
+			return a.Architecture < b.Architecture
+		}
+		if a.Version != b.Version {
+			return a.Version < b.Version
+		}
+		return a.Filename < b.Filename
+	})
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(rendered); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
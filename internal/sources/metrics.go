@@ -0,0 +1,81 @@
+package sources
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/your-username/alldownloads/internal/tracing"
+)
+
+var upstreamResponsesTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "fetch_upstream_responses_total",
+		Help: "Responses from upstream download sites by product, host and status code (\"error\" when no response arrived)",
+	},
+	[]string{"product_id", "host", "code"},
+)
+
+// Collectors returns the metrics of outgoing fetcher requests, for the
+// worker to register.
+func Collectors() []prometheus.Collector {
+	return []prometheus.Collector{upstreamResponsesTotal}
+}
+
+type productIDKey struct{}
+
+// WithProductID labels the upstream requests made with ctx as fetches of
+// productID.
+func WithProductID(ctx context.Context, productID string) context.Context {
+	return context.WithValue(ctx, productIDKey{}, productID)
+}
+
+// instrumentedTransport counts upstream responses by status code and
+// records a client span for each request.
+type instrumentedTransport struct {
+	next http.RoundTripper
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	productID, _ := req.Context().Value(productIDKey{}).(string)
+
+	ctx, span := tracing.Tracer().Start(req.Context(), req.Method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(req.Method),
+			semconv.URLFull(req.URL.Redacted()),
+			semconv.ServerAddress(req.URL.Hostname()),
+			attribute.String("product.id", productID),
+		),
+	)
+	defer span.End()
+
+	// A RoundTripper must not modify the caller's request.
+	req = req.Clone(ctx)
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+
+	resp, err := t.next.RoundTrip(req)
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+		span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode))
+		if resp.StatusCode >= http.StatusBadRequest {
+			span.SetStatus(codes.Error, resp.Status)
+		}
+	} else {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	upstreamResponsesTotal.WithLabelValues(productID, req.URL.Host, code).Inc()
+
+	return resp, err
+}
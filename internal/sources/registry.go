@@ -0,0 +1,81 @@
+package sources
+
+import "sort"
+
+// Live vendor URLs the fetchers scrape. WithBaseURL replaces them.
+const (
+	ubuntuBaseURL    = "http://releases.ubuntu.com"
+	debianBaseURL    = "https://cdimage.debian.org/debian-cd/current/amd64/iso-cd"
+	archBaseURL      = "https://mirror.rackspace.com/archlinux"
+	chromeBaseURL    = "https://versionhistory.googleapis.com"
+	firefoxBaseURL   = "https://product-details.mozilla.org"
+	vscodeBaseURL    = "https://update.code.visualstudio.com"
+	githubAPIBaseURL = "https://api.github.com"
+)
+
+type registration struct {
+	newFetcher func(opts ...Option) Fetcher
+	// baseURL is empty for fetchers that only link to fixed download URLs.
+	baseURL string
+}
+
+var registry = map[string]registration{
+	"ubuntu":          {func(opts ...Option) Fetcher { return NewUbuntuFetcher(opts...) }, ubuntuBaseURL},
+	"debian":          {func(opts ...Option) Fetcher { return NewDebianFetcher(opts...) }, debianBaseURL},
+	"arch":            {func(opts ...Option) Fetcher { return NewArchFetcher(opts...) }, archBaseURL},
+	"kali":            {func(opts ...Option) Fetcher { return NewKaliFetcher(opts...) }, ""},
+	"windows":         {func(opts ...Option) Fetcher { return NewWindowsFetcher(opts...) }, ""},
+	"termius":         {func(opts ...Option) Fetcher { return NewTermiusFetcher(opts...) }, ""},
+	"telegram":        {func(opts ...Option) Fetcher { return NewTelegramFetcher(opts...) }, githubAPIBaseURL},
+	"whatsapp":        {func(opts ...Option) Fetcher { return NewWhatsAppFetcher(opts...) }, ""},
+	"tailscale":       {func(opts ...Option) Fetcher { return NewTailscaleFetcher(opts...) }, ""},
+	"nextcloud":       {func(opts ...Option) Fetcher { return NewNextcloudFetcher(opts...) }, githubAPIBaseURL},
+	"chrome":          {func(opts ...Option) Fetcher { return NewChromeFetcher(opts...) }, chromeBaseURL},
+	"firefox":         {func(opts ...Option) Fetcher { return NewFirefoxFetcher(opts...) }, firefoxBaseURL},
+	"brave":           {func(opts ...Option) Fetcher { return NewBraveFetcher(opts...) }, githubAPIBaseURL},
+	"vscode":          {func(opts ...Option) Fetcher { return NewVSCodeFetcher(opts...) }, vscodeBaseURL},
+	"notepadplusplus": {func(opts ...Option) Fetcher { return NewNotepadPlusPlusFetcher(opts...) }, githubAPIBaseURL},
+	"powershell":      {func(opts ...Option) Fetcher { return NewPowerShellFetcher(opts...) }, githubAPIBaseURL},
+	"office":          {func(opts ...Option) Fetcher { return NewOfficeFetcher(opts...) }, ""},
+	"manual":          {func(opts ...Option) Fetcher { return NewManualFetcher(opts...) }, ""},
+}
+
+// NewFetchers returns one instance of every registered fetcher, keyed by the
+// name products reference in their fetcher column.
+func NewFetchers(opts ...Option) map[string]Fetcher {
+	fetchers := make(map[string]Fetcher, len(registry))
+	for name, r := range registry {
+		fetchers[name] = r.newFetcher(opts...)
+	}
+	return fetchers
+}
+
+// NewFetcher returns the fetcher registered under name, or false if there is
+// none.
+func NewFetcher(name string, opts ...Option) (Fetcher, bool) {
+	r, ok := registry[name]
+	if !ok {
+		return nil, false
+	}
+	return r.newFetcher(opts...), true
+}
+
+// BaseURL returns the live vendor URL the named fetcher scrapes, or "" if it
+// only links to fixed download URLs.
+func BaseURL(name string) string {
+	return registry[name].baseURL
+}
+
+func IsRegistered(name string) bool {
+	_, ok := registry[name]
+	return ok
+}
+
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
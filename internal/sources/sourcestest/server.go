@@ -0,0 +1,207 @@
+// Package sourcestest replays recorded vendor responses so that fetchers can
+// run without network access, and records those responses from the live
+// sites.
+//
+// A fixture directory holds one raw HTTP response per request, at
+// <method>/<path>, with "index" standing in for paths ending in a slash:
+// GET /24.04/SHA256SUMS is replayed from GET/24.04/SHA256SUMS and a HEAD of
+// the directory listing from HEAD/index. Query strings are ignored.
+package sourcestest
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// recordedHeaders are the response headers fetchers look at. Everything else,
+// such as dates and cookies, would only add noise to the fixtures.
+var recordedHeaders = []string{
+	"Content-Length",
+	"Content-Type",
+	"Location",
+	"X-RateLimit-Remaining",
+	"X-RateLimit-Reset",
+}
+
+// Server serves a fixture directory. Point a fetcher at it with
+// sources.WithBaseURL(s.URL) and sources.WithTransport(s.Transport()).
+type Server struct {
+	*httptest.Server
+
+	dir      string
+	upstream string
+	client   *http.Client
+
+	mu      sync.Mutex
+	missing []string
+}
+
+// Replay serves the responses recorded in dir.
+func Replay(dir string) *Server {
+	s := &Server{dir: dir}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.replay))
+	return s
+}
+
+// Record forwards requests to upstream, the vendor URL the fetcher normally
+// scrapes, and saves each response in dir before passing it on.
+func Record(dir, upstream string) *Server {
+	s := &Server{
+		dir:      dir,
+		upstream: strings.TrimSuffix(upstream, "/"),
+		client: &http.Client{
+			// Redirects are recorded as they are, so that replaying them
+			// exercises the fetcher's own handling.
+			CheckRedirect: func(*http.Request, []*http.Request) error {
+				return http.ErrUseLastResponse
+			},
+		},
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.record))
+	return s
+}
+
+// Transport reaches the server and refuses every other host, so that a
+// fetcher following a link to a vendor CDN fails quickly instead of going to
+// the network.
+func (s *Server) Transport() http.RoundTripper {
+	next := s.Client().Transport
+	host := s.Listener.Addr().String()
+
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if req.URL.Host != host {
+			return nil, fmt.Errorf("sourcestest: %s is not recorded", req.URL)
+		}
+		return next.RoundTrip(req)
+	})
+}
+
+// Missing lists the requests that had no fixture, as "METHOD /path".
+func (s *Server) Missing() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	missing := append([]string(nil), s.missing...)
+	sort.Strings(missing)
+	return missing
+}
+
+func (s *Server) fixturePath(r *http.Request) string {
+	name := path.Clean("/" + r.URL.Path)
+	if strings.HasSuffix(r.URL.Path, "/") {
+		name = path.Join(name, "index")
+	}
+	return filepath.Join(s.dir, r.Method, filepath.FromSlash(name))
+}
+
+func (s *Server) replay(w http.ResponseWriter, r *http.Request) {
+	data, err := os.ReadFile(s.fixturePath(r))
+	if errors.Is(err, os.ErrNotExist) {
+		s.mu.Lock()
+		s.missing = append(s.missing, r.Method+" "+r.URL.Path)
+		s.mu.Unlock()
+		http.Error(w, "no fixture recorded", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(data)), r)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("invalid fixture: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer resp.Body.Close()
+
+	writeResponse(w, resp)
+}
+
+func (s *Server) record(w http.ResponseWriter, r *http.Request) {
+	req, err := http.NewRequestWithContext(r.Context(), r.Method, s.upstream+r.URL.RequestURI(), nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, header := range []string{"Accept", "User-Agent"} {
+		req.Header.Set(header, r.Header.Get(header))
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	// Redirects within the vendor site must come back to this server.
+	if location := resp.Header.Get("Location"); strings.HasPrefix(location, s.upstream+"/") {
+		resp.Header.Set("Location", strings.TrimPrefix(location, s.upstream))
+	}
+	// The transport may have decompressed the body, invalidating the
+	// upstream length; replays take the length from the body instead.
+	if r.Method != http.MethodHead {
+		resp.Header.Del("Content-Length")
+	}
+
+	if err := s.save(s.fixturePath(r), resp, body); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	writeResponse(w, resp)
+}
+
+func (s *Server) save(name string, resp *http.Response, body []byte) error {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "HTTP/1.1 %s\n", resp.Status)
+	for _, header := range recordedHeaders {
+		if value := resp.Header.Get(header); value != "" {
+			fmt.Fprintf(&buf, "%s: %s\n", header, value)
+		}
+	}
+	buf.WriteString("\n")
+	buf.Write(body)
+
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		return fmt.Errorf("failed to create fixture directory: %w", err)
+	}
+	if err := os.WriteFile(name, buf.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write fixture: %w", err)
+	}
+	return nil
+}
+
+func writeResponse(w http.ResponseWriter, resp *http.Response) {
+	for _, header := range recordedHeaders {
+		if value := resp.Header.Get(header); value != "" {
+			w.Header().Set(header, value)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
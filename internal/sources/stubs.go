@@ -5,7 +5,10 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/your-username/alldownloads/internal/store"
 )
@@ -14,8 +17,9 @@ type KaliFetcher struct {
 	client *HTTPClient
 }
 
-func NewKaliFetcher() *KaliFetcher {
-	return &KaliFetcher{client: NewHTTPClient()}
+func NewKaliFetcher(opts ...Option) *KaliFetcher {
+	_, client := applyOptions("", opts)
+	return &KaliFetcher{client: client}
 }
 
 func (f *KaliFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, error) {
@@ -59,8 +63,9 @@ type WindowsFetcher struct {
 	client *HTTPClient
 }
 
-func NewWindowsFetcher() *WindowsFetcher {
-	return &WindowsFetcher{client: NewHTTPClient()}
+func NewWindowsFetcher(opts ...Option) *WindowsFetcher {
+	_, client := applyOptions("", opts)
+	return &WindowsFetcher{client: client}
 }
 
 func (f *WindowsFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, error) {
@@ -104,8 +109,9 @@ type TermiusFetcher struct {
 	client *HTTPClient
 }
 
-func NewTermiusFetcher() *TermiusFetcher {
-	return &TermiusFetcher{client: NewHTTPClient()}
+func NewTermiusFetcher(opts ...Option) *TermiusFetcher {
+	_, client := applyOptions("", opts)
+	return &TermiusFetcher{client: client}
 }
 
 func (f *TermiusFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, error) {
@@ -161,18 +167,20 @@ func (f *TermiusFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, er
 }
 
 type TelegramFetcher struct {
-	client *HTTPClient
+	client  *HTTPClient
+	baseURL string
 }
 
-func NewTelegramFetcher() *TelegramFetcher {
-	return &TelegramFetcher{client: NewHTTPClient()}
+func NewTelegramFetcher(opts ...Option) *TelegramFetcher {
+	baseURL, client := applyOptions(githubAPIBaseURL, opts)
+	return &TelegramFetcher{client: client, baseURL: baseURL}
 }
 
 func (f *TelegramFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, error) {
 	var versions []*store.ProductVersion
 
 	// Get latest release from GitHub API
-	apiURL := "https://api.github.com/repos/telegramdesktop/tdesktop/releases/latest"
+	apiURL := f.baseURL + "/repos/telegramdesktop/tdesktop/releases/latest"
 	resp, err := f.client.GetJSON(ctx, apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch Telegram release info: %w", err)
@@ -249,8 +257,9 @@ type WhatsAppFetcher struct {
 	client *HTTPClient
 }
 
-func NewWhatsAppFetcher() *WhatsAppFetcher {
-	return &WhatsAppFetcher{client: NewHTTPClient()}
+func NewWhatsAppFetcher(opts ...Option) *WhatsAppFetcher {
+	_, client := applyOptions("", opts)
+	return &WhatsAppFetcher{client: client}
 }
 
 func (f *WhatsAppFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, error) {
@@ -303,8 +312,9 @@ type TailscaleFetcher struct {
 	client *HTTPClient
 }
 
-func NewTailscaleFetcher() *TailscaleFetcher {
-	return &TailscaleFetcher{client: NewHTTPClient()}
+func NewTailscaleFetcher(opts ...Option) *TailscaleFetcher {
+	_, client := applyOptions("", opts)
+	return &TailscaleFetcher{client: client}
 }
 
 func (f *TailscaleFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, error) {
@@ -359,18 +369,20 @@ func (f *TailscaleFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion,
 }
 
 type NextcloudFetcher struct {
-	client *HTTPClient
+	client  *HTTPClient
+	baseURL string
 }
 
-func NewNextcloudFetcher() *NextcloudFetcher {
-	return &NextcloudFetcher{client: NewHTTPClient()}
+func NewNextcloudFetcher(opts ...Option) *NextcloudFetcher {
+	baseURL, client := applyOptions(githubAPIBaseURL, opts)
+	return &NextcloudFetcher{client: client, baseURL: baseURL}
 }
 
 func (f *NextcloudFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, error) {
 	var versions []*store.ProductVersion
 
 	// Get latest release from GitHub API
-	apiURL := "https://api.github.com/repos/nextcloud-releases/desktop/releases/latest"
+	apiURL := f.baseURL + "/repos/nextcloud-releases/desktop/releases/latest"
 	resp, err := f.client.GetJSON(ctx, apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch Nextcloud release info: %w", err)
@@ -444,24 +456,34 @@ func (f *NextcloudFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion,
 }
 
 type BraveFetcher struct {
-	client *HTTPClient
+	client  *HTTPClient
+	baseURL string
 }
 
-func NewBraveFetcher() *BraveFetcher {
-	return &BraveFetcher{client: NewHTTPClient()}
+func NewBraveFetcher(opts ...Option) *BraveFetcher {
+	baseURL, client := applyOptions(githubAPIBaseURL, opts)
+	return &BraveFetcher{client: client, baseURL: baseURL}
 }
 
 func (f *BraveFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, error) {
 	var versions []*store.ProductVersion
 
 	// Get latest release from GitHub API
-	apiURL := "https://api.github.com/repos/brave/brave-browser/releases/latest"
+	apiURL := f.baseURL + "/repos/brave/brave-browser/releases/latest"
 	resp, err := f.client.GetJSON(ctx, apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch Brave release info: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode != http.StatusOK {
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			reset, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+			return nil, fmt.Errorf("GitHub API rate limit exceeded until %s", time.Unix(reset, 0).UTC().Format(time.RFC3339))
+		}
+		return nil, fmt.Errorf("failed to fetch Brave release info: %s", resp.Status)
+	}
+
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read response body: %w", err)
@@ -527,18 +549,20 @@ func (f *BraveFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, erro
 }
 
 type NotepadPlusPlusFetcher struct {
-	client *HTTPClient
+	client  *HTTPClient
+	baseURL string
 }
 
-func NewNotepadPlusPlusFetcher() *NotepadPlusPlusFetcher {
-	return &NotepadPlusPlusFetcher{client: NewHTTPClient()}
+func NewNotepadPlusPlusFetcher(opts ...Option) *NotepadPlusPlusFetcher {
+	baseURL, client := applyOptions(githubAPIBaseURL, opts)
+	return &NotepadPlusPlusFetcher{client: client, baseURL: baseURL}
 }
 
 func (f *NotepadPlusPlusFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, error) {
 	var versions []*store.ProductVersion
 
 	// Get latest release from GitHub API
-	apiURL := "https://api.github.com/repos/notepad-plus-plus/notepad-plus-plus/releases/latest"
+	apiURL := f.baseURL + "/repos/notepad-plus-plus/notepad-plus-plus/releases/latest"
 	resp, err := f.client.GetJSON(ctx, apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch Notepad++ release info: %w", err)
@@ -600,18 +624,20 @@ func (f *NotepadPlusPlusFetcher) Fetch(ctx context.Context) ([]*store.ProductVer
 }
 
 type PowerShellFetcher struct {
-	client *HTTPClient
+	client  *HTTPClient
+	baseURL string
 }
 
-func NewPowerShellFetcher() *PowerShellFetcher {
-	return &PowerShellFetcher{client: NewHTTPClient()}
+func NewPowerShellFetcher(opts ...Option) *PowerShellFetcher {
+	baseURL, client := applyOptions(githubAPIBaseURL, opts)
+	return &PowerShellFetcher{client: client, baseURL: baseURL}
 }
 
 func (f *PowerShellFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, error) {
 	var versions []*store.ProductVersion
 
 	// Get latest release from GitHub API
-	apiURL := "https://api.github.com/repos/PowerShell/PowerShell/releases/latest"
+	apiURL := f.baseURL + "/repos/PowerShell/PowerShell/releases/latest"
 	resp, err := f.client.GetJSON(ctx, apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch PowerShell release info: %w", err)
@@ -652,7 +678,7 @@ func (f *PowerShellFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion,
 			}
 		}
 		// macOS PKG installers
-		if strings.Contains(asset.Name, "powershell-") && strings.Contains(asset.Name, "osx") && strings.HasSuffix(asset.Name, ".pkg") {
+		if strings.Contains(asset.Name, "powershell-") && strings.Contains(asset.Name, "osx-x64") && strings.HasSuffix(asset.Name, ".pkg") {
 			platform = store.PlatformMacOS
 			arch = store.ArchAMD64
 			matched = true
@@ -687,8 +713,9 @@ type OfficeFetcher struct {
 	client *HTTPClient
 }
 
-func NewOfficeFetcher() *OfficeFetcher {
-	return &OfficeFetcher{client: NewHTTPClient()}
+func NewOfficeFetcher(opts ...Option) *OfficeFetcher {
+	_, client := applyOptions("", opts)
+	return &OfficeFetcher{client: client}
 }
 
 func (f *OfficeFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, error) {
@@ -741,4 +768,16 @@ func (f *OfficeFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, err
 	}
 
 	return versions, nil
-}
\ No newline at end of file
+}
+
+// ManualFetcher never returns versions. Products that reference it are
+// maintained entirely through the admin version API and are not scheduled.
+type ManualFetcher struct{}
+
+func NewManualFetcher(opts ...Option) *ManualFetcher {
+	return &ManualFetcher{}
+}
+
+func (f *ManualFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, error) {
+	return nil, nil
+}
@@ -0,0 +1,81 @@
+package sources
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/your-username/alldownloads/internal/tracing"
+	"github.com/your-username/alldownloads/internal/tracing/tracingtest"
+)
+
+func TestHTTPClientTracesRequests(t *testing.T) {
+	exporter := tracingtest.NewRecorder(t)
+
+	var traceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer upstream.Close()
+
+	ctx, parent := tracing.Tracer().Start(WithProductID(context.Background(), "ubuntu"), "fetch ubuntu")
+	client := NewHTTPClient()
+
+	resp, err := client.Get(ctx, upstream.URL+"/releases")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("recorded %d spans, want 2", len(spans))
+	}
+	span, _ := tracingtest.Find(exporter, http.MethodGet)
+	if span.SpanKind != trace.SpanKindClient {
+		t.Fatalf("span kind is %v, want client", span.SpanKind)
+	}
+	if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Fatal("request span is not a child of the fetch span")
+	}
+	if want := "00-" + span.SpanContext.TraceID().String() + "-" + span.SpanContext.SpanID().String() + "-01"; traceparent != want {
+		t.Fatalf("upstream received traceparent %q, want %q", traceparent, want)
+	}
+
+	attributes := map[string]string{}
+	for _, kv := range span.Attributes {
+		attributes[string(kv.Key)] = kv.Value.Emit()
+	}
+	for key, want := range map[string]string{
+		"http.request.method":       "GET",
+		"http.response.status_code": "200",
+		"url.full":                  upstream.URL + "/releases",
+		"product.id":                "ubuntu",
+	} {
+		if attributes[key] != want {
+			t.Errorf("attribute %s = %q, want %q", key, attributes[key], want)
+		}
+	}
+
+	exporter.Reset()
+	resp, err = client.Get(context.Background(), upstream.URL+"/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	span, _ = tracingtest.Find(exporter, http.MethodGet)
+	if span.Status.Code != codes.Error {
+		t.Fatalf("404 response left span status %v", span.Status.Code)
+	}
+}
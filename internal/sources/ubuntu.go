@@ -11,19 +11,22 @@ import (
 )
 
 type UbuntuFetcher struct {
-	client *HTTPClient
+	client  *HTTPClient
+	baseURL string
 }
 
-func NewUbuntuFetcher() *UbuntuFetcher {
+func NewUbuntuFetcher(opts ...Option) *UbuntuFetcher {
+	baseURL, client := applyOptions(ubuntuBaseURL, opts)
 	return &UbuntuFetcher{
-		client: NewHTTPClient(),
+		client:  client,
+		baseURL: baseURL,
 	}
 }
 
 func (f *UbuntuFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, error) {
 	var versions []*store.ProductVersion
 
-	releasesURL := "http://releases.ubuntu.com/"
+	releasesURL := f.baseURL + "/"
 	resp, err := f.client.Get(ctx, releasesURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch Ubuntu releases: %w", err)
@@ -64,7 +67,7 @@ func (f *UbuntuFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, err
 func (f *UbuntuFetcher) fetchVersionDetails(ctx context.Context, version string) ([]*store.ProductVersion, error) {
 	var versions []*store.ProductVersion
 
-	versionURL := fmt.Sprintf("http://releases.ubuntu.com/%s/", version)
+	versionURL := fmt.Sprintf("%s/%s/", f.baseURL, version)
 	resp, err := f.client.Get(ctx, versionURL)
 	if err != nil {
 		return nil, err
@@ -109,10 +112,16 @@ func (f *UbuntuFetcher) fetchVersionDetails(ctx context.Context, version string)
 			checksum = ""
 		}
 
+		channel := store.ChannelStable
+		if isUbuntuLTS(version) {
+			channel = store.ChannelLTS
+		}
+
 		pv := &store.ProductVersion{
 			Version:      version,
 			Platform:     platform,
 			Architecture: arch,
+			Channel:      channel,
 			DownloadURL:  downloadURL,
 			Checksum:     checksum,
 			ChecksumType: "sha256",
@@ -159,4 +168,13 @@ func shouldSkipUbuntuVersion(version string) bool {
 		}
 	}
 	return false
-}
\ No newline at end of file
+}
+
+// Ubuntu LTS releases ship every two years in April: 20.04, 22.04, 24.04.
+func isUbuntuLTS(version string) bool {
+	var year, month int
+	if _, err := fmt.Sscanf(version, "%d.%d", &year, &month); err != nil {
+		return false
+	}
+	return month == 4 && year%2 == 0
+}
@@ -10,7 +10,8 @@ import (
 )
 
 type VSCodeFetcher struct {
-	client *HTTPClient
+	client  *HTTPClient
+	baseURL string
 }
 
 type VSCodeUpdate struct {
@@ -18,16 +19,18 @@ type VSCodeUpdate struct {
 	Version string `json:"name"`
 }
 
-func NewVSCodeFetcher() *VSCodeFetcher {
+func NewVSCodeFetcher(opts ...Option) *VSCodeFetcher {
+	baseURL, client := applyOptions(vscodeBaseURL, opts)
 	return &VSCodeFetcher{
-		client: NewHTTPClient(),
+		client:  client,
+		baseURL: baseURL,
 	}
 }
 
 func (f *VSCodeFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, error) {
 	var versions []*store.ProductVersion
 
-	apiURL := "https://update.code.visualstudio.com/api/update/win32-x64-user/stable/latest"
+	apiURL := f.baseURL + "/api/update/win32-x64-user/stable/latest"
 	resp, err := f.client.GetJSON(ctx, apiURL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch VS Code update info: %w", err)
@@ -46,14 +49,14 @@ func (f *VSCodeFetcher) Fetch(ctx context.Context) ([]*store.ProductVersion, err
 
 	downloadURLs := map[string]map[string]string{
 		store.PlatformWindows: {
-			store.ArchAMD64: fmt.Sprintf("https://update.code.visualstudio.com/latest/win32-x64-user/stable"),
-			store.Arch386:   fmt.Sprintf("https://update.code.visualstudio.com/latest/win32-user/stable"),
+			store.ArchAMD64: f.baseURL + "/latest/win32-x64-user/stable",
+			store.Arch386:   f.baseURL + "/latest/win32-user/stable",
 		},
 		store.PlatformMacOS: {
-			store.ArchAMD64: fmt.Sprintf("https://update.code.visualstudio.com/latest/darwin/stable"),
+			store.ArchAMD64: f.baseURL + "/latest/darwin/stable",
 		},
 		store.PlatformLinux: {
-			store.ArchAMD64: fmt.Sprintf("https://update.code.visualstudio.com/latest/linux-x64/stable"),
+			store.ArchAMD64: f.baseURL + "/latest/linux-x64/stable",
 		},
 	}
 
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+const apiKeyColumns = `id, name, prefix, key_hash, scopes, rate_limit_per_minute, expires_at, last_used_at, revoked_at, created_at`
+
+func scanAPIKey(row pgx.Row, k *APIKey) error {
+	return row.Scan(&k.ID, &k.Name, &k.Prefix, &k.KeyHash, &k.Scopes, &k.RateLimitPerMinute,
+		&k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.CreatedAt)
+}
+
+func (s *PostgresStore) CreateAPIKey(ctx context.Context, key *APIKey) error {
+	if key.ID == "" {
+		key.ID = uuid.New().String()
+	}
+	key.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO api_keys (id, name, prefix, key_hash, scopes, rate_limit_per_minute, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+
+	_, err := s.db.Exec(ctx, query, key.ID, key.Name, key.Prefix, key.KeyHash, key.Scopes,
+		key.RateLimitPerMinute, key.ExpiresAt, key.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("api key prefix %s: %w", key.Prefix, ErrConflict)
+		}
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) GetAPIKey(ctx context.Context, id string) (*APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = $1`
+
+	var k APIKey
+	if err := scanAPIKey(s.db.QueryRow(ctx, query, id), &k); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return &k, nil
+}
+
+func (s *PostgresStore) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE prefix = $1`
+
+	var k APIKey
+	if err := scanAPIKey(s.db.QueryRow(ctx, query, prefix), &k); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return &k, nil
+}
+
+func (s *PostgresStore) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY created_at DESC`
+
+	rows, err := s.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		var k APIKey
+		if err := scanAPIKey(rows, &k); err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+
+	return keys, rows.Err()
+}
+
+func (s *PostgresStore) RevokeAPIKey(ctx context.Context, id string) error {
+	tag, err := s.db.Exec(ctx, "UPDATE api_keys SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL", id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("api key %s: %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	if _, err := s.db.Exec(ctx, "UPDATE api_keys SET last_used_at = $2 WHERE id = $1", id, usedAt); err != nil {
+		return fmt.Errorf("failed to update api key last use: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const auditColumns = `id, occurred_at, actor_type, actor_id, actor_name, action, target_type, target_id, request_id, client_ip, changes`
+
+func scanAuditEntry(row pgx.Row, e *AuditEntry) error {
+	var changes []byte
+	if err := row.Scan(&e.ID, &e.OccurredAt, &e.ActorType, &e.ActorID, &e.ActorName, &e.Action,
+		&e.TargetType, &e.TargetID, &e.RequestID, &e.ClientIP, &changes); err != nil {
+		return err
+	}
+	return json.Unmarshal(changes, &e.Changes)
+}
+
+// CreateAuditEntry appends to the audit log. The table rejects updates and
+// deletes, so there are deliberately no other write methods.
+func (s *PostgresStore) CreateAuditEntry(ctx context.Context, entry *AuditEntry) error {
+	if entry.OccurredAt.IsZero() {
+		entry.OccurredAt = time.Now()
+	}
+	if entry.Changes == nil {
+		entry.Changes = map[string]AuditChange{}
+	}
+
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit changes: %w", err)
+	}
+
+	query := `
+		INSERT INTO audit_log (occurred_at, actor_type, actor_id, actor_name, action, target_type, target_id, request_id, client_ip, changes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`
+
+	err = s.db.QueryRow(ctx, query, entry.OccurredAt, entry.ActorType, entry.ActorID, entry.ActorName,
+		entry.Action, entry.TargetType, entry.TargetID, entry.RequestID, entry.ClientIP, changes).Scan(&entry.ID)
+	if err != nil {
+		return fmt.Errorf("failed to create audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// ListAuditEntries returns entries newest first, paginated by id.
+func (s *PostgresStore) ListAuditEntries(ctx context.Context, filter AuditFilter) (*AuditPage, error) {
+	limit := clampLimit(filter.Limit)
+
+	var conditions []string
+	var args []interface{}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.ActorID != "" {
+		conditions = append(conditions, "actor_id = "+addArg(filter.ActorID))
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = "+addArg(filter.Action))
+	}
+	if filter.TargetType != "" {
+		conditions = append(conditions, "target_type = "+addArg(filter.TargetType))
+	}
+	if filter.TargetID != "" {
+		conditions = append(conditions, "target_id = "+addArg(filter.TargetID))
+	}
+	if filter.RequestID != "" {
+		conditions = append(conditions, "request_id = "+addArg(filter.RequestID))
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "occurred_at >= "+addArg(*filter.Since))
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "occurred_at < "+addArg(*filter.Until))
+	}
+	if filter.Cursor != "" {
+		values, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != 1 {
+			return nil, ErrInvalidCursor
+		}
+		id, err := strconv.ParseInt(values[0], 10, 64)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		conditions = append(conditions, "id < "+addArg(id))
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_log`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT " + addArg(limit+1)
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		if err := scanAuditEntry(rows, &e); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate audit log: %w", err)
+	}
+
+	page := &AuditPage{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.NextCursor = encodeCursor([]string{strconv.FormatInt(page.Entries[limit-1].ID, 10)})
+	}
+
+	return page, nil
+}
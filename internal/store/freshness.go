@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RecordFetchSuccess stores when productID last fetched successfully and
+// clears its stale flag. updated_at only moves when the product recovers, so
+// that validators change with the flag but not on every scheduled fetch. It
+// reports whether the product was stale until now, and false when the
+// product no longer exists.
+func (s *PostgresStore) RecordFetchSuccess(ctx context.Context, productID string, at time.Time) (bool, error) {
+	query := `
+		UPDATE products p
+		SET last_success_at = $2, stale = FALSE, stale_since = NULL,
+			updated_at = CASE WHEN old.stale THEN NOW() ELSE p.updated_at END
+		FROM (SELECT id, stale FROM products WHERE id = $1 FOR UPDATE) old
+		WHERE p.id = old.id
+		RETURNING old.stale
+	`
+
+	var wasStale bool
+	if err := s.db.QueryRow(ctx, query, productID, at).Scan(&wasStale); err != nil {
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to record fetch success: %w", err)
+	}
+
+	return wasStale, nil
+}
+
+// MarkProductStale flags productID as stale since the given time unless it
+// already is. It reports whether this call set the flag, so that when
+// several workers evaluate freshness only one of them raises the alert.
+// since is usually in the past, so updated_at is what tells clients holding
+// a validator that the flag changed.
+func (s *PostgresStore) MarkProductStale(ctx context.Context, productID string, since time.Time) (bool, error) {
+	tag, err := s.db.Exec(ctx, `UPDATE products SET stale = TRUE, stale_since = $2, updated_at = NOW() WHERE id = $1 AND NOT stale`, productID, since)
+	if err != nil {
+		return false, fmt.Errorf("failed to mark product stale: %w", err)
+	}
+
+	return tag.RowsAffected() == 1, nil
+}
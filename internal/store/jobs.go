@@ -0,0 +1,181 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const fetchJobColumns = `id, product_id, status, started_at, completed_at, coalesce(error, ''), created_at, updated_at`
+
+func scanFetchJob(row pgx.Row, job *FetchJob) error {
+	return row.Scan(&job.ID, &job.ProductID, &job.Status, &job.StartedAt, &job.CompletedAt, &job.Error, &job.CreatedAt, &job.UpdatedAt)
+}
+
+var jobSort = sortSpec{name: "created_at", columns: []string{"created_at", "id"}, casts: []string{"timestamptz", "uuid"}, desc: true}
+
+// ListFetchJobs returns jobs newest first.
+func (s *PostgresStore) ListFetchJobs(ctx context.Context, filter JobFilter) (*JobPage, error) {
+	limit := clampLimit(filter.Limit)
+
+	var conditions []string
+	var args []interface{}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.ProductID != "" {
+		conditions = append(conditions, "j.product_id = "+addArg(filter.ProductID))
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "j.status = "+addArg(filter.Status))
+	}
+	if filter.Cursor != "" {
+		condition, cursorArgs, err := jobSort.keysetCondition("j", filter.Cursor, len(args))
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, cursorArgs...)
+	}
+
+	query := `SELECT ` + fetchJobColumns + ` FROM fetch_jobs j`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY " + jobSort.orderBy("j")
+	query += " LIMIT " + addArg(limit+1)
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fetch jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []FetchJob{}
+	for rows.Next() {
+		var job FetchJob
+		if err := scanFetchJob(rows, &job); err != nil {
+			return nil, fmt.Errorf("failed to scan fetch job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate fetch jobs: %w", err)
+	}
+
+	page := &JobPage{Jobs: jobs}
+	if len(jobs) > limit {
+		page.Jobs = jobs[:limit]
+		last := page.Jobs[limit-1]
+		page.NextCursor = jobSort.cursor([]string{last.CreatedAt.UTC().Format(time.RFC3339Nano), last.ID})
+	}
+
+	return page, nil
+}
+
+// GetRecentFetchJobs returns up to perProduct of the newest jobs of each of
+// productIDs in a single query, keyed by product ID.
+func (s *PostgresStore) GetRecentFetchJobs(ctx context.Context, productIDs []string, perProduct int) (map[string][]FetchJob, error) {
+	query := `
+		SELECT ` + fetchJobColumns + `
+		FROM (
+			SELECT *, row_number() OVER (PARTITION BY product_id ORDER BY created_at DESC, id DESC) AS rn
+			FROM fetch_jobs
+			WHERE product_id = ANY($1)
+		) j
+		WHERE rn <= $2
+		ORDER BY product_id, created_at DESC, id DESC
+	`
+
+	rows, err := s.db.Query(ctx, query, productIDs, perProduct)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent fetch jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := make(map[string][]FetchJob, len(productIDs))
+	for rows.Next() {
+		var job FetchJob
+		if err := scanFetchJob(rows, &job); err != nil {
+			return nil, fmt.Errorf("failed to scan fetch job: %w", err)
+		}
+		jobs[job.ProductID] = append(jobs[job.ProductID], job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate fetch jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// ListDeadFetchJobs returns the newest job of each product when it failed,
+// or when it has been running since before runningBefore.
+func (s *PostgresStore) ListDeadFetchJobs(ctx context.Context, runningBefore time.Time) ([]FetchJob, error) {
+	query := `
+		SELECT ` + fetchJobColumns + `
+		FROM (
+			SELECT *, row_number() OVER (PARTITION BY product_id ORDER BY created_at DESC, id DESC) AS rn
+			FROM fetch_jobs
+		) j
+		WHERE rn = 1 AND (status = $1 OR (status = $2 AND started_at < $3))
+		ORDER BY created_at, id
+	`
+
+	rows, err := s.db.Query(ctx, query, JobStatusFailed, JobStatusRunning, runningBefore)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead fetch jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []FetchJob{}
+	for rows.Next() {
+		var job FetchJob
+		if err := scanFetchJob(rows, &job); err != nil {
+			return nil, fmt.Errorf("failed to scan fetch job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate fetch jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// GetLastSuccessfulFetches returns when each product's most recent
+// completed fetch finished, keyed by product ID. Products that never
+// completed a fetch are absent.
+func (s *PostgresStore) GetLastSuccessfulFetches(ctx context.Context) (map[string]time.Time, error) {
+	query := `
+		SELECT product_id, max(completed_at)
+		FROM fetch_jobs
+		WHERE status = $1 AND completed_at IS NOT NULL
+		GROUP BY product_id
+	`
+
+	rows, err := s.db.Query(ctx, query, JobStatusCompleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query last successful fetches: %w", err)
+	}
+	defer rows.Close()
+
+	fetches := make(map[string]time.Time)
+	for rows.Next() {
+		var productID string
+		var completedAt time.Time
+		if err := rows.Scan(&productID, &completedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan last successful fetch: %w", err)
+		}
+		fetches[productID] = completedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate last successful fetches: %w", err)
+	}
+
+	return fetches, nil
+}
@@ -5,33 +5,44 @@ import (
 )
 
 type Product struct {
-	ID          string    `json:"id" db:"id"`
-	Name        string    `json:"name" db:"name"`
-	Vendor      string    `json:"vendor" db:"vendor"`
-	Category    string    `json:"category" db:"category"`
-	Description string    `json:"description" db:"description"`
-	IconURL     string    `json:"icon_url" db:"icon_url"`
-	WebsiteURL  string    `json:"website_url" db:"website_url"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID          string `json:"id" db:"id"`
+	Name        string `json:"name" db:"name"`
+	Vendor      string `json:"vendor" db:"vendor"`
+	Category    string `json:"category" db:"category"`
+	Description string `json:"description" db:"description"`
+	IconURL     string `json:"icon_url" db:"icon_url"`
+	WebsiteURL  string `json:"website_url" db:"website_url"`
+	Fetcher     string `json:"fetcher" db:"fetcher"`
+	// MaxAgeSeconds is the freshness SLO; 0 uses the configured default.
+	MaxAgeSeconds int `json:"max_age_seconds" db:"max_age_seconds"`
+	// LastSuccessAt, Stale and StaleSince are maintained by the worker.
+	LastSuccessAt *time.Time `json:"last_success_at" db:"last_success_at"`
+	Stale         bool       `json:"stale" db:"stale"`
+	StaleSince    *time.Time `json:"stale_since,omitempty" db:"stale_since"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
 }
 
 type ProductVersion struct {
-	ID          string    `json:"id" db:"id"`
-	ProductID   string    `json:"product_id" db:"product_id"`
-	Version     string    `json:"version" db:"version"`
-	Platform    string    `json:"platform" db:"platform"`
-	Architecture string   `json:"architecture" db:"architecture"`
-	DownloadURL string    `json:"download_url" db:"download_url"`
-	Checksum    string    `json:"checksum" db:"checksum"`
-	ChecksumType string   `json:"checksum_type" db:"checksum_type"`
-	FileSize    int64     `json:"file_size" db:"file_size"`
-	Filename    string    `json:"filename" db:"filename"`
-	IsLatest    bool      `json:"is_latest" db:"is_latest"`
-	ETag        string    `json:"etag" db:"etag"`
-	LastFetched time.Time `json:"last_fetched" db:"last_fetched"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID           string    `json:"id" db:"id"`
+	ProductID    string    `json:"product_id" db:"product_id"`
+	Version      string    `json:"version" db:"version"`
+	Platform     string    `json:"platform" db:"platform"`
+	Architecture string    `json:"architecture" db:"architecture"`
+	Channel      string    `json:"channel" db:"channel"`
+	DownloadURL  string    `json:"download_url" db:"download_url"`
+	Checksum     string    `json:"checksum" db:"checksum"`
+	ChecksumType string    `json:"checksum_type" db:"checksum_type"`
+	FileSize     int64     `json:"file_size" db:"file_size"`
+	Filename     string    `json:"filename" db:"filename"`
+	IsLatest     bool      `json:"is_latest" db:"is_latest"`
+	Source       string    `json:"source" db:"source"`
+	Pinned       bool      `json:"pinned" db:"pinned"`
+	Hidden       bool      `json:"hidden" db:"hidden"`
+	ETag         string    `json:"etag" db:"etag"`
+	LastFetched  time.Time `json:"last_fetched" db:"last_fetched"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type ProductWithVersions struct {
@@ -40,14 +51,14 @@ type ProductWithVersions struct {
 }
 
 type FetchJob struct {
-	ID          string    `json:"id" db:"id"`
-	ProductID   string    `json:"product_id" db:"product_id"`
-	Status      string    `json:"status" db:"status"`
+	ID          string     `json:"id" db:"id"`
+	ProductID   string     `json:"product_id" db:"product_id"`
+	Status      string     `json:"status" db:"status"`
 	StartedAt   *time.Time `json:"started_at" db:"started_at"`
 	CompletedAt *time.Time `json:"completed_at" db:"completed_at"`
-	Error       string    `json:"error" db:"error"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	Error       string     `json:"error" db:"error"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
 }
 
 const (
@@ -70,9 +81,137 @@ const (
 	PlatformWeb     = "web"
 )
 
+// FetcherManual is the fetcher of products maintained entirely through the
+// admin version API.
+const FetcherManual = "manual"
+
+// Scheduled reports whether the worker fetches p. Manually maintained
+// products are neither refreshed nor held to the freshness SLO.
+func (p *Product) Scheduled() bool {
+	return p.Fetcher != FetcherManual
+}
+
+const (
+	VersionSourceFetcher = "fetcher"
+	VersionSourceManual  = "manual"
+)
+
+const (
+	ChannelStable = "stable"
+	ChannelLTS    = "lts"
+	ChannelBeta   = "beta"
+)
+
 const (
 	ArchAMD64 = "amd64"
 	ArchARM64 = "arm64"
 	Arch386   = "386"
 	ArchARM   = "arm"
-)
\ No newline at end of file
+)
+
+// Validator summarises when the rows behind a response last changed, for
+// HTTP conditional requests. Rows catches deletions, which leave no
+// timestamp behind.
+type Validator struct {
+	LastModified time.Time
+	Rows         int64
+}
+
+type ProductFilter struct {
+	Category     string
+	Vendor       string
+	Platform     string
+	Architecture string
+	Search       string
+	Sort         string
+	Cursor       string
+	Limit        int
+}
+
+type ProductPage struct {
+	Products   []Product `json:"products"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+type VersionFilter struct {
+	ProductID    string
+	Platform     string
+	Architecture string
+	Channel      string
+	LatestOnly   bool
+	UpdatedSince *time.Time
+	HasChecksum  *bool
+	// IncludeHidden returns versions an admin has hidden from public listings.
+	IncludeHidden bool
+	Sort          string
+	Cursor        string
+	Limit         int
+}
+
+type VersionPage struct {
+	Versions   []ProductVersion `json:"versions"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+type JobFilter struct {
+	ProductID string
+	Status    string
+	Cursor    string
+	Limit     int
+}
+
+type JobPage struct {
+	Jobs       []FetchJob `json:"jobs"`
+	NextCursor string     `json:"next_cursor,omitempty"`
+}
+
+type APIKey struct {
+	ID                 string     `json:"id" db:"id"`
+	Name               string     `json:"name" db:"name"`
+	Prefix             string     `json:"prefix" db:"prefix"`
+	KeyHash            string     `json:"-" db:"key_hash"`
+	Scopes             []string   `json:"scopes" db:"scopes"`
+	RateLimitPerMinute int        `json:"rate_limit_per_minute" db:"rate_limit_per_minute"`
+	ExpiresAt          *time.Time `json:"expires_at" db:"expires_at"`
+	LastUsedAt         *time.Time `json:"last_used_at" db:"last_used_at"`
+	RevokedAt          *time.Time `json:"revoked_at" db:"revoked_at"`
+	CreatedAt          time.Time  `json:"created_at" db:"created_at"`
+}
+
+// AuditChange is the value of one field before and after a change. Before is
+// null for creations and After is null for deletions.
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type AuditEntry struct {
+	ID         int64                  `json:"id" db:"id"`
+	OccurredAt time.Time              `json:"occurred_at" db:"occurred_at"`
+	ActorType  string                 `json:"actor_type" db:"actor_type"`
+	ActorID    string                 `json:"actor_id" db:"actor_id"`
+	ActorName  string                 `json:"actor_name" db:"actor_name"`
+	Action     string                 `json:"action" db:"action"`
+	TargetType string                 `json:"target_type" db:"target_type"`
+	TargetID   string                 `json:"target_id" db:"target_id"`
+	RequestID  string                 `json:"request_id" db:"request_id"`
+	ClientIP   string                 `json:"client_ip" db:"client_ip"`
+	Changes    map[string]AuditChange `json:"changes" db:"changes"`
+}
+
+type AuditFilter struct {
+	ActorID    string
+	Action     string
+	TargetType string
+	TargetID   string
+	RequestID  string
+	Since      *time.Time
+	Until      *time.Time
+	Cursor     string
+	Limit      int
+}
+
+type AuditPage struct {
+	Entries    []AuditEntry `json:"entries"`
+	NextCursor string       `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("invalid sort")
+)
+
+// sortSpec describes a keyset ordering. The last column must be unique so
+// that a cursor always identifies exactly one position in the result set.
+type sortSpec struct {
+	name    string
+	columns []string
+	casts   []string
+	desc    bool
+}
+
+// key names the ordering as the sort parameter does, such as -updated_at.
+func (s sortSpec) key() string {
+	if s.desc {
+		return "-" + s.name
+	}
+	return s.name
+}
+
+func (s sortSpec) orderBy(alias string) string {
+	direction := "ASC"
+	if s.desc {
+		direction = "DESC"
+	}
+
+	parts := make([]string, len(s.columns))
+	for i, column := range s.columns {
+		parts[i] = fmt.Sprintf("%s.%s %s", alias, column, direction)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// cursor encodes the position after a row with the given column values. The
+// sort is recorded too, so that a cursor is only accepted for the ordering
+// it was issued for.
+func (s sortSpec) cursor(values []string) string {
+	return encodeCursor(append([]string{s.key()}, values...))
+}
+
+// parseCursor decodes a cursor issued by cursor for this ordering and
+// converts each value to its column's type, so that a tampered cursor is
+// ErrInvalidCursor rather than a failed cast in the database.
+func (s sortSpec) parseCursor(cursor string) ([]interface{}, error) {
+	values, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != len(s.columns)+1 || values[0] != s.key() {
+		return nil, ErrInvalidCursor
+	}
+
+	args := make([]interface{}, len(s.columns))
+	for i, value := range values[1:] {
+		switch s.casts[i] {
+		case "timestamptz":
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err != nil {
+				return nil, ErrInvalidCursor
+			}
+			args[i] = t
+		case "uuid":
+			if _, err := uuid.Parse(value); err != nil {
+				return nil, ErrInvalidCursor
+			}
+			args[i] = value
+		default:
+			args[i] = value
+		}
+	}
+	return args, nil
+}
+
+// keysetCondition returns a row comparison such as
+// (p.vendor, p.name, p.id) > ($4, $5, $6) and the argument values for it.
+func (s sortSpec) keysetCondition(alias string, cursor string, argOffset int) (string, []interface{}, error) {
+	args, err := s.parseCursor(cursor)
+	if err != nil {
+		return "", nil, err
+	}
+
+	columns := make([]string, len(s.columns))
+	placeholders := make([]string, len(s.columns))
+	for i, column := range s.columns {
+		columns[i] = alias + "." + column
+		placeholders[i] = fmt.Sprintf("$%d", argOffset+i+1)
+		if s.casts[i] != "" {
+			placeholders[i] += "::" + s.casts[i]
+		}
+	}
+
+	operator := ">"
+	if s.desc {
+		operator = "<"
+	}
+
+	condition := fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), operator, strings.Join(placeholders, ", "))
+	return condition, args, nil
+}
+
+func parseSort(sort string, specs map[string]sortSpec, defaultSort string) (sortSpec, error) {
+	if sort == "" {
+		sort = defaultSort
+	}
+
+	desc := strings.HasPrefix(sort, "-")
+	name := strings.TrimPrefix(sort, "-")
+	spec, ok := specs[name]
+	if !ok {
+		return sortSpec{}, fmt.Errorf("%w: %s", ErrInvalidSort, sort)
+	}
+	spec.name = name
+	spec.desc = desc
+	return spec, nil
+}
+
+func encodeCursor(values []string) string {
+	data, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(cursor string) ([]string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var values []string
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return values, nil
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		return MaxPageSize
+	}
+	return limit
+}
+
+// buildPrefixTSQuery turns free text into a to_tsquery expression that
+// matches every term as a prefix, so "ubu desk" finds "Ubuntu Desktop".
+func buildPrefixTSQuery(search string) string {
+	terms := searchTerms(search)
+	for i, term := range terms {
+		terms[i] = "'" + term + "':*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+// searchTerms splits free text into lower-cased words, dropping the
+// punctuation that has a meaning in query syntax.
+func searchTerms(search string) []string {
+	var terms []string
+	for _, word := range strings.Fields(search) {
+		term := strings.Map(func(r rune) rune {
+			if r == '-' || r == '_' || r == '.' || r == '+' {
+				return r
+			}
+			if (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r > 127 {
+				return r
+			}
+			return -1
+		}, word)
+		term = strings.Trim(term, "-_.+")
+		if term == "" {
+			continue
+		}
+		terms = append(terms, strings.ToLower(term))
+	}
+	return terms
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseCursor(t *testing.T) {
+	byUpdated, err := parseSort("-updated_at", versionSorts, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	updated := time.Date(2024, 1, 15, 10, 30, 0, 123456789, time.UTC)
+	id := "0b6c3f5e-8d0a-4d7e-9f1c-2a4b6c8d0e1f"
+
+	args, err := byUpdated.parseCursor(byUpdated.cursor([]string{updated.Format(time.RFC3339Nano), id}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(args) != 2 || !args[0].(time.Time).Equal(updated) || args[1] != id {
+		t.Fatalf("parseCursor = %v, want [%v %s]", args, updated, id)
+	}
+
+	byProduct, _ := parseSort("product", versionSorts, "")
+	ascending, _ := parseSort("updated_at", versionSorts, "")
+	for _, tt := range []struct {
+		name   string
+		cursor string
+	}{
+		{"not base64", "not a cursor"},
+		{"not JSON", encodeBase64("[\"-updated_at\",")},
+		{"not a list of strings", encodeBase64(`{"sort":"-updated_at"}`)},
+		{"empty", ""},
+		{"issued for another sort", byProduct.cursor([]string{"ubuntu", "linux", "amd64", id})},
+		{"issued for the other direction", ascending.cursor([]string{updated.Format(time.RFC3339Nano), id})},
+		{"missing a value", byUpdated.cursor([]string{updated.Format(time.RFC3339Nano)})},
+		{"an extra value", byUpdated.cursor([]string{updated.Format(time.RFC3339Nano), id, id})},
+		{"a bad timestamp", byUpdated.cursor([]string{"yesterday", id})},
+		{"a bad UUID", byUpdated.cursor([]string{updated.Format(time.RFC3339Nano), "1 OR 1=1"})},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if args, err := byUpdated.parseCursor(tt.cursor); !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("parseCursor = %v, %v, want ErrInvalidCursor", args, err)
+			}
+		})
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	for _, tt := range []struct {
+		sort      string
+		values    []string
+		condition string
+	}{
+		{"vendor", []string{"Canonical", "Ubuntu", "ubuntu"}, "(p.vendor, p.name, p.id) > ($3, $4, $5)"},
+		{"-name", []string{"Ubuntu", "ubuntu"}, "(p.name, p.id) < ($3, $4)"},
+		{"-updated_at", []string{"2024-01-15T10:30:00Z", "ubuntu"}, "(p.updated_at, p.id) < ($3::timestamptz, $4)"},
+	} {
+		t.Run(tt.sort, func(t *testing.T) {
+			spec, err := parseSort(tt.sort, productSorts, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			condition, args, err := spec.keysetCondition("p", spec.cursor(tt.values), 2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if condition != tt.condition {
+				t.Errorf("condition = %q, want %q", condition, tt.condition)
+			}
+			if len(args) != len(tt.values) {
+				t.Errorf("args = %v, want one per value of %v", args, tt.values)
+			}
+		})
+	}
+
+	spec, _ := parseSort("name", productSorts, "")
+	if _, _, err := spec.keysetCondition("p", "not a cursor", 0); !errors.Is(err, ErrInvalidCursor) {
+		t.Fatalf("keysetCondition with a bad cursor = %v, want ErrInvalidCursor", err)
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	spec, err := parseSort("", productSorts, "vendor")
+	if err != nil || spec.key() != "vendor" || spec.desc {
+		t.Fatalf("default sort = %+v, %v", spec, err)
+	}
+	spec, err = parseSort("-updated_at", productSorts, "vendor")
+	if err != nil || spec.key() != "-updated_at" || !spec.desc {
+		t.Fatalf("descending sort = %+v, %v", spec, err)
+	}
+	for _, sort := range []string{"price", "--name", "name-", "Name"} {
+		if _, err := parseSort(sort, productSorts, "vendor"); !errors.Is(err, ErrInvalidSort) {
+			t.Errorf("parseSort(%q) = %v, want ErrInvalidSort", sort, err)
+		}
+	}
+}
+
+func TestClampLimit(t *testing.T) {
+	for limit, want := range map[int]int{-1: DefaultPageSize, 0: DefaultPageSize, 1: 1, MaxPageSize: MaxPageSize, MaxPageSize + 1: MaxPageSize} {
+		if got := clampLimit(limit); got != want {
+			t.Errorf("clampLimit(%d) = %d, want %d", limit, got, want)
+		}
+	}
+}
+
+func encodeBase64(s string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(s))
+}
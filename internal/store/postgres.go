@@ -2,12 +2,20 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/google/uuid"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+	ErrConflict = errors.New("already exists")
 )
 
 type PostgresStore struct {
@@ -24,6 +32,7 @@ func NewPostgresStore(databaseURL string) (*PostgresStore, error) {
 	config.MinConns = 5
 	config.MaxConnLifetime = time.Hour
 	config.MaxConnIdleTime = time.Minute * 30
+	config.ConnConfig.Tracer = queryTracer{}
 
 	db, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
@@ -41,9 +50,38 @@ func (s *PostgresStore) Close() {
 	s.db.Close()
 }
 
+// Ping acquires a pooled connection and round-trips to the database.
+func (s *PostgresStore) Ping(ctx context.Context) error {
+	return s.db.Ping(ctx)
+}
+
+// Driver names the backend.
+func (s *PostgresStore) Driver() string {
+	return "postgres"
+}
+
+// Stats reports the connection pool's current usage.
+func (s *PostgresStore) Stats() PoolStats {
+	stat := s.db.Stat()
+	return PoolStats{
+		TotalConns:    stat.TotalConns(),
+		AcquiredConns: stat.AcquiredConns(),
+		IdleConns:     stat.IdleConns(),
+		MaxConns:      stat.MaxConns(),
+	}
+}
+
+const productColumns = `id, name, vendor, category, description, icon_url, website_url, fetcher, max_age_seconds,
+		       last_success_at, stale, stale_since, created_at, updated_at`
+
+func scanProduct(row pgx.Row, p *Product) error {
+	return row.Scan(&p.ID, &p.Name, &p.Vendor, &p.Category, &p.Description, &p.IconURL, &p.WebsiteURL, &p.Fetcher,
+		&p.MaxAgeSeconds, &p.LastSuccessAt, &p.Stale, &p.StaleSince, &p.CreatedAt, &p.UpdatedAt)
+}
+
 func (s *PostgresStore) GetProducts(ctx context.Context) ([]Product, error) {
 	query := `
-		SELECT id, name, vendor, category, description, icon_url, website_url, created_at, updated_at
+		SELECT ` + productColumns + `
 		FROM products
 		ORDER BY vendor, name
 	`
@@ -57,7 +95,7 @@ func (s *PostgresStore) GetProducts(ctx context.Context) ([]Product, error) {
 	var products []Product
 	for rows.Next() {
 		var p Product
-		err := rows.Scan(&p.ID, &p.Name, &p.Vendor, &p.Category, &p.Description, &p.IconURL, &p.WebsiteURL, &p.CreatedAt, &p.UpdatedAt)
+		err := scanProduct(rows, &p)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan product: %w", err)
 		}
@@ -67,15 +105,127 @@ func (s *PostgresStore) GetProducts(ctx context.Context) ([]Product, error) {
 	return products, nil
 }
 
+var productSorts = map[string]sortSpec{
+	"vendor":     {columns: []string{"vendor", "name", "id"}, casts: []string{"", "", ""}},
+	"name":       {columns: []string{"name", "id"}, casts: []string{"", ""}},
+	"updated_at": {columns: []string{"updated_at", "id"}, casts: []string{"timestamptz", ""}},
+}
+
+// productSearchVector must match the expression of idx_products_search so
+// that free-text queries can use the GIN index.
+const productSearchVector = `to_tsvector('simple', coalesce(p.name, '') || ' ' || coalesce(p.vendor, '') || ' ' || coalesce(p.description, ''))`
+
+func (s *PostgresStore) ListProducts(ctx context.Context, filter ProductFilter) (*ProductPage, error) {
+	sort, err := parseSort(filter.Sort, productSorts, "vendor")
+	if err != nil {
+		return nil, err
+	}
+	limit := clampLimit(filter.Limit)
+
+	var conditions []string
+	var args []interface{}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Category != "" {
+		conditions = append(conditions, "p.category = "+addArg(filter.Category))
+	}
+	if filter.Vendor != "" {
+		conditions = append(conditions, "lower(p.vendor) = lower("+addArg(filter.Vendor)+")")
+	}
+	if filter.Platform != "" || filter.Architecture != "" {
+		versionConditions := []string{"pv.product_id = p.id", "NOT pv.hidden"}
+		if filter.Platform != "" {
+			versionConditions = append(versionConditions, "pv.platform = "+addArg(filter.Platform))
+		}
+		if filter.Architecture != "" {
+			versionConditions = append(versionConditions, "pv.architecture = "+addArg(filter.Architecture))
+		}
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM product_versions pv WHERE "+strings.Join(versionConditions, " AND ")+")")
+	}
+	if filter.Search != "" {
+		tsquery := buildPrefixTSQuery(filter.Search)
+		if tsquery == "" {
+			return &ProductPage{Products: []Product{}}, nil
+		}
+		conditions = append(conditions, productSearchVector+" @@ to_tsquery('simple', "+addArg(tsquery)+")")
+	}
+	if filter.Cursor != "" {
+		condition, cursorArgs, err := sort.keysetCondition("p", filter.Cursor, len(args))
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, cursorArgs...)
+	}
+
+	query := `
+		SELECT ` + productColumns + `
+		FROM products p
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY " + sort.orderBy("p")
+	query += " LIMIT " + addArg(limit+1)
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
+	}
+	defer rows.Close()
+
+	products := []Product{}
+	for rows.Next() {
+		var p Product
+		err := scanProduct(rows, &p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate products: %w", err)
+	}
+
+	page := &ProductPage{Products: products}
+	if len(products) > limit {
+		page.Products = products[:limit]
+		last := page.Products[limit-1]
+		page.NextCursor = sort.cursor(productSortValues(last, sort))
+	}
+
+	return page, nil
+}
+
+func productSortValues(p Product, sort sortSpec) []string {
+	values := make([]string, len(sort.columns))
+	for i, column := range sort.columns {
+		switch column {
+		case "vendor":
+			values[i] = p.Vendor
+		case "name":
+			values[i] = p.Name
+		case "updated_at":
+			values[i] = p.UpdatedAt.UTC().Format(time.RFC3339Nano)
+		case "id":
+			values[i] = p.ID
+		}
+	}
+	return values
+}
+
 func (s *PostgresStore) GetProduct(ctx context.Context, id string) (*Product, error) {
 	query := `
-		SELECT id, name, vendor, category, description, icon_url, website_url, created_at, updated_at
+		SELECT ` + productColumns + `
 		FROM products
 		WHERE id = $1
 	`
 
 	var p Product
-	err := s.db.QueryRow(ctx, query, id).Scan(&p.ID, &p.Name, &p.Vendor, &p.Category, &p.Description, &p.IconURL, &p.WebsiteURL, &p.CreatedAt, &p.UpdatedAt)
+	err := scanProduct(s.db.QueryRow(ctx, query, id), &p)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -106,12 +256,66 @@ func (s *PostgresStore) GetProductWithVersions(ctx context.Context, id string) (
 	}, nil
 }
 
+// GetProductsValidator summarises the rows behind the product list. Version
+// timestamps are included because the platform filters and hidden flags
+// depend on them.
+func (s *PostgresStore) GetProductsValidator(ctx context.Context) (*Validator, error) {
+	query := `
+		SELECT GREATEST(
+		           (SELECT max(GREATEST(updated_at, last_success_at, stale_since)) FROM products),
+		           (SELECT max(updated_at) FROM product_versions)
+		       ),
+		       (SELECT count(*) FROM products) + (SELECT count(*) FROM product_versions)
+	`
+
+	var lastModified *time.Time
+	var v Validator
+	if err := s.db.QueryRow(ctx, query).Scan(&lastModified, &v.Rows); err != nil {
+		return nil, fmt.Errorf("failed to get products validator: %w", err)
+	}
+	if lastModified != nil {
+		v.LastModified = *lastModified
+	}
+
+	return &v, nil
+}
+
+// GetProductValidator summarises the rows behind GetProductWithVersions. It
+// returns nil when the product does not exist.
+func (s *PostgresStore) GetProductValidator(ctx context.Context, id string) (*Validator, error) {
+	query := `
+		SELECT GREATEST(p.updated_at, p.last_success_at, p.stale_since, max(v.updated_at), max(v.last_fetched)), count(v.id)
+		FROM products p
+		LEFT JOIN product_versions v ON v.product_id = p.id AND NOT v.hidden
+		WHERE p.id = $1
+		GROUP BY p.id
+	`
+
+	var v Validator
+	if err := s.db.QueryRow(ctx, query, id).Scan(&v.LastModified, &v.Rows); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get product validator: %w", err)
+	}
+
+	return &v, nil
+}
+
+const productVersionColumns = `id, product_id, version, platform, architecture, channel, download_url, checksum, checksum_type,
+		       file_size, filename, is_latest, source, pinned, hidden, etag, last_fetched, created_at, updated_at`
+
+func scanProductVersion(row pgx.Row, v *ProductVersion) error {
+	return row.Scan(&v.ID, &v.ProductID, &v.Version, &v.Platform, &v.Architecture, &v.Channel,
+		&v.DownloadURL, &v.Checksum, &v.ChecksumType, &v.FileSize, &v.Filename,
+		&v.IsLatest, &v.Source, &v.Pinned, &v.Hidden, &v.ETag, &v.LastFetched, &v.CreatedAt, &v.UpdatedAt)
+}
+
 func (s *PostgresStore) GetProductVersions(ctx context.Context, productID string) ([]ProductVersion, error) {
 	query := `
-		SELECT id, product_id, version, platform, architecture, download_url, checksum, checksum_type,
-		       file_size, filename, is_latest, etag, last_fetched, created_at, updated_at
+		SELECT ` + productVersionColumns + `
 		FROM product_versions
-		WHERE product_id = $1
+		WHERE product_id = $1 AND NOT hidden
 		ORDER BY is_latest DESC, created_at DESC
 	`
 
@@ -124,10 +328,7 @@ func (s *PostgresStore) GetProductVersions(ctx context.Context, productID string
 	var versions []ProductVersion
 	for rows.Next() {
 		var v ProductVersion
-		err := rows.Scan(&v.ID, &v.ProductID, &v.Version, &v.Platform, &v.Architecture,
-			&v.DownloadURL, &v.Checksum, &v.ChecksumType, &v.FileSize, &v.Filename,
-			&v.IsLatest, &v.ETag, &v.LastFetched, &v.CreatedAt, &v.UpdatedAt)
-		if err != nil {
+		if err := scanProductVersion(rows, &v); err != nil {
 			return nil, fmt.Errorf("failed to scan product version: %w", err)
 		}
 		versions = append(versions, v)
@@ -136,6 +337,180 @@ func (s *PostgresStore) GetProductVersions(ctx context.Context, productID string
 	return versions, nil
 }
 
+// GetProductsByIDs returns the products that exist among ids, keyed by ID.
+func (s *PostgresStore) GetProductsByIDs(ctx context.Context, ids []string) (map[string]*Product, error) {
+	query := `
+		SELECT ` + productColumns + `
+		FROM products
+		WHERE id = ANY($1)
+	`
+
+	rows, err := s.db.Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
+	}
+	defer rows.Close()
+
+	products := make(map[string]*Product, len(ids))
+	for rows.Next() {
+		var p Product
+		err := scanProduct(rows, &p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		products[p.ID] = &p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate products: %w", err)
+	}
+
+	return products, nil
+}
+
+// GetVersionsByProductIDs is GetProductVersions for many products in a
+// single query, keyed by product ID.
+func (s *PostgresStore) GetVersionsByProductIDs(ctx context.Context, productIDs []string) (map[string][]ProductVersion, error) {
+	query := `
+		SELECT ` + productVersionColumns + `
+		FROM product_versions
+		WHERE product_id = ANY($1) AND NOT hidden
+		ORDER BY product_id, is_latest DESC, created_at DESC
+	`
+
+	rows, err := s.db.Query(ctx, query, productIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product versions: %w", err)
+	}
+	defer rows.Close()
+
+	versions := make(map[string][]ProductVersion, len(productIDs))
+	for rows.Next() {
+		var v ProductVersion
+		if err := scanProductVersion(rows, &v); err != nil {
+			return nil, fmt.Errorf("failed to scan product version: %w", err)
+		}
+		versions[v.ProductID] = append(versions[v.ProductID], v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate product versions: %w", err)
+	}
+
+	return versions, nil
+}
+
+var versionSorts = map[string]sortSpec{
+	"updated_at": {columns: []string{"updated_at", "id"}, casts: []string{"timestamptz", "uuid"}},
+	"product":    {columns: []string{"product_id", "platform", "architecture", "id"}, casts: []string{"", "", "", "uuid"}},
+}
+
+func (s *PostgresStore) ListVersions(ctx context.Context, filter VersionFilter) (*VersionPage, error) {
+	sort, err := parseSort(filter.Sort, versionSorts, "-updated_at")
+	if err != nil {
+		return nil, err
+	}
+	limit := clampLimit(filter.Limit)
+
+	var conditions []string
+	var args []interface{}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if !filter.IncludeHidden {
+		conditions = append(conditions, "NOT v.hidden")
+	}
+	if filter.ProductID != "" {
+		conditions = append(conditions, "v.product_id = "+addArg(filter.ProductID))
+	}
+	if filter.Platform != "" {
+		conditions = append(conditions, "v.platform = "+addArg(filter.Platform))
+	}
+	if filter.Architecture != "" {
+		conditions = append(conditions, "v.architecture = "+addArg(filter.Architecture))
+	}
+	if filter.Channel != "" {
+		conditions = append(conditions, "v.channel = "+addArg(filter.Channel))
+	}
+	if filter.LatestOnly {
+		conditions = append(conditions, "v.is_latest = true")
+	}
+	if filter.UpdatedSince != nil {
+		conditions = append(conditions, "v.updated_at >= "+addArg(*filter.UpdatedSince))
+	}
+	if filter.HasChecksum != nil {
+		if *filter.HasChecksum {
+			conditions = append(conditions, "coalesce(v.checksum, '') <> ''")
+		} else {
+			conditions = append(conditions, "coalesce(v.checksum, '') = ''")
+		}
+	}
+	if filter.Cursor != "" {
+		condition, cursorArgs, err := sort.keysetCondition("v", filter.Cursor, len(args))
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, cursorArgs...)
+	}
+
+	query := `
+		SELECT ` + productVersionColumns + `
+		FROM product_versions v
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY " + sort.orderBy("v")
+	query += " LIMIT " + addArg(limit+1)
+
+	rows, err := s.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query versions: %w", err)
+	}
+	defer rows.Close()
+
+	versions := []ProductVersion{}
+	for rows.Next() {
+		var v ProductVersion
+		if err := scanProductVersion(rows, &v); err != nil {
+			return nil, fmt.Errorf("failed to scan product version: %w", err)
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate versions: %w", err)
+	}
+
+	page := &VersionPage{Versions: versions}
+	if len(versions) > limit {
+		page.Versions = versions[:limit]
+		last := page.Versions[limit-1]
+		page.NextCursor = sort.cursor(versionSortValues(last, sort))
+	}
+
+	return page, nil
+}
+
+func versionSortValues(v ProductVersion, sort sortSpec) []string {
+	values := make([]string, len(sort.columns))
+	for i, column := range sort.columns {
+		switch column {
+		case "updated_at":
+			values[i] = v.UpdatedAt.UTC().Format(time.RFC3339Nano)
+		case "product_id":
+			values[i] = v.ProductID
+		case "platform":
+			values[i] = v.Platform
+		case "architecture":
+			values[i] = v.Architecture
+		case "id":
+			values[i] = v.ID
+		}
+	}
+	return values
+}
+
 func (s *PostgresStore) CreateProduct(ctx context.Context, product *Product) error {
 	if product.ID == "" {
 		product.ID = uuid.New().String()
@@ -144,13 +519,18 @@ func (s *PostgresStore) CreateProduct(ctx context.Context, product *Product) err
 	product.UpdatedAt = time.Now()
 
 	query := `
-		INSERT INTO products (id, name, vendor, category, description, icon_url, website_url, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		INSERT INTO products (id, name, vendor, category, description, icon_url, website_url, fetcher, max_age_seconds,
+		                      created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
 	`
 
 	_, err := s.db.Exec(ctx, query, product.ID, product.Name, product.Vendor, product.Category,
-		product.Description, product.IconURL, product.WebsiteURL, product.CreatedAt, product.UpdatedAt)
+		product.Description, product.IconURL, product.WebsiteURL, product.Fetcher, product.MaxAgeSeconds,
+		product.CreatedAt, product.UpdatedAt)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("product %s: %w", product.ID, ErrConflict)
+		}
 		return fmt.Errorf("failed to create product: %w", err)
 	}
 
@@ -162,20 +542,38 @@ func (s *PostgresStore) UpdateProduct(ctx context.Context, product *Product) err
 
 	query := `
 		UPDATE products
-		SET name = $2, vendor = $3, category = $4, description = $5, icon_url = $6, website_url = $7, updated_at = $8
+		SET name = $2, vendor = $3, category = $4, description = $5, icon_url = $6, website_url = $7, fetcher = $8,
+		    max_age_seconds = $9, updated_at = $10
 		WHERE id = $1
 	`
 
-	_, err := s.db.Exec(ctx, query, product.ID, product.Name, product.Vendor, product.Category,
-		product.Description, product.IconURL, product.WebsiteURL, product.UpdatedAt)
+	tag, err := s.db.Exec(ctx, query, product.ID, product.Name, product.Vendor, product.Category,
+		product.Description, product.IconURL, product.WebsiteURL, product.Fetcher, product.MaxAgeSeconds, product.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("failed to update product: %w", err)
 	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("product %s: %w", product.ID, ErrNotFound)
+	}
+
+	return nil
+}
+
+func (s *PostgresStore) DeleteProduct(ctx context.Context, id string) error {
+	tag, err := s.db.Exec(ctx, "DELETE FROM products WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete product: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("product %s: %w", id, ErrNotFound)
+	}
 
 	return nil
 }
 
-func (s *PostgresStore) CreateOrUpdateProductVersion(ctx context.Context, version *ProductVersion) error {
+// CreateOrUpdateProductVersion upserts a fetched version and reports whether
+// it was new. version.ID is set to the ID of the stored row.
+func (s *PostgresStore) CreateOrUpdateProductVersion(ctx context.Context, version *ProductVersion) (bool, error) {
 	if version.ID == "" {
 		version.ID = uuid.New().String()
 	}
@@ -184,14 +582,21 @@ func (s *PostgresStore) CreateOrUpdateProductVersion(ctx context.Context, versio
 	if version.CreatedAt.IsZero() {
 		version.CreatedAt = time.Now()
 	}
+	if version.Channel == "" {
+		version.Channel = ChannelStable
+	}
 
+	// updated_at only moves when the download itself changes, so that it can
+	// be used to answer "what changed recently" rather than "what was fetched".
+	// Rows an admin has created or edited are left untouched.
 	query := `
-		INSERT INTO product_versions (id, product_id, version, platform, architecture, download_url,
+		INSERT INTO product_versions (id, product_id, version, platform, architecture, channel, download_url,
 		                            checksum, checksum_type, file_size, filename, is_latest, etag,
 		                            last_fetched, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
 		ON CONFLICT (product_id, version, platform, architecture)
 		DO UPDATE SET
+			channel = EXCLUDED.channel,
 			download_url = EXCLUDED.download_url,
 			checksum = EXCLUDED.checksum,
 			checksum_type = EXCLUDED.checksum_type,
@@ -200,15 +605,156 @@ func (s *PostgresStore) CreateOrUpdateProductVersion(ctx context.Context, versio
 			is_latest = EXCLUDED.is_latest,
 			etag = EXCLUDED.etag,
 			last_fetched = EXCLUDED.last_fetched,
-			updated_at = EXCLUDED.updated_at
+			updated_at = CASE
+				WHEN (product_versions.channel, product_versions.download_url, product_versions.checksum,
+				      product_versions.checksum_type, product_versions.file_size, product_versions.filename)
+				     IS DISTINCT FROM
+				     (EXCLUDED.channel, EXCLUDED.download_url, EXCLUDED.checksum,
+				      EXCLUDED.checksum_type, EXCLUDED.file_size, EXCLUDED.filename)
+				THEN EXCLUDED.updated_at
+				ELSE product_versions.updated_at
+			END
+		WHERE product_versions.source <> 'manual'
+		RETURNING id, (xmax = 0)
 	`
 
-	_, err := s.db.Exec(ctx, query, version.ID, version.ProductID, version.Version, version.Platform,
-		version.Architecture, version.DownloadURL, version.Checksum, version.ChecksumType,
+	var inserted bool
+	err := s.db.QueryRow(ctx, query, version.ID, version.ProductID, version.Version, version.Platform,
+		version.Architecture, version.Channel, version.DownloadURL, version.Checksum, version.ChecksumType,
 		version.FileSize, version.Filename, version.IsLatest, version.ETag,
+		version.LastFetched, version.CreatedAt, version.UpdatedAt).Scan(&version.ID, &inserted)
+	if err != nil {
+		// No row comes back when a manual entry blocked the update.
+		if err == pgx.ErrNoRows {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to create or update product version: %w", err)
+	}
+
+	return inserted, nil
+}
+
+func (s *PostgresStore) GetProductVersion(ctx context.Context, id string) (*ProductVersion, error) {
+	query := `SELECT ` + productVersionColumns + ` FROM product_versions WHERE id = $1`
+
+	var v ProductVersion
+	if err := scanProductVersion(s.db.QueryRow(ctx, query, id), &v); err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get product version: %w", err)
+	}
+
+	return &v, nil
+}
+
+func (s *PostgresStore) CreateManualVersion(ctx context.Context, version *ProductVersion) error {
+	if version.ID == "" {
+		version.ID = uuid.New().String()
+	}
+	version.Source = VersionSourceManual
+	version.LastFetched = time.Now()
+	version.CreatedAt = time.Now()
+	version.UpdatedAt = time.Now()
+	if version.Channel == "" {
+		version.Channel = ChannelStable
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if version.Pinned {
+		if err := unpinSiblings(ctx, tx, version); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO product_versions (id, product_id, version, platform, architecture, channel, download_url,
+		                            checksum, checksum_type, file_size, filename, is_latest, source, pinned, hidden,
+		                            etag, last_fetched, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, false, $12, $13, $14, '', $15, $16, $17)
+	`
+
+	_, err = tx.Exec(ctx, query, version.ID, version.ProductID, version.Version, version.Platform,
+		version.Architecture, version.Channel, version.DownloadURL, version.Checksum, version.ChecksumType,
+		version.FileSize, version.Filename, version.Source, version.Pinned, version.Hidden,
 		version.LastFetched, version.CreatedAt, version.UpdatedAt)
 	if err != nil {
-		return fmt.Errorf("failed to create or update product version: %w", err)
+		if isUniqueViolation(err) {
+			return fmt.Errorf("version %s %s/%s: %w", version.Version, version.Platform, version.Architecture, ErrConflict)
+		}
+		return fmt.Errorf("failed to create manual version: %w", err)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// UpdateProductVersion saves an admin edit. Callers set Source to
+// VersionSourceManual when download details change so that later fetches
+// do not overwrite them; pinning and hiding alone keep the original source.
+func (s *PostgresStore) UpdateProductVersion(ctx context.Context, version *ProductVersion) error {
+	version.UpdatedAt = time.Now()
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if version.Pinned {
+		if err := unpinSiblings(ctx, tx, version); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		UPDATE product_versions
+		SET version = $2, platform = $3, architecture = $4, channel = $5, download_url = $6, checksum = $7,
+		    checksum_type = $8, file_size = $9, filename = $10, source = $11, pinned = $12, hidden = $13, updated_at = $14
+		WHERE id = $1
+	`
+
+	tag, err := tx.Exec(ctx, query, version.ID, version.Version, version.Platform, version.Architecture,
+		version.Channel, version.DownloadURL, version.Checksum, version.ChecksumType, version.FileSize,
+		version.Filename, version.Source, version.Pinned, version.Hidden, version.UpdatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return fmt.Errorf("version %s %s/%s: %w", version.Version, version.Platform, version.Architecture, ErrConflict)
+		}
+		return fmt.Errorf("failed to update product version: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("product version %s: %w", version.ID, ErrNotFound)
+	}
+
+	return tx.Commit(ctx)
+}
+
+// unpinSiblings keeps at most one pinned version per platform and architecture.
+func unpinSiblings(ctx context.Context, tx pgx.Tx, version *ProductVersion) error {
+	query := `
+		UPDATE product_versions
+		SET pinned = false
+		WHERE product_id = $1 AND platform = $2 AND architecture = $3 AND id <> $4 AND pinned
+	`
+
+	if _, err := tx.Exec(ctx, query, version.ProductID, version.Platform, version.Architecture, version.ID); err != nil {
+		return fmt.Errorf("failed to unpin other versions: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresStore) DeleteProductVersion(ctx context.Context, id string) error {
+	tag, err := s.db.Exec(ctx, "DELETE FROM product_versions WHERE id = $1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete product version: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("product version %s: %w", id, ErrNotFound)
 	}
 
 	return nil
@@ -232,8 +778,8 @@ func (s *PostgresStore) MarkLatestVersions(ctx context.Context, productID string
 		WHERE id IN (
 			SELECT DISTINCT ON (platform, architecture) id
 			FROM product_versions
-			WHERE product_id = $1
-			ORDER BY platform, architecture, created_at DESC
+			WHERE product_id = $1 AND NOT hidden
+			ORDER BY platform, architecture, pinned DESC, created_at DESC
 		)
 	`
 
@@ -302,4 +848,9 @@ func (s *PostgresStore) GetFetchJob(ctx context.Context, id string) (*FetchJob,
 	}
 
 	return &job, nil
-}
\ No newline at end of file
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
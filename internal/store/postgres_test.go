@@ -0,0 +1,44 @@
+package store_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/your-username/alldownloads/internal/migrate"
+	"github.com/your-username/alldownloads/internal/store"
+	"github.com/your-username/alldownloads/internal/store/storetest"
+)
+
+// TestPostgresStore runs against the database in TEST_DATABASE_URL, after
+// migrating it to the latest schema. The suite leaves its rows behind, so
+// point it at a scratch database.
+func TestPostgresStore(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	migrations, err := migrate.Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := migrate.Open(context.Background(), databaseURL, migrations, zap.NewNop())
+	if err != nil {
+		t.Fatalf("failed to open migrator: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("failed to migrate: %v", err)
+	}
+	m.Close(context.Background())
+
+	storetest.Run(t, func(t *testing.T) store.Store {
+		s, err := store.Open(databaseURL)
+		if err != nil {
+			t.Fatalf("failed to open store: %v", err)
+		}
+		return s
+	})
+}
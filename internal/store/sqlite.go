@@ -0,0 +1,920 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+)
+
+//go:embed sqlite_schema.sql
+var sqliteSchema string
+
+// sqliteSchemaVersion is recorded in PRAGMA user_version once sqliteSchema
+// has been applied.
+const sqliteSchemaVersion = 1
+
+// sqliteTimeFormat is how timestamps are stored. Every value has the same
+// width and zone so that SQLite can order and compare them as text.
+const sqliteTimeFormat = "2006-01-02 15:04:05.000000000+00:00"
+
+// SQLiteStore keeps everything in a single SQLite file, for single-node
+// installs that do not want to run Postgres. It needs cgo.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	// Transactions take the write lock up front so that two of them never
+	// deadlock upgrading from a read lock; other writers wait up to five
+	// seconds for it.
+	dsn := path + "?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL&_txlock=immediate"
+
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	db.SetMaxOpenConns(4)
+
+	s := &SQLiteStore{db: db}
+	if err := s.initSchema(context.Background()); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// initSchema creates the tables and seeds the products in a new database.
+// There are no SQLite migrations yet: a database written by a newer binary
+// is refused rather than guessed at.
+func (s *SQLiteStore) initSchema(ctx context.Context) error {
+	var version int
+	if err := s.db.QueryRowContext(ctx, "PRAGMA user_version").Scan(&version); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+	if version > sqliteSchemaVersion {
+		return fmt.Errorf("database schema version %d is newer than this binary supports (%d)", version, sqliteSchemaVersion)
+	}
+	if version == sqliteSchemaVersion {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, sqliteSchema); err != nil {
+		return fmt.Errorf("failed to create schema: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("PRAGMA user_version = %d", sqliteSchemaVersion)); err != nil {
+		return fmt.Errorf("failed to record schema version: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) Close() {
+	s.db.Close()
+}
+
+func (s *SQLiteStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// Driver names the backend.
+func (s *SQLiteStore) Driver() string {
+	return "sqlite"
+}
+
+// Stats reports the connection pool's current usage.
+func (s *SQLiteStore) Stats() PoolStats {
+	stat := s.db.Stats()
+	return PoolStats{
+		TotalConns:    int32(stat.OpenConnections),
+		AcquiredConns: int32(stat.InUse),
+		IdleConns:     int32(stat.Idle),
+		MaxConns:      int32(stat.MaxOpenConnections),
+	}
+}
+
+// sqliteTime formats t for storage.
+func sqliteTime(t time.Time) string {
+	return t.UTC().Format(sqliteTimeFormat)
+}
+
+// sqliteNullTime formats t for storage, keeping nil as NULL.
+func sqliteNullTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return sqliteTime(*t)
+}
+
+// parseSQLiteTime parses the result of an expression over timestamps, which
+// the driver returns as text. The empty string is the zero time.
+func parseSQLiteTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(sqliteTimeFormat, value)
+}
+
+// sqlitePlaceholders returns an argument list such as (?1, ?2) for values,
+// appending them to args.
+func sqlitePlaceholders(args *[]interface{}, values []string) string {
+	placeholders := make([]string, len(values))
+	for i, value := range values {
+		*args = append(*args, value)
+		placeholders[i] = fmt.Sprintf("?%d", len(*args))
+	}
+	return "(" + strings.Join(placeholders, ", ") + ")"
+}
+
+// sqliteKeysetCondition is keysetCondition for SQLite, which has no casts:
+// timestamp cursor values are converted to the stored format instead.
+func (s sortSpec) sqliteKeysetCondition(alias string, cursor string, argOffset int) (string, []interface{}, error) {
+	args, err := s.parseCursor(cursor)
+	if err != nil {
+		return "", nil, err
+	}
+
+	columns := make([]string, len(s.columns))
+	placeholders := make([]string, len(s.columns))
+	for i, column := range s.columns {
+		columns[i] = alias + "." + column
+		placeholders[i] = fmt.Sprintf("?%d", argOffset+i+1)
+		if t, ok := args[i].(time.Time); ok {
+			args[i] = sqliteTime(t)
+		}
+	}
+
+	operator := ">"
+	if s.desc {
+		operator = "<"
+	}
+
+	condition := fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), operator, strings.Join(placeholders, ", "))
+	return condition, args, nil
+}
+
+// sqliteSearchCondition matches products whose name, vendor or description
+// has a word starting with each term, like the prefix tsquery on Postgres.
+func sqliteSearchCondition(terms []string, addArg func(interface{}) string) string {
+	text := `(' ' || p.name || ' ' || p.vendor || ' ' || p.description)`
+	escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+	conditions := make([]string, len(terms))
+	for i, term := range terms {
+		conditions[i] = text + " LIKE " + addArg("% "+escaper.Replace(term)+"%") + ` ESCAPE '\'`
+	}
+	return strings.Join(conditions, " AND ")
+}
+
+func isSQLiteUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey)
+}
+
+func (s *SQLiteStore) GetProducts(ctx context.Context) ([]Product, error) {
+	query := `
+		SELECT ` + productColumns + `
+		FROM products
+		ORDER BY vendor, name
+	`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
+	}
+	defer rows.Close()
+
+	var products []Product
+	for rows.Next() {
+		var p Product
+		if err := scanProduct(rows, &p); err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		products = append(products, p)
+	}
+
+	return products, rows.Err()
+}
+
+func (s *SQLiteStore) ListProducts(ctx context.Context, filter ProductFilter) (*ProductPage, error) {
+	sort, err := parseSort(filter.Sort, productSorts, "vendor")
+	if err != nil {
+		return nil, err
+	}
+	limit := clampLimit(filter.Limit)
+
+	var conditions []string
+	var args []interface{}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("?%d", len(args))
+	}
+
+	if filter.Category != "" {
+		conditions = append(conditions, "p.category = "+addArg(filter.Category))
+	}
+	if filter.Vendor != "" {
+		conditions = append(conditions, "lower(p.vendor) = lower("+addArg(filter.Vendor)+")")
+	}
+	if filter.Platform != "" || filter.Architecture != "" {
+		versionConditions := []string{"pv.product_id = p.id", "NOT pv.hidden"}
+		if filter.Platform != "" {
+			versionConditions = append(versionConditions, "pv.platform = "+addArg(filter.Platform))
+		}
+		if filter.Architecture != "" {
+			versionConditions = append(versionConditions, "pv.architecture = "+addArg(filter.Architecture))
+		}
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM product_versions pv WHERE "+strings.Join(versionConditions, " AND ")+")")
+	}
+	if filter.Search != "" {
+		terms := searchTerms(filter.Search)
+		if len(terms) == 0 {
+			return &ProductPage{Products: []Product{}}, nil
+		}
+		conditions = append(conditions, sqliteSearchCondition(terms, addArg))
+	}
+	if filter.Cursor != "" {
+		condition, cursorArgs, err := sort.sqliteKeysetCondition("p", filter.Cursor, len(args))
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, cursorArgs...)
+	}
+
+	query := `
+		SELECT ` + productColumns + `
+		FROM products p
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY " + sort.orderBy("p")
+	query += " LIMIT " + addArg(limit+1)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
+	}
+	defer rows.Close()
+
+	products := []Product{}
+	for rows.Next() {
+		var p Product
+		if err := scanProduct(rows, &p); err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		products = append(products, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate products: %w", err)
+	}
+
+	page := &ProductPage{Products: products}
+	if len(products) > limit {
+		page.Products = products[:limit]
+		last := page.Products[limit-1]
+		page.NextCursor = sort.cursor(productSortValues(last, sort))
+	}
+
+	return page, nil
+}
+
+func (s *SQLiteStore) GetProduct(ctx context.Context, id string) (*Product, error) {
+	query := `
+		SELECT ` + productColumns + `
+		FROM products
+		WHERE id = ?1
+	`
+
+	var p Product
+	if err := scanProduct(s.db.QueryRowContext(ctx, query, id), &p); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get product: %w", err)
+	}
+
+	return &p, nil
+}
+
+func (s *SQLiteStore) GetProductWithVersions(ctx context.Context, id string) (*ProductWithVersions, error) {
+	product, err := s.GetProduct(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if product == nil {
+		return nil, nil
+	}
+
+	versions, err := s.GetProductVersions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProductWithVersions{
+		Product:  *product,
+		Versions: versions,
+	}, nil
+}
+
+// GetProductsValidator summarises the rows behind the product list. The
+// multi-argument max() is NULL if any argument is, hence the coalesces.
+func (s *SQLiteStore) GetProductsValidator(ctx context.Context) (*Validator, error) {
+	query := `
+		SELECT max(
+		           coalesce((SELECT max(max(updated_at, coalesce(last_success_at, ''), coalesce(stale_since, ''))) FROM products), ''),
+		           coalesce((SELECT max(updated_at) FROM product_versions), '')
+		       ),
+		       (SELECT count(*) FROM products) + (SELECT count(*) FROM product_versions)
+	`
+
+	var lastModified string
+	var v Validator
+	if err := s.db.QueryRowContext(ctx, query).Scan(&lastModified, &v.Rows); err != nil {
+		return nil, fmt.Errorf("failed to get products validator: %w", err)
+	}
+
+	var err error
+	if v.LastModified, err = parseSQLiteTime(lastModified); err != nil {
+		return nil, fmt.Errorf("failed to get products validator: %w", err)
+	}
+
+	return &v, nil
+}
+
+// GetProductValidator summarises the rows behind GetProductWithVersions. It
+// returns nil when the product does not exist.
+func (s *SQLiteStore) GetProductValidator(ctx context.Context, id string) (*Validator, error) {
+	query := `
+		SELECT max(p.updated_at, coalesce(p.last_success_at, ''), coalesce(p.stale_since, ''),
+		           coalesce(max(v.updated_at), ''), coalesce(max(v.last_fetched), '')),
+		       count(v.id)
+		FROM products p
+		LEFT JOIN product_versions v ON v.product_id = p.id AND NOT v.hidden
+		WHERE p.id = ?1
+		GROUP BY p.id
+	`
+
+	var lastModified string
+	var v Validator
+	if err := s.db.QueryRowContext(ctx, query, id).Scan(&lastModified, &v.Rows); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get product validator: %w", err)
+	}
+
+	var err error
+	if v.LastModified, err = parseSQLiteTime(lastModified); err != nil {
+		return nil, fmt.Errorf("failed to get product validator: %w", err)
+	}
+
+	return &v, nil
+}
+
+func (s *SQLiteStore) GetProductVersions(ctx context.Context, productID string) ([]ProductVersion, error) {
+	query := `
+		SELECT ` + productVersionColumns + `
+		FROM product_versions
+		WHERE product_id = ?1 AND NOT hidden
+		ORDER BY is_latest DESC, created_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, productID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product versions: %w", err)
+	}
+	defer rows.Close()
+
+	var versions []ProductVersion
+	for rows.Next() {
+		var v ProductVersion
+		if err := scanProductVersion(rows, &v); err != nil {
+			return nil, fmt.Errorf("failed to scan product version: %w", err)
+		}
+		versions = append(versions, v)
+	}
+
+	return versions, rows.Err()
+}
+
+// GetProductsByIDs returns the products that exist among ids, keyed by ID.
+func (s *SQLiteStore) GetProductsByIDs(ctx context.Context, ids []string) (map[string]*Product, error) {
+	products := make(map[string]*Product, len(ids))
+	if len(ids) == 0 {
+		return products, nil
+	}
+
+	var args []interface{}
+	query := `
+		SELECT ` + productColumns + `
+		FROM products
+		WHERE id IN ` + sqlitePlaceholders(&args, ids)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query products: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p Product
+		if err := scanProduct(rows, &p); err != nil {
+			return nil, fmt.Errorf("failed to scan product: %w", err)
+		}
+		products[p.ID] = &p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate products: %w", err)
+	}
+
+	return products, nil
+}
+
+// GetVersionsByProductIDs is GetProductVersions for many products in a
+// single query, keyed by product ID.
+func (s *SQLiteStore) GetVersionsByProductIDs(ctx context.Context, productIDs []string) (map[string][]ProductVersion, error) {
+	versions := make(map[string][]ProductVersion, len(productIDs))
+	if len(productIDs) == 0 {
+		return versions, nil
+	}
+
+	var args []interface{}
+	query := `
+		SELECT ` + productVersionColumns + `
+		FROM product_versions
+		WHERE product_id IN ` + sqlitePlaceholders(&args, productIDs) + ` AND NOT hidden
+		ORDER BY product_id, is_latest DESC, created_at DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query product versions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var v ProductVersion
+		if err := scanProductVersion(rows, &v); err != nil {
+			return nil, fmt.Errorf("failed to scan product version: %w", err)
+		}
+		versions[v.ProductID] = append(versions[v.ProductID], v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate product versions: %w", err)
+	}
+
+	return versions, nil
+}
+
+func (s *SQLiteStore) ListVersions(ctx context.Context, filter VersionFilter) (*VersionPage, error) {
+	sort, err := parseSort(filter.Sort, versionSorts, "-updated_at")
+	if err != nil {
+		return nil, err
+	}
+	limit := clampLimit(filter.Limit)
+
+	var conditions []string
+	var args []interface{}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("?%d", len(args))
+	}
+
+	if !filter.IncludeHidden {
+		conditions = append(conditions, "NOT v.hidden")
+	}
+	if filter.ProductID != "" {
+		conditions = append(conditions, "v.product_id = "+addArg(filter.ProductID))
+	}
+	if filter.Platform != "" {
+		conditions = append(conditions, "v.platform = "+addArg(filter.Platform))
+	}
+	if filter.Architecture != "" {
+		conditions = append(conditions, "v.architecture = "+addArg(filter.Architecture))
+	}
+	if filter.Channel != "" {
+		conditions = append(conditions, "v.channel = "+addArg(filter.Channel))
+	}
+	if filter.LatestOnly {
+		conditions = append(conditions, "v.is_latest")
+	}
+	if filter.UpdatedSince != nil {
+		conditions = append(conditions, "v.updated_at >= "+addArg(sqliteTime(*filter.UpdatedSince)))
+	}
+	if filter.HasChecksum != nil {
+		if *filter.HasChecksum {
+			conditions = append(conditions, "v.checksum <> ''")
+		} else {
+			conditions = append(conditions, "v.checksum = ''")
+		}
+	}
+	if filter.Cursor != "" {
+		condition, cursorArgs, err := sort.sqliteKeysetCondition("v", filter.Cursor, len(args))
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, cursorArgs...)
+	}
+
+	query := `
+		SELECT ` + productVersionColumns + `
+		FROM product_versions v
+	`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY " + sort.orderBy("v")
+	query += " LIMIT " + addArg(limit+1)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query versions: %w", err)
+	}
+	defer rows.Close()
+
+	versions := []ProductVersion{}
+	for rows.Next() {
+		var v ProductVersion
+		if err := scanProductVersion(rows, &v); err != nil {
+			return nil, fmt.Errorf("failed to scan product version: %w", err)
+		}
+		versions = append(versions, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate versions: %w", err)
+	}
+
+	page := &VersionPage{Versions: versions}
+	if len(versions) > limit {
+		page.Versions = versions[:limit]
+		last := page.Versions[limit-1]
+		page.NextCursor = sort.cursor(versionSortValues(last, sort))
+	}
+
+	return page, nil
+}
+
+func (s *SQLiteStore) CreateProduct(ctx context.Context, product *Product) error {
+	if product.ID == "" {
+		product.ID = uuid.New().String()
+	}
+	product.CreatedAt = time.Now()
+	product.UpdatedAt = time.Now()
+
+	query := `
+		INSERT INTO products (id, name, vendor, category, description, icon_url, website_url, fetcher, max_age_seconds,
+		                      created_at, updated_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11)
+	`
+
+	_, err := s.db.ExecContext(ctx, query, product.ID, product.Name, product.Vendor, product.Category,
+		product.Description, product.IconURL, product.WebsiteURL, product.Fetcher, product.MaxAgeSeconds,
+		sqliteTime(product.CreatedAt), sqliteTime(product.UpdatedAt))
+	if err != nil {
+		if isSQLiteUniqueViolation(err) {
+			return fmt.Errorf("product %s: %w", product.ID, ErrConflict)
+		}
+		return fmt.Errorf("failed to create product: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) UpdateProduct(ctx context.Context, product *Product) error {
+	product.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE products
+		SET name = ?2, vendor = ?3, category = ?4, description = ?5, icon_url = ?6, website_url = ?7, fetcher = ?8,
+		    max_age_seconds = ?9, updated_at = ?10
+		WHERE id = ?1
+	`
+
+	result, err := s.db.ExecContext(ctx, query, product.ID, product.Name, product.Vendor, product.Category,
+		product.Description, product.IconURL, product.WebsiteURL, product.Fetcher, product.MaxAgeSeconds,
+		sqliteTime(product.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to update product: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("product %s: %w", product.ID, ErrNotFound)
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) DeleteProduct(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM products WHERE id = ?1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete product: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("product %s: %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+// RecordFetchSuccess stores when productID last fetched successfully and
+// clears its stale flag. It reports whether the product was stale until
+// now, and false when the product no longer exists.
+func (s *SQLiteStore) RecordFetchSuccess(ctx context.Context, productID string, at time.Time) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var wasStale bool
+	if err := tx.QueryRowContext(ctx, "SELECT stale FROM products WHERE id = ?1", productID).Scan(&wasStale); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to record fetch success: %w", err)
+	}
+
+	query := `UPDATE products SET last_success_at = ?2, stale = FALSE, stale_since = NULL,
+		updated_at = CASE WHEN stale THEN ?3 ELSE updated_at END WHERE id = ?1`
+	if _, err := tx.ExecContext(ctx, query, productID, sqliteTime(at), sqliteTime(time.Now())); err != nil {
+		return false, fmt.Errorf("failed to record fetch success: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to record fetch success: %w", err)
+	}
+	return wasStale, nil
+}
+
+// MarkProductStale flags productID as stale since the given time unless it
+// already is. It reports whether this call set the flag.
+func (s *SQLiteStore) MarkProductStale(ctx context.Context, productID string, since time.Time) (bool, error) {
+	result, err := s.db.ExecContext(ctx, `UPDATE products SET stale = TRUE, stale_since = ?2, updated_at = ?3 WHERE id = ?1 AND NOT stale`,
+		productID, sqliteTime(since), sqliteTime(time.Now()))
+	if err != nil {
+		return false, fmt.Errorf("failed to mark product stale: %w", err)
+	}
+
+	n, _ := result.RowsAffected()
+	return n == 1, nil
+}
+
+// CreateOrUpdateProductVersion upserts a fetched version and reports whether
+// it was new. version.ID is set to the ID of the stored row. SQLite's
+// upsert cannot tell an insert from an update, so the row is looked up
+// first inside the transaction.
+func (s *SQLiteStore) CreateOrUpdateProductVersion(ctx context.Context, version *ProductVersion) (bool, error) {
+	if version.ID == "" {
+		version.ID = uuid.New().String()
+	}
+	version.LastFetched = time.Now()
+	version.UpdatedAt = time.Now()
+	if version.CreatedAt.IsZero() {
+		version.CreatedAt = time.Now()
+	}
+	if version.Channel == "" {
+		version.Channel = ChannelStable
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var existing ProductVersion
+	err = tx.QueryRowContext(ctx, `
+		SELECT id, source, channel, download_url, checksum, checksum_type, file_size, filename
+		FROM product_versions
+		WHERE product_id = ?1 AND version = ?2 AND platform = ?3 AND architecture = ?4
+	`, version.ProductID, version.Version, version.Platform, version.Architecture).Scan(&existing.ID, &existing.Source,
+		&existing.Channel, &existing.DownloadURL, &existing.Checksum, &existing.ChecksumType, &existing.FileSize, &existing.Filename)
+
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		query := `
+			INSERT INTO product_versions (id, product_id, version, platform, architecture, channel, download_url,
+			                            checksum, checksum_type, file_size, filename, is_latest, etag,
+			                            last_fetched, created_at, updated_at)
+			VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, ?12, ?13, ?14, ?15, ?16)
+		`
+		_, err = tx.ExecContext(ctx, query, version.ID, version.ProductID, version.Version, version.Platform,
+			version.Architecture, version.Channel, version.DownloadURL, version.Checksum, version.ChecksumType,
+			version.FileSize, version.Filename, version.IsLatest, version.ETag,
+			sqliteTime(version.LastFetched), sqliteTime(version.CreatedAt), sqliteTime(version.UpdatedAt))
+		if err != nil {
+			return false, fmt.Errorf("failed to create or update product version: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return false, fmt.Errorf("failed to create or update product version: %w", err)
+		}
+		return true, nil
+	case err != nil:
+		return false, fmt.Errorf("failed to create or update product version: %w", err)
+	case existing.Source == VersionSourceManual:
+		// Rows an admin has created or edited are left untouched.
+		return false, nil
+	}
+
+	// updated_at only moves when the download itself changes, as on Postgres.
+	query := `
+		UPDATE product_versions
+		SET channel = ?2, download_url = ?3, checksum = ?4, checksum_type = ?5, file_size = ?6, filename = ?7,
+		    is_latest = ?8, etag = ?9, last_fetched = ?10,
+		    updated_at = CASE WHEN ?11 THEN ?12 ELSE updated_at END
+		WHERE id = ?1
+	`
+	changed := existing.Channel != version.Channel || existing.DownloadURL != version.DownloadURL ||
+		existing.Checksum != version.Checksum || existing.ChecksumType != version.ChecksumType ||
+		existing.FileSize != version.FileSize || existing.Filename != version.Filename
+
+	_, err = tx.ExecContext(ctx, query, existing.ID, version.Channel, version.DownloadURL, version.Checksum,
+		version.ChecksumType, version.FileSize, version.Filename, version.IsLatest, version.ETag,
+		sqliteTime(version.LastFetched), changed, sqliteTime(version.UpdatedAt))
+	if err != nil {
+		return false, fmt.Errorf("failed to create or update product version: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to create or update product version: %w", err)
+	}
+
+	version.ID = existing.ID
+	return false, nil
+}
+
+func (s *SQLiteStore) GetProductVersion(ctx context.Context, id string) (*ProductVersion, error) {
+	query := `SELECT ` + productVersionColumns + ` FROM product_versions WHERE id = ?1`
+
+	var v ProductVersion
+	if err := scanProductVersion(s.db.QueryRowContext(ctx, query, id), &v); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get product version: %w", err)
+	}
+
+	return &v, nil
+}
+
+func (s *SQLiteStore) CreateManualVersion(ctx context.Context, version *ProductVersion) error {
+	if version.ID == "" {
+		version.ID = uuid.New().String()
+	}
+	version.Source = VersionSourceManual
+	version.LastFetched = time.Now()
+	version.CreatedAt = time.Now()
+	version.UpdatedAt = time.Now()
+	if version.Channel == "" {
+		version.Channel = ChannelStable
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if version.Pinned {
+		if err := sqliteUnpinSiblings(ctx, tx, version); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		INSERT INTO product_versions (id, product_id, version, platform, architecture, channel, download_url,
+		                            checksum, checksum_type, file_size, filename, is_latest, source, pinned, hidden,
+		                            etag, last_fetched, created_at, updated_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10, ?11, FALSE, ?12, ?13, ?14, '', ?15, ?16, ?17)
+	`
+
+	_, err = tx.ExecContext(ctx, query, version.ID, version.ProductID, version.Version, version.Platform,
+		version.Architecture, version.Channel, version.DownloadURL, version.Checksum, version.ChecksumType,
+		version.FileSize, version.Filename, version.Source, version.Pinned, version.Hidden,
+		sqliteTime(version.LastFetched), sqliteTime(version.CreatedAt), sqliteTime(version.UpdatedAt))
+	if err != nil {
+		if isSQLiteUniqueViolation(err) {
+			return fmt.Errorf("version %s %s/%s: %w", version.Version, version.Platform, version.Architecture, ErrConflict)
+		}
+		return fmt.Errorf("failed to create manual version: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// UpdateProductVersion saves an admin edit. Callers set Source to
+// VersionSourceManual when download details change so that later fetches
+// do not overwrite them; pinning and hiding alone keep the original source.
+func (s *SQLiteStore) UpdateProductVersion(ctx context.Context, version *ProductVersion) error {
+	version.UpdatedAt = time.Now()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if version.Pinned {
+		if err := sqliteUnpinSiblings(ctx, tx, version); err != nil {
+			return err
+		}
+	}
+
+	query := `
+		UPDATE product_versions
+		SET version = ?2, platform = ?3, architecture = ?4, channel = ?5, download_url = ?6, checksum = ?7,
+		    checksum_type = ?8, file_size = ?9, filename = ?10, source = ?11, pinned = ?12, hidden = ?13, updated_at = ?14
+		WHERE id = ?1
+	`
+
+	result, err := tx.ExecContext(ctx, query, version.ID, version.Version, version.Platform, version.Architecture,
+		version.Channel, version.DownloadURL, version.Checksum, version.ChecksumType, version.FileSize,
+		version.Filename, version.Source, version.Pinned, version.Hidden, sqliteTime(version.UpdatedAt))
+	if err != nil {
+		if isSQLiteUniqueViolation(err) {
+			return fmt.Errorf("version %s %s/%s: %w", version.Version, version.Platform, version.Architecture, ErrConflict)
+		}
+		return fmt.Errorf("failed to update product version: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("product version %s: %w", version.ID, ErrNotFound)
+	}
+
+	return tx.Commit()
+}
+
+// sqliteUnpinSiblings keeps at most one pinned version per platform and
+// architecture.
+func sqliteUnpinSiblings(ctx context.Context, tx *sql.Tx, version *ProductVersion) error {
+	query := `
+		UPDATE product_versions
+		SET pinned = FALSE
+		WHERE product_id = ?1 AND platform = ?2 AND architecture = ?3 AND id <> ?4 AND pinned
+	`
+
+	if _, err := tx.ExecContext(ctx, query, version.ProductID, version.Platform, version.Architecture, version.ID); err != nil {
+		return fmt.Errorf("failed to unpin other versions: %w", err)
+	}
+	return nil
+}
+
+func (s *SQLiteStore) DeleteProductVersion(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, "DELETE FROM product_versions WHERE id = ?1", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete product version: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("product version %s: %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) MarkLatestVersions(ctx context.Context, productID string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "UPDATE product_versions SET is_latest = FALSE WHERE product_id = ?1", productID)
+	if err != nil {
+		return fmt.Errorf("failed to unmark latest versions: %w", err)
+	}
+
+	// row_number() stands in for Postgres' DISTINCT ON.
+	query := `
+		UPDATE product_versions
+		SET is_latest = TRUE
+		WHERE id IN (
+			SELECT id FROM (
+				SELECT id, row_number() OVER (PARTITION BY platform, architecture ORDER BY pinned DESC, created_at DESC) AS rn
+				FROM product_versions
+				WHERE product_id = ?1 AND NOT hidden
+			)
+			WHERE rn = 1
+		)
+	`
+
+	_, err = tx.ExecContext(ctx, query, productID)
+	if err != nil {
+		return fmt.Errorf("failed to mark latest versions: %w", err)
+	}
+
+	return tx.Commit()
+}
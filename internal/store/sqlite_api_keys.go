@@ -0,0 +1,124 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// scanSQLiteAPIKey is scanAPIKey for SQLite, which keeps scopes as a JSON
+// array rather than a text[].
+func scanSQLiteAPIKey(row interface{ Scan(...interface{}) error }, k *APIKey) error {
+	var scopes string
+	if err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.KeyHash, &scopes, &k.RateLimitPerMinute,
+		&k.ExpiresAt, &k.LastUsedAt, &k.RevokedAt, &k.CreatedAt); err != nil {
+		return err
+	}
+	return json.Unmarshal([]byte(scopes), &k.Scopes)
+}
+
+func (s *SQLiteStore) CreateAPIKey(ctx context.Context, key *APIKey) error {
+	if key.ID == "" {
+		key.ID = uuid.New().String()
+	}
+	key.CreatedAt = time.Now()
+
+	scopes := key.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	encoded, err := json.Marshal(scopes)
+	if err != nil {
+		return fmt.Errorf("failed to encode api key scopes: %w", err)
+	}
+
+	query := `
+		INSERT INTO api_keys (id, name, prefix, key_hash, scopes, rate_limit_per_minute, expires_at, created_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8)
+	`
+
+	_, err = s.db.ExecContext(ctx, query, key.ID, key.Name, key.Prefix, key.KeyHash, string(encoded),
+		key.RateLimitPerMinute, sqliteNullTime(key.ExpiresAt), sqliteTime(key.CreatedAt))
+	if err != nil {
+		if isSQLiteUniqueViolation(err) {
+			return fmt.Errorf("api key prefix %s: %w", key.Prefix, ErrConflict)
+		}
+		return fmt.Errorf("failed to create api key: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) GetAPIKey(ctx context.Context, id string) (*APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE id = ?1`
+
+	var k APIKey
+	if err := scanSQLiteAPIKey(s.db.QueryRowContext(ctx, query, id), &k); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return &k, nil
+}
+
+func (s *SQLiteStore) GetAPIKeyByPrefix(ctx context.Context, prefix string) (*APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE prefix = ?1`
+
+	var k APIKey
+	if err := scanSQLiteAPIKey(s.db.QueryRowContext(ctx, query, prefix), &k); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get api key: %w", err)
+	}
+
+	return &k, nil
+}
+
+func (s *SQLiteStore) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	query := `SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY created_at DESC`
+
+	rows, err := s.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		var k APIKey
+		if err := scanSQLiteAPIKey(rows, &k); err != nil {
+			return nil, fmt.Errorf("failed to scan api key: %w", err)
+		}
+		keys = append(keys, k)
+	}
+
+	return keys, rows.Err()
+}
+
+func (s *SQLiteStore) RevokeAPIKey(ctx context.Context, id string) error {
+	result, err := s.db.ExecContext(ctx, "UPDATE api_keys SET revoked_at = ?2 WHERE id = ?1 AND revoked_at IS NULL",
+		id, sqliteTime(time.Now()))
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("api key %s: %w", id, ErrNotFound)
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	if _, err := s.db.ExecContext(ctx, "UPDATE api_keys SET last_used_at = ?2 WHERE id = ?1", id, sqliteTime(usedAt)); err != nil {
+		return fmt.Errorf("failed to update api key last use: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CreateAuditEntry appends to the audit log. Triggers reject updates and
+// deletes, as on Postgres.
+func (s *SQLiteStore) CreateAuditEntry(ctx context.Context, entry *AuditEntry) error {
+	if entry.OccurredAt.IsZero() {
+		entry.OccurredAt = time.Now()
+	}
+	if entry.Changes == nil {
+		entry.Changes = map[string]AuditChange{}
+	}
+
+	changes, err := json.Marshal(entry.Changes)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit changes: %w", err)
+	}
+
+	query := `
+		INSERT INTO audit_log (occurred_at, actor_type, actor_id, actor_name, action, target_type, target_id, request_id, client_ip, changes)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8, ?9, ?10)
+	`
+
+	result, err := s.db.ExecContext(ctx, query, sqliteTime(entry.OccurredAt), entry.ActorType, entry.ActorID, entry.ActorName,
+		entry.Action, entry.TargetType, entry.TargetID, entry.RequestID, entry.ClientIP, string(changes))
+	if err != nil {
+		return fmt.Errorf("failed to create audit entry: %w", err)
+	}
+	if entry.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("failed to create audit entry: %w", err)
+	}
+
+	return nil
+}
+
+// ListAuditEntries returns entries newest first, paginated by id.
+func (s *SQLiteStore) ListAuditEntries(ctx context.Context, filter AuditFilter) (*AuditPage, error) {
+	limit := clampLimit(filter.Limit)
+
+	var conditions []string
+	var args []interface{}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("?%d", len(args))
+	}
+
+	if filter.ActorID != "" {
+		conditions = append(conditions, "actor_id = "+addArg(filter.ActorID))
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = "+addArg(filter.Action))
+	}
+	if filter.TargetType != "" {
+		conditions = append(conditions, "target_type = "+addArg(filter.TargetType))
+	}
+	if filter.TargetID != "" {
+		conditions = append(conditions, "target_id = "+addArg(filter.TargetID))
+	}
+	if filter.RequestID != "" {
+		conditions = append(conditions, "request_id = "+addArg(filter.RequestID))
+	}
+	if filter.Since != nil {
+		conditions = append(conditions, "occurred_at >= "+addArg(sqliteTime(*filter.Since)))
+	}
+	if filter.Until != nil {
+		conditions = append(conditions, "occurred_at < "+addArg(sqliteTime(*filter.Until)))
+	}
+	if filter.Cursor != "" {
+		values, err := decodeCursor(filter.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != 1 {
+			return nil, ErrInvalidCursor
+		}
+		id, err := strconv.ParseInt(values[0], 10, 64)
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+		conditions = append(conditions, "id < "+addArg(id))
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_log`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC LIMIT " + addArg(limit+1)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query audit log: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []AuditEntry{}
+	for rows.Next() {
+		var e AuditEntry
+		if err := scanAuditEntry(rows, &e); err != nil {
+			return nil, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate audit log: %w", err)
+	}
+
+	page := &AuditPage{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		page.NextCursor = encodeCursor([]string{strconv.FormatInt(page.Entries[limit-1].ID, 10)})
+	}
+
+	return page, nil
+}
@@ -0,0 +1,236 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (s *SQLiteStore) CreateFetchJob(ctx context.Context, job *FetchJob) error {
+	if job.ID == "" {
+		job.ID = uuid.New().String()
+	}
+	job.CreatedAt = time.Now()
+	job.UpdatedAt = time.Now()
+
+	query := `
+		INSERT INTO fetch_jobs (id, product_id, status, started_at, completed_at, error, created_at, updated_at)
+		VALUES (?1, ?2, ?3, ?4, ?5, ?6, ?7, ?8)
+	`
+
+	_, err := s.db.ExecContext(ctx, query, job.ID, job.ProductID, job.Status, sqliteNullTime(job.StartedAt),
+		sqliteNullTime(job.CompletedAt), job.Error, sqliteTime(job.CreatedAt), sqliteTime(job.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to create fetch job: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) UpdateFetchJob(ctx context.Context, job *FetchJob) error {
+	job.UpdatedAt = time.Now()
+
+	query := `
+		UPDATE fetch_jobs
+		SET status = ?2, started_at = ?3, completed_at = ?4, error = ?5, updated_at = ?6
+		WHERE id = ?1
+	`
+
+	_, err := s.db.ExecContext(ctx, query, job.ID, job.Status, sqliteNullTime(job.StartedAt),
+		sqliteNullTime(job.CompletedAt), job.Error, sqliteTime(job.UpdatedAt))
+	if err != nil {
+		return fmt.Errorf("failed to update fetch job: %w", err)
+	}
+
+	return nil
+}
+
+func (s *SQLiteStore) GetFetchJob(ctx context.Context, id string) (*FetchJob, error) {
+	query := `SELECT ` + fetchJobColumns + ` FROM fetch_jobs WHERE id = ?1`
+
+	var job FetchJob
+	if err := scanFetchJob(s.db.QueryRowContext(ctx, query, id), &job); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get fetch job: %w", err)
+	}
+
+	return &job, nil
+}
+
+// ListFetchJobs returns jobs newest first.
+func (s *SQLiteStore) ListFetchJobs(ctx context.Context, filter JobFilter) (*JobPage, error) {
+	limit := clampLimit(filter.Limit)
+
+	var conditions []string
+	var args []interface{}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("?%d", len(args))
+	}
+
+	if filter.ProductID != "" {
+		conditions = append(conditions, "j.product_id = "+addArg(filter.ProductID))
+	}
+	if filter.Status != "" {
+		conditions = append(conditions, "j.status = "+addArg(filter.Status))
+	}
+	if filter.Cursor != "" {
+		condition, cursorArgs, err := jobSort.sqliteKeysetCondition("j", filter.Cursor, len(args))
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+		args = append(args, cursorArgs...)
+	}
+
+	query := `SELECT ` + fetchJobColumns + ` FROM fetch_jobs j`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY " + jobSort.orderBy("j")
+	query += " LIMIT " + addArg(limit+1)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fetch jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []FetchJob{}
+	for rows.Next() {
+		var job FetchJob
+		if err := scanFetchJob(rows, &job); err != nil {
+			return nil, fmt.Errorf("failed to scan fetch job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate fetch jobs: %w", err)
+	}
+
+	page := &JobPage{Jobs: jobs}
+	if len(jobs) > limit {
+		page.Jobs = jobs[:limit]
+		last := page.Jobs[limit-1]
+		page.NextCursor = jobSort.cursor([]string{last.CreatedAt.UTC().Format(time.RFC3339Nano), last.ID})
+	}
+
+	return page, nil
+}
+
+// GetRecentFetchJobs returns up to perProduct of the newest jobs of each of
+// productIDs in a single query, keyed by product ID.
+func (s *SQLiteStore) GetRecentFetchJobs(ctx context.Context, productIDs []string, perProduct int) (map[string][]FetchJob, error) {
+	jobs := make(map[string][]FetchJob, len(productIDs))
+	if len(productIDs) == 0 {
+		return jobs, nil
+	}
+
+	var args []interface{}
+	inList := sqlitePlaceholders(&args, productIDs)
+	args = append(args, perProduct)
+	query := `
+		SELECT ` + fetchJobColumns + `
+		FROM (
+			SELECT *, row_number() OVER (PARTITION BY product_id ORDER BY created_at DESC, id DESC) AS rn
+			FROM fetch_jobs
+			WHERE product_id IN ` + inList + `
+		) j
+		WHERE rn <= ?` + fmt.Sprint(len(args)) + `
+		ORDER BY product_id, created_at DESC, id DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query recent fetch jobs: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var job FetchJob
+		if err := scanFetchJob(rows, &job); err != nil {
+			return nil, fmt.Errorf("failed to scan fetch job: %w", err)
+		}
+		jobs[job.ProductID] = append(jobs[job.ProductID], job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate fetch jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// ListDeadFetchJobs returns the newest job of each product when it failed,
+// or when it has been running since before runningBefore.
+func (s *SQLiteStore) ListDeadFetchJobs(ctx context.Context, runningBefore time.Time) ([]FetchJob, error) {
+	query := `
+		SELECT ` + fetchJobColumns + `
+		FROM (
+			SELECT *, row_number() OVER (PARTITION BY product_id ORDER BY created_at DESC, id DESC) AS rn
+			FROM fetch_jobs
+		) j
+		WHERE rn = 1 AND (status = ?1 OR (status = ?2 AND started_at < ?3))
+		ORDER BY created_at, id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, JobStatusFailed, JobStatusRunning, sqliteTime(runningBefore))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dead fetch jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []FetchJob{}
+	for rows.Next() {
+		var job FetchJob
+		if err := scanFetchJob(rows, &job); err != nil {
+			return nil, fmt.Errorf("failed to scan fetch job: %w", err)
+		}
+		jobs = append(jobs, job)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate fetch jobs: %w", err)
+	}
+
+	return jobs, nil
+}
+
+// GetLastSuccessfulFetches returns when each product's most recent
+// completed fetch finished, keyed by product ID. Products that never
+// completed a fetch are absent.
+func (s *SQLiteStore) GetLastSuccessfulFetches(ctx context.Context) (map[string]time.Time, error) {
+	query := `
+		SELECT product_id, max(completed_at)
+		FROM fetch_jobs
+		WHERE status = ?1 AND completed_at IS NOT NULL
+		GROUP BY product_id
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, JobStatusCompleted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query last successful fetches: %w", err)
+	}
+	defer rows.Close()
+
+	fetches := make(map[string]time.Time)
+	for rows.Next() {
+		var productID, completedAt string
+		if err := rows.Scan(&productID, &completedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan last successful fetch: %w", err)
+		}
+		if fetches[productID], err = parseSQLiteTime(completedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan last successful fetch: %w", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to iterate last successful fetches: %w", err)
+	}
+
+	return fetches, nil
+}
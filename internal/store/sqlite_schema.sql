@@ -0,0 +1,133 @@
+-- Schema of the SQLite backend: the tables the Postgres migrations build up
+-- to, in a single script. Timestamps are stored as fixed-width UTC text (see
+-- sqliteTimeFormat) so that they sort and compare as strings.
+
+CREATE TABLE IF NOT EXISTS products (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    vendor TEXT NOT NULL,
+    category TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    icon_url TEXT NOT NULL DEFAULT '',
+    website_url TEXT NOT NULL DEFAULT '',
+    fetcher TEXT NOT NULL DEFAULT '',
+    max_age_seconds INTEGER NOT NULL DEFAULT 0 CHECK (max_age_seconds >= 0),
+    last_success_at TIMESTAMP,
+    stale BOOLEAN NOT NULL DEFAULT FALSE,
+    stale_since TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%S.000000000+00:00', 'now')),
+    updated_at TIMESTAMP NOT NULL DEFAULT (strftime('%Y-%m-%d %H:%M:%S.000000000+00:00', 'now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_products_vendor ON products(vendor);
+CREATE INDEX IF NOT EXISTS idx_products_category ON products(category);
+CREATE INDEX IF NOT EXISTS idx_products_name_id ON products(name, id);
+CREATE INDEX IF NOT EXISTS idx_products_updated_at_id ON products(updated_at, id);
+
+CREATE TABLE IF NOT EXISTS product_versions (
+    id TEXT PRIMARY KEY,
+    product_id TEXT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    version TEXT NOT NULL,
+    platform TEXT NOT NULL,
+    architecture TEXT NOT NULL,
+    channel TEXT NOT NULL DEFAULT 'stable',
+    download_url TEXT NOT NULL,
+    checksum TEXT NOT NULL DEFAULT '',
+    checksum_type TEXT NOT NULL DEFAULT '',
+    file_size INTEGER NOT NULL DEFAULT 0,
+    filename TEXT NOT NULL DEFAULT '',
+    is_latest BOOLEAN NOT NULL DEFAULT FALSE,
+    source TEXT NOT NULL DEFAULT 'fetcher',
+    pinned BOOLEAN NOT NULL DEFAULT FALSE,
+    hidden BOOLEAN NOT NULL DEFAULT FALSE,
+    etag TEXT NOT NULL DEFAULT '',
+    last_fetched TIMESTAMP NOT NULL,
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL,
+    UNIQUE (product_id, version, platform, architecture)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_versions_product_platform_arch ON product_versions(product_id, platform, architecture);
+CREATE INDEX IF NOT EXISTS idx_product_versions_updated_at_id ON product_versions(updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_product_versions_platform_arch ON product_versions(platform, architecture);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_versions_one_pin
+    ON product_versions(product_id, platform, architecture) WHERE pinned;
+
+CREATE TABLE IF NOT EXISTS fetch_jobs (
+    id TEXT PRIMARY KEY,
+    product_id TEXT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    status TEXT NOT NULL DEFAULT 'pending',
+    started_at TIMESTAMP,
+    completed_at TIMESTAMP,
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL,
+    updated_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_fetch_jobs_product_id ON fetch_jobs(product_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_fetch_jobs_status ON fetch_jobs(status);
+CREATE INDEX IF NOT EXISTS idx_fetch_jobs_created_at ON fetch_jobs(created_at, id);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    prefix TEXT NOT NULL UNIQUE,
+    key_hash TEXT NOT NULL,
+    -- A JSON array of scope names.
+    scopes TEXT NOT NULL DEFAULT '[]',
+    rate_limit_per_minute INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_created_at ON api_keys(created_at);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    occurred_at TIMESTAMP NOT NULL,
+    actor_type TEXT NOT NULL,
+    actor_id TEXT NOT NULL,
+    actor_name TEXT NOT NULL DEFAULT '',
+    action TEXT NOT NULL,
+    target_type TEXT NOT NULL,
+    target_id TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    client_ip TEXT NOT NULL DEFAULT '',
+    changes TEXT NOT NULL DEFAULT '{}'
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_occurred_at ON audit_log(occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_log_actor ON audit_log(actor_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_target ON audit_log(target_type, target_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_action ON audit_log(action, id);
+
+-- The log is append-only: reject any attempt to rewrite history.
+CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+BEGIN
+    SELECT RAISE(ABORT, 'audit_log is append-only');
+END;
+
+INSERT OR IGNORE INTO products (id, name, vendor, category, description, icon_url, website_url, fetcher) VALUES
+('ubuntu', 'Ubuntu', 'Canonical', 'os', 'Popular Linux distribution known for its ease of use and strong community support', 'https://assets.ubuntu.com/v1/29985a98-ubuntu-logo32.png', 'https://ubuntu.com/', 'ubuntu'),
+('debian', 'Debian', 'Debian Project', 'os', 'Stable and secure Linux distribution, the foundation for many other distributions', 'https://www.debian.org/logos/openlogo-nd.svg', 'https://www.debian.org/', 'debian'),
+('arch', 'Arch Linux', 'Arch Linux', 'os', 'Lightweight and flexible Linux distribution that follows the KISS principle', 'https://archlinux.org/static/logos/archlinux-logo-dark-scalable.518881f04ca9.svg', 'https://archlinux.org/', 'arch'),
+('kali', 'Kali Linux', 'Offensive Security', 'os', 'Penetration testing and security auditing Linux distribution', 'https://www.kali.org/images/kali-logo.svg', 'https://www.kali.org/', 'kali'),
+('windows', 'Windows 11', 'Microsoft', 'os', 'Latest version of Microsoft Windows operating system', 'https://img-prod-cms-rt-microsoft-com.akamaized.net/cms/api/am/imageFileData/RE4nqTh', 'https://www.microsoft.com/windows/', 'windows'),
+('chrome', 'Google Chrome', 'Google', 'app', 'Fast and secure web browser developed by Google', 'https://www.google.com/chrome/static/images/chrome-logo.svg', 'https://www.google.com/chrome/', 'chrome'),
+('firefox', 'Mozilla Firefox', 'Mozilla', 'app', 'Open-source web browser focused on privacy and customization', 'https://www.mozilla.org/media/protocol/img/logos/firefox/browser/logo.eb1324e44442.svg', 'https://www.mozilla.org/firefox/', 'firefox'),
+('brave', 'Brave Browser', 'Brave Software', 'app', 'Privacy-focused web browser that blocks ads and trackers by default', 'https://brave.com/static-assets/images/brave-logo.svg', 'https://brave.com/', 'brave'),
+('vscode', 'Visual Studio Code', 'Microsoft', 'tool', 'Lightweight and powerful source code editor with extensive extension support', 'https://code.visualstudio.com/assets/images/code-stable.png', 'https://code.visualstudio.com/', 'vscode'),
+('termius', 'Termius', 'Termius Corporation', 'tool', 'Cross-platform SSH client with synchronization capabilities', 'https://termius.com/static/uploads/2020/06/icon-512.png', 'https://termius.com/', 'termius'),
+('telegram', 'Telegram Desktop', 'Telegram FZ-LLC', 'app', 'Cloud-based messaging app focused on speed and security', 'https://telegram.org/img/t_logo.png', 'https://desktop.telegram.org/', 'telegram'),
+('whatsapp', 'WhatsApp Desktop', 'Meta', 'app', 'Desktop client for WhatsApp messaging service', 'https://static.whatsapp.net/rsrc.php/v3/yP/r/rYZqPCBaG70.png', 'https://www.whatsapp.com/download', 'whatsapp'),
+('tailscale', 'Tailscale', 'Tailscale Inc.', 'tool', 'VPN service that makes devices and applications accessible anywhere in the world', 'https://tailscale.com/kb/1017/install/tailscale-icon.png', 'https://tailscale.com/', 'tailscale'),
+('nextcloud', 'Nextcloud Desktop', 'Nextcloud GmbH', 'tool', 'Desktop sync client for Nextcloud file hosting service', 'https://nextcloud.com/wp-content/uploads/2022/04/nextcloud-logo-blue.svg', 'https://nextcloud.com/', 'nextcloud'),
+('notepadplusplus', 'Notepad++', 'Don Ho', 'tool', 'Free source code editor and Notepad replacement for Windows', 'https://notepad-plus-plus.org/images/logo.svg', 'https://notepad-plus-plus.org/', 'notepadplusplus'),
+('powershell', 'PowerShell', 'Microsoft', 'tool', 'Cross-platform task automation solution made up of a command-line shell, scripting language, and configuration management framework', 'https://raw.githubusercontent.com/PowerShell/PowerShell/master/assets/ps_black_64.svg', 'https://github.com/PowerShell/PowerShell', 'powershell');
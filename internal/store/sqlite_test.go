@@ -0,0 +1,46 @@
+package store_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/your-username/alldownloads/internal/store"
+	"github.com/your-username/alldownloads/internal/store/storetest"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		s, err := store.Open("sqlite://" + filepath.Join(t.TempDir(), "alldownloads.db"))
+		if err != nil {
+			t.Fatalf("failed to open store: %v", err)
+		}
+		return s
+	})
+}
+
+func TestSQLiteStoreReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "alldownloads.db")
+
+	s, err := store.NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	if err := s.DeleteProduct(context.Background(), "ubuntu"); err != nil {
+		t.Fatalf("DeleteProduct: %v", err)
+	}
+	s.Close()
+
+	// The seed products are only inserted into a new database.
+	s, err = store.NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	defer s.Close()
+	if p, err := s.GetProduct(context.Background(), "ubuntu"); p != nil || err != nil {
+		t.Fatalf("deleted seed product came back: %v, %v", p, err)
+	}
+	if p, _ := s.GetProduct(context.Background(), "debian"); p == nil || p.Fetcher != "debian" {
+		t.Fatalf("seed product = %+v", p)
+	}
+}
@@ -0,0 +1,94 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Store is what the API, worker and CLI need from the database. Postgres
+// is the production backend; SQLite serves single-node installs. Both are
+// held to the same behaviour by the suite in storetest.
+//
+// Getters return nil and no error when the row does not exist. Mutations
+// of a row that does not exist return ErrNotFound, and inserts that clash
+// with an existing row return ErrConflict.
+type Store interface {
+	Close()
+	Ping(ctx context.Context) error
+	// Driver names the backend, e.g. for health checks.
+	Driver() string
+	Stats() PoolStats
+
+	GetProducts(ctx context.Context) ([]Product, error)
+	ListProducts(ctx context.Context, filter ProductFilter) (*ProductPage, error)
+	GetProduct(ctx context.Context, id string) (*Product, error)
+	GetProductWithVersions(ctx context.Context, id string) (*ProductWithVersions, error)
+	GetProductsByIDs(ctx context.Context, ids []string) (map[string]*Product, error)
+	GetProductsValidator(ctx context.Context) (*Validator, error)
+	GetProductValidator(ctx context.Context, id string) (*Validator, error)
+	CreateProduct(ctx context.Context, product *Product) error
+	UpdateProduct(ctx context.Context, product *Product) error
+	DeleteProduct(ctx context.Context, id string) error
+	RecordFetchSuccess(ctx context.Context, productID string, at time.Time) (bool, error)
+	MarkProductStale(ctx context.Context, productID string, since time.Time) (bool, error)
+
+	GetProductVersions(ctx context.Context, productID string) ([]ProductVersion, error)
+	GetVersionsByProductIDs(ctx context.Context, productIDs []string) (map[string][]ProductVersion, error)
+	ListVersions(ctx context.Context, filter VersionFilter) (*VersionPage, error)
+	GetProductVersion(ctx context.Context, id string) (*ProductVersion, error)
+	CreateOrUpdateProductVersion(ctx context.Context, version *ProductVersion) (bool, error)
+	CreateManualVersion(ctx context.Context, version *ProductVersion) error
+	UpdateProductVersion(ctx context.Context, version *ProductVersion) error
+	DeleteProductVersion(ctx context.Context, id string) error
+	MarkLatestVersions(ctx context.Context, productID string) error
+
+	CreateFetchJob(ctx context.Context, job *FetchJob) error
+	UpdateFetchJob(ctx context.Context, job *FetchJob) error
+	GetFetchJob(ctx context.Context, id string) (*FetchJob, error)
+	ListFetchJobs(ctx context.Context, filter JobFilter) (*JobPage, error)
+	GetRecentFetchJobs(ctx context.Context, productIDs []string, perProduct int) (map[string][]FetchJob, error)
+	ListDeadFetchJobs(ctx context.Context, runningBefore time.Time) ([]FetchJob, error)
+	GetLastSuccessfulFetches(ctx context.Context) (map[string]time.Time, error)
+
+	CreateAPIKey(ctx context.Context, key *APIKey) error
+	GetAPIKey(ctx context.Context, id string) (*APIKey, error)
+	GetAPIKeyByPrefix(ctx context.Context, prefix string) (*APIKey, error)
+	ListAPIKeys(ctx context.Context) ([]APIKey, error)
+	RevokeAPIKey(ctx context.Context, id string) error
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
+
+	CreateAuditEntry(ctx context.Context, entry *AuditEntry) error
+	ListAuditEntries(ctx context.Context, filter AuditFilter) (*AuditPage, error)
+}
+
+var (
+	_ Store = (*PostgresStore)(nil)
+	_ Store = (*SQLiteStore)(nil)
+)
+
+// PoolStats reports connection pool usage.
+type PoolStats struct {
+	TotalConns    int32
+	AcquiredConns int32
+	IdleConns     int32
+	MaxConns      int32
+}
+
+const sqliteScheme = "sqlite:"
+
+// IsSQLiteURL reports whether databaseURL names a SQLite database, written
+// sqlite:///var/lib/alldownloads.db or sqlite:alldownloads.db.
+func IsSQLiteURL(databaseURL string) bool {
+	return strings.HasPrefix(databaseURL, sqliteScheme)
+}
+
+// Open connects to the backend databaseURL names: SQLite for sqlite: URLs
+// and Postgres otherwise.
+func Open(databaseURL string) (Store, error) {
+	if IsSQLiteURL(databaseURL) {
+		path := strings.TrimPrefix(strings.TrimPrefix(databaseURL, sqliteScheme), "//")
+		return NewSQLiteStore(path)
+	}
+	return NewPostgresStore(databaseURL)
+}
@@ -0,0 +1,1057 @@
+// Package storetest is a conformance suite for store.Store implementations.
+// Every backend runs the same tests, so behaviour the API relies on, such as
+// nil for missing rows, keyset pagination and manual versions surviving a
+// fetch, cannot drift between them.
+//
+// The suite only touches rows it creates, under IDs unique to the run, so it
+// can share a database with seed data or with earlier runs.
+package storetest
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/your-username/alldownloads/internal/store"
+)
+
+// Run runs the suite. newStore is called once per test and returns a store
+// with the schema in place; Run closes it when the test ends.
+func Run(t *testing.T, newStore func(t *testing.T) store.Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, s store.Store, id func(string) string)
+	}{
+		{"Products", testProducts},
+		{"ListProducts", testListProducts},
+		{"Validators", testValidators},
+		{"Freshness", testFreshness},
+		{"FetchedVersions", testFetchedVersions},
+		{"ManualVersions", testManualVersions},
+		{"FetchesAfterAdminEdits", testFetchesAfterAdminEdits},
+		{"ListVersions", testListVersions},
+		{"FetchJobs", testFetchJobs},
+		{"APIKeys", testAPIKeys},
+		{"AuditLog", testAuditLog},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			s := newStore(t)
+			t.Cleanup(s.Close)
+
+			run := uuid.New().String()[:8]
+			tt.fn(t, s, func(name string) string {
+				return fmt.Sprintf("storetest-%s-%s", run, name)
+			})
+		})
+	}
+}
+
+// sameTime compares timestamps at the precision Postgres keeps.
+func sameTime(a, b time.Time) bool {
+	d := a.Sub(b)
+	return d > -time.Millisecond && d < time.Millisecond
+}
+
+// tick makes sure the next timestamp a store takes differs from the last,
+// even on a backend that keeps only microseconds.
+func tick() {
+	time.Sleep(2 * time.Millisecond)
+}
+
+func mustCreateProduct(t *testing.T, s store.Store, p *store.Product) {
+	t.Helper()
+	if p.Category == "" {
+		p.Category = store.CategoryApp
+	}
+	if p.Fetcher == "" {
+		p.Fetcher = p.ID
+	}
+	if err := s.CreateProduct(context.Background(), p); err != nil {
+		t.Fatalf("CreateProduct(%s): %v", p.ID, err)
+	}
+}
+
+func fetchedVersion(productID, version, platform, arch string) *store.ProductVersion {
+	return &store.ProductVersion{
+		ProductID:    productID,
+		Version:      version,
+		Platform:     platform,
+		Architecture: arch,
+		DownloadURL:  "https://example.com/" + productID + "/" + version + "/" + platform + "-" + arch,
+		Filename:     productID + "-" + version,
+		FileSize:     1024,
+	}
+}
+
+func mustUpsertVersion(t *testing.T, s store.Store, v *store.ProductVersion) bool {
+	t.Helper()
+	inserted, err := s.CreateOrUpdateProductVersion(context.Background(), v)
+	if err != nil {
+		t.Fatalf("CreateOrUpdateProductVersion(%s %s): %v", v.ProductID, v.Version, err)
+	}
+	return inserted
+}
+
+// hide stores v, which need not exist yet, as hidden by an admin.
+func hide(t *testing.T, s store.Store, v *store.ProductVersion) {
+	t.Helper()
+	if v.ID == "" {
+		mustUpsertVersion(t, s, v)
+	}
+	stored, err := s.GetProductVersion(context.Background(), v.ID)
+	if err != nil || stored == nil {
+		t.Fatalf("GetProductVersion(%s) = %v, %v", v.ID, stored, err)
+	}
+	stored.Hidden = true
+	if err := s.UpdateProductVersion(context.Background(), stored); err != nil {
+		t.Fatalf("UpdateProductVersion: %v", err)
+	}
+}
+
+func testProducts(t *testing.T, s store.Store, id func(string) string) {
+	ctx := context.Background()
+
+	p := &store.Product{
+		ID:            id("editor"),
+		Name:          "Editor",
+		Vendor:        "Storetest",
+		Category:      store.CategoryTool,
+		Description:   "A text editor",
+		IconURL:       "https://example.com/icon.png",
+		WebsiteURL:    "https://example.com/",
+		Fetcher:       "editor",
+		MaxAgeSeconds: 3600,
+	}
+	mustCreateProduct(t, s, p)
+
+	got, err := s.GetProduct(ctx, p.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetProduct = %v, %v", got, err)
+	}
+	if got.Name != p.Name || got.Vendor != p.Vendor || got.Category != p.Category || got.Description != p.Description ||
+		got.IconURL != p.IconURL || got.WebsiteURL != p.WebsiteURL || got.Fetcher != p.Fetcher || got.MaxAgeSeconds != p.MaxAgeSeconds {
+		t.Fatalf("GetProduct = %+v, want %+v", got, p)
+	}
+	if !sameTime(got.CreatedAt, p.CreatedAt) || !sameTime(got.UpdatedAt, p.UpdatedAt) {
+		t.Fatalf("timestamps %v/%v, want %v/%v", got.CreatedAt, got.UpdatedAt, p.CreatedAt, p.UpdatedAt)
+	}
+	if got.Stale || got.LastSuccessAt != nil || got.StaleSince != nil {
+		t.Fatalf("new product has freshness state %+v", got)
+	}
+
+	if err := s.CreateProduct(ctx, &store.Product{ID: p.ID, Name: "Again", Vendor: "Storetest", Category: store.CategoryTool}); !errors.Is(err, store.ErrConflict) {
+		t.Fatalf("CreateProduct with a taken ID = %v, want ErrConflict", err)
+	}
+
+	tick()
+	p.Name = "Editor Pro"
+	p.MaxAgeSeconds = 0
+	if err := s.UpdateProduct(ctx, p); err != nil {
+		t.Fatalf("UpdateProduct: %v", err)
+	}
+	got, _ = s.GetProduct(ctx, p.ID)
+	if got.Name != "Editor Pro" || got.MaxAgeSeconds != 0 || !got.UpdatedAt.After(got.CreatedAt) {
+		t.Fatalf("after UpdateProduct = %+v", got)
+	}
+
+	missing := &store.Product{ID: id("missing"), Name: "Missing", Vendor: "Storetest", Category: store.CategoryApp}
+	if err := s.UpdateProduct(ctx, missing); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("UpdateProduct of a missing product = %v, want ErrNotFound", err)
+	}
+	if got, err := s.GetProduct(ctx, missing.ID); got != nil || err != nil {
+		t.Fatalf("GetProduct of a missing product = %v, %v, want nil, nil", got, err)
+	}
+	if got, err := s.GetProductWithVersions(ctx, missing.ID); got != nil || err != nil {
+		t.Fatalf("GetProductWithVersions of a missing product = %v, %v, want nil, nil", got, err)
+	}
+
+	other := &store.Product{ID: id("other"), Name: "Other", Vendor: "Storetest"}
+	mustCreateProduct(t, s, other)
+
+	byID, err := s.GetProductsByIDs(ctx, []string{p.ID, other.ID, missing.ID})
+	if err != nil {
+		t.Fatalf("GetProductsByIDs: %v", err)
+	}
+	if len(byID) != 2 || byID[p.ID] == nil || byID[other.ID] == nil {
+		t.Fatalf("GetProductsByIDs = %v, want %s and %s", byID, p.ID, other.ID)
+	}
+	if byID, err := s.GetProductsByIDs(ctx, nil); err != nil || len(byID) != 0 {
+		t.Fatalf("GetProductsByIDs(nil) = %v, %v", byID, err)
+	}
+
+	all, err := s.GetProducts(ctx)
+	if err != nil {
+		t.Fatalf("GetProducts: %v", err)
+	}
+	found := 0
+	for _, product := range all {
+		if product.ID == p.ID || product.ID == other.ID {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Fatalf("GetProducts returned %d of the 2 created products", found)
+	}
+
+	// Deleting a product takes its versions and jobs with it.
+	version := fetchedVersion(other.ID, "1.0", store.PlatformLinux, store.ArchAMD64)
+	mustUpsertVersion(t, s, version)
+	job := &store.FetchJob{ProductID: other.ID, Status: store.JobStatusPending}
+	if err := s.CreateFetchJob(ctx, job); err != nil {
+		t.Fatalf("CreateFetchJob: %v", err)
+	}
+	if err := s.DeleteProduct(ctx, other.ID); err != nil {
+		t.Fatalf("DeleteProduct: %v", err)
+	}
+	if got, err := s.GetProductVersion(ctx, version.ID); got != nil || err != nil {
+		t.Fatalf("version of a deleted product = %v, %v", got, err)
+	}
+	if got, err := s.GetFetchJob(ctx, job.ID); got != nil || err != nil {
+		t.Fatalf("job of a deleted product = %v, %v", got, err)
+	}
+	if err := s.DeleteProduct(ctx, other.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("DeleteProduct twice = %v, want ErrNotFound", err)
+	}
+}
+
+func testListProducts(t *testing.T, s store.Store, id func(string) string) {
+	ctx := context.Background()
+	vendor := id("vendor")
+
+	products := []*store.Product{
+		{ID: id("c"), Name: "Gamma Browser", Vendor: vendor, Category: store.CategoryApp, Description: "Browses the web"},
+		{ID: id("a"), Name: "Alpha Desktop", Vendor: vendor, Category: store.CategoryOS, Description: "A desktop environment"},
+		{ID: id("b"), Name: "Beta Shell", Vendor: vendor, Category: store.CategoryTool, Description: "Command-line shell"},
+	}
+	for _, p := range products {
+		mustCreateProduct(t, s, p)
+		tick()
+	}
+	mustUpsertVersion(t, s, fetchedVersion(id("a"), "1.0", store.PlatformLinux, store.ArchARM64))
+	hide(t, s, fetchedVersion(id("c"), "1.0", store.PlatformLinux, store.ArchARM64))
+
+	ids := func(page *store.ProductPage) []string {
+		var ids []string
+		for _, p := range page.Products {
+			ids = append(ids, p.ID)
+		}
+		return ids
+	}
+	list := func(filter store.ProductFilter) *store.ProductPage {
+		t.Helper()
+		page, err := s.ListProducts(ctx, filter)
+		if err != nil {
+			t.Fatalf("ListProducts(%+v): %v", filter, err)
+		}
+		return page
+	}
+	expect := func(filter store.ProductFilter, want ...string) {
+		t.Helper()
+		got := ids(list(filter))
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Fatalf("ListProducts(%+v) = %v, want %v", filter, got, want)
+		}
+	}
+
+	// The vendor filter ignores case.
+	expect(store.ProductFilter{Vendor: strings.ToUpper(vendor)}, id("a"), id("b"), id("c"))
+	expect(store.ProductFilter{Vendor: vendor, Sort: "-name"}, id("c"), id("b"), id("a"))
+	expect(store.ProductFilter{Vendor: vendor, Sort: "-updated_at"}, id("b"), id("a"), id("c"))
+	expect(store.ProductFilter{Vendor: vendor, Category: store.CategoryTool}, id("b"))
+	// Hidden versions do not count towards the platform filters.
+	expect(store.ProductFilter{Vendor: vendor, Platform: store.PlatformLinux, Architecture: store.ArchARM64}, id("a"))
+	expect(store.ProductFilter{Vendor: vendor, Platform: store.PlatformWindows})
+
+	// Search matches word prefixes in the name, vendor and description.
+	expect(store.ProductFilter{Vendor: vendor, Search: "alp desk"}, id("a"))
+	expect(store.ProductFilter{Vendor: vendor, Search: "COMMAND"}, id("b"))
+	expect(store.ProductFilter{Vendor: vendor, Search: "rowser"})
+	expect(store.ProductFilter{Vendor: vendor, Search: "!!"})
+
+	first := list(store.ProductFilter{Vendor: vendor, Sort: "name", Limit: 2})
+	if fmt.Sprint(ids(first)) != fmt.Sprint([]string{id("a"), id("b")}) || first.NextCursor == "" {
+		t.Fatalf("first page = %v, cursor %q", ids(first), first.NextCursor)
+	}
+	second := list(store.ProductFilter{Vendor: vendor, Sort: "name", Limit: 2, Cursor: first.NextCursor})
+	if fmt.Sprint(ids(second)) != fmt.Sprint([]string{id("c")}) || second.NextCursor != "" {
+		t.Fatalf("second page = %v, cursor %q", ids(second), second.NextCursor)
+	}
+
+	first = list(store.ProductFilter{Vendor: vendor, Sort: "-updated_at", Limit: 1})
+	second = list(store.ProductFilter{Vendor: vendor, Sort: "-updated_at", Limit: 1, Cursor: first.NextCursor})
+	if fmt.Sprint(ids(second)) != fmt.Sprint([]string{id("a")}) {
+		t.Fatalf("second page by -updated_at = %v", ids(second))
+	}
+
+	if _, err := s.ListProducts(ctx, store.ProductFilter{Sort: "price"}); !errors.Is(err, store.ErrInvalidSort) {
+		t.Fatalf("unknown sort = %v, want ErrInvalidSort", err)
+	}
+	if _, err := s.ListProducts(ctx, store.ProductFilter{Cursor: "not a cursor"}); !errors.Is(err, store.ErrInvalidCursor) {
+		t.Fatalf("bad cursor = %v, want ErrInvalidCursor", err)
+	}
+
+	// A cursor only works with the sort it was issued for, and its values
+	// are checked before they reach the database.
+	byName := list(store.ProductFilter{Vendor: vendor, Sort: "name", Limit: 1})
+	for _, filter := range []store.ProductFilter{
+		{Sort: "-updated_at", Cursor: byName.NextCursor},
+		{Sort: "-name", Cursor: byName.NextCursor},
+		{Sort: "updated_at", Cursor: forgeCursor("updated_at", "yesterday", id("a"))},
+		{Sort: "name", Cursor: forgeCursor("name", "Alpha")},
+	} {
+		if _, err := s.ListProducts(ctx, filter); !errors.Is(err, store.ErrInvalidCursor) {
+			t.Fatalf("ListProducts(%+v) = %v, want ErrInvalidCursor", filter, err)
+		}
+	}
+}
+
+// forgeCursor builds a cursor the way the stores encode them, so that tests
+// can hand them values no page would have issued.
+func forgeCursor(values ...string) string {
+	data, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func testValidators(t *testing.T, s store.Store, id func(string) string) {
+	ctx := context.Background()
+
+	before, err := s.GetProductsValidator(ctx)
+	if err != nil {
+		t.Fatalf("GetProductsValidator: %v", err)
+	}
+
+	p := &store.Product{ID: id("validated"), Name: "Validated", Vendor: "Storetest"}
+	mustCreateProduct(t, s, p)
+
+	v, err := s.GetProductValidator(ctx, p.ID)
+	if err != nil || v == nil {
+		t.Fatalf("GetProductValidator = %v, %v", v, err)
+	}
+	if v.Rows != 0 || !sameTime(v.LastModified, p.UpdatedAt) {
+		t.Fatalf("validator of a new product = %+v, want 0 rows at %v", v, p.UpdatedAt)
+	}
+
+	tick()
+	version := fetchedVersion(p.ID, "1.0", store.PlatformLinux, store.ArchAMD64)
+	mustUpsertVersion(t, s, version)
+	v, _ = s.GetProductValidator(ctx, p.ID)
+	if v.Rows != 1 || !sameTime(v.LastModified, version.UpdatedAt) {
+		t.Fatalf("validator after a version = %+v, want 1 row at %v", v, version.UpdatedAt)
+	}
+
+	// The worker marks a product stale from when its last fetch succeeded,
+	// which is long past; the validators must still move.
+	tick()
+	marked := time.Now()
+	if _, err := s.MarkProductStale(ctx, p.ID, marked.Add(-time.Hour)); err != nil {
+		t.Fatalf("MarkProductStale: %v", err)
+	}
+	v, _ = s.GetProductValidator(ctx, p.ID)
+	if v.LastModified.Before(marked.Add(-time.Millisecond)) {
+		t.Fatalf("validator after going stale = %+v, want at least %v", v, marked)
+	}
+
+	after, err := s.GetProductsValidator(ctx)
+	if err != nil {
+		t.Fatalf("GetProductsValidator: %v", err)
+	}
+	if after.Rows != before.Rows+2 || after.LastModified.Before(marked.Add(-time.Millisecond)) {
+		t.Fatalf("products validator went from %+v to %+v", before, after)
+	}
+
+	tick()
+	recovered := time.Now()
+	if _, err := s.RecordFetchSuccess(ctx, p.ID, recovered.Add(-time.Hour)); err != nil {
+		t.Fatalf("RecordFetchSuccess: %v", err)
+	}
+	if v, _ = s.GetProductValidator(ctx, p.ID); v.LastModified.Before(recovered.Add(-time.Millisecond)) {
+		t.Fatalf("validator after recovering = %+v, want at least %v", v, recovered)
+	}
+	if after, _ = s.GetProductsValidator(ctx); after.LastModified.Before(recovered.Add(-time.Millisecond)) {
+		t.Fatalf("products validator after recovering = %+v, want at least %v", after, recovered)
+	}
+
+	// Routine fetches of a fresh product leave updated_at alone; the
+	// validators only follow the new last_success_at itself.
+	current, _ := s.GetProduct(ctx, p.ID)
+	tick()
+	if _, err := s.RecordFetchSuccess(ctx, p.ID, recovered.Add(-time.Minute)); err != nil {
+		t.Fatalf("RecordFetchSuccess: %v", err)
+	}
+	if got, _ := s.GetProduct(ctx, p.ID); !sameTime(got.UpdatedAt, current.UpdatedAt) {
+		t.Fatalf("updated_at after a routine fetch = %v, want it unchanged from %v", got.UpdatedAt, current.UpdatedAt)
+	}
+	if again, _ := s.GetProductValidator(ctx, p.ID); !sameTime(again.LastModified, v.LastModified) {
+		t.Fatalf("validator after a routine fetch = %+v, want it unchanged from %+v", again, v)
+	}
+
+	if v, err := s.GetProductValidator(ctx, id("missing")); v != nil || err != nil {
+		t.Fatalf("GetProductValidator of a missing product = %v, %v, want nil, nil", v, err)
+	}
+}
+
+func testFreshness(t *testing.T, s store.Store, id func(string) string) {
+	ctx := context.Background()
+
+	p := &store.Product{ID: id("fresh"), Name: "Fresh", Vendor: "Storetest"}
+	mustCreateProduct(t, s, p)
+
+	since := time.Now().Add(-time.Hour)
+	if marked, err := s.MarkProductStale(ctx, p.ID, since); err != nil || !marked {
+		t.Fatalf("MarkProductStale = %v, %v, want true", marked, err)
+	}
+	if marked, err := s.MarkProductStale(ctx, p.ID, time.Now()); err != nil || marked {
+		t.Fatalf("MarkProductStale of a stale product = %v, %v, want false", marked, err)
+	}
+	got, _ := s.GetProduct(ctx, p.ID)
+	if !got.Stale || got.StaleSince == nil || !sameTime(*got.StaleSince, since) {
+		t.Fatalf("stale product = %+v, want stale since %v", got, since)
+	}
+
+	at := time.Now()
+	if wasStale, err := s.RecordFetchSuccess(ctx, p.ID, at); err != nil || !wasStale {
+		t.Fatalf("RecordFetchSuccess = %v, %v, want true", wasStale, err)
+	}
+	if wasStale, err := s.RecordFetchSuccess(ctx, p.ID, at); err != nil || wasStale {
+		t.Fatalf("RecordFetchSuccess of a fresh product = %v, %v, want false", wasStale, err)
+	}
+	got, _ = s.GetProduct(ctx, p.ID)
+	if got.Stale || got.StaleSince != nil || got.LastSuccessAt == nil || !sameTime(*got.LastSuccessAt, at) {
+		t.Fatalf("fresh product = %+v, want last success at %v", got, at)
+	}
+
+	if wasStale, err := s.RecordFetchSuccess(ctx, id("missing"), at); err != nil || wasStale {
+		t.Fatalf("RecordFetchSuccess of a missing product = %v, %v, want false, nil", wasStale, err)
+	}
+}
+
+func testFetchedVersions(t *testing.T, s store.Store, id func(string) string) {
+	ctx := context.Background()
+
+	p := &store.Product{ID: id("fetched"), Name: "Fetched", Vendor: "Storetest"}
+	mustCreateProduct(t, s, p)
+
+	v := fetchedVersion(p.ID, "1.0", store.PlatformLinux, store.ArchAMD64)
+	v.Checksum, v.ChecksumType, v.ETag = "abc", "sha256", `"v1"`
+	if !mustUpsertVersion(t, s, v) {
+		t.Fatal("first upsert did not report an insert")
+	}
+	got, err := s.GetProductVersion(ctx, v.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetProductVersion = %v, %v", got, err)
+	}
+	if got.Channel != store.ChannelStable || got.Source != store.VersionSourceFetcher || got.Checksum != "abc" ||
+		got.ETag != `"v1"` || got.FileSize != 1024 || got.Pinned || got.Hidden {
+		t.Fatalf("stored version = %+v", got)
+	}
+	created := got
+
+	// Fetching the same download again leaves updated_at alone.
+	tick()
+	again := fetchedVersion(p.ID, "1.0", store.PlatformLinux, store.ArchAMD64)
+	again.Checksum, again.ChecksumType, again.ETag = "abc", "sha256", `"v2"`
+	if mustUpsertVersion(t, s, again) {
+		t.Fatal("second upsert reported an insert")
+	}
+	if again.ID != v.ID {
+		t.Fatalf("upsert returned ID %s, want %s", again.ID, v.ID)
+	}
+	got, _ = s.GetProductVersion(ctx, v.ID)
+	if got.ETag != `"v2"` || !sameTime(got.UpdatedAt, created.UpdatedAt) || !got.LastFetched.After(created.LastFetched) {
+		t.Fatalf("refetched version = %+v, want updated_at %v", got, created.UpdatedAt)
+	}
+
+	// A changed download moves it.
+	tick()
+	again.Checksum = "def"
+	mustUpsertVersion(t, s, again)
+	got, _ = s.GetProductVersion(ctx, v.ID)
+	if got.Checksum != "def" || !got.UpdatedAt.After(created.UpdatedAt) {
+		t.Fatalf("changed version = %+v, want a later updated_at than %v", got, created.UpdatedAt)
+	}
+
+	// Fetches do not overwrite an admin's edit.
+	got.DownloadURL = "https://mirror.example.com/fetched.tar.gz"
+	got.Source = store.VersionSourceManual
+	if err := s.UpdateProductVersion(ctx, got); err != nil {
+		t.Fatalf("UpdateProductVersion: %v", err)
+	}
+	again.Checksum = "ghi"
+	if mustUpsertVersion(t, s, again) {
+		t.Fatal("upsert over a manual version reported an insert")
+	}
+	got, _ = s.GetProductVersion(ctx, v.ID)
+	if got.Checksum != "def" || got.DownloadURL != "https://mirror.example.com/fetched.tar.gz" {
+		t.Fatalf("fetch overwrote a manual version: %+v", got)
+	}
+
+	if got, err := s.GetProductVersion(ctx, uuid.New().String()); got != nil || err != nil {
+		t.Fatalf("GetProductVersion of a missing version = %v, %v, want nil, nil", got, err)
+	}
+	if err := s.DeleteProductVersion(ctx, v.ID); err != nil {
+		t.Fatalf("DeleteProductVersion: %v", err)
+	}
+	if err := s.DeleteProductVersion(ctx, v.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("DeleteProductVersion twice = %v, want ErrNotFound", err)
+	}
+}
+
+func testManualVersions(t *testing.T, s store.Store, id func(string) string) {
+	ctx := context.Background()
+
+	p := &store.Product{ID: id("manual"), Name: "Manual", Vendor: "Storetest"}
+	mustCreateProduct(t, s, p)
+
+	older := fetchedVersion(p.ID, "1.0", store.PlatformWindows, store.ArchAMD64)
+	if err := s.CreateManualVersion(ctx, older); err != nil {
+		t.Fatalf("CreateManualVersion: %v", err)
+	}
+	if older.Source != store.VersionSourceManual || older.Channel != store.ChannelStable {
+		t.Fatalf("manual version = %+v", older)
+	}
+	if err := s.CreateManualVersion(ctx, fetchedVersion(p.ID, "1.0", store.PlatformWindows, store.ArchAMD64)); !errors.Is(err, store.ErrConflict) {
+		t.Fatalf("duplicate CreateManualVersion = %v, want ErrConflict", err)
+	}
+
+	tick()
+	newer := fetchedVersion(p.ID, "2.0", store.PlatformWindows, store.ArchAMD64)
+	mustUpsertVersion(t, s, newer)
+	tick()
+	beta := fetchedVersion(p.ID, "3.0-beta", store.PlatformWindows, store.ArchAMD64)
+	beta.Channel = store.ChannelBeta
+	beta.Pinned = true
+	if err := s.CreateManualVersion(ctx, beta); err != nil {
+		t.Fatalf("CreateManualVersion pinned: %v", err)
+	}
+	arm := fetchedVersion(p.ID, "2.0", store.PlatformWindows, store.ArchARM64)
+	mustUpsertVersion(t, s, arm)
+
+	// Pinning one version unpins the others of the same platform and
+	// architecture, and the pin decides which version is latest.
+	older.Pinned = true
+	if err := s.UpdateProductVersion(ctx, older); err != nil {
+		t.Fatalf("UpdateProductVersion pinning: %v", err)
+	}
+	if got, _ := s.GetProductVersion(ctx, beta.ID); got.Pinned {
+		t.Fatal("pinning a version left its sibling pinned")
+	}
+
+	if err := s.MarkLatestVersions(ctx, p.ID); err != nil {
+		t.Fatalf("MarkLatestVersions: %v", err)
+	}
+	latest := map[string]bool{}
+	versions, err := s.GetProductVersions(ctx, p.ID)
+	if err != nil {
+		t.Fatalf("GetProductVersions: %v", err)
+	}
+	for _, v := range versions {
+		latest[v.Version+"/"+v.Architecture] = v.IsLatest
+	}
+	want := map[string]bool{"1.0/amd64": true, "2.0/amd64": false, "3.0-beta/amd64": false, "2.0/arm64": true}
+	if fmt.Sprint(latest) != fmt.Sprint(want) {
+		t.Fatalf("latest versions = %v, want %v", latest, want)
+	}
+	if len(versions) != 4 || !versions[0].IsLatest || !versions[1].IsLatest {
+		t.Fatalf("GetProductVersions does not list the latest versions first: %+v", versions)
+	}
+
+	// Hidden versions drop out of the public listings and cannot be latest.
+	older.Pinned = false
+	older.Hidden = true
+	if err := s.UpdateProductVersion(ctx, older); err != nil {
+		t.Fatalf("UpdateProductVersion hiding: %v", err)
+	}
+	if err := s.MarkLatestVersions(ctx, p.ID); err != nil {
+		t.Fatalf("MarkLatestVersions: %v", err)
+	}
+	if got, _ := s.GetProductVersion(ctx, older.ID); got == nil || got.IsLatest || !got.Hidden {
+		t.Fatalf("hidden version = %+v", got)
+	}
+	if got, _ := s.GetProductVersion(ctx, beta.ID); !got.IsLatest {
+		t.Fatal("the newest visible version did not become latest")
+	}
+	byProduct, err := s.GetVersionsByProductIDs(ctx, []string{p.ID, id("missing")})
+	if err != nil {
+		t.Fatalf("GetVersionsByProductIDs: %v", err)
+	}
+	if len(byProduct) != 1 || len(byProduct[p.ID]) != 3 {
+		t.Fatalf("GetVersionsByProductIDs = %v, want the 3 visible versions", byProduct)
+	}
+	withVersions, err := s.GetProductWithVersions(ctx, p.ID)
+	if err != nil || withVersions == nil || len(withVersions.Versions) != 3 {
+		t.Fatalf("GetProductWithVersions = %+v, %v", withVersions, err)
+	}
+
+	// Editing a version into the key of another is a conflict.
+	arm.Architecture = store.ArchAMD64
+	if err := s.UpdateProductVersion(ctx, arm); !errors.Is(err, store.ErrConflict) {
+		t.Fatalf("UpdateProductVersion onto an existing version = %v, want ErrConflict", err)
+	}
+	missing := fetchedVersion(p.ID, "9.9", store.PlatformLinux, store.ArchAMD64)
+	missing.ID = uuid.New().String()
+	if err := s.UpdateProductVersion(ctx, missing); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("UpdateProductVersion of a missing version = %v, want ErrNotFound", err)
+	}
+}
+
+// testFetchesAfterAdminEdits checks that the worker's next fetch leaves
+// what an admin created, edited or pinned as it was.
+func testFetchesAfterAdminEdits(t *testing.T, s store.Store, id func(string) string) {
+	ctx := context.Background()
+
+	p := &store.Product{ID: id("curated"), Name: "Curated", Vendor: "Storetest"}
+	mustCreateProduct(t, s, p)
+
+	manual := fetchedVersion(p.ID, "1.0", store.PlatformLinux, store.ArchAMD64)
+	manual.DownloadURL = "https://mirror.example.com/curated-1.0.tar.gz"
+	manual.Checksum, manual.ChecksumType = "abc", "sha256"
+	manual.Channel = store.ChannelLTS
+	if err := s.CreateManualVersion(ctx, manual); err != nil {
+		t.Fatalf("CreateManualVersion: %v", err)
+	}
+	created, err := s.GetProductVersion(ctx, manual.ID)
+	if err != nil || created == nil {
+		t.Fatalf("GetProductVersion = %v, %v", created, err)
+	}
+
+	// A fetch of the same download is neither an insert nor an update.
+	tick()
+	fetched := fetchedVersion(p.ID, "1.0", store.PlatformLinux, store.ArchAMD64)
+	fetched.Checksum, fetched.ChecksumType, fetched.ETag = "def", "sha256", `"upstream"`
+	fetched.IsLatest = true
+	if mustUpsertVersion(t, s, fetched) {
+		t.Fatal("fetch over a manual version reported an insert")
+	}
+	got, _ := s.GetProductVersion(ctx, manual.ID)
+	if got.Source != store.VersionSourceManual || got.DownloadURL != manual.DownloadURL || got.Checksum != "abc" ||
+		got.Channel != store.ChannelLTS || got.ETag != "" || got.IsLatest ||
+		!sameTime(got.UpdatedAt, created.UpdatedAt) || !sameTime(got.LastFetched, created.LastFetched) {
+		t.Fatalf("fetch changed a manual version: %+v, was %+v", got, created)
+	}
+	if versions, _ := s.GetProductVersions(ctx, p.ID); len(versions) != 1 {
+		t.Fatalf("fetch over a manual version left %d versions, want 1", len(versions))
+	}
+
+	// Nor does it bring back a version an admin hid.
+	hidden := fetchedVersion(p.ID, "0.9", store.PlatformLinux, store.ArchAMD64)
+	mustUpsertVersion(t, s, hidden)
+	stored, _ := s.GetProductVersion(ctx, hidden.ID)
+	stored.Hidden, stored.Source = true, store.VersionSourceManual
+	if err := s.UpdateProductVersion(ctx, stored); err != nil {
+		t.Fatalf("UpdateProductVersion hiding: %v", err)
+	}
+	mustUpsertVersion(t, s, fetchedVersion(p.ID, "0.9", store.PlatformLinux, store.ArchAMD64))
+	if got, _ := s.GetProductVersion(ctx, hidden.ID); !got.Hidden {
+		t.Fatal("fetch unhid a version")
+	}
+
+	// A pinned version stays latest while newer releases are fetched
+	// after it, including refetches of the pinned version itself.
+	pinned := fetchedVersion(p.ID, "2.0", store.PlatformMacOS, store.ArchARM64)
+	mustUpsertVersion(t, s, pinned)
+	stored, _ = s.GetProductVersion(ctx, pinned.ID)
+	stored.Pinned, stored.Source = true, store.VersionSourceManual
+	if err := s.UpdateProductVersion(ctx, stored); err != nil {
+		t.Fatalf("UpdateProductVersion pinning: %v", err)
+	}
+	var newest *store.ProductVersion
+	for _, version := range []string{"2.1", "3.0"} {
+		tick()
+		newest = fetchedVersion(p.ID, version, store.PlatformMacOS, store.ArchARM64)
+		mustUpsertVersion(t, s, newest)
+	}
+	mustUpsertVersion(t, s, fetchedVersion(p.ID, "2.0", store.PlatformMacOS, store.ArchARM64))
+
+	latest := func() map[string]bool {
+		t.Helper()
+		if err := s.MarkLatestVersions(ctx, p.ID); err != nil {
+			t.Fatalf("MarkLatestVersions: %v", err)
+		}
+		versions, err := s.GetProductVersions(ctx, p.ID)
+		if err != nil {
+			t.Fatalf("GetProductVersions: %v", err)
+		}
+		latest := map[string]bool{}
+		for _, v := range versions {
+			if v.IsLatest {
+				latest[v.Version+"/"+v.Platform] = true
+			}
+		}
+		return latest
+	}
+	want := map[string]bool{"1.0/linux": true, "2.0/macos": true}
+	if got := latest(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("latest versions = %v, want %v", got, want)
+	}
+	if got, _ := s.GetProductVersion(ctx, pinned.ID); !got.Pinned {
+		t.Fatal("fetch unpinned a version")
+	}
+
+	// Unpinning hands latest back to the newest release.
+	stored, _ = s.GetProductVersion(ctx, pinned.ID)
+	stored.Pinned = false
+	if err := s.UpdateProductVersion(ctx, stored); err != nil {
+		t.Fatalf("UpdateProductVersion unpinning: %v", err)
+	}
+	want = map[string]bool{"1.0/linux": true, newest.Version + "/macos": true}
+	if got := latest(); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("latest versions after unpinning = %v, want %v", got, want)
+	}
+}
+
+func testListVersions(t *testing.T, s store.Store, id func(string) string) {
+	ctx := context.Background()
+
+	p := &store.Product{ID: id("listed"), Name: "Listed", Vendor: "Storetest"}
+	mustCreateProduct(t, s, p)
+
+	start := time.Now()
+	var created []*store.ProductVersion
+	for _, spec := range []struct{ version, platform, arch, channel, checksum string }{
+		{"1.0", store.PlatformLinux, store.ArchAMD64, store.ChannelStable, "aaa"},
+		{"1.0", store.PlatformLinux, store.ArchARM64, store.ChannelStable, ""},
+		{"1.0", store.PlatformWindows, store.ArchAMD64, store.ChannelLTS, "bbb"},
+		{"2.0", store.PlatformMacOS, store.ArchARM64, store.ChannelBeta, ""},
+	} {
+		tick()
+		v := fetchedVersion(p.ID, spec.version, spec.platform, spec.arch)
+		v.Channel, v.Checksum = spec.channel, spec.checksum
+		mustUpsertVersion(t, s, v)
+		created = append(created, v)
+	}
+	hide(t, s, created[3])
+	if err := s.MarkLatestVersions(ctx, p.ID); err != nil {
+		t.Fatalf("MarkLatestVersions: %v", err)
+	}
+
+	list := func(filter store.VersionFilter) *store.VersionPage {
+		t.Helper()
+		filter.ProductID = p.ID
+		page, err := s.ListVersions(ctx, filter)
+		if err != nil {
+			t.Fatalf("ListVersions(%+v): %v", filter, err)
+		}
+		return page
+	}
+	expect := func(filter store.VersionFilter, want ...*store.ProductVersion) {
+		t.Helper()
+		var got, wantIDs []string
+		for _, v := range list(filter).Versions {
+			got = append(got, v.ID)
+		}
+		for _, v := range want {
+			wantIDs = append(wantIDs, v.ID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(wantIDs) {
+			t.Fatalf("ListVersions(%+v) = %v, want %v", filter, got, wantIDs)
+		}
+	}
+
+	// The default order is most recently updated first.
+	expect(store.VersionFilter{}, created[2], created[1], created[0])
+	expect(store.VersionFilter{IncludeHidden: true}, created[3], created[2], created[1], created[0])
+	expect(store.VersionFilter{Sort: "product"}, created[0], created[1], created[2])
+	expect(store.VersionFilter{Platform: store.PlatformLinux, Architecture: store.ArchARM64}, created[1])
+	expect(store.VersionFilter{Channel: store.ChannelLTS}, created[2])
+	expect(store.VersionFilter{LatestOnly: true, Platform: store.PlatformLinux}, created[1], created[0])
+	hasChecksum, noChecksum := true, false
+	expect(store.VersionFilter{HasChecksum: &hasChecksum}, created[2], created[0])
+	expect(store.VersionFilter{HasChecksum: &noChecksum}, created[1])
+	since := created[1].UpdatedAt.Add(-time.Millisecond)
+	expect(store.VersionFilter{UpdatedSince: &since}, created[2], created[1])
+	expect(store.VersionFilter{UpdatedSince: &start, Channel: store.ChannelBeta})
+
+	first := list(store.VersionFilter{Limit: 2})
+	if len(first.Versions) != 2 || first.NextCursor == "" {
+		t.Fatalf("first page = %d versions, cursor %q", len(first.Versions), first.NextCursor)
+	}
+	expect(store.VersionFilter{Limit: 2, Cursor: first.NextCursor}, created[0])
+
+	first = list(store.VersionFilter{Sort: "product", Limit: 1})
+	expect(store.VersionFilter{Sort: "product", Limit: 5, Cursor: first.NextCursor}, created[1], created[2])
+
+	for _, filter := range []store.VersionFilter{
+		{Cursor: first.NextCursor},
+		{Sort: "product", Cursor: forgeCursor("product", p.ID, store.PlatformLinux, store.ArchAMD64, "not-a-uuid")},
+	} {
+		if _, err := s.ListVersions(ctx, filter); !errors.Is(err, store.ErrInvalidCursor) {
+			t.Fatalf("ListVersions(%+v) = %v, want ErrInvalidCursor", filter, err)
+		}
+	}
+}
+
+func testFetchJobs(t *testing.T, s store.Store, id func(string) string) {
+	ctx := context.Background()
+
+	healthy := &store.Product{ID: id("healthy"), Name: "Healthy", Vendor: "Storetest"}
+	failing := &store.Product{ID: id("failing"), Name: "Failing", Vendor: "Storetest"}
+	stuck := &store.Product{ID: id("stuck"), Name: "Stuck", Vendor: "Storetest"}
+	for _, p := range []*store.Product{healthy, failing, stuck} {
+		mustCreateProduct(t, s, p)
+	}
+
+	createJob := func(productID string) *store.FetchJob {
+		t.Helper()
+		tick()
+		job := &store.FetchJob{ProductID: productID, Status: store.JobStatusPending}
+		if err := s.CreateFetchJob(ctx, job); err != nil {
+			t.Fatalf("CreateFetchJob: %v", err)
+		}
+		return job
+	}
+	finishJob := func(job *store.FetchJob, status, message string, startedAt time.Time) {
+		t.Helper()
+		job.Status, job.Error, job.StartedAt = status, message, &startedAt
+		if status != store.JobStatusRunning {
+			completedAt := startedAt.Add(time.Second)
+			job.CompletedAt = &completedAt
+		}
+		if err := s.UpdateFetchJob(ctx, job); err != nil {
+			t.Fatalf("UpdateFetchJob: %v", err)
+		}
+	}
+
+	now := time.Now()
+	first := createJob(healthy.ID)
+	finishJob(first, store.JobStatusFailed, "timeout", now.Add(-time.Hour))
+	second := createJob(healthy.ID)
+	finishJob(second, store.JobStatusCompleted, "", now.Add(-30*time.Minute))
+	third := createJob(healthy.ID)
+	createJob(failing.ID)
+	failed := createJob(failing.ID)
+	finishJob(failed, store.JobStatusFailed, "404", now.Add(-time.Minute))
+	running := createJob(stuck.ID)
+	finishJob(running, store.JobStatusRunning, "", now.Add(-2*time.Hour))
+
+	got, err := s.GetFetchJob(ctx, failed.ID)
+	if err != nil || got == nil {
+		t.Fatalf("GetFetchJob = %v, %v", got, err)
+	}
+	if got.Status != store.JobStatusFailed || got.Error != "404" || got.StartedAt == nil || !sameTime(*got.StartedAt, *failed.StartedAt) ||
+		got.CompletedAt == nil || !sameTime(*got.CompletedAt, *failed.CompletedAt) {
+		t.Fatalf("stored job = %+v, want %+v", got, failed)
+	}
+	if got, err := s.GetFetchJob(ctx, uuid.New().String()); got != nil || err != nil {
+		t.Fatalf("GetFetchJob of a missing job = %v, %v, want nil, nil", got, err)
+	}
+
+	page, err := s.ListFetchJobs(ctx, store.JobFilter{ProductID: healthy.ID, Limit: 2})
+	if err != nil {
+		t.Fatalf("ListFetchJobs: %v", err)
+	}
+	if len(page.Jobs) != 2 || page.Jobs[0].ID != third.ID || page.Jobs[1].ID != second.ID || page.NextCursor == "" {
+		t.Fatalf("first page of jobs = %+v", page)
+	}
+	page, err = s.ListFetchJobs(ctx, store.JobFilter{ProductID: healthy.ID, Limit: 2, Cursor: page.NextCursor})
+	if err != nil {
+		t.Fatalf("ListFetchJobs: %v", err)
+	}
+	if len(page.Jobs) != 1 || page.Jobs[0].ID != first.ID || page.NextCursor != "" {
+		t.Fatalf("second page of jobs = %+v", page)
+	}
+	for _, cursor := range []string{
+		forgeCursor("-created_at", "last week", first.ID),
+		forgeCursor("-created_at", time.Now().Format(time.RFC3339Nano), "not-a-uuid"),
+	} {
+		if _, err := s.ListFetchJobs(ctx, store.JobFilter{Cursor: cursor}); !errors.Is(err, store.ErrInvalidCursor) {
+			t.Fatalf("ListFetchJobs with a forged cursor = %v, want ErrInvalidCursor", err)
+		}
+	}
+	page, err = s.ListFetchJobs(ctx, store.JobFilter{ProductID: healthy.ID, Status: store.JobStatusCompleted})
+	if err != nil || len(page.Jobs) != 1 || page.Jobs[0].ID != second.ID {
+		t.Fatalf("completed jobs = %+v, %v", page, err)
+	}
+
+	recent, err := s.GetRecentFetchJobs(ctx, []string{healthy.ID, failing.ID, id("missing")}, 2)
+	if err != nil {
+		t.Fatalf("GetRecentFetchJobs: %v", err)
+	}
+	if len(recent) != 2 || len(recent[healthy.ID]) != 2 || recent[healthy.ID][0].ID != third.ID || recent[failing.ID][0].ID != failed.ID {
+		t.Fatalf("GetRecentFetchJobs = %+v", recent)
+	}
+	if recent, err := s.GetRecentFetchJobs(ctx, nil, 2); err != nil || len(recent) != 0 {
+		t.Fatalf("GetRecentFetchJobs(nil) = %v, %v", recent, err)
+	}
+
+	// The healthy product's failure has been superseded; the failing
+	// product's has not, and the stuck product's job has run too long.
+	dead, err := s.ListDeadFetchJobs(ctx, now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("ListDeadFetchJobs: %v", err)
+	}
+	var deadIDs []string
+	for _, job := range dead {
+		switch job.ProductID {
+		case healthy.ID, failing.ID, stuck.ID:
+			deadIDs = append(deadIDs, job.ID)
+		}
+	}
+	if fmt.Sprint(deadIDs) != fmt.Sprint([]string{failed.ID, running.ID}) {
+		t.Fatalf("dead jobs = %v, want %v", deadIDs, []string{failed.ID, running.ID})
+	}
+
+	lastSuccess, err := s.GetLastSuccessfulFetches(ctx)
+	if err != nil {
+		t.Fatalf("GetLastSuccessfulFetches: %v", err)
+	}
+	if at, ok := lastSuccess[healthy.ID]; !ok || !sameTime(at, *second.CompletedAt) {
+		t.Fatalf("last success of %s = %v, want %v", healthy.ID, at, *second.CompletedAt)
+	}
+	if _, ok := lastSuccess[failing.ID]; ok {
+		t.Fatalf("product %s never succeeded but has a last success", failing.ID)
+	}
+}
+
+func testAPIKeys(t *testing.T, s store.Store, id func(string) string) {
+	ctx := context.Background()
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	key := &store.APIKey{
+		Name:               "ci",
+		Prefix:             id("key"),
+		KeyHash:            "hash",
+		Scopes:             []string{"read", "refresh"},
+		RateLimitPerMinute: 60,
+		ExpiresAt:          &expiresAt,
+	}
+	if err := s.CreateAPIKey(ctx, key); err != nil {
+		t.Fatalf("CreateAPIKey: %v", err)
+	}
+	if err := s.CreateAPIKey(ctx, &store.APIKey{Name: "dup", Prefix: key.Prefix, KeyHash: "other"}); !errors.Is(err, store.ErrConflict) {
+		t.Fatalf("CreateAPIKey with a taken prefix = %v, want ErrConflict", err)
+	}
+
+	got, err := s.GetAPIKeyByPrefix(ctx, key.Prefix)
+	if err != nil || got == nil {
+		t.Fatalf("GetAPIKeyByPrefix = %v, %v", got, err)
+	}
+	if got.ID != key.ID || got.KeyHash != "hash" || fmt.Sprint(got.Scopes) != "[read refresh]" || got.RateLimitPerMinute != 60 ||
+		got.ExpiresAt == nil || !sameTime(*got.ExpiresAt, expiresAt) || got.LastUsedAt != nil || got.RevokedAt != nil {
+		t.Fatalf("stored key = %+v", got)
+	}
+
+	unscoped := &store.APIKey{Name: "bare", Prefix: id("bare"), KeyHash: "hash", Scopes: []string{}}
+	if err := s.CreateAPIKey(ctx, unscoped); err != nil {
+		t.Fatalf("CreateAPIKey without scopes: %v", err)
+	}
+	if got, _ := s.GetAPIKey(ctx, unscoped.ID); got == nil || len(got.Scopes) != 0 || got.ExpiresAt != nil {
+		t.Fatalf("key without scopes = %+v", got)
+	}
+
+	usedAt := time.Now()
+	if err := s.TouchAPIKey(ctx, key.ID, usedAt); err != nil {
+		t.Fatalf("TouchAPIKey: %v", err)
+	}
+	if err := s.RevokeAPIKey(ctx, key.ID); err != nil {
+		t.Fatalf("RevokeAPIKey: %v", err)
+	}
+	if err := s.RevokeAPIKey(ctx, key.ID); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("RevokeAPIKey twice = %v, want ErrNotFound", err)
+	}
+	got, _ = s.GetAPIKey(ctx, key.ID)
+	if got.LastUsedAt == nil || !sameTime(*got.LastUsedAt, usedAt) || got.RevokedAt == nil {
+		t.Fatalf("used and revoked key = %+v", got)
+	}
+
+	keys, err := s.ListAPIKeys(ctx)
+	if err != nil {
+		t.Fatalf("ListAPIKeys: %v", err)
+	}
+	found := 0
+	for _, k := range keys {
+		if k.ID == key.ID || k.ID == unscoped.ID {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Fatalf("ListAPIKeys returned %d of the 2 created keys", found)
+	}
+
+	if got, err := s.GetAPIKey(ctx, uuid.New().String()); got != nil || err != nil {
+		t.Fatalf("GetAPIKey of a missing key = %v, %v, want nil, nil", got, err)
+	}
+	if got, err := s.GetAPIKeyByPrefix(ctx, id("missing")); got != nil || err != nil {
+		t.Fatalf("GetAPIKeyByPrefix of a missing key = %v, %v, want nil, nil", got, err)
+	}
+}
+
+func testAuditLog(t *testing.T, s store.Store, id func(string) string) {
+	ctx := context.Background()
+	requestID := id("request")
+
+	start := time.Now()
+	var entries []*store.AuditEntry
+	for i, action := range []string{"product.create", "product.update", "product.delete"} {
+		tick()
+		entry := &store.AuditEntry{
+			ActorType:  "api_key",
+			ActorID:    id("actor"),
+			ActorName:  "ci",
+			Action:     action,
+			TargetType: "product",
+			TargetID:   fmt.Sprintf("p%d", i),
+			RequestID:  requestID,
+			ClientIP:   "192.0.2.1",
+		}
+		if i == 1 {
+			entry.Changes = map[string]store.AuditChange{"name": {Before: "Old", After: "New"}}
+		}
+		if err := s.CreateAuditEntry(ctx, entry); err != nil {
+			t.Fatalf("CreateAuditEntry: %v", err)
+		}
+		if entry.ID == 0 {
+			t.Fatal("CreateAuditEntry did not set the ID")
+		}
+		entries = append(entries, entry)
+	}
+
+	list := func(filter store.AuditFilter) *store.AuditPage {
+		t.Helper()
+		page, err := s.ListAuditEntries(ctx, filter)
+		if err != nil {
+			t.Fatalf("ListAuditEntries(%+v): %v", filter, err)
+		}
+		return page
+	}
+
+	page := list(store.AuditFilter{RequestID: requestID, Limit: 2})
+	if len(page.Entries) != 2 || page.Entries[0].ID != entries[2].ID || page.Entries[1].ID != entries[1].ID || page.NextCursor == "" {
+		t.Fatalf("first page of the audit log = %+v", page)
+	}
+	updated := page.Entries[1]
+	if updated.ActorName != "ci" || updated.ClientIP != "192.0.2.1" || !sameTime(updated.OccurredAt, entries[1].OccurredAt) ||
+		fmt.Sprint(updated.Changes) != fmt.Sprint(map[string]store.AuditChange{"name": {Before: "Old", After: "New"}}) {
+		t.Fatalf("stored audit entry = %+v", updated)
+	}
+	page = list(store.AuditFilter{RequestID: requestID, Limit: 2, Cursor: page.NextCursor})
+	if len(page.Entries) != 1 || page.Entries[0].ID != entries[0].ID || page.NextCursor != "" {
+		t.Fatalf("second page of the audit log = %+v", page)
+	}
+	if page.Entries[0].Changes == nil {
+		t.Fatal("an entry without changes came back with nil changes")
+	}
+
+	page = list(store.AuditFilter{ActorID: id("actor"), Action: "product.delete", TargetType: "product", TargetID: "p2"})
+	if len(page.Entries) != 1 || page.Entries[0].ID != entries[2].ID {
+		t.Fatalf("filtered audit log = %+v", page)
+	}
+	until := entries[1].OccurredAt.Add(-time.Millisecond)
+	page = list(store.AuditFilter{RequestID: requestID, Since: &start, Until: &until})
+	if len(page.Entries) != 1 || page.Entries[0].ID != entries[0].ID {
+		t.Fatalf("audit log between %v and %v = %+v", start, until, page)
+	}
+
+	if _, err := s.ListAuditEntries(ctx, store.AuditFilter{Cursor: "bad"}); !errors.Is(err, store.ErrInvalidCursor) {
+		t.Fatalf("bad cursor = %v, want ErrInvalidCursor", err)
+	}
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/your-username/alldownloads/internal/tracing"
+)
+
+// queryTracer records a client span for every statement sent to Postgres,
+// including those run inside transactions.
+type queryTracer struct{}
+
+func (queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	operation := queryOperation(data.SQL)
+
+	ctx, _ = tracing.Tracer().Start(ctx, operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemPostgreSQL,
+			semconv.DBOperationName(operation),
+			semconv.DBQueryText(data.SQL),
+		),
+	)
+	return ctx
+}
+
+func (queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	span := trace.SpanFromContext(ctx)
+	if data.Err == nil {
+		span.SetAttributes(attribute.Int64("db.rows_affected", data.CommandTag.RowsAffected()))
+	}
+	tracing.End(span, data.Err)
+}
+
+// queryOperation returns the statement's leading keyword, e.g. SELECT, as
+// a low-cardinality span name.
+func queryOperation(sql string) string {
+	fields := strings.Fields(sql)
+	if len(fields) == 0 {
+		return "postgresql"
+	}
+	return strings.ToUpper(fields[0])
+}
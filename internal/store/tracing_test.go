@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/your-username/alldownloads/internal/tracing"
+	"github.com/your-username/alldownloads/internal/tracing/tracingtest"
+)
+
+func TestQueryTracer(t *testing.T) {
+	exporter := tracingtest.NewRecorder(t)
+	tracer := queryTracer{}
+
+	ctx, parent := tracing.Tracer().Start(context.Background(), "process fetch_jobs")
+
+	queryCtx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "\n\t\tupdate fetch_jobs SET status = $1 WHERE id = $2"})
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{CommandTag: pgconn.NewCommandTag("UPDATE 1")})
+
+	queryCtx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT id FROM products"})
+	tracer.TraceQueryEnd(queryCtx, nil, pgx.TraceQueryEndData{Err: errors.New("connection reset")})
+	parent.End()
+
+	update, ok := tracingtest.Find(exporter, "UPDATE")
+	if !ok {
+		t.Fatal("no UPDATE span recorded")
+	}
+	if update.SpanKind != trace.SpanKindClient || update.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Fatal("query span is not a client span under the job span")
+	}
+	attributes := map[string]string{}
+	for _, kv := range update.Attributes {
+		attributes[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attributes["db.system"] != "postgresql" || attributes["db.rows_affected"] != "1" {
+		t.Fatalf("unexpected attributes %v", attributes)
+	}
+
+	selectSpan, ok := tracingtest.Find(exporter, "SELECT")
+	if !ok {
+		t.Fatal("no SELECT span recorded")
+	}
+	if selectSpan.Status.Code != codes.Error || len(selectSpan.Events) == 0 {
+		t.Fatal("failed query did not record its error")
+	}
+}
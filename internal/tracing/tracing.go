@@ -0,0 +1,119 @@
+// Package tracing sets up OpenTelemetry for the API and the worker, and
+// carries trace context across the job queue.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/your-username/alldownloads/internal/version"
+)
+
+const instrumentationName = "github.com/your-username/alldownloads"
+
+type Config struct {
+	// Endpoint is the base URL of an OTLP/HTTP collector, e.g.
+	// http://otel-collector:4318. Spans are not exported when it is empty,
+	// but trace context is still propagated.
+	Endpoint    string
+	ServiceName string
+	// SampleRatio is the share of new traces recorded. Traces started
+	// upstream follow the caller's sampling decision.
+	SampleRatio float64
+}
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. The returned function flushes buffered spans and should be
+// called on shutdown.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	endpoint, err := url.JoinPath(cfg.Endpoint, "v1/traces")
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OTLP endpoint: %w", err)
+	}
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	// Attributes from OTEL_RESOURCE_ATTRIBUTES and OTEL_SERVICE_NAME are
+	// detected last and override the defaults.
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			semconv.ServiceName(cfg.ServiceName),
+			semconv.ServiceVersion(version.Get().Version),
+		),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tracing resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer that the application's spans are started with.
+// It is looked up on every call so that providers installed later, such as
+// in tests, take effect.
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// Inject returns the trace context of ctx in a form that can travel with a
+// message, or nil when ctx carries none.
+func Inject(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	if len(carrier) == 0 {
+		return nil
+	}
+	return carrier
+}
+
+// Extract returns ctx with the trace context that Inject stored in carrier.
+func Extract(ctx context.Context, carrier map[string]string) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.MapCarrier(carrier))
+}
+
+// End records err on span, if any, and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// TraceID returns the ID of the trace ctx belongs to, for correlating logs,
+// or "" when there is none.
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.HasTraceID() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
@@ -0,0 +1,45 @@
+// Package tracingtest records the spans of a test in memory.
+package tracingtest
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// NewRecorder installs a global tracer provider that keeps every span in
+// memory and the W3C propagator. Both are restored when the test ends, so
+// tests using it must not run in parallel.
+func NewRecorder(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	previousProvider := otel.GetTracerProvider()
+	previousPropagator := otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	t.Cleanup(func() {
+		_ = provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	return exporter
+}
+
+// Find returns the first ended span named name.
+func Find(exporter *tracetest.InMemoryExporter, name string) (tracetest.SpanStub, bool) {
+	for _, span := range exporter.GetSpans() {
+		if span.Name == name {
+			return span, true
+		}
+	}
+	return tracetest.SpanStub{}, false
+}
@@ -0,0 +1,53 @@
+// Package version identifies the running build.
+package version
+
+import (
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// Version is stamped from the VERSION file at build time:
+//
+//	go build -ldflags "-X github.com/your-username/alldownloads/internal/version.Version=$(cat VERSION)"
+var Version string
+
+type Info struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit,omitempty"`
+}
+
+// Get reports the build's version. Without the linker flag it falls back to
+// a VERSION file in the working directory, as when running from a checkout,
+// and then to the module version recorded by the Go toolchain.
+func Get() Info {
+	return get()
+}
+
+var get = sync.OnceValue(func() Info {
+	info := Info{Version: Version}
+
+	build, ok := debug.ReadBuildInfo()
+	if ok {
+		for _, setting := range build.Settings {
+			if setting.Key == "vcs.revision" {
+				info.Commit = setting.Value
+			}
+		}
+	}
+
+	if info.Version == "" {
+		if data, err := os.ReadFile("VERSION"); err == nil {
+			info.Version = strings.TrimSpace(string(data))
+		}
+	}
+	if info.Version == "" && ok && build.Main.Version != "(devel)" {
+		info.Version = strings.TrimPrefix(build.Main.Version, "v")
+	}
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+
+	return info
+})
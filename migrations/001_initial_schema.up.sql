@@ -1,7 +1,7 @@
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
 
 CREATE TABLE products (
-    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    id VARCHAR(255) PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
     vendor VARCHAR(255) NOT NULL,
     category VARCHAR(50) NOT NULL,
@@ -14,7 +14,7 @@ CREATE TABLE products (
 
 CREATE TABLE product_versions (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    product_id VARCHAR(255) NOT NULL REFERENCES products(id) ON DELETE CASCADE,
     version VARCHAR(255) NOT NULL,
     platform VARCHAR(50) NOT NULL,
     architecture VARCHAR(50) NOT NULL,
@@ -33,7 +33,7 @@ CREATE TABLE product_versions (
 
 CREATE TABLE fetch_jobs (
     id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
-    product_id UUID NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    product_id VARCHAR(255) NOT NULL REFERENCES products(id) ON DELETE CASCADE,
     status VARCHAR(50) NOT NULL DEFAULT 'pending',
     started_at TIMESTAMP WITH TIME ZONE,
     completed_at TIMESTAMP WITH TIME ZONE,
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_product_versions_product_platform_arch;
+DROP INDEX IF EXISTS idx_products_updated_at_id;
+DROP INDEX IF EXISTS idx_products_name_id;
+DROP INDEX IF EXISTS idx_products_vendor_lower;
+DROP INDEX IF EXISTS idx_products_search;
@@ -0,0 +1,6 @@
+CREATE INDEX IF NOT EXISTS idx_products_search ON products
+    USING GIN (to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(vendor, '') || ' ' || coalesce(description, '')));
+CREATE INDEX IF NOT EXISTS idx_products_vendor_lower ON products(lower(vendor));
+CREATE INDEX IF NOT EXISTS idx_products_name_id ON products(name, id);
+CREATE INDEX IF NOT EXISTS idx_products_updated_at_id ON products(updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_product_versions_product_platform_arch ON product_versions(product_id, platform, architecture);
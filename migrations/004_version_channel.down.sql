@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_product_versions_channel;
+DROP INDEX IF EXISTS idx_product_versions_platform_arch;
+DROP INDEX IF EXISTS idx_product_versions_updated_at_id;
+
+ALTER TABLE product_versions DROP COLUMN IF EXISTS channel;
@@ -0,0 +1,5 @@
+ALTER TABLE product_versions ADD COLUMN IF NOT EXISTS channel VARCHAR(50) NOT NULL DEFAULT 'stable';
+
+CREATE INDEX IF NOT EXISTS idx_product_versions_updated_at_id ON product_versions(updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_product_versions_platform_arch ON product_versions(platform, architecture);
+CREATE INDEX IF NOT EXISTS idx_product_versions_channel ON product_versions(channel);
@@ -0,0 +1,3 @@
+ALTER TABLE products ADD COLUMN IF NOT EXISTS fetcher VARCHAR(100) NOT NULL DEFAULT '';
+
+UPDATE products SET fetcher = id WHERE fetcher = '';
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_product_versions_one_pin;
+
+ALTER TABLE product_versions DROP COLUMN IF EXISTS hidden;
+ALTER TABLE product_versions DROP COLUMN IF EXISTS pinned;
+ALTER TABLE product_versions DROP COLUMN IF EXISTS source;
@@ -0,0 +1,6 @@
+ALTER TABLE product_versions ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'fetcher';
+ALTER TABLE product_versions ADD COLUMN IF NOT EXISTS pinned BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE product_versions ADD COLUMN IF NOT EXISTS hidden BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_versions_one_pin
+    ON product_versions(product_id, platform, architecture) WHERE pinned;
@@ -0,0 +1,14 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    prefix VARCHAR(32) NOT NULL UNIQUE,
+    key_hash VARCHAR(128) NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    rate_limit_per_minute INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_created_at ON api_keys(created_at);
@@ -0,0 +1,4 @@
+ALTER TABLE products DROP COLUMN IF EXISTS stale_since;
+ALTER TABLE products DROP COLUMN IF EXISTS stale;
+ALTER TABLE products DROP COLUMN IF EXISTS last_success_at;
+ALTER TABLE products DROP COLUMN IF EXISTS max_age_seconds;
@@ -0,0 +1,12 @@
+-- Freshness SLO: the worker marks a product stale when its last successful
+-- fetch is older than max_age_seconds (0 uses FRESHNESS_MAX_AGE).
+ALTER TABLE products ADD COLUMN IF NOT EXISTS max_age_seconds INTEGER NOT NULL DEFAULT 0 CHECK (max_age_seconds >= 0);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS last_success_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS stale BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE products ADD COLUMN IF NOT EXISTS stale_since TIMESTAMP WITH TIME ZONE;
+
+UPDATE products p SET last_success_at = (
+    SELECT max(j.completed_at)
+    FROM fetch_jobs j
+    WHERE j.product_id = p.id AND j.status = 'completed'
+);
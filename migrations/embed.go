@@ -0,0 +1,8 @@
+// Package migrations holds the database schema as numbered SQL scripts and
+// embeds them, so that every binary carries the schema it was built for.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -1,7 +1,7 @@
 'use client';
 
 import { motion } from 'framer-motion';
-import { ExternalLink, Download, Copy, Clock, Package } from 'lucide-react';
+import { ExternalLink, Download, Copy, Clock, Package, AlertTriangle } from 'lucide-react';
 import { Product, ProductVersion } from '@/types';
 import { Button } from '@/components/ui/button';
 import { Card, CardContent, CardDescription, CardHeader, CardTitle } from '@/components/ui/card';
@@ -26,9 +26,11 @@ export function ProductCard({ product, versions = [], onViewDetails }: ProductCa
   const [copied, setCopied] = useState<string | null>(null);
 
   const latestVersions = versions.filter(v => v.is_latest);
-  const lastUpdated = versions.length > 0
-    ? Math.max(...versions.map(v => new Date(v.last_fetched).getTime()))
-    : new Date(product.updated_at).getTime();
+  const lastUpdated = product.last_success_at
+    ? new Date(product.last_success_at).getTime()
+    : versions.length > 0
+      ? Math.max(...versions.map(v => new Date(v.last_fetched).getTime()))
+      : new Date(product.updated_at).getTime();
 
   const handleCopy = async (text: string, type: string) => {
     try {
@@ -85,12 +87,15 @@ export function ProductCard({ product, versions = [], onViewDetails }: ProductCa
               </div>
             </div>
             <motion.div
-              className={`text-xs px-2 py-1 rounded-full ${getFreshnessColor(new Date(lastUpdated).toISOString())}`}
+              className={`text-xs px-2 py-1 rounded-full ${product.stale ? 'bg-red-500/20 text-red-400' : getFreshnessColor(new Date(lastUpdated).toISOString())}`}
               initial={{ scale: 0 }}
               animate={{ scale: 1 }}
               transition={{ delay: 0.3 }}
+              title={product.stale ? 'Updates are failing; this data may be out of date' : undefined}
             >
-              <Clock className="w-3 h-3 inline mr-1" />
+              {product.stale
+                ? <AlertTriangle className="w-3 h-3 inline mr-1" />
+                : <Clock className="w-3 h-3 inline mr-1" />}
               {getTimeAgo(new Date(lastUpdated).toISOString())}
             </motion.div>
           </div>
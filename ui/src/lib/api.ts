@@ -1,9 +1,16 @@
-import { Product, ProductWithVersions, ProductsResponse } from '@/types';
+import { JobEvent, JobEventType, Product, ProductQuery, ProductWithVersions, ProductsResponse } from '@/types';
 
 const API_BASE_URL = process.env.NEXT_PUBLIC_API_BASE_URL || process.env.API_BASE_URL || 'http://localhost:8080';
 
+// Error responses are RFC 7807 problems; code is stable across releases.
+interface Problem {
+  code?: string;
+  detail?: string;
+  request_id?: string;
+}
+
 class APIError extends Error {
-  constructor(public status: number, message: string) {
+  constructor(public status: number, message: string, public code?: string, public requestId?: string) {
     super(message);
     this.name = 'APIError';
   }
@@ -21,23 +28,40 @@ async function fetchAPI<T>(endpoint: string, options: RequestInit = {}): Promise
   });
 
   if (!response.ok) {
-    throw new APIError(response.status, `API Error: ${response.status} ${response.statusText}`);
+    const problem: Problem = await response.json().catch(() => ({}));
+    throw new APIError(
+      response.status,
+      problem.detail || `API Error: ${response.status} ${response.statusText}`,
+      problem.code,
+      problem.request_id,
+    );
   }
 
   return response.json();
 }
 
-export async function getProducts(): Promise<Product[]> {
-  const response = await fetchAPI<ProductsResponse>('/api/products');
+export async function getProducts(query: ProductQuery = {}): Promise<Product[]> {
+  const response = await getProductsPage(query);
   return response.products;
 }
 
+export async function getProductsPage(query: ProductQuery = {}): Promise<ProductsResponse> {
+  const params = new URLSearchParams();
+  Object.entries(query).forEach(([key, value]) => {
+    if (value !== undefined && value !== '') {
+      params.set(key, String(value));
+    }
+  });
+  const qs = params.toString();
+  return fetchAPI<ProductsResponse>(`/api/v1/products${qs ? `?${qs}` : ''}`);
+}
+
 export async function getProduct(id: string): Promise<ProductWithVersions> {
-  return fetchAPI<ProductWithVersions>(`/api/products/${id}`);
+  return fetchAPI<ProductWithVersions>(`/api/v1/products/${id}`);
 }
 
 export async function refreshProducts(authToken: string): Promise<{ message: string; jobs_queued: number }> {
-  return fetchAPI('/api/refresh', {
+  return fetchAPI('/api/v1/refresh', {
     method: 'POST',
     headers: {
       'Authorization': `Bearer ${authToken}`,
@@ -45,6 +69,24 @@ export async function refreshProducts(authToken: string): Promise<{ message: str
   });
 }
 
+const jobEventTypes: JobEventType[] = ['job.queued', 'job.running', 'job.completed', 'job.failed', 'version.new', 'product.stale', 'product.fresh'];
+
+// subscribeToEvents listens to /api/v1/events. EventSource reconnects on its own
+// and sends Last-Event-ID, so no events are missed across short drops.
+// Returns a function that closes the stream.
+export function subscribeToEvents(onEvent: (event: JobEvent) => void, productId?: string): () => void {
+  const qs = productId ? `?product_id=${encodeURIComponent(productId)}` : '';
+  const source = new EventSource(`${API_BASE_URL}/api/v1/events${qs}`, { withCredentials: true });
+
+  jobEventTypes.forEach((type) => {
+    source.addEventListener(type, (message) => {
+      onEvent(JSON.parse((message as MessageEvent).data) as JobEvent);
+    });
+  });
+
+  return () => source.close();
+}
+
 export async function getHealthCheck(): Promise<{ status: string; timestamp: string; version: string }> {
-  return fetchAPI('/api/health');
+  return fetchAPI('/api/v1/health');
 }
\ No newline at end of file
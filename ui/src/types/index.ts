@@ -6,6 +6,11 @@ export interface Product {
   description: string;
   icon_url: string;
   website_url: string;
+  fetcher: string;
+  max_age_seconds: number;
+  last_success_at: string | null;
+  stale: boolean;
+  stale_since?: string;
   created_at: string;
   updated_at: string;
 }
@@ -16,12 +21,16 @@ export interface ProductVersion {
   version: string;
   platform: string;
   architecture: string;
+  channel: string;
   download_url: string;
   checksum: string;
   checksum_type: string;
   file_size: number;
   filename: string;
   is_latest: boolean;
+  source: 'fetcher' | 'manual';
+  pinned: boolean;
+  hidden: boolean;
   etag: string;
   last_fetched: string;
   created_at: string;
@@ -52,6 +61,34 @@ export interface APIResponse<T> {
 
 export interface ProductsResponse {
   products: Product[];
+  next_cursor?: string;
+}
+
+export interface ProductQuery {
+  category?: string;
+  vendor?: string;
+  platform?: string;
+  architecture?: string;
+  q?: string;
+  sort?: string;
+  limit?: number;
+  cursor?: string;
+}
+
+export type JobEventType = 'job.queued' | 'job.running' | 'job.completed' | 'job.failed' | 'version.new' | 'product.stale' | 'product.fresh';
+
+export interface JobEvent {
+  id: string;
+  type: JobEventType;
+  at: string;
+  job_id?: string;
+  product_id?: string;
+  version?: string;
+  platform?: string;
+  architecture?: string;
+  versions?: number;
+  new_versions?: number;
+  error?: string;
 }
 
 export type Category = 'os' | 'app' | 'tool';